@@ -0,0 +1,186 @@
+// Command all-in-one runs service A and service B in one process, for
+// local development and small deployments. Service A's proxy hands lookups
+// to service B's router in memory instead of over HTTP; production keeps
+// the split binaries, which have the features left out here (discovery,
+// canary and shadow traffic, caches on disk, async lookups, integrations
+// and the admin endpoints).
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+
+	"github.com/offerni/weathercheck/internal/cache"
+	"github.com/offerni/weathercheck/internal/cep"
+	"github.com/offerni/weathercheck/internal/httpapi"
+	"github.com/offerni/weathercheck/internal/ibge"
+	"github.com/offerni/weathercheck/internal/mock"
+	"github.com/offerni/weathercheck/internal/telemetry"
+	"github.com/offerni/weathercheck/internal/temperature"
+	"github.com/offerni/weathercheck/internal/weather"
+)
+
+// addr is service A's port, the one clients know
+const addr = ":8080"
+
+// compressionLevel matches service A's
+const compressionLevel = 5
+
+// serviceBURL is where service A's proxy believes service B is; requests
+// to it never leave the process
+var serviceBURL = &url.URL{Scheme: "http", Host: "service-b"}
+
+type config struct {
+	ProviderMode   string
+	ViaCEPURL      string
+	WeatherAPIURL  string
+	WeatherAPIKey  string
+	Precision      int
+	HandlerTimeout time.Duration
+	CEPFallback    bool
+	Readings       cache.Options
+}
+
+func loadConfig() config {
+	precision := 2
+	if v := os.Getenv("TEMPERATURE_PRECISION"); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil || p < 0 || p > temperature.MaxPrecision {
+			log.Fatalf("Invalid TEMPERATURE_PRECISION %q (expected 0-%d)", v, temperature.MaxPrecision)
+		}
+		precision = p
+	}
+	handlerTimeout, err := time.ParseDuration(envOr("HANDLER_TIMEOUT", "10s"))
+	if err != nil || handlerTimeout < 0 {
+		log.Fatalf("Invalid HANDLER_TIMEOUT %q", os.Getenv("HANDLER_TIMEOUT"))
+	}
+	readings, err := cache.FromEnv("READINGS", 10000)
+	if err != nil {
+		log.Fatalf("Invalid readings settings: %v", err)
+	}
+
+	return config{
+		ProviderMode:   envOr("PROVIDER_MODE", "live"),
+		ViaCEPURL:      envOr("VIACEP_BASE_URL", cep.DefaultBaseURL),
+		WeatherAPIURL:  envOr("WEATHER_API_BASE_URL", weather.DefaultBaseURL),
+		WeatherAPIKey:  os.Getenv("WEATHER_API_KEY"),
+		Precision:      precision,
+		HandlerTimeout: handlerTimeout,
+		CEPFallback:    os.Getenv("CEP_PREFIX_FALLBACK") == "true",
+		Readings:       readings,
+	}
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// inProcessEndpoint is service B as service A's proxy sees it: always the
+// one endpoint, and never down
+type inProcessEndpoint struct{}
+
+func (inProcessEndpoint) Next() (*url.URL, error) { return serviceBURL, nil }
+func (inProcessEndpoint) MarkDown(*url.URL)       {}
+
+// newServiceB builds service B's lookup routes
+func newServiceB(cfg config, logger *log.Logger) http.Handler {
+	tracer := otel.Tracer("service-b")
+	var (
+		cepResolver          httpapi.CEPResolver
+		municipalityResolver httpapi.MunicipalityResolver
+		weatherProvider      httpapi.WeatherProvider
+	)
+	switch cfg.ProviderMode {
+	case "live":
+		client := &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
+		cepResolver = cep.NewClient(client, cfg.ViaCEPURL, tracer)
+		municipalityResolver = ibge.NewClient(client, tracer)
+		weatherProvider = weather.NewClient(client, cfg.WeatherAPIURL, cfg.WeatherAPIKey, tracer)
+	case "mock":
+		cepResolver, municipalityResolver, weatherProvider = mock.NewCEPClient(tracer), mock.NewIBGEClient(tracer), mock.NewWeatherClient(tracer)
+	default:
+		log.Fatalf("Unknown PROVIDER_MODE %q (expected live or mock)", cfg.ProviderMode)
+	}
+	if cfg.CEPFallback {
+		cepResolver = cep.NewPrefixFallback(cepResolver)
+	}
+
+	readings := httpapi.NewReadings(cfg.Readings, 0)
+	handler := httpapi.NewWeatherHandler(cepResolver, municipalityResolver, weatherProvider, readings, cfg.Precision, tracer, logger)
+	summaryHandler := httpapi.NewSummaryHandler(cepResolver, weatherProvider, tracer, logger)
+
+	r := chi.NewRouter()
+	r.NotFound(httpapi.NotFound)
+	r.Use(func(next http.Handler) http.Handler {
+		return otelhttp.NewHandler(next, "service-b")
+	})
+	r.Use(httpapi.Recoverer(logger))
+
+	r.Method(http.MethodPost, "/weather", httpapi.Timeout(cfg.HandlerTimeout, logger)(handler))
+	r.Get("/summary", summaryHandler.ServeCity)
+	r.Method(http.MethodGet, "/summary/{cep}", summaryHandler)
+	r.Method(http.MethodGet, "/ddd/{ddd}", httpapi.NewDDDHandler(weatherProvider, cfg.Precision, tracer, logger))
+	r.Get("/health", httpapi.Health)
+	return r
+}
+
+// newServiceA builds service A's routes in front of serviceB
+func newServiceA(serviceB http.Handler, logger *log.Logger, metrics http.Handler) http.Handler {
+	tracer := otel.Tracer("service-a")
+	proxy := httpapi.NewServiceBProxy(inProcessEndpoint{}, httpapi.NewInProcessTransport(serviceB), logger)
+
+	r := chi.NewRouter()
+	r.NotFound(httpapi.NotFound)
+	r.Use(func(next http.Handler) http.Handler {
+		return otelhttp.NewHandler(next, "service-a")
+	})
+	r.Use(httpapi.TraceID)
+	r.Use(httpapi.AccessLog(os.Stdout))
+	r.Use(httpapi.Recoverer(logger))
+	r.Use(middleware.Compress(compressionLevel,
+		httpapi.FormatJSON, httpapi.FormatXML, httpapi.FormatMsgPack,
+	))
+
+	weatherHandler := httpapi.NewValidationHandler(httpapi.NewCoalescer(proxy), tracer)
+	r.With(httpapi.V2).Method(http.MethodPost, "/weather", weatherHandler)
+	r.With(httpapi.V2).Method(http.MethodPost, "/v2/weather", weatherHandler)
+	r.Method(http.MethodGet, "/summary", proxy)
+	r.Method(http.MethodGet, "/summary/{cep}", proxy)
+	r.Method(http.MethodGet, "/ddd/{ddd}", proxy)
+
+	r.Get("/health", httpapi.Health)
+	r.Method(http.MethodGet, "/metrics", metrics)
+	return r
+}
+
+func main() {
+	// One trace per lookup, with service A's and service B's spans in it
+	shutdown := telemetry.InitTracer("all-in-one")
+	defer shutdown()
+
+	metrics, shutdownMetrics := telemetry.InitMeter("all-in-one")
+	defer shutdownMetrics()
+
+	cfg := loadConfig()
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+
+	r := newServiceA(newServiceB(cfg, logger), logger, metrics)
+
+	fmt.Println("All-in-one starting on port 8080")
+	if err := httpapi.ListenAndServe(httpapi.ServerConfig{Addr: addr}, r, r, logger); err != nil {
+		log.Fatal(err)
+	}
+}
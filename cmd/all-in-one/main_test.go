@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/offerni/weathercheck/internal/cache"
+	"github.com/offerni/weathercheck/internal/httpapi"
+	"github.com/offerni/weathercheck/internal/mock"
+)
+
+func TestAllInOne(t *testing.T) {
+	cfg := config{ProviderMode: "mock", Precision: 1, HandlerTimeout: time.Second, Readings: cache.Options{MaxEntries: 10}}
+	logger := log.New(io.Discard, "", 0)
+	srv := httptest.NewServer(newServiceA(newServiceB(cfg, logger), logger, http.NotFoundHandler()))
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{"lookup", "/weather", `{"cep":"01001000"}`, http.StatusOK, ""},
+		{"v2 lookup", "/v2/weather", `{"cep":"01001000"}`, http.StatusOK, ""},
+		{"invalid CEP", "/weather", `{"cep":"123"}`, http.StatusUnprocessableEntity, "invalid_zipcode"},
+		{"unknown CEP", "/weather", `{"cep":"` + mock.NotFoundCEP + `"}`, http.StatusNotFound, "zipcode_not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(srv.URL+tt.path, httpapi.FormatJSON, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("Post: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, body)
+			}
+
+			var got struct {
+				City string `json:"city"`
+				Code string `json:"code"`
+				Data *struct {
+					City string `json:"city"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(body, &got); err != nil {
+				t.Fatalf("answer %s: %v", body, err)
+			}
+			if got.Data != nil {
+				got.City = got.Data.City
+			}
+			switch {
+			case tt.wantCode != "" && got.Code != tt.wantCode:
+				t.Errorf("code = %q, want %q", got.Code, tt.wantCode)
+			case tt.wantCode == "" && got.City == "":
+				t.Errorf("answer has no city: %s", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+
+	"github.com/offerni/weathercheck/internal/cep"
+	"github.com/offerni/weathercheck/internal/mock"
+)
+
+// cepimport resolves a CSV of CEPs through ViaCEP ahead of time and appends
+// the addresses to a snapshot file that service B preloads with
+// CEP_SNAPSHOT_FILE. CEPs already in the snapshot are skipped, so re-runs
+// only resolve new ones.
+func main() {
+	in := flag.String("in", "-", "CSV file with CEPs in the first column (or a column named cep); - reads stdin")
+	out := flag.String("out", "ceps.jsonl", "snapshot file to append resolved addresses to")
+	parallel := flag.Int("parallel", 4, "number of concurrent ViaCEP lookups")
+	useMock := flag.Bool("mock", false, "resolve with the offline mock instead of ViaCEP")
+	viaCEP := flag.String("viacep", cep.DefaultBaseURL, "ViaCEP base URL, for a mirror or a local mock")
+	flag.Parse()
+
+	ceps, err := readCEPs(*in)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// Skip what a previous run already resolved
+	if existing, err := cep.LoadSnapshot(*out, nil); err == nil {
+		pending := ceps[:0]
+		for _, code := range ceps {
+			if !existing.Has(code) {
+				pending = append(pending, code)
+			}
+		}
+		fmt.Fprintf(os.Stderr, "%d CEPs already in %s\n", len(ceps)-len(pending), *out)
+		ceps = pending
+	} else if !errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	f, err := os.OpenFile(*out, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	defer f.Close()
+
+	tracer := otel.Tracer("cepimport")
+	var resolver cep.Resolver = cep.NewClient(http.DefaultClient, *viaCEP, tracer)
+	if *useMock {
+		resolver = mock.NewCEPClient(tracer)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	resolved, notFound, failed := resolve(ctx, resolver, ceps, *parallel, f)
+	fmt.Fprintf(os.Stderr, "resolved %d, not found %d, failed %d\n", resolved, notFound, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// readCEPs returns the distinct valid CEPs in the CSV at path, reporting
+// invalid ones on stderr.
+func readCEPs(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	column := 0
+	if len(records) > 0 {
+		for i, name := range records[0] {
+			if strings.EqualFold(strings.TrimSpace(name), "cep") {
+				column = i
+				records = records[1:]
+				break
+			}
+		}
+	}
+
+	var ceps []string
+	seen := map[string]bool{}
+	for _, record := range records {
+		if column >= len(record) {
+			continue
+		}
+		code := strings.NewReplacer("-", "", ".", "", " ", "").Replace(record[column])
+		if !cep.Validate(code) {
+			fmt.Fprintf(os.Stderr, "skipping invalid CEP %q\n", record[column])
+			continue
+		}
+		if !seen[code] {
+			seen[code] = true
+			ceps = append(ceps, code)
+		}
+	}
+	return ceps, nil
+}
+
+// resolve looks ceps up with parallel workers and writes each address found
+func resolve(ctx context.Context, resolver cep.Resolver, ceps []string, parallel int, out io.Writer) (resolved, notFound, failed int) {
+	jobs := make(chan string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for i := 0; i < max(parallel, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for code := range jobs {
+				address, err := resolver.Lookup(ctx, code)
+
+				mu.Lock()
+				switch {
+				case errors.Is(err, cep.ErrNotFound) || errors.Is(err, cep.ErrInvalid):
+					notFound++
+				case err != nil:
+					failed++
+					fmt.Fprintf(os.Stderr, "failed to resolve %s: %v\n", code, err)
+				default:
+					if err := cep.WriteSnapshot(out, address); err != nil {
+						failed++
+						fmt.Fprintf(os.Stderr, "failed to write %s: %v\n", code, err)
+					} else {
+						resolved++
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, code := range ceps {
+		select {
+		case jobs <- code:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return resolved, notFound, failed
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// defaultMix approximates real traffic: a few big-city CEPs dominate, a long
+// tail follows, and a small share of requests is invalid or unknown.
+var defaultMix = []weightedCEP{
+	{"01001000", 30}, // São Paulo
+	{"20040020", 15}, // Rio de Janeiro
+	{"30130010", 10}, // Belo Horizonte
+	{"80010000", 8},  // Curitiba
+	{"90010000", 8},  // Porto Alegre
+	{"40020000", 6},  // Salvador
+	{"50010000", 5},  // Recife
+	{"60060000", 5},  // Fortaleza
+	{"69005000", 4},  // Manaus
+	{"17055250", 4},  // Bauru
+	{"00000000", 3},  // unknown
+	{"1234567", 2},   // invalid
+}
+
+type weightedCEP struct {
+	cep    string
+	weight int
+}
+
+type sample struct {
+	status  int
+	latency time.Duration
+}
+
+func main() {
+	apiURL := flag.String("url", "http://localhost:8080", "service-a base URL")
+	duration := flag.Duration("duration", 30*time.Second, "how long to generate load")
+	concurrency := flag.Int("concurrency", 10, "number of concurrent workers")
+	rate := flag.Float64("rate", 0, "total requests per second across workers (0 = as fast as possible)")
+	mixFile := flag.String("ceps", "", "file with \"<cep> <weight>\" lines replacing the built-in mix")
+	flag.Parse()
+
+	mix := defaultMix
+	if *mixFile != "" {
+		var err error
+		if mix, err = loadMix(*mixFile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *duration)
+	defer cancel()
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	// Pace all workers off one ticker when a rate is set
+	var ticks <-chan time.Time
+	if *rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / *rate))
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	endpoint := strings.TrimRight(*apiURL, "/") + "/weather"
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	picker := newPicker(mix)
+
+	var (
+		mu      sync.Mutex
+		samples []sample
+		wg      sync.WaitGroup
+	)
+	start := time.Now()
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			for {
+				if ticks != nil {
+					select {
+					case <-ctx.Done():
+						return
+					case <-ticks:
+					}
+				} else if ctx.Err() != nil {
+					return
+				}
+
+				s := send(ctx, httpClient, endpoint, picker.pick(rng))
+				if ctx.Err() != nil && s.status == 0 {
+					return
+				}
+				mu.Lock()
+				samples = append(samples, s)
+				mu.Unlock()
+			}
+		}(time.Now().UnixNano() + int64(i))
+	}
+	wg.Wait()
+
+	report(os.Stdout, samples, time.Since(start))
+}
+
+func send(ctx context.Context, httpClient *http.Client, endpoint, cep string) sample {
+	body, _ := json.Marshal(map[string]string{"cep": cep})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return sample{}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return sample{latency: time.Since(start)}
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	return sample{status: resp.StatusCode, latency: time.Since(start)}
+}
+
+type picker struct {
+	ceps       []string
+	cumulative []int
+}
+
+func newPicker(mix []weightedCEP) *picker {
+	p := &picker{}
+	total := 0
+	for _, w := range mix {
+		total += w.weight
+		p.ceps = append(p.ceps, w.cep)
+		p.cumulative = append(p.cumulative, total)
+	}
+	return p
+}
+
+func (p *picker) pick(rng *rand.Rand) string {
+	n := rng.Intn(p.cumulative[len(p.cumulative)-1])
+	i := sort.SearchInts(p.cumulative, n+1)
+	return p.ceps[i]
+}
+
+func loadMix(path string) ([]weightedCEP, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mix []weightedCEP
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		weight := 1
+		if len(fields) > 1 {
+			if weight, err = strconv.Atoi(fields[1]); err != nil || weight <= 0 {
+				return nil, fmt.Errorf("%s:%d: invalid weight %q", path, line, fields[1])
+			}
+		}
+		mix = append(mix, weightedCEP{cep: fields[0], weight: weight})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(mix) == 0 {
+		return nil, fmt.Errorf("%s: no CEPs", path)
+	}
+	return mix, nil
+}
+
+func report(w io.Writer, samples []sample, elapsed time.Duration) {
+	if len(samples) == 0 {
+		fmt.Fprintln(w, "no requests completed")
+		return
+	}
+
+	statuses := map[int]int{}
+	latencies := make([]time.Duration, 0, len(samples))
+	for _, s := range samples {
+		statuses[s.status]++
+		latencies = append(latencies, s.latency)
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	fmt.Fprintf(w, "requests: %d in %s (%.1f req/s)\n\n", len(samples), elapsed.Round(time.Millisecond), float64(len(samples))/elapsed.Seconds())
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tCOUNT")
+	codes := make([]int, 0, len(statuses))
+	for code := range statuses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		label := strconv.Itoa(code)
+		if code == 0 {
+			label = "error"
+		}
+		fmt.Fprintf(tw, "%s\t%d\n", label, statuses[code])
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "P50\tP90\tP99\tMAX")
+	fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n",
+		percentile(latencies, 0.50), percentile(latencies, 0.90), percentile(latencies, 0.99), latencies[len(latencies)-1].Round(time.Microsecond))
+	tw.Flush()
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(float64(len(sorted)-1) * p)
+	return sorted[i].Round(time.Microsecond)
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/offerni/weathercheck/internal/cep"
+	"github.com/offerni/weathercheck/internal/httpapi"
+	"github.com/offerni/weathercheck/internal/summary"
+)
+
+// Intents the assistant adapters understand, without Alexa's "Intent" suffix
+const (
+	intentWeatherByCEP  = "WeatherByCEP"
+	intentWeatherByCity = "WeatherByCity"
+)
+
+// assistantReply holds the spoken answers that are not a summary
+type assistantReply struct {
+	welcome, invalidCEP, noCity, notFound, unavailable, degrees string
+}
+
+var assistantReplies = map[string]assistantReply{
+	"pt-BR": {
+		welcome:     "Diga um CEP ou o nome de uma cidade para saber o clima.",
+		invalidCEP:  "Não entendi o CEP. Diga os oito dígitos, por exemplo zero um zero zero um zero zero zero.",
+		noCity:      "De qual cidade você quer saber o clima?",
+		notFound:    "Não encontrei o clima para %s.",
+		unavailable: "Não foi possível consultar o clima agora. Tente novamente mais tarde.",
+		degrees:     " graus",
+	},
+	"en": {
+		welcome:     "Say a CEP or a city name to hear the weather.",
+		invalidCEP:  "I didn't catch the CEP. Please say all eight digits.",
+		noCity:      "Which city would you like the weather for?",
+		notFound:    "I couldn't find the weather for %s.",
+		unavailable: "The weather is unavailable right now. Please try again later.",
+		degrees:     " degrees",
+	},
+}
+
+// assistantQuery is an assistant request reduced to what the lookup needs
+type assistantQuery struct {
+	Intent string
+	CEP    string
+	City   string
+	Locale string
+}
+
+// assistantHandler answers voice assistant webhooks by mapping their
+// weather intents onto service B's summaries. Platform adapters decode the
+// request into an assistantQuery and encode the speech back; requests must
+// carry the shared token as a bearer token or ?token=.
+type assistantHandler struct {
+	summarizer httpapi.Summarizer
+	token      string
+	decode     func(body []byte) (assistantQuery, error)
+	encode     func(w http.ResponseWriter, body []byte, speech string, end bool)
+	tracer     oteltrace.Tracer
+	logger     *log.Logger
+}
+
+func (h *assistantHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "assistant-webhook")
+	defer span.End()
+
+	if !h.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	query, err := h.decode(body)
+	if err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	span.SetAttributes(attribute.String("assistant.intent", query.Intent))
+
+	speech, end := h.answer(ctx, query)
+	h.encode(w, body, speech, end)
+}
+
+// answer returns what to say for query and whether the conversation is over
+func (h *assistantHandler) answer(ctx context.Context, query assistantQuery) (string, bool) {
+	lang := summary.Match(query.Locale).String()
+	replies := assistantReplies[lang]
+
+	switch query.Intent {
+	case intentWeatherByCEP:
+		// Spoken CEPs arrive as "0 1 0 0 1-0 0 0" or similar
+		code := strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, query.CEP)
+		if !cep.Validate(code) {
+			return replies.invalidCEP, false
+		}
+		resp, err := h.summarizer.Summarize(ctx, code, lang)
+		return h.speak(ctx, resp, err, replies, code)
+	case intentWeatherByCity:
+		city := strings.TrimSpace(query.City)
+		if city == "" {
+			return replies.noCity, false
+		}
+		resp, err := h.summarizer.SummarizeCity(ctx, city, lang)
+		return h.speak(ctx, resp, err, replies, city)
+	default:
+		return replies.welcome, false
+	}
+}
+
+// speak turns service B's summary response into speech; subject names the
+// CEP or city in the not-found reply
+func (h *assistantHandler) speak(ctx context.Context, resp *http.Response, err error, replies assistantReply, subject string) (string, bool) {
+	if err != nil {
+		httpapi.Logf(ctx, h.logger, "Failed to summarize weather for assistant: %v", err)
+		return replies.unavailable, true
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return fmt.Sprintf(replies.notFound, subject), true
+	default:
+		return replies.unavailable, true
+	}
+
+	var s httpapi.SummaryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		httpapi.Logf(ctx, h.logger, "Failed to decode summary from service B: %v", err)
+		return replies.unavailable, true
+	}
+	// Text-to-speech reads "°C" poorly
+	return strings.ReplaceAll(s.Summary, "°C", replies.degrees), true
+}
+
+func (h *assistantHandler) authorized(r *http.Request) bool {
+	token := r.URL.Query().Get("token")
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		token = bearer
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+// Alexa custom skill webhook
+
+type alexaRequest struct {
+	Request struct {
+		Type   string `json:"type"`
+		Locale string `json:"locale"`
+		Intent struct {
+			Name  string `json:"name"`
+			Slots map[string]struct {
+				Value string `json:"value"`
+			} `json:"slots"`
+		} `json:"intent"`
+	} `json:"request"`
+}
+
+type alexaResponse struct {
+	Version  string `json:"version"`
+	Response struct {
+		OutputSpeech struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"outputSpeech"`
+		ShouldEndSession bool `json:"shouldEndSession"`
+	} `json:"response"`
+}
+
+func decodeAlexa(body []byte) (assistantQuery, error) {
+	var req alexaRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return assistantQuery{}, err
+	}
+
+	query := assistantQuery{Locale: req.Request.Locale}
+	if req.Request.Type == "IntentRequest" {
+		query.Intent = strings.TrimSuffix(req.Request.Intent.Name, "Intent")
+		query.CEP = req.Request.Intent.Slots["cep"].Value
+		query.City = req.Request.Intent.Slots["city"].Value
+	}
+	return query, nil
+}
+
+func encodeAlexa(w http.ResponseWriter, _ []byte, speech string, end bool) {
+	var resp alexaResponse
+	resp.Version = "1.0"
+	resp.Response.OutputSpeech.Type = "PlainText"
+	resp.Response.OutputSpeech.Text = speech
+	resp.Response.ShouldEndSession = end
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Google Actions Builder webhook
+
+type googleRequest struct {
+	Intent struct {
+		Name   string `json:"name"`
+		Params map[string]struct {
+			Resolved any `json:"resolved"`
+		} `json:"params"`
+	} `json:"intent"`
+	Session struct {
+		ID string `json:"id"`
+	} `json:"session"`
+	User struct {
+		Locale string `json:"locale"`
+	} `json:"user"`
+}
+
+type googleResponse struct {
+	Session struct {
+		ID string `json:"id"`
+	} `json:"session"`
+	Prompt struct {
+		FirstSimple struct {
+			Speech string `json:"speech"`
+			Text   string `json:"text"`
+		} `json:"firstSimple"`
+	} `json:"prompt"`
+	Scene *googleScene `json:"scene,omitempty"`
+}
+
+type googleScene struct {
+	Next struct {
+		Name string `json:"name"`
+	} `json:"next"`
+}
+
+func decodeGoogle(body []byte) (assistantQuery, error) {
+	var req googleRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return assistantQuery{}, err
+	}
+
+	// Resolved values may be numbers, e.g. a CEP typed as an integer
+	param := func(name string) string {
+		if v := req.Intent.Params[name].Resolved; v != nil {
+			return fmt.Sprint(v)
+		}
+		return ""
+	}
+	return assistantQuery{
+		Intent: req.Intent.Name,
+		CEP:    param("cep"),
+		City:   param("city"),
+		Locale: req.User.Locale,
+	}, nil
+}
+
+func encodeGoogle(w http.ResponseWriter, body []byte, speech string, end bool) {
+	var req googleRequest
+	json.Unmarshal(body, &req)
+
+	var resp googleResponse
+	resp.Session.ID = req.Session.ID
+	resp.Prompt.FirstSimple.Speech = speech
+	resp.Prompt.FirstSimple.Text = speech
+	if end {
+		resp.Scene = &googleScene{}
+		resp.Scene.Next.Name = "actions.scene.END_CONVERSATION"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
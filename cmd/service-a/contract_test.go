@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/offerni/weathercheck/internal/contract"
+	"github.com/offerni/weathercheck/internal/httpapi"
+	"github.com/offerni/weathercheck/internal/privacy"
+)
+
+// TestContract checks service A sends service B the recorded requests and
+// handles the recorded answers, in JSON as they are and re-encoded
+func TestContract(t *testing.T) {
+	interactions, err := contract.Interactions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Service B answers each recorded request with its recorded answer
+	var received *http.Request
+	var receivedBody []byte
+	var current contract.Interaction
+	serviceB := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			return
+		}
+		received = r
+		receivedBody, _ = io.ReadAll(r.Body)
+		for name, value := range current.Response.Headers {
+			w.Header().Set(name, value)
+		}
+		w.WriteHeader(current.Response.Status)
+		w.Write(current.Response.Body)
+	}))
+	defer serviceB.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	redactor, _ := privacy.New(privacy.Off, "")
+	cfg := config{
+		ServiceBURL:     serviceB.URL,
+		Discovery:       "static",
+		ResolveInterval: time.Minute,
+		LogLevel:        "error",
+		SampleRatio:     1,
+		SlowThreshold:   time.Second,
+		Privacy:         redactor,
+	}
+	r, _ := newRouter(ctx, cfg, log.New(io.Discard, "", 0), http.NotFoundHandler())
+
+	for _, i := range interactions {
+		current = i
+		for _, format := range []string{httpapi.FormatJSON, httpapi.FormatXML} {
+			t.Run(i.Description+" in "+format, func(t *testing.T) {
+				received = nil
+				req := httptest.NewRequest(i.Request.Method, i.Request.URL(), bytes.NewReader(i.Request.Body))
+				for name, value := range i.Request.Headers {
+					req.Header.Set(name, value)
+				}
+				req.Header.Set("Accept", format)
+				rec := httptest.NewRecorder()
+				r.ServeHTTP(rec, req)
+
+				if received == nil {
+					t.Fatalf("service B was not called: %d %s", rec.Code, rec.Body)
+				}
+				want, _ := url.ParseQuery(i.Request.Query)
+				if received.Method != i.Request.Method || received.URL.Path != i.Request.Path || !reflect.DeepEqual(received.URL.Query(), want) {
+					t.Errorf("service B got %s %s, want %s %s", received.Method, received.URL.RequestURI(), i.Request.Method, i.Request.URL())
+				}
+				for name, want := range i.Request.Headers {
+					if got := received.Header.Get(name); got != want {
+						t.Errorf("service B got %s %q, want %q", name, got, want)
+					}
+				}
+				if len(i.Request.Body) > 0 && !sameJSON(receivedBody, i.Request.Body) {
+					t.Errorf("service B got body %s, want %s", receivedBody, i.Request.Body)
+				}
+
+				if rec.Code != i.Response.Status {
+					t.Fatalf("status = %d, want %d: %s", rec.Code, i.Response.Status, rec.Body)
+				}
+				if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, format) {
+					t.Errorf("Content-Type = %q, want %s", got, format)
+				}
+				if format == httpapi.FormatJSON && !sameJSON(rec.Body.Bytes(), i.Response.Body) {
+					t.Errorf("answer = %s, want service B's %s", rec.Body, i.Response.Body)
+				}
+			})
+		}
+	}
+}
+
+func sameJSON(a, b []byte) bool {
+	var x, y any
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return false
+	}
+	xs, _ := json.Marshal(x)
+	ys, _ := json.Marshal(y)
+	return bytes.Equal(xs, ys)
+}
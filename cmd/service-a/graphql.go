@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/offerni/weathercheck/internal/cep"
+	"github.com/offerni/weathercheck/internal/httpapi"
+)
+
+const graphQLSchema = `
+	schema {
+		query: Query
+	}
+
+	type Query {
+		weather(cep: String!): Weather!
+	}
+
+	type Weather {
+		city: String!
+		temp_C: Float!
+		temp_F: Float!
+		temp_K: Float!
+		condition: String!
+		icon: String!
+		feels_like_C: Float
+		dew_point_C: Float
+	}
+`
+
+type graphQLResolver struct {
+	forwarder httpapi.Forwarder
+	tracer    oteltrace.Tracer
+}
+
+type weatherResolver struct {
+	weather httpapi.WeatherResponse
+}
+
+func (w *weatherResolver) City() string { return w.weather.City }
+
+func (w *weatherResolver) TempC() float64 { return w.weather.TempC }
+
+// Service B returns Fahrenheit and Kelvin unless asked otherwise
+func (w *weatherResolver) TempF() float64 { return *w.weather.TempF }
+
+func (w *weatherResolver) TempK() float64 { return *w.weather.TempK }
+
+func (w *weatherResolver) Condition() string { return w.weather.Condition }
+
+func (w *weatherResolver) Icon() string { return w.weather.Icon }
+
+func (w *weatherResolver) FeelsLikeC() *float64 { return w.weather.FeelsLikeC }
+
+func (w *weatherResolver) DewPointC() *float64 { return w.weather.DewPointC }
+
+func (g *graphQLResolver) Weather(ctx context.Context, args struct{ CEP string }) (*weatherResolver, error) {
+	ctx, span := g.tracer.Start(ctx, "graphql-weather")
+	defer span.End()
+
+	if !cep.Validate(args.CEP) {
+		return nil, errors.New("invalid zipcode")
+	}
+
+	resp, err := g.forwarder.Forward(ctx, httpapi.CEPRequest{CEP: args.CEP})
+	if err != nil {
+		return nil, errors.New("failed to forward request")
+	}
+	defer resp.Body.Close()
+
+	// Service B errors are surfaced as GraphQL errors with the same message
+	if resp.StatusCode != http.StatusOK {
+		var errResp httpapi.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || errResp.Message == "" {
+			return nil, errors.New(http.StatusText(resp.StatusCode))
+		}
+		return nil, errors.New(errResp.Message)
+	}
+
+	var weather httpapi.WeatherResponse
+	if err := json.NewDecoder(resp.Body).Decode(&weather); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return &weatherResolver{weather: weather}, nil
+}
+
+func newGraphQLHandler(forwarder httpapi.Forwarder, tracer oteltrace.Tracer) http.Handler {
+	schema := graphql.MustParseSchema(graphQLSchema, &graphQLResolver{forwarder: forwarder, tracer: tracer})
+	return &relay.Handler{Schema: schema}
+}
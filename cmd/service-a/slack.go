@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/offerni/weathercheck/internal/httpapi"
+)
+
+// slackMaxSkew is how old a signed Slack request may be, against replays
+const slackMaxSkew = 5 * time.Minute
+
+// slackHandler answers a Slack slash command such as "/weather 01310100"
+// with the CEP's city and temperatures.
+type slackHandler struct {
+	forwarder     httpapi.Forwarder
+	signingSecret string
+	tracer        oteltrace.Tracer
+	logger        *log.Logger
+}
+
+type slackMessage struct {
+	ResponseType string       `json:"response_type"`
+	Text         string       `json:"text"`
+	Blocks       []slackBlock `json:"blocks,omitempty"`
+}
+
+type slackBlock struct {
+	Type string     `json:"type"`
+	Text *slackText `json:"text,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func (h *slackHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "slack-command")
+	defer span.End()
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	if !h.validSignature(r, body, time.Now()) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+
+	match := cepInText.FindStringSubmatch(form.Get("text"))
+	if match == nil {
+		h.reply(w, slackMessage{ResponseType: "ephemeral", Text: fmt.Sprintf("Usage: `%s 01310100`", form.Get("command"))})
+		return
+	}
+
+	h.reply(w, h.lookup(ctx, match[1]+match[2]))
+}
+
+// lookup builds the reply for code: the weather in the channel, or an error
+// only the caller sees
+func (h *slackHandler) lookup(ctx context.Context, code string) slackMessage {
+	resp, err := h.forwarder.Forward(ctx, httpapi.CEPRequest{CEP: code})
+	if err != nil {
+		httpapi.Logf(ctx, h.logger, "Failed to look up weather for Slack: %v", err)
+		return slackMessage{ResponseType: "ephemeral", Text: "The weather is unavailable right now, please try again later."}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp httpapi.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || errResp.Message == "" {
+			errResp.Message = http.StatusText(resp.StatusCode)
+		}
+		return slackMessage{ResponseType: "ephemeral", Text: fmt.Sprintf("Could not get the weather for %s: %s", code, errResp.Message)}
+	}
+
+	var weather httpapi.WeatherResponse
+	if err := json.NewDecoder(resp.Body).Decode(&weather); err != nil {
+		httpapi.Logf(ctx, h.logger, "Failed to decode weather from service B: %v", err)
+		return slackMessage{ResponseType: "ephemeral", Text: "The weather is unavailable right now, please try again later."}
+	}
+
+	temps := fmt.Sprintf("%.1f °C", weather.TempC)
+	if weather.TempF != nil {
+		temps += fmt.Sprintf(" · %.1f °F", *weather.TempF)
+	}
+	if weather.TempK != nil {
+		temps += fmt.Sprintf(" · %.1f K", *weather.TempK)
+	}
+	details := temps
+	if weather.FeelsLikeC != nil {
+		details += fmt.Sprintf("\nFeels like %.1f °C", *weather.FeelsLikeC)
+	}
+
+	return slackMessage{
+		ResponseType: "in_channel",
+		Text:         fmt.Sprintf("%s: %s", weather.City, temps),
+		Blocks: []slackBlock{
+			{Type: "header", Text: &slackText{Type: "plain_text", Text: weather.City}},
+			{Type: "section", Text: &slackText{Type: "mrkdwn", Text: details}},
+		},
+	}
+}
+
+func (h *slackHandler) reply(w http.ResponseWriter, msg slackMessage) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(msg)
+}
+
+// validSignature checks X-Slack-Signature: "v0=" and the hex HMAC-SHA256,
+// keyed by the signing secret, of "v0:<timestamp>:<body>". Requests older
+// than slackMaxSkew are refused.
+func (h *slackHandler) validSignature(r *http.Request, body []byte, now time.Time) bool {
+	ts, err := strconv.ParseInt(r.Header.Get("X-Slack-Request-Timestamp"), 10, 64)
+	if err != nil || math.Abs(now.Sub(time.Unix(ts, 0)).Seconds()) > slackMaxSkew.Seconds() {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(h.signingSecret))
+	fmt.Fprintf(mac, "v0:%d:%s", ts, body)
+	want := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(want), []byte(r.Header.Get("X-Slack-Signature")))
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/offerni/weathercheck/internal/httpapi"
+)
+
+// maxWebhookBody bounds the form payloads accepted from chat webhooks
+const maxWebhookBody = 64 << 10
+
+// cepInText finds a CEP in free text, with or without the dash
+var cepInText = regexp.MustCompile(`\b(\d{5})-?(\d{3})\b`)
+
+// twilioReplies holds the SMS replies that are not a summary, per language
+var twilioReplies = map[string]struct{ noCEP, notFound, unavailable string }{
+	"pt-BR": {
+		noCEP:       "Envie um CEP com 8 dígitos, por exemplo 01001000.",
+		notFound:    "Não encontramos o CEP %s.",
+		unavailable: "Não foi possível consultar o clima agora. Tente novamente mais tarde.",
+	},
+	"en": {
+		noCEP:       "Text a CEP with 8 digits, for example 01001000.",
+		notFound:    "We could not find CEP %s.",
+		unavailable: "The weather is unavailable right now. Please try again later.",
+	},
+}
+
+// twilioHandler answers Twilio's inbound SMS webhook: the message should
+// contain a CEP, and the reply is that CEP's weather summary. Brazilian
+// numbers get replies in Portuguese, others in English.
+type twilioHandler struct {
+	summarizer httpapi.Summarizer
+	authToken  string
+	webhookURL string
+	tracer     oteltrace.Tracer
+	logger     *log.Logger
+}
+
+// twiml is the reply document Twilio expects
+type twiml struct {
+	XMLName xml.Name `xml:"Response"`
+	Message string   `xml:"Message"`
+}
+
+func (h *twilioHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "twilio-sms")
+	defer span.End()
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxWebhookBody)
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+
+	if !h.validSignature(r) {
+		span.SetAttributes(attribute.Bool("twilio.signature_valid", false))
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+
+	lang := "en"
+	if from := r.PostForm.Get("From"); strings.HasPrefix(from, "+55") {
+		lang = "pt-BR"
+	}
+	replies := twilioReplies[lang]
+
+	match := cepInText.FindStringSubmatch(r.PostForm.Get("Body"))
+	if match == nil {
+		h.reply(w, replies.noCEP)
+		return
+	}
+	code := match[1] + match[2]
+
+	text, status := h.summarize(ctx, code, lang)
+	switch {
+	case status == http.StatusOK:
+		h.reply(w, text)
+	case status == http.StatusNotFound:
+		h.reply(w, fmt.Sprintf(replies.notFound, code))
+	default:
+		h.reply(w, replies.unavailable)
+	}
+}
+
+// summarize returns the CEP's summary and service B's status code
+func (h *twilioHandler) summarize(ctx context.Context, code, lang string) (string, int) {
+	resp, err := h.summarizer.Summarize(ctx, code, lang)
+	if err != nil {
+		httpapi.Logf(ctx, h.logger, "Failed to summarize weather for SMS: %v", err)
+		return "", http.StatusBadGateway
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", resp.StatusCode
+	}
+
+	var summary httpapi.SummaryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+		httpapi.Logf(ctx, h.logger, "Failed to decode summary from service B: %v", err)
+		return "", http.StatusBadGateway
+	}
+	return summary.Summary, http.StatusOK
+}
+
+func (h *twilioHandler) reply(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(twiml{Message: message})
+}
+
+// validSignature checks X-Twilio-Signature: the base64 HMAC-SHA1, keyed by
+// the auth token, of the webhook URL followed by every form field's name
+// and value in name order.
+func (h *twilioHandler) validSignature(r *http.Request) bool {
+	webhookURL := h.webhookURL
+	if webhookURL == "" {
+		scheme := "http"
+		if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		webhookURL = scheme + "://" + r.Host + r.URL.RequestURI()
+	}
+
+	names := make([]string, 0, len(r.PostForm))
+	for name := range r.PostForm {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var payload strings.Builder
+	payload.WriteString(webhookURL)
+	for _, name := range names {
+		for _, value := range r.PostForm[name] {
+			payload.WriteString(name)
+			payload.WriteString(value)
+		}
+	}
+
+	mac := hmac.New(sha1.New, []byte(h.authToken))
+	mac.Write([]byte(payload.String()))
+	want := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(want), []byte(r.Header.Get("X-Twilio-Signature")))
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/offerni/weathercheck/internal/cache"
+	"github.com/offerni/weathercheck/internal/contract"
+	"github.com/offerni/weathercheck/internal/privacy"
+)
+
+// TestContract replays the requests service A sends and checks service B
+// answers them as service A expects
+func TestContract(t *testing.T) {
+	interactions, err := contract.Interactions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	redactor, _ := privacy.New(privacy.Off, "")
+	cfg := config{
+		ProviderMode:    "mock",
+		WeatherProvider: "weatherapi",
+		Precision:       2,
+		LogLevel:        "error",
+		SampleRatio:     1,
+		SlowThreshold:   time.Second,
+		HandlerTimeout:  10 * time.Second,
+		Readings:        cache.Options{MaxEntries: 10},
+		SnapshotTTL:     48 * time.Hour,
+		Privacy:         redactor,
+	}
+	r, _ := newRouter(cfg, log.New(io.Discard, "", 0), http.NotFoundHandler())
+
+	for _, i := range interactions {
+		t.Run(i.Description, func(t *testing.T) {
+			req := httptest.NewRequest(i.Request.Method, i.Request.URL(), strings.NewReader(string(i.Request.Body)))
+			for name, value := range i.Request.Headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != i.Response.Status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, i.Response.Status, rec.Body)
+			}
+			for name, want := range i.Response.Headers {
+				if got := rec.Header().Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+			if err := contract.MatchShape(i.Response.Body, rec.Body.Bytes()); err != nil {
+				t.Errorf("answer %s: %v", rec.Body, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+//go:build lambda || cloudfunctions
+
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.opentelemetry.io/otel"
+)
+
+// flushTraces exports pending spans before the platform freezes the
+// instance between requests
+func flushTraces(ctx context.Context) {
+	flusher, ok := otel.GetTracerProvider().(interface{ ForceFlush(context.Context) error })
+	if !ok {
+		return
+	}
+	if err := flusher.ForceFlush(ctx); err != nil {
+		log.Printf("Error flushing traces: %v", err)
+	}
+}
@@ -0,0 +1,967 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/http/httpproxy"
+
+	"github.com/offerni/weathercheck/internal/admin"
+	"github.com/offerni/weathercheck/internal/audit"
+	"github.com/offerni/weathercheck/internal/cache"
+	"github.com/offerni/weathercheck/internal/cep"
+	"github.com/offerni/weathercheck/internal/chaos"
+	"github.com/offerni/weathercheck/internal/dnscache"
+	"github.com/offerni/weathercheck/internal/flags"
+	"github.com/offerni/weathercheck/internal/geohash"
+	"github.com/offerni/weathercheck/internal/heatrisk"
+	"github.com/offerni/weathercheck/internal/httpapi"
+	"github.com/offerni/weathercheck/internal/ibge"
+	"github.com/offerni/weathercheck/internal/mock"
+	"github.com/offerni/weathercheck/internal/pollen"
+	"github.com/offerni/weathercheck/internal/privacy"
+	"github.com/offerni/weathercheck/internal/retention"
+	"github.com/offerni/weathercheck/internal/schedule"
+	"github.com/offerni/weathercheck/internal/sealing"
+	"github.com/offerni/weathercheck/internal/snapshot"
+	"github.com/offerni/weathercheck/internal/startup"
+	"github.com/offerni/weathercheck/internal/status"
+	"github.com/offerni/weathercheck/internal/telemetry"
+	"github.com/offerni/weathercheck/internal/temperature"
+	"github.com/offerni/weathercheck/internal/upstream"
+	"github.com/offerni/weathercheck/internal/vcr"
+	"github.com/offerni/weathercheck/internal/weather"
+)
+
+// lastReadingsSize bounds how many places keep a reading for degraded
+// answers, and a pollen answer, unless READINGS_SIZE or POLLEN_CACHE_SIZE
+// say otherwise
+const lastReadingsSize = 10000
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+type config struct {
+	WeatherAPIKey   string
+	ProviderMode    string
+	WeatherProvider string
+	CompareProvider string
+	Environment     string
+	FlagsFile       string
+	Precision       int
+	VCRMode         string
+	VCRDir          string
+	LogLevel        string
+	SampleRatio     float64
+	SlowThreshold   time.Duration
+	HandlerTimeout  time.Duration
+	ReadingsTTL     time.Duration
+	ReadingsCell    int
+	Readings        cache.Options
+	CEPSnapshot     string
+	CEPCacheFile    string
+	CEPCacheTTL     time.Duration
+	CEPCacheKey     string
+	CEPCacheKMSKey  string
+	CEPFallback     bool
+	SnapshotFile    string
+	SnapshotTTL     time.Duration
+	HeatRiskCEPs    []string
+	HeatRiskEvery   time.Duration
+	Schedules       []schedule.Group
+	RainThreshold   float64
+	Coastal         map[string]bool
+	PollenProvider  string
+	PollenAPIKey    string
+	PollenTTL       time.Duration
+	PollenCache     cache.Options
+	ViaCEPURL       string
+	WeatherAPIURL   string
+	QuotaCooldown   time.Duration
+	UpstreamProxy   string
+	UserAgent       string
+	UpstreamHeaders upstream.Headers
+	CaptureRatio    float64
+	DNSCacheTTL     time.Duration
+	DNSPins         map[string][]string
+	DNSServer       string
+	IPFamily        upstream.Family
+	FallbackDelay   time.Duration
+	Pool            upstream.Pool
+	Retry           upstream.Retry
+	RetryBudget     float64
+	MaxConcurrent   int
+	Bulkheads       map[string]int
+	BulkheadWait    time.Duration
+	Adaptive        bool
+	Tolerance       float64
+	AdminToken      string
+	FailedLookups   int
+	TopCities       int
+	AuditLogFile    string
+	AuditRetention  time.Duration
+	PurgeInterval   time.Duration
+	Privacy         *privacy.Redactor
+	ProfilingAddr   string
+	StartupWait     time.Duration
+	Propagators     string
+	Routes          httpapi.Routes
+	Server          httpapi.ServerConfig
+	Chaos           chaos.Config
+}
+
+func loadConfig() config {
+	chaosCfg, err := chaos.FromEnv()
+	if err != nil {
+		log.Fatalf("Invalid chaos settings: %v", err)
+	}
+
+	routes, err := httpapi.ParseRoutes(os.Getenv("DISABLED_ROUTES"))
+	if err != nil {
+		log.Fatalf("Invalid DISABLED_ROUTES: %v", err)
+	}
+
+	redactor, err := privacy.New(privacy.Mode(envOr("PRIVACY_MODE", "off")), os.Getenv("PRIVACY_SALT"))
+	if err != nil {
+		log.Fatalf("Invalid PRIVACY_MODE or PRIVACY_SALT: %v", err)
+	}
+
+	precision := 2
+	if v := os.Getenv("TEMPERATURE_PRECISION"); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil || p < 0 || p > temperature.MaxPrecision {
+			log.Fatalf("Invalid TEMPERATURE_PRECISION %q (expected 0-%d)", v, temperature.MaxPrecision)
+		}
+		precision = p
+	}
+
+	sampleRatio := 1.0
+	if v := os.Getenv("TRACE_SAMPLE_RATIO"); v != "" {
+		r, err := strconv.ParseFloat(v, 64)
+		if err != nil || r < 0 || r > 1 {
+			log.Fatalf("Invalid TRACE_SAMPLE_RATIO %q (expected 0-1)", v)
+		}
+		sampleRatio = r
+	}
+
+	slowThreshold := time.Second
+	if v := os.Getenv("SLOW_TRACE_THRESHOLD"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			log.Fatalf("Invalid SLOW_TRACE_THRESHOLD %q", v)
+		}
+		slowThreshold = d
+	}
+
+	startupWait, err := time.ParseDuration(envOr("STARTUP_WAIT", "0"))
+	if err != nil || startupWait < 0 {
+		log.Fatalf("Invalid STARTUP_WAIT %q", os.Getenv("STARTUP_WAIT"))
+	}
+
+	handlerTimeout, err := time.ParseDuration(envOr("HANDLER_TIMEOUT", "10s"))
+	if err != nil || handlerTimeout < 0 {
+		log.Fatalf("Invalid HANDLER_TIMEOUT %q", os.Getenv("HANDLER_TIMEOUT"))
+	}
+
+	var readingsTTL time.Duration
+	if v := os.Getenv("READINGS_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			log.Fatalf("Invalid READINGS_TTL %q", v)
+		}
+		readingsTTL = d
+	}
+
+	// Addresses rarely change, so CEPs are kept long
+	cepCacheTTL, err := time.ParseDuration(envOr("CEP_CACHE_TTL", "720h"))
+	if err != nil || cepCacheTTL < 0 {
+		log.Fatalf("Invalid CEP_CACHE_TTL %q", os.Getenv("CEP_CACHE_TTL"))
+	}
+
+	readingsOpts, err := cache.FromEnv("READINGS", lastReadingsSize)
+	if err != nil {
+		log.Fatalf("Invalid readings settings: %v", err)
+	}
+
+	// Neighbouring cities within one geohash cell share their readings
+	readingsCell, err := strconv.Atoi(envOr("READINGS_GEOHASH_PRECISION", "5"))
+	if err != nil || readingsCell < 0 || readingsCell > geohash.MaxPrecision {
+		log.Fatalf("Invalid READINGS_GEOHASH_PRECISION %q (expected 0 to %d)", os.Getenv("READINGS_GEOHASH_PRECISION"), geohash.MaxPrecision)
+	}
+
+	// Comparisons with yesterday need at least a day of snapshots
+	snapshotTTL, err := time.ParseDuration(envOr("SNAPSHOT_RETENTION", "48h"))
+	if err != nil || snapshotTTL < 25*time.Hour {
+		log.Fatalf("Invalid SNAPSHOT_RETENTION %q (expected at least 25h)", os.Getenv("SNAPSHOT_RETENTION"))
+	}
+
+	// The audit log is kept whole unless told otherwise
+	auditRetention, err := time.ParseDuration(envOr("AUDIT_LOG_RETENTION", "0"))
+	if err != nil || auditRetention < 0 {
+		log.Fatalf("Invalid AUDIT_LOG_RETENTION %q", os.Getenv("AUDIT_LOG_RETENTION"))
+	}
+	purgeInterval, err := time.ParseDuration(envOr("PURGE_INTERVAL", "1h"))
+	if err != nil || purgeInterval <= 0 {
+		log.Fatalf("Invalid PURGE_INTERVAL %q (expected a positive duration)", os.Getenv("PURGE_INTERVAL"))
+	}
+
+	var heatRiskCEPs []string
+	if v := os.Getenv("HEAT_RISK_CEPS"); v != "" {
+		for _, code := range strings.Split(v, ",") {
+			code = strings.TrimSpace(code)
+			if !cep.Validate(code) {
+				log.Fatalf("Invalid HEAT_RISK_CEPS: %q is not a CEP", code)
+			}
+			heatRiskCEPs = append(heatRiskCEPs, code)
+		}
+	}
+	heatRiskEvery, err := time.ParseDuration(envOr("HEAT_RISK_INTERVAL", "1h"))
+	if err != nil || heatRiskEvery <= 0 {
+		log.Fatalf("Invalid HEAT_RISK_INTERVAL %q", os.Getenv("HEAT_RISK_INTERVAL"))
+	}
+
+	// Groups of CEPs refreshed on cron schedules, keeping their snapshots
+	// and caches warm
+	schedules, err := schedule.ParseGroups(os.Getenv("SNAPSHOT_SCHEDULES"), cep.Validate)
+	if err != nil {
+		log.Fatalf("Invalid SNAPSHOT_SCHEDULES: %v", err)
+	}
+
+	rainThreshold, err := strconv.ParseFloat(envOr("RAIN_PROBABILITY_THRESHOLD", "50"), 64)
+	if err != nil || rainThreshold <= 0 || rainThreshold > 100 {
+		log.Fatalf("Invalid RAIN_PROBABILITY_THRESHOLD %q (expected a percentage above 0)", os.Getenv("RAIN_PROBABILITY_THRESHOLD"))
+	}
+
+	// Coastal municipalities, by IBGE code, get GET /marine/{cep}
+	coastal := make(map[string]bool)
+	if v := os.Getenv("COASTAL_MUNICIPALITIES"); v != "" {
+		for _, code := range strings.Split(v, ",") {
+			code = strings.TrimSpace(code)
+			if !ibge.Validate(code) {
+				log.Fatalf("Invalid COASTAL_MUNICIPALITIES: %q is not an IBGE code", code)
+			}
+			coastal[code] = true
+		}
+	}
+
+	pollenProvider := os.Getenv("POLLEN_PROVIDER")
+	if pollenProvider != "" && pollenProvider != "google" {
+		log.Fatalf("Unknown POLLEN_PROVIDER %q (expected google)", pollenProvider)
+	}
+	pollenCache, err := cache.FromEnv("POLLEN_CACHE", lastReadingsSize)
+	if err != nil {
+		log.Fatalf("Invalid pollen cache settings: %v", err)
+	}
+	pollenTTL, err := time.ParseDuration(envOr("POLLEN_CACHE_TTL", "1h"))
+	if err != nil || pollenTTL < 0 {
+		log.Fatalf("Invalid POLLEN_CACHE_TTL %q", os.Getenv("POLLEN_CACHE_TTL"))
+	}
+
+	// Mirrors and local mocks stand in for the public APIs
+	viaCEPURL := envOr("VIACEP_BASE_URL", cep.DefaultBaseURL)
+	if u, err := url.Parse(viaCEPURL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		log.Fatalf("Invalid VIACEP_BASE_URL %q (expected an http or https URL)", viaCEPURL)
+	}
+	weatherAPIURL := envOr("WEATHER_API_BASE_URL", weather.DefaultBaseURL)
+	if u, err := url.Parse(weatherAPIURL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		log.Fatalf("Invalid WEATHER_API_BASE_URL %q (expected an http or https URL)", weatherAPIURL)
+	}
+
+	quotaCooldown, err := time.ParseDuration(envOr("WEATHER_QUOTA_COOLDOWN", "15m"))
+	if err != nil || quotaCooldown < 0 {
+		log.Fatalf("Invalid WEATHER_QUOTA_COOLDOWN %q", os.Getenv("WEATHER_QUOTA_COOLDOWN"))
+	}
+
+	if v := os.Getenv("UPSTREAM_PROXY"); v != "" {
+		u, err := url.Parse(v)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") {
+			log.Fatalf("Invalid UPSTREAM_PROXY %q (expected an http, https or socks5 URL)", v)
+		}
+	}
+
+	upstreamHeaders, err := upstream.ParseHeaders(os.Getenv("UPSTREAM_HEADERS"))
+	if err != nil {
+		log.Fatalf("Invalid UPSTREAM_HEADERS: %v", err)
+	}
+
+	failedLookups, err := strconv.Atoi(envOr("FAILED_LOOKUPS_SIZE", "100"))
+	if err != nil || failedLookups < 0 {
+		log.Fatalf("Invalid FAILED_LOOKUPS_SIZE %q", os.Getenv("FAILED_LOOKUPS_SIZE"))
+	}
+	topCities, err := strconv.Atoi(envOr("METRICS_TOP_CITIES", "20"))
+	if err != nil || topCities < 0 {
+		log.Fatalf("Invalid METRICS_TOP_CITIES %q", os.Getenv("METRICS_TOP_CITIES"))
+	}
+
+	captureRatio, err := strconv.ParseFloat(envOr("UPSTREAM_CAPTURE_RATIO", "0"), 64)
+	if err != nil || captureRatio < 0 || captureRatio > 1 {
+		log.Fatalf("Invalid UPSTREAM_CAPTURE_RATIO %q (expected 0-1)", os.Getenv("UPSTREAM_CAPTURE_RATIO"))
+	}
+
+	var dnsCacheTTL time.Duration
+	if v := os.Getenv("UPSTREAM_DNS_CACHE_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			log.Fatalf("Invalid UPSTREAM_DNS_CACHE_TTL %q", v)
+		}
+		dnsCacheTTL = d
+	}
+
+	dnsPins, err := dnscache.ParsePins(os.Getenv("UPSTREAM_DNS_PINS"))
+	if err != nil {
+		log.Fatalf("Invalid UPSTREAM_DNS_PINS: %v", err)
+	}
+
+	ipFamily, err := upstream.ParseFamily(envOr("UPSTREAM_IP_FAMILY", string(upstream.AnyFamily)))
+	if err != nil {
+		log.Fatalf("Invalid UPSTREAM_IP_FAMILY: %v", err)
+	}
+
+	// A negative delay turns happy eyeballs off
+	fallbackDelay, err := time.ParseDuration(envOr("UPSTREAM_DIAL_FALLBACK_DELAY", "300ms"))
+	if err != nil {
+		log.Fatalf("Invalid UPSTREAM_DIAL_FALLBACK_DELAY %q", os.Getenv("UPSTREAM_DIAL_FALLBACK_DELAY"))
+	}
+
+	maxIdleConns, err := strconv.Atoi(envOr("UPSTREAM_MAX_IDLE_CONNS_PER_HOST", "16"))
+	if err != nil || maxIdleConns < 0 {
+		log.Fatalf("Invalid UPSTREAM_MAX_IDLE_CONNS_PER_HOST %q", os.Getenv("UPSTREAM_MAX_IDLE_CONNS_PER_HOST"))
+	}
+	maxConns, err := strconv.Atoi(envOr("UPSTREAM_MAX_CONNS_PER_HOST", "0"))
+	if err != nil || maxConns < 0 {
+		log.Fatalf("Invalid UPSTREAM_MAX_CONNS_PER_HOST %q", os.Getenv("UPSTREAM_MAX_CONNS_PER_HOST"))
+	}
+	idleConnTimeout, err := time.ParseDuration(envOr("UPSTREAM_IDLE_CONN_TIMEOUT", "90s"))
+	if err != nil || idleConnTimeout < 0 {
+		log.Fatalf("Invalid UPSTREAM_IDLE_CONN_TIMEOUT %q", os.Getenv("UPSTREAM_IDLE_CONN_TIMEOUT"))
+	}
+
+	maxRetries, err := strconv.Atoi(envOr("UPSTREAM_MAX_RETRIES", "1"))
+	if err != nil || maxRetries < 0 {
+		log.Fatalf("Invalid UPSTREAM_MAX_RETRIES %q", os.Getenv("UPSTREAM_MAX_RETRIES"))
+	}
+	retryBackoff, err := time.ParseDuration(envOr("UPSTREAM_RETRY_BACKOFF", "100ms"))
+	if err != nil || retryBackoff < 0 {
+		log.Fatalf("Invalid UPSTREAM_RETRY_BACKOFF %q", os.Getenv("UPSTREAM_RETRY_BACKOFF"))
+	}
+	// Retries across all upstreams may not exceed this share of requests
+	retryBudget, err := strconv.ParseFloat(envOr("UPSTREAM_RETRY_BUDGET", "0.2"), 64)
+	if err != nil || retryBudget < 0 || retryBudget > 1 {
+		log.Fatalf("Invalid UPSTREAM_RETRY_BUDGET %q (expected 0-1)", os.Getenv("UPSTREAM_RETRY_BUDGET"))
+	}
+
+	// Bulkheads keep one slow upstream from holding every goroutine and
+	// connection; the default limit applies to each upstream on its own
+	maxConcurrent, err := strconv.Atoi(envOr("UPSTREAM_MAX_CONCURRENT", "64"))
+	if err != nil || maxConcurrent < 0 {
+		log.Fatalf("Invalid UPSTREAM_MAX_CONCURRENT %q", os.Getenv("UPSTREAM_MAX_CONCURRENT"))
+	}
+	bulkheads, err := upstream.ParseLimits(os.Getenv("UPSTREAM_MAX_CONCURRENT_BY_PROVIDER"))
+	if err != nil {
+		log.Fatalf("Invalid UPSTREAM_MAX_CONCURRENT_BY_PROVIDER: %v", err)
+	}
+	bulkheadWait, err := time.ParseDuration(envOr("UPSTREAM_BULKHEAD_WAIT", "100ms"))
+	if err != nil || bulkheadWait < 0 {
+		log.Fatalf("Invalid UPSTREAM_BULKHEAD_WAIT %q", os.Getenv("UPSTREAM_BULKHEAD_WAIT"))
+	}
+
+	tolerance, err := strconv.ParseFloat(envOr("UPSTREAM_ADAPTIVE_LATENCY_TOLERANCE", "2"), 64)
+	if err != nil || tolerance <= 1 {
+		log.Fatalf("Invalid UPSTREAM_ADAPTIVE_LATENCY_TOLERANCE %q (expected more than 1)", os.Getenv("UPSTREAM_ADAPTIVE_LATENCY_TOLERANCE"))
+	}
+
+	upgrades := os.Getenv("GRACEFUL_UPGRADE") == "true"
+	// The new process can't open the file while the old one holds its lock
+	if upgrades && os.Getenv("CEP_CACHE_FILE") != "" {
+		log.Fatalf("GRACEFUL_UPGRADE can't be used with CEP_CACHE_FILE")
+	}
+
+	return config{
+		WeatherAPIKey:   os.Getenv("WEATHER_API_KEY"),
+		ProviderMode:    envOr("PROVIDER_MODE", "live"),
+		WeatherProvider: envOr("WEATHER_PROVIDER", "weatherapi"),
+		CompareProvider: os.Getenv("WEATHER_COMPARE_PROVIDER"),
+		Environment:     envOr("APP_ENV", "development"),
+		FlagsFile:       os.Getenv("FLAGS_FILE"),
+		Precision:       precision,
+		VCRMode:         os.Getenv("UPSTREAM_VCR_MODE"),
+		VCRDir:          envOr("UPSTREAM_VCR_DIR", "testdata/fixtures"),
+		LogLevel:        envOr("LOG_LEVEL", "info"),
+		SampleRatio:     sampleRatio,
+		SlowThreshold:   slowThreshold,
+		HandlerTimeout:  handlerTimeout,
+		ReadingsTTL:     readingsTTL,
+		ReadingsCell:    readingsCell,
+		Readings:        readingsOpts,
+		CEPSnapshot:     os.Getenv("CEP_SNAPSHOT_FILE"),
+		CEPCacheFile:    os.Getenv("CEP_CACHE_FILE"),
+		CEPCacheTTL:     cepCacheTTL,
+		CEPCacheKey:     os.Getenv("CEP_CACHE_KEY"),
+		CEPCacheKMSKey:  os.Getenv("CEP_CACHE_KMS_KEY"),
+		CEPFallback:     os.Getenv("CEP_PREFIX_FALLBACK") == "true",
+		SnapshotFile:    os.Getenv("SNAPSHOT_FILE"),
+		SnapshotTTL:     snapshotTTL,
+		HeatRiskCEPs:    heatRiskCEPs,
+		HeatRiskEvery:   heatRiskEvery,
+		Schedules:       schedules,
+		RainThreshold:   rainThreshold,
+		Coastal:         coastal,
+		PollenProvider:  pollenProvider,
+		PollenAPIKey:    os.Getenv("POLLEN_API_KEY"),
+		PollenTTL:       pollenTTL,
+		PollenCache:     pollenCache,
+		ViaCEPURL:       viaCEPURL,
+		WeatherAPIURL:   weatherAPIURL,
+		QuotaCooldown:   quotaCooldown,
+		UpstreamProxy:   os.Getenv("UPSTREAM_PROXY"),
+		UserAgent:       envOr("UPSTREAM_USER_AGENT", "weathercheck-service-b/"+telemetry.ServiceVersion+" (+https://github.com/offerni/weathercheck)"),
+		UpstreamHeaders: upstreamHeaders,
+		CaptureRatio:    captureRatio,
+		DNSCacheTTL:     dnsCacheTTL,
+		DNSPins:         dnsPins,
+		DNSServer:       os.Getenv("UPSTREAM_DNS_SERVER"),
+		IPFamily:        ipFamily,
+		FallbackDelay:   fallbackDelay,
+		AdminToken:      os.Getenv("ADMIN_TOKEN"),
+		FailedLookups:   failedLookups,
+		TopCities:       topCities,
+		AuditLogFile:    os.Getenv("AUDIT_LOG_FILE"),
+		AuditRetention:  auditRetention,
+		PurgeInterval:   purgeInterval,
+		Privacy:         redactor,
+		ProfilingAddr:   os.Getenv("PROFILING_ADDR"),
+		StartupWait:     startupWait,
+		Propagators:     envOr("OTEL_PROPAGATORS", telemetry.DefaultPropagators),
+		Routes:          routes,
+		Pool: upstream.Pool{
+			MaxIdleConnsPerHost: maxIdleConns,
+			MaxConnsPerHost:     maxConns,
+			IdleConnTimeout:     idleConnTimeout,
+		},
+		Retry:         upstream.Retry{MaxRetries: maxRetries, Backoff: retryBackoff},
+		RetryBudget:   retryBudget,
+		MaxConcurrent: maxConcurrent,
+		Bulkheads:     bulkheads,
+		BulkheadWait:  bulkheadWait,
+		Adaptive:      os.Getenv("UPSTREAM_ADAPTIVE_CONCURRENCY") == "true",
+		Tolerance:     tolerance,
+		Server: httpapi.ServerConfig{
+			Addr:        ":8081",
+			TLSCertFile: os.Getenv("TLS_CERT_FILE"),
+			TLSKeyFile:  os.Getenv("TLS_KEY_FILE"),
+			AdminAddr:   os.Getenv("ADMIN_ADDR"),
+			Upgrades:    upgrades,
+		},
+		Chaos: chaosCfg,
+	}
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// providers are the upstreams service B answers from
+type providers struct {
+	cep          httpapi.CEPResolver
+	municipality httpapi.MunicipalityResolver
+	weather      httpapi.WeatherProvider
+	marine       httpapi.MarineProvider
+	// pollen is nil unless POLLEN_PROVIDER is set
+	pollen pollen.Provider
+}
+
+// newProviders picks the real upstream clients or the offline mocks
+// according to PROVIDER_MODE.
+func newProviders(cfg config, dns *dnscache.Resolver, flagsClient *flags.Client, tracer oteltrace.Tracer, logger *log.Logger) providers {
+	switch cfg.ProviderMode {
+	case "live":
+		var lookup func(ctx context.Context, host string) ([]string, error)
+		if dns != nil {
+			lookup = dns.LookupHost
+		}
+		dialer := upstream.NewDialer(cfg.IPFamily, cfg.FallbackDelay, lookup)
+		// Each upstream gets its own connection pool, and all share one
+		// retry budget
+		budget := upstream.NewRetryBudget(cfg.RetryBudget)
+		client := func(name string) *http.Client {
+			return &http.Client{Transport: otelhttp.NewTransport(upstreamTransport(cfg, dialer, budget, name, logger))}
+		}
+
+		// Once the quota runs out, stop spending calls until it is back
+		var provider weather.Provider = weather.NewQuotaGuard(newWeatherProvider(cfg.WeatherProvider, cfg, client(cfg.WeatherProvider), tracer), cfg.QuotaCooldown, logger)
+		// Compare against a second provider without changing the answers
+		if cfg.CompareProvider != "" {
+			secondary := newWeatherProvider(cfg.CompareProvider, cfg, client(cfg.CompareProvider), tracer)
+			enabled := func(ctx context.Context) bool { return flagsClient.Enabled(ctx, flags.ProviderComparison, true) }
+			provider = weather.NewComparingProvider(provider, secondary, cfg.CompareProvider, enabled, logger)
+		}
+		p := providers{
+			cep:          cep.NewClient(client("viacep"), cfg.ViaCEPURL, tracer),
+			municipality: ibge.NewClient(client("ibge"), tracer),
+			weather:      provider,
+			// Only WeatherAPI has tides, whichever provider answers the weather
+			marine: weather.NewClient(client("weatherapi-marine"), cfg.WeatherAPIURL, cfg.WeatherAPIKey, tracer),
+		}
+		if cfg.PollenProvider == "google" {
+			geocoder := weather.NewOpenMeteoClient(client("open-meteo-geocoding"), tracer)
+			p.pollen = pollen.NewGoogleClient(client("google-pollen"), cfg.PollenAPIKey, geocoder, tracer)
+		}
+		return p
+	case "mock":
+		p := providers{
+			cep:          mock.NewCEPClient(tracer),
+			municipality: mock.NewIBGEClient(tracer),
+			weather:      mock.NewWeatherClient(tracer),
+			marine:       mock.NewMarineClient(tracer),
+		}
+		if cfg.PollenProvider != "" {
+			p.pollen = mock.NewPollenClient(tracer)
+		}
+		return p
+	default:
+		log.Fatalf("Unknown PROVIDER_MODE %q (expected live or mock)", cfg.ProviderMode)
+		return providers{}
+	}
+}
+
+// cepCacheSealer encrypts the CEP cache under CEP_CACHE_KEY, or the data
+// key in CEP_CACHE_KMS_KEY once KMS has decrypted it; addresses are stored
+// in plain with neither.
+func cepCacheSealer(cfg config) (cache.Sealer, error) {
+	var key []byte
+	var err error
+	switch {
+	case cfg.CEPCacheKey != "" && cfg.CEPCacheKMSKey != "":
+		return nil, errors.New("set CEP_CACHE_KEY or CEP_CACHE_KMS_KEY, not both")
+	case cfg.CEPCacheKey != "":
+		key, err = sealing.DecodeKey(cfg.CEPCacheKey)
+	case cfg.CEPCacheKMSKey != "":
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		key, err = sealing.DecryptKMS(ctx, cfg.CEPCacheKMSKey)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sealing.New(key)
+}
+
+func newWeatherProvider(name string, cfg config, httpClient *http.Client, tracer oteltrace.Tracer) weather.Provider {
+	switch name {
+	case "weatherapi":
+		return weather.NewClient(httpClient, cfg.WeatherAPIURL, cfg.WeatherAPIKey, tracer)
+	case "open-meteo":
+		return weather.NewOpenMeteoClient(httpClient, tracer)
+	default:
+		log.Fatalf("Unknown weather provider %q (expected weatherapi or open-meteo)", name)
+		return nil
+	}
+}
+
+// weatherUpstreams are the weather calls whose concurrency adapts to the
+// provider's latency under UPSTREAM_ADAPTIVE_CONCURRENCY
+var weatherUpstreams = map[string]bool{"weatherapi": true, "open-meteo": true, "weatherapi-marine": true}
+
+// adaptiveLimits starts the adaptive limit low and lets it grow up to the
+// provider's bulkhead, if it has one
+func adaptiveLimits(bulkhead int, tolerance float64) upstream.AdaptiveLimits {
+	ceiling := 256
+	if bulkhead > 0 {
+		ceiling = bulkhead
+	}
+	return upstream.AdaptiveLimits{Initial: min(16, ceiling), Min: min(2, ceiling), Max: ceiling, Tolerance: tolerance}
+}
+
+// upstreamTransport gives the named provider its own connection pool, dialed
+// with the configured IP family and sent through the egress proxy, if any,
+// and optionally records or replays its calls, injects upstream failures and
+// retries failed calls within the retry budget all providers share. Its
+// bulkhead caps how many of its calls are in flight, retries included, and
+// weather calls may adapt their own limit below it.
+func upstreamTransport(cfg config, dialer *upstream.Dialer, budget *upstream.RetryBudget, provider string, logger *log.Logger) http.RoundTripper {
+	// Without UPSTREAM_PROXY, HTTP_PROXY, HTTPS_PROXY and NO_PROXY still apply
+	t := upstream.NewTransport(cfg.Pool, dialer)
+	if cfg.UpstreamProxy != "" {
+		t.Proxy = egressProxy(cfg.UpstreamProxy)
+	}
+
+	// Captures see the request as sent, configured headers included
+	header := cfg.UpstreamHeaders.For(provider)
+	var configured []string
+	for name := range header {
+		configured = append(configured, name)
+	}
+	redact := upstream.NewRedactor([]string{cfg.WeatherAPIKey, cfg.PollenAPIKey}, configured, cfg.Privacy)
+	// Failed lookups keep the upstream answers behind them
+	recorded := upstream.Record(provider, redact, upstream.WithCapture(provider, cfg.CaptureRatio, redact, logger, t))
+	transport := upstream.Instrument(provider, upstream.WithHeaders(cfg.UserAgent, header, recorded))
+	if cfg.VCRMode != "" {
+		t, err := vcr.NewTransport(vcr.Mode(cfg.VCRMode), cfg.VCRDir, transport)
+		if err != nil {
+			log.Fatalf("Invalid UPSTREAM_VCR_MODE: %v", err)
+		}
+		transport = t
+	}
+	if cfg.Chaos.UpstreamRate > 0 {
+		transport = chaos.NewTransport(cfg.Chaos, transport)
+	}
+	if cfg.Retry.MaxRetries > 0 {
+		transport = upstream.WithRetries(provider, cfg.Retry, budget, transport)
+	}
+	limit, ok := cfg.Bulkheads[provider]
+	if !ok {
+		limit = cfg.MaxConcurrent
+	}
+	if cfg.Adaptive && weatherUpstreams[provider] {
+		transport = upstream.WithAdaptiveLimit(provider, adaptiveLimits(limit, cfg.Tolerance), transport)
+	}
+	return upstream.WithBulkhead(provider, limit, cfg.BulkheadWait, transport)
+}
+
+// egressProxy sends every upstream call through proxyURL, except for hosts
+// listed in NO_PROXY
+func egressProxy(proxyURL string) func(*http.Request) (*url.URL, error) {
+	proxy := (&httpproxy.Config{
+		HTTPProxy:  proxyURL,
+		HTTPSProxy: proxyURL,
+		NoProxy:    httpproxy.FromEnvironment().NoProxy,
+	}).ProxyFunc()
+	return func(r *http.Request) (*url.URL, error) {
+		return proxy(r.URL)
+	}
+}
+
+// newRouter returns the public router and the one serving the admin, status
+// and metrics endpoints, the same one unless ADMIN_ADDR is set.
+func newRouter(cfg config, logger *log.Logger, metrics http.Handler) (http.Handler, http.Handler) {
+	tracer := otel.Tracer("service-b")
+	flagsClient := flags.Init(context.Background(), "service-b", cfg.FlagsFile, cfg.Environment, logger)
+	// Cache upstream DNS lookups, or pin hosts, only when configured
+	var dns *dnscache.Resolver
+	if cfg.DNSCacheTTL > 0 || len(cfg.DNSPins) > 0 || cfg.DNSServer != "" {
+		dns = dnscache.New(cfg.DNSCacheTTL, cfg.DNSPins, cfg.DNSServer, logger)
+	}
+	upstreams := newProviders(cfg, dns, flagsClient, tracer, logger)
+	cepResolver, municipalityResolver, weatherProvider := upstreams.cep, upstreams.municipality, upstreams.weather
+	readings := httpapi.NewReadings(cfg.Readings, cfg.ReadingsCell)
+	readings.SetTTL(cfg.ReadingsTTL)
+
+	// Status page fed by the upstream calls, the caches and requests
+	board := status.NewBoard("service-b", telemetry.ServiceVersion, logger)
+	board.AddCache("readings", readings.Stats)
+	if dns != nil {
+		board.AddCache("dns", dns.Stats)
+	}
+	cepName, ibgeName, weatherName := "viacep", "ibge", cfg.WeatherProvider
+	if cfg.ProviderMode == "mock" {
+		cepName, ibgeName, weatherName = "mock-cep", "mock-ibge", "mock-weather"
+	}
+	cepResolver = trackedCEP{CEPResolver: cepResolver, name: cepName, board: board}
+	// Single-node deployments keep the CEPs they resolved across restarts
+	// Jobs dropping stored data past its retention, run every PURGE_INTERVAL
+	var purgeJobs []retention.Job
+	var cepCache *cache.Disk[cep.Address]
+	if cfg.CEPCacheFile != "" {
+		sealer, err := cepCacheSealer(cfg)
+		if err != nil {
+			log.Fatalf("Invalid CEP cache key: %v", err)
+		}
+		if cepCache, err = cache.OpenDisk[cep.Address](cfg.CEPCacheFile, "cep", sealer); err != nil {
+			log.Fatalf("Invalid CEP_CACHE_FILE: %v", err)
+		}
+		cepCache.SetTTL(cfg.CEPCacheTTL)
+		pruned, err := cepCache.Prune()
+		if err != nil {
+			logger.Printf("Failed to prune %s: %v", cfg.CEPCacheFile, err)
+		}
+		logger.Printf("Loaded %d CEPs from %s, dropped %d expired or unreadable", cepCache.Len(), cfg.CEPCacheFile, pruned)
+		board.AddCache("cep-cache", cepCache.Stats)
+		purgeJobs = append(purgeJobs, retention.Job{Name: "CEP cache", Purge: cepCache.Prune})
+		cepResolver = cep.NewCached(cepResolver, newCEPStore(cepCache, cfg.Privacy))
+	}
+	// CEPs resolved ahead of time by cmd/cepimport skip ViaCEP entirely
+	if cfg.CEPSnapshot != "" {
+		snapshot, err := cep.LoadSnapshot(cfg.CEPSnapshot, cepResolver)
+		if err != nil {
+			log.Fatalf("Invalid CEP_SNAPSHOT_FILE: %v", err)
+		}
+		logger.Printf("Loaded %d CEPs from %s", snapshot.Len(), cfg.CEPSnapshot)
+		board.AddCache("cep-snapshot", snapshot.Stats)
+		cepResolver = snapshot
+	}
+	// With ViaCEP down, the CEP's prefix still tells the larger municipalities
+	if cfg.CEPFallback {
+		fallback := cep.NewPrefixFallback(cepResolver)
+		board.AddCache("cep-prefix-fallback", fallback.Stats)
+		cepResolver = fallback
+	}
+	municipalityResolver = trackedMunicipality{MunicipalityResolver: municipalityResolver, name: ibgeName, board: board}
+	weatherProvider = trackedWeather{WeatherProvider: weatherProvider, name: weatherName, board: board}
+	snapshots, err := snapshot.Open(cfg.SnapshotFile, cfg.SnapshotTTL)
+	if err != nil {
+		log.Fatalf("Invalid SNAPSHOT_FILE: %v", err)
+	}
+	weatherProvider = snapshotWeather{WeatherProvider: weatherProvider, snapshots: snapshots, logger: logger}
+	purgeJobs = append(purgeJobs, retention.Job{Name: "snapshots", Purge: snapshots.Purge})
+	if cfg.TopCities > 0 {
+		weatherProvider = newCityLookups(weatherProvider, cfg.TopCities)
+	}
+	handler := httpapi.NewWeatherHandler(cepResolver, municipalityResolver, weatherProvider, readings, cfg.Precision, tracer, logger)
+
+	logLevel, err := admin.NewLogLevel(cfg.LogLevel)
+	if err != nil {
+		log.Fatalf("Invalid LOG_LEVEL: %v", err)
+	}
+	telemetry.SetSampleRatio(cfg.SampleRatio)
+	telemetry.SetSlowThreshold(cfg.SlowThreshold)
+
+	// Setup Chi router
+	r := chi.NewRouter()
+	r.NotFound(httpapi.NotFound)
+
+	// Add OpenTelemetry middleware
+	r.Use(func(next http.Handler) http.Handler {
+		return otelhttp.NewHandler(next, "service-b")
+	})
+	r.Use(httpapi.TraceID)
+	// JSON access log, inside the span so each line has its trace ID
+	accessLog := logLevel.RequestLogger(httpapi.AccessLog(cfg.Privacy.Writer(os.Stdout)))
+	r.Use(accessLog)
+	r.Use(board.Middleware)
+	r.Use(httpapi.Recoverer(logger))
+	r.Use(flags.Middleware)
+
+	// Admin, status and metrics endpoints, on their own listener when
+	// ADMIN_ADDR is set so it can be firewalled apart
+	ops := chi.Router(r)
+	if cfg.Server.AdminAddr != "" {
+		ops = chi.NewRouter()
+		ops.NotFound(httpapi.NotFound)
+		ops.Use(func(next http.Handler) http.Handler {
+			return otelhttp.NewHandler(next, "service-b-admin")
+		})
+		ops.Use(httpapi.TraceID)
+		ops.Use(accessLog)
+		ops.Use(httpapi.Recoverer(logger))
+	}
+
+	// Recent and active requests, for when the tracing backend is down;
+	// served with the admin endpoints below
+	r.Use(httpapi.RequestTrace("service-b", cfg.Privacy.String))
+
+	// Fault injection for resilience testing, off unless CHAOS_* is set
+	if cfg.Chaos.Enabled() {
+		logger.Printf("Chaos faults enabled: %+v", cfg.Chaos)
+		r.Use(chaos.Middleware(cfg.Chaos))
+	}
+
+	// Routes
+	contract := httpapi.LoadContract(openAPISpec, logger)
+	weatherRoute := httpapi.Timeout(cfg.HandlerTimeout, logger)(contract.Validate("invalid zipcode")(handler))
+	// Failed lookups are kept for admins to inspect and replay
+	var failures *httpapi.Failures
+	if cfg.AdminToken != "" && cfg.Routes.Enabled(httpapi.RoutesAdmin) && cfg.FailedLookups > 0 {
+		failures = httpapi.NewFailures(cfg.FailedLookups)
+		r.With(failures.Middleware).Method(http.MethodPost, "/weather", weatherRoute)
+	} else {
+		r.Method(http.MethodPost, "/weather", weatherRoute)
+	}
+	summaryHandler := httpapi.NewSummaryHandler(cepResolver, weatherProvider, tracer, logger)
+	r.With(contract.Validate("invalid city")).Get("/summary", summaryHandler.ServeCity)
+	r.With(contract.Validate("invalid zipcode")).Method(http.MethodGet, "/summary/{cep}", summaryHandler)
+	r.With(contract.Validate("invalid area code")).Method(http.MethodGet, "/ddd/{ddd}", httpapi.NewDDDHandler(weatherProvider, cfg.Precision, tracer, logger))
+	if cfg.Routes.Enabled(httpapi.RoutesHistory) {
+		r.With(contract.Validate("invalid zipcode")).Method(http.MethodGet, "/compare/{cep}", httpapi.NewCompareHandler(cepResolver, weatherProvider, snapshots, cfg.Precision, tracer, logger))
+	}
+	forecast := cfg.Routes.Enabled(httpapi.RoutesForecast)
+	if forecast {
+		r.With(contract.Validate("invalid zipcode")).Method(http.MethodGet, "/rain/{cep}", httpapi.NewRainHandler(cepResolver, weatherProvider, cfg.RainThreshold, tracer, logger))
+	}
+	if forecast && len(cfg.Coastal) > 0 {
+		r.With(contract.Validate("invalid zipcode")).Method(http.MethodGet, "/marine/{cep}", httpapi.NewMarineHandler(cepResolver, upstreams.marine, cfg.Coastal, tracer, logger))
+	}
+	if forecast && upstreams.pollen != nil {
+		pollenAnswers := cache.New[httpapi.PollenResponse]("pollen", cfg.PollenCache)
+		board.AddCache("pollen", pollenAnswers.Stats)
+		r.With(contract.Validate("invalid zipcode")).Method(http.MethodGet, "/pollen/{cep}", httpapi.NewPollenHandler(cepResolver, upstreams.pollen, pollenAnswers, cfg.PollenTTL, tracer, logger))
+	}
+	if forecast && len(cfg.HeatRiskCEPs) > 0 {
+		monitor := heatrisk.NewMonitor(cfg.HeatRiskCEPs, cepResolver, weatherProvider, logger)
+		go monitor.Run(context.Background(), cfg.HeatRiskEvery)
+		r.With(contract.Validate("invalid zipcode")).Method(http.MethodGet, "/risk/{cep}", httpapi.NewRiskHandler(monitor, tracer))
+	}
+	var scheduler *schedule.Scheduler
+	if len(cfg.Schedules) > 0 {
+		scheduler = schedule.New(cfg.Schedules, func(ctx context.Context, code string) error {
+			address, err := cepResolver.Lookup(ctx, code)
+			if err != nil {
+				return err
+			}
+			_, err = weatherProvider.Current(ctx, address.Localidade)
+			return err
+		}, logger)
+		go scheduler.Run(context.Background())
+	}
+
+	// API documentation
+	if cfg.Routes.Enabled(httpapi.RoutesDocs) {
+		r.Get("/openapi.json", contract.SpecHandler)
+		r.Get("/docs", contract.DocsHandler)
+	}
+
+	// Health check, status page and metrics
+	r.Get("/health", httpapi.Health)
+	ops.Method(http.MethodGet, "/status", board)
+	ops.Method(http.MethodGet, "/metrics", metrics)
+
+	// Runtime configuration, only when an admin token is configured
+	if cfg.AdminToken != "" && cfg.Routes.Enabled(httpapi.RoutesAdmin) {
+		effective := func() any {
+			masked := cfg
+			masked.WeatherAPIKey = admin.Mask(masked.WeatherAPIKey)
+			masked.PollenAPIKey = admin.Mask(masked.PollenAPIKey)
+			masked.AdminToken = admin.Mask(masked.AdminToken)
+			masked.CEPCacheKey = admin.Mask(masked.CEPCacheKey)
+			masked.UpstreamProxy = admin.MaskURL(masked.UpstreamProxy)
+			masked.ViaCEPURL = admin.MaskURL(masked.ViaCEPURL)
+			masked.WeatherAPIURL = admin.MaskURL(masked.WeatherAPIURL)
+			// Gateway tokens travel in these
+			masked.UpstreamHeaders = upstream.Headers{}
+			for provider, header := range cfg.UpstreamHeaders {
+				masked.UpstreamHeaders[provider] = http.Header{}
+				for name, values := range header {
+					for _, v := range values {
+						masked.UpstreamHeaders[provider].Add(name, admin.Mask(v))
+					}
+				}
+			}
+			return masked
+		}
+		tokens, err := admin.ParseTokens(cfg.AdminToken)
+		if err != nil {
+			log.Fatalf("Invalid ADMIN_TOKEN: %v", err)
+		}
+		var auditLog *audit.Log
+		if cfg.AuditLogFile != "" {
+			if auditLog, err = audit.Open(cfg.AuditLogFile); err != nil {
+				log.Fatalf("Invalid AUDIT_LOG_FILE: %v", err)
+			}
+		}
+		settings := map[string]admin.Setting{
+			"log_level":            logLevel.Setting(),
+			"sample_ratio":         admin.Ratio(telemetry.SampleRatio, telemetry.SetSampleRatio),
+			"slow_trace_threshold": admin.Duration(telemetry.SlowThreshold, telemetry.SetSlowThreshold),
+			"readings_ttl":         admin.Duration(readings.TTL, readings.SetTTL),
+		}
+		if cepCache != nil {
+			settings["cep_cache_ttl"] = admin.Duration(cepCache.TTL, cepCache.SetTTL)
+		}
+		ops.Handle("/admin/config", admin.NewHandler(tokens, effective, settings, auditLog, logger))
+		ops.Method(http.MethodGet, httpapi.RequestsPath, admin.RequireToken(tokens, httpapi.RequestsHandler(), auditLog, logger))
+		if failures != nil {
+			failuresHandler := admin.NewFailuresHandler(tokens, failures, weatherRoute, auditLog, logger)
+			ops.Handle(admin.FailuresPath, failuresHandler)
+			ops.Handle(admin.FailuresPath+"/*", failuresHandler)
+		}
+		if scheduler != nil {
+			ops.Method(http.MethodGet, admin.SchedulePath, admin.ScheduleHandler(tokens, scheduler, auditLog, logger))
+		}
+
+		// What service B keeps about a CEP, deleted on request. Snapshots
+		// and readings are kept by city, not CEP, so they stay
+		stores := make(map[string]admin.Forget)
+		if cepCache != nil {
+			stores["cep_cache"] = func(code string) (int, error) {
+				// Entries written before PRIVACY_MODE was turned on are
+				// still under the plain CEP
+				deleted := 0
+				for _, key := range slices.Compact([]string{cfg.Privacy.Key(code), code}) {
+					found, err := cepCache.Delete(key)
+					if err != nil {
+						return deleted, err
+					}
+					if found {
+						deleted++
+					}
+				}
+				return deleted, nil
+			}
+		}
+		if failures != nil {
+			stores["failed_lookups"] = func(code string) (int, error) {
+				// Upstream calls keep the CEP redacted by the privacy mode
+				redacted := cfg.Privacy.CEP(code)
+				return failures.RemoveFunc(func(l httpapi.FailedLookup) bool {
+					return l.Mentions(code) || (redacted != code && l.Mentions(redacted))
+				}), nil
+			}
+		}
+		ops.Handle(admin.DataPath+"/*", admin.NewDataHandler(tokens, stores, cfg.Privacy.CEP, auditLog, logger))
+
+		if auditLog != nil && cfg.AuditRetention > 0 {
+			purgeJobs = append(purgeJobs, retention.Job{Name: "audit log", Purge: func() (int, error) { return auditLog.Purge(cfg.AuditRetention) }})
+		}
+	}
+	if cfg.PurgeInterval > 0 {
+		go retention.Run(context.Background(), cfg.PurgeInterval, purgeJobs, logger)
+	}
+
+	return r, ops
+}
+
+func main() {
+	// Initialize tracing
+	shutdown := telemetry.InitTracer("service-b")
+	defer shutdown()
+
+	// Initialize metrics
+	metrics, shutdownMetrics := telemetry.InitMeter("service-b")
+	defer shutdownMetrics()
+
+	cfg := loadConfig()
+	logger := log.New(cfg.Privacy.Writer(os.Stderr), "", log.LstdFlags)
+	telemetry.SetRedactor(cfg.Privacy.String)
+
+	// Read and forward trace context in the formats our callers speak
+	if err := telemetry.SetPropagators(cfg.Propagators); err != nil {
+		log.Fatalf("Invalid OTEL_PROPAGATORS: %v", err)
+	}
+
+	// Continuous profiling, on its own port, only when configured
+	if cfg.ProfilingAddr != "" {
+		telemetry.StartProfiling(cfg.ProfilingAddr, logger)
+	}
+
+	// Wait for the trace collector rather than start without it; the
+	// weather APIs are someone else's, so they are left to the retries
+	if cfg.StartupWait > 0 {
+		collector, err := startup.FromURLs("zipkin", telemetry.ZipkinEndpoint())
+		if err != nil {
+			log.Fatalf("Invalid dependency address: %v", err)
+		}
+		if err := startup.Wait(context.Background(), []startup.Dependency{collector}, cfg.StartupWait, logger); err != nil {
+			log.Fatalf("Failed to reach dependencies: %v", err)
+		}
+	}
+
+	r, ops := newRouter(cfg, logger, metrics)
+	serve(cfg, r, ops, logger)
+}
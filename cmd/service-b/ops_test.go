@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/offerni/weathercheck/internal/cache"
+	"github.com/offerni/weathercheck/internal/httpapi"
+	"github.com/offerni/weathercheck/internal/privacy"
+	"github.com/offerni/weathercheck/internal/schedule"
+)
+
+// TestDebugRequests checks /debug/requests is served with /metrics and
+// /status, behind the admin token, whether or not ADMIN_ADDR splits them
+// off the public router
+func TestDebugRequests(t *testing.T) {
+	for _, adminAddr := range []string{"", ":9090"} {
+		t.Run("ADMIN_ADDR="+adminAddr, func(t *testing.T) {
+			redactor, _ := privacy.New(privacy.Off, "")
+			cfg := config{
+				ProviderMode:    "mock",
+				WeatherProvider: "weatherapi",
+				Precision:       2,
+				LogLevel:        "error",
+				SampleRatio:     1,
+				SlowThreshold:   time.Second,
+				HandlerTimeout:  10 * time.Second,
+				Readings:        cache.Options{MaxEntries: 10},
+				SnapshotTTL:     48 * time.Hour,
+				Privacy:         redactor,
+				AdminToken:      "t0ken",
+				Server:          httpapi.ServerConfig{AdminAddr: adminAddr},
+			}
+			r, ops := newRouter(cfg, log.New(io.Discard, "", 0), http.NotFoundHandler())
+
+			lookup := httptest.NewRequest(http.MethodPost, "/weather", strings.NewReader(`{"cep":"01001000"}`))
+			lookup.Header.Set("Content-Type", httpapi.FormatJSON)
+			r.ServeHTTP(httptest.NewRecorder(), lookup)
+
+			tests := []struct {
+				name, auth string
+				want       int
+			}{
+				{"no token", "", http.StatusUnauthorized},
+				{"wrong token", "Bearer nope", http.StatusUnauthorized},
+				{"admin token", "Bearer t0ken", http.StatusOK},
+			}
+			for _, tt := range tests {
+				req := httptest.NewRequest(http.MethodGet, httpapi.RequestsPath, nil)
+				if tt.auth != "" {
+					req.Header.Set("Authorization", tt.auth)
+				}
+				rec := httptest.NewRecorder()
+				ops.ServeHTTP(rec, req)
+				if rec.Code != tt.want {
+					t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+				}
+				if tt.want == http.StatusOK && !strings.Contains(rec.Body.String(), "service-b") {
+					t.Errorf("%s: page doesn't list the service-b requests:\n%s", tt.name, rec.Body)
+				}
+			}
+		})
+	}
+}
+
+// TestAdminSchedule checks the scheduled groups and their next runs are
+// listed behind the admin token
+func TestAdminSchedule(t *testing.T) {
+	groups, err := schedule.ParseGroups("coastal=*/10 * * * *:11010000,88010000;inland=@hourly:13015904", func(string) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	redactor, _ := privacy.New(privacy.Off, "")
+	cfg := config{
+		ProviderMode:    "mock",
+		WeatherProvider: "weatherapi",
+		Precision:       2,
+		LogLevel:        "error",
+		SampleRatio:     1,
+		SlowThreshold:   time.Second,
+		HandlerTimeout:  10 * time.Second,
+		Readings:        cache.Options{MaxEntries: 10},
+		SnapshotTTL:     48 * time.Hour,
+		Schedules:       groups,
+		Privacy:         redactor,
+		AdminToken:      "t0ken",
+	}
+	_, ops := newRouter(cfg, log.New(io.Discard, "", 0), http.NotFoundHandler())
+
+	rec := httptest.NewRecorder()
+	ops.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/schedule", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("without a token: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/schedule", nil)
+	req.Header.Set("Authorization", "Bearer t0ken")
+	rec = httptest.NewRecorder()
+	ops.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var resp struct {
+		Groups []struct {
+			Name     string    `json:"name"`
+			Schedule string    `json:"schedule"`
+			CEPs     []string  `json:"ceps"`
+			NextRun  time.Time `json:"next_run"`
+		} `json:"groups"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Groups) != 2 || resp.Groups[0].Name != "coastal" || resp.Groups[1].Schedule != "@hourly" || len(resp.Groups[0].CEPs) != 2 {
+		t.Fatalf("groups = %+v", resp.Groups)
+	}
+	for _, g := range resp.Groups {
+		if !g.NextRun.After(time.Now()) || g.NextRun.After(time.Now().Add(time.Hour)) {
+			t.Errorf("%s: next run %s isn't within the hour", g.Name, g.NextRun)
+		}
+	}
+}
+
+// TestAdminDeleteData checks DELETE /admin/data/{cep} drops the CEP from
+// the disk cache, under its hashed key in privacy mode
+func TestAdminDeleteData(t *testing.T) {
+	redactor, _ := privacy.New(privacy.Hash, "salt")
+	cfg := config{
+		ProviderMode:    "mock",
+		WeatherProvider: "weatherapi",
+		Precision:       2,
+		LogLevel:        "error",
+		SampleRatio:     1,
+		SlowThreshold:   time.Second,
+		HandlerTimeout:  10 * time.Second,
+		Readings:        cache.Options{MaxEntries: 10},
+		SnapshotTTL:     48 * time.Hour,
+		CEPCacheFile:    filepath.Join(t.TempDir(), "ceps.db"),
+		FailedLookups:   10,
+		Privacy:         redactor,
+		AdminToken:      "t0ken",
+	}
+	r, ops := newRouter(cfg, log.New(io.Discard, "", 0), http.NotFoundHandler())
+
+	lookup := httptest.NewRequest(http.MethodPost, "/weather", strings.NewReader(`{"cep":"01001000"}`))
+	lookup.Header.Set("Content-Type", httpapi.FormatJSON)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, lookup)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("lookup: status = %d", rec.Code)
+	}
+
+	for _, want := range []int{1, 0} {
+		req := httptest.NewRequest(http.MethodDelete, "/admin/data/01001000", nil)
+		req.Header.Set("Authorization", "Bearer t0ken")
+		rec := httptest.NewRecorder()
+		ops.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+		}
+		var resp struct {
+			Deleted map[string]int `json:"deleted"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Deleted["cep_cache"] != want {
+			t.Errorf("deleted = %v, want %d from cep_cache", resp.Deleted, want)
+		}
+		if _, ok := resp.Deleted["failed_lookups"]; !ok {
+			t.Errorf("deleted = %v, want failed_lookups listed", resp.Deleted)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"time"
+
+	"github.com/offerni/weathercheck/internal/cep"
+	"github.com/offerni/weathercheck/internal/privacy"
+)
+
+// privateStore keeps resolved CEPs on disk under their hashed form and
+// without the street address, which answering a weather lookup never
+// needs, so the file holds no personal data in privacy mode
+type privateStore struct {
+	cep.Store
+	privacy *privacy.Redactor
+}
+
+// newCEPStore returns store as is unless privacy redaction is on
+func newCEPStore(store cep.Store, redactor *privacy.Redactor) cep.Store {
+	if !redactor.Enabled() {
+		return store
+	}
+	return privateStore{Store: store, privacy: redactor}
+}
+
+func (s privateStore) Get(code string) (cep.Address, time.Time, bool) {
+	address, storedAt, ok := s.Store.Get(s.privacy.Key(code))
+	if ok {
+		address.CEP = code
+	}
+	return address, storedAt, ok
+}
+
+func (s privateStore) Set(code string, address cep.Address) error {
+	address.CEP, address.Logradouro, address.Complemento, address.Bairro = "", "", "", ""
+	return s.Store.Set(s.privacy.Key(code), address)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/offerni/weathercheck/internal/cep"
+	"github.com/offerni/weathercheck/internal/privacy"
+)
+
+// mapStore is a cep.Store kept in a map
+type mapStore map[string]cep.Address
+
+func (s mapStore) Get(code string) (cep.Address, time.Time, bool) {
+	a, ok := s[code]
+	return a, time.Now(), ok
+}
+
+func (s mapStore) Set(code string, address cep.Address) error {
+	s[code] = address
+	return nil
+}
+
+func TestPrivateStore(t *testing.T) {
+	address := cep.Address{CEP: "01001-000", Logradouro: "Praça da Sé", Bairro: "Sé", Localidade: "São Paulo", UF: "SP"}
+	for _, mode := range []privacy.Mode{privacy.Off, privacy.Hash, privacy.Truncate} {
+		t.Run(string(mode), func(t *testing.T) {
+			redactor, err := privacy.New(mode, "salt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			disk := mapStore{}
+			store := newCEPStore(disk, redactor)
+			store.Set("01001000", address)
+
+			got, _, ok := store.Get("01001000")
+			if !ok || got.Localidade != "São Paulo" {
+				t.Fatalf("Get = %+v, %v; want the stored city", got, ok)
+			}
+			for key, kept := range disk {
+				if mode == privacy.Off {
+					if key != "01001000" || kept.Logradouro != address.Logradouro {
+						t.Errorf("off kept %q: %+v, want the CEP and full address", key, kept)
+					}
+					continue
+				}
+				if strings.Contains(key, "01001") {
+					t.Errorf("key %q has the CEP", key)
+				}
+				if kept.CEP != "" || kept.Logradouro != "" || kept.Bairro != "" {
+					t.Errorf("kept %+v, want no CEP or street address", kept)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+//go:build cloudfunctions && !lambda
+
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/GoogleCloudPlatform/functions-framework-go/funcframework"
+	"github.com/GoogleCloudPlatform/functions-framework-go/functions"
+)
+
+// functionName is the entry point to deploy, and the default
+// FUNCTION_TARGET
+const functionName = "ServiceB"
+
+// serve runs the router as a Google Cloud Functions HTTP function, on the
+// port the platform sets in PORT. As with Lambda, there is no second
+// listener, so the admin router is left out.
+func serve(_ config, handler, _ http.Handler, _ *log.Logger) {
+	functions.HTTP(functionName, func(w http.ResponseWriter, r *http.Request) {
+		// Export spans before the instance is throttled between requests
+		defer flushTraces(r.Context())
+		handler.ServeHTTP(w, r)
+	})
+
+	// Without a target the framework serves each function under its own
+	// name instead of at /
+	if os.Getenv("FUNCTION_TARGET") == "" {
+		os.Setenv("FUNCTION_TARGET", functionName)
+	}
+	port := envOr("PORT", "8080")
+	log.Printf("Service B starting as Cloud Function %s on port %s", functionName, port)
+	if err := funcframework.Start(port); err != nil {
+		log.Fatal(err)
+	}
+}
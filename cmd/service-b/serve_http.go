@@ -0,0 +1,18 @@
+//go:build !lambda && !cloudfunctions
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/offerni/weathercheck/internal/httpapi"
+)
+
+func serve(cfg config, handler, admin http.Handler, logger *log.Logger) {
+	fmt.Println("Service B starting on port 8081")
+	if err := httpapi.ListenAndServe(cfg.Server, handler, admin, logger); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,81 @@
+//go:build lambda
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+// serve runs the router as an AWS Lambda function behind API Gateway,
+// translating proxy events to HTTP requests and back. There is no second
+// listener, so the admin router is left out.
+func serve(_ config, handler, _ http.Handler, _ *log.Logger) {
+	log.Println("Service B starting as AWS Lambda handler")
+	lambda.Start(func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		// Export spans before the execution environment is frozen
+		defer flushTraces(ctx)
+
+		req, err := toHTTPRequest(ctx, event)
+		if err != nil {
+			return events.APIGatewayProxyResponse{StatusCode: http.StatusBadRequest}, nil
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		return events.APIGatewayProxyResponse{
+			StatusCode:        rec.Code,
+			MultiValueHeaders: rec.Header(),
+			Body:              rec.Body.String(),
+		}, nil
+	})
+}
+
+func toHTTPRequest(ctx context.Context, event events.APIGatewayProxyRequest) (*http.Request, error) {
+	body := event.Body
+	if event.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return nil, err
+		}
+		body = string(decoded)
+	}
+
+	query := url.Values{}
+	for k, vs := range event.MultiValueQueryStringParameters {
+		query[k] = vs
+	}
+	for k, v := range event.QueryStringParameters {
+		if _, ok := query[k]; !ok {
+			query.Set(k, v)
+		}
+	}
+
+	target := (&url.URL{Path: event.Path, RawQuery: query.Encode()}).String()
+	req, err := http.NewRequestWithContext(ctx, event.HTTPMethod, target, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	for k, vs := range event.MultiValueHeaders {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	for k, v := range event.Headers {
+		if req.Header.Get(k) == "" {
+			req.Header.Set(k, v)
+		}
+	}
+
+	return req, nil
+}
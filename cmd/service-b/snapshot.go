@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/offerni/weathercheck/internal/httpapi"
+	"github.com/offerni/weathercheck/internal/snapshot"
+	"github.com/offerni/weathercheck/internal/weather"
+)
+
+// snapshotWeather records every successful reading as a snapshot, for the
+// comparisons with yesterday
+type snapshotWeather struct {
+	httpapi.WeatherProvider
+	snapshots *snapshot.Store
+	logger    *log.Logger
+}
+
+func (s snapshotWeather) Current(ctx context.Context, city string) (*weather.Conditions, error) {
+	data, err := s.WeatherProvider.Current(ctx, city)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.snapshots.Record(city, data.TempC, time.Now()); err != nil {
+		httpapi.Logf(ctx, s.logger, "Failed to record snapshot for %s: %v", city, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/offerni/weathercheck/internal/cep"
+	"github.com/offerni/weathercheck/internal/httpapi"
+	"github.com/offerni/weathercheck/internal/ibge"
+	"github.com/offerni/weathercheck/internal/status"
+	"github.com/offerni/weathercheck/internal/weather"
+)
+
+// trackedCEP reports each lookup's outcome on the status board. Unknown or
+// invalid CEPs are answers, not upstream failures.
+type trackedCEP struct {
+	httpapi.CEPResolver
+	name  string
+	board *status.Board
+}
+
+func (t trackedCEP) Lookup(ctx context.Context, code string) (*cep.Address, error) {
+	addr, err := t.CEPResolver.Lookup(ctx, code)
+	if errors.Is(err, cep.ErrNotFound) || errors.Is(err, cep.ErrInvalid) {
+		t.board.Observe(t.name, nil)
+	} else {
+		t.board.Observe(t.name, err)
+	}
+	return addr, err
+}
+
+// trackedMunicipality reports each IBGE lookup's outcome on the status
+// board
+type trackedMunicipality struct {
+	httpapi.MunicipalityResolver
+	name  string
+	board *status.Board
+}
+
+func (t trackedMunicipality) Lookup(ctx context.Context, code string) (*ibge.Municipality, error) {
+	m, err := t.MunicipalityResolver.Lookup(ctx, code)
+	if errors.Is(err, ibge.ErrNotFound) {
+		t.board.Observe(t.name, nil)
+	} else {
+		t.board.Observe(t.name, err)
+	}
+	return m, err
+}
+
+// trackedWeather reports each provider call's outcome on the status board
+type trackedWeather struct {
+	httpapi.WeatherProvider
+	name  string
+	board *status.Board
+}
+
+func (t trackedWeather) Current(ctx context.Context, city string) (*weather.Conditions, error) {
+	data, err := t.WeatherProvider.Current(ctx, city)
+	if errors.Is(err, weather.ErrLocationNotFound) {
+		t.board.Observe(t.name, nil)
+	} else {
+		t.board.Observe(t.name, err)
+	}
+	return data, err
+}
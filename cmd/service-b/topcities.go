@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/offerni/weathercheck/internal/httpapi"
+	"github.com/offerni/weathercheck/internal/telemetry"
+	"github.com/offerni/weathercheck/internal/weather"
+)
+
+// topCityMinLookups is how many lookups a city needs to get its own label
+const topCityMinLookups = 10
+
+// cityLookups counts weather lookups by city, under a label bounded by
+// cities so the busiest ones show by name and the rest as other
+type cityLookups struct {
+	httpapi.WeatherProvider
+	cities  *telemetry.TopValues
+	lookups metric.Int64Counter
+}
+
+func newCityLookups(next httpapi.WeatherProvider, size int) cityLookups {
+	meter := otel.Meter("github.com/offerni/weathercheck/cmd/service-b")
+	lookups, _ := meter.Int64Counter("weather.lookups.by_city",
+		metric.WithDescription("Weather lookups by city, the busiest by name and the rest as other"))
+	return cityLookups{
+		WeatherProvider: next,
+		cities:          telemetry.NewTopValues(size, topCityMinLookups),
+		lookups:         lookups,
+	}
+}
+
+func (c cityLookups) Current(ctx context.Context, city string) (*weather.Conditions, error) {
+	c.lookups.Add(ctx, 1, metric.WithAttributes(attribute.String("city", c.cities.Label(city))))
+	return c.WeatherProvider.Current(ctx, city)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/offerni/weathercheck/internal/temperature"
+	"github.com/offerni/weathercheck/pkg/client"
+)
+
+type result struct {
+	CEP   string   `json:"cep"`
+	City  string   `json:"city,omitempty"`
+	TempC *float64 `json:"temp_C,omitempty"`
+	TempF *float64 `json:"temp_F,omitempty"`
+	TempK *float64 `json:"temp_K,omitempty"`
+	TempR *float64 `json:"temp_R,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+func main() {
+	apiURL := flag.String("url", envOr("WEATHERCHECK_URL", "http://localhost:8080"), "service-a base URL")
+	cepFlag := flag.String("cep", "", "CEP to look up (additional CEPs may be passed as arguments)")
+	units := flag.String("units", "c,f,k", "comma-separated temperature units to show: c, f, k, r (Rankine)")
+	output := flag.String("output", "table", "output format: table or json")
+	standalone := flag.Bool("standalone", false, "call ViaCEP and WeatherAPI directly instead of service-a (needs WEATHER_API_KEY)")
+	timeout := flag.Duration("timeout", 30*time.Second, "overall timeout")
+	flag.Parse()
+
+	ceps := flag.Args()
+	if *cepFlag != "" {
+		ceps = append([]string{*cepFlag}, ceps...)
+	}
+	if len(ceps) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: weathercheck [flags] -cep <cep> [cep...]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	show, err := temperature.ParseUnits(*units)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var lookups []client.BatchResult
+	if *standalone {
+		lookups = lookupStandalone(ctx, ceps)
+	} else {
+		symbols := make([]string, len(show))
+		for i, u := range show {
+			symbols[i] = string(u)
+		}
+		lookups = client.New(*apiURL, client.WithUnits(symbols...)).Batch(ctx, ceps)
+	}
+
+	results := make([]result, 0, len(lookups))
+	failed := false
+	for _, l := range lookups {
+		results = append(results, toResult(l, show))
+		if l.Err != nil {
+			failed = true
+		}
+	}
+
+	switch *output {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(results)
+	case "table":
+		writeTable(os.Stdout, results, show)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown output format %q\n", *output)
+		os.Exit(2)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func toResult(l client.BatchResult, show []temperature.Unit) result {
+	r := result{CEP: l.CEP}
+	if l.Err != nil {
+		r.Error = l.Err.Error()
+		return r
+	}
+
+	r.City = l.Weather.City
+	for _, u := range show {
+		switch u {
+		case temperature.Celsius:
+			r.TempC = &l.Weather.TempC
+		case temperature.Fahrenheit:
+			r.TempF = l.Weather.TempF
+		case temperature.Kelvin:
+			r.TempK = l.Weather.TempK
+		case temperature.Rankine:
+			r.TempR = l.Weather.TempR
+			if r.TempR == nil {
+				// The API doesn't return Rankine by default, so derive it here
+				rankine := temperature.Round(temperature.Rankine.FromCelsius(l.Weather.TempC), standalonePrecision)
+				r.TempR = &rankine
+			}
+		}
+	}
+	return r
+}
+
+func writeTable(out io.Writer, results []result, show []temperature.Unit) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	header := []string{"CEP", "CITY"}
+	for _, u := range show {
+		header = append(header, "TEMP_"+string(u))
+	}
+	fmt.Fprintln(w, strings.Join(append(header, "ERROR"), "\t"))
+
+	for _, r := range results {
+		row := []string{r.CEP, r.City}
+		temps := map[temperature.Unit]*float64{
+			temperature.Celsius:    r.TempC,
+			temperature.Fahrenheit: r.TempF,
+			temperature.Kelvin:     r.TempK,
+			temperature.Rankine:    r.TempR,
+		}
+		for _, u := range show {
+			if t := temps[u]; t != nil {
+				row = append(row, fmt.Sprintf("%.1f", *t))
+			} else {
+				row = append(row, "-")
+			}
+		}
+		fmt.Fprintln(w, strings.Join(append(row, r.Error), "\t"))
+	}
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/otel"
+
+	"github.com/offerni/weathercheck/internal/cep"
+	"github.com/offerni/weathercheck/internal/httpapi"
+	"github.com/offerni/weathercheck/internal/mock"
+	"github.com/offerni/weathercheck/internal/temperature"
+	"github.com/offerni/weathercheck/internal/weather"
+	"github.com/offerni/weathercheck/pkg/client"
+)
+
+// standalonePrecision matches service B's default TEMPERATURE_PRECISION
+const standalonePrecision = 2
+
+// lookupStandalone resolves each CEP against ViaCEP and WeatherAPI directly,
+// mirroring what service-a and service-b do together.
+func lookupStandalone(ctx context.Context, ceps []string) []client.BatchResult {
+	tracer := otel.Tracer("weathercheck")
+	var cepClient httpapi.CEPResolver = cep.NewClient(http.DefaultClient, envOr("VIACEP_BASE_URL", cep.DefaultBaseURL), tracer)
+	var weatherClient httpapi.WeatherProvider = weather.NewClient(http.DefaultClient, envOr("WEATHER_API_BASE_URL", weather.DefaultBaseURL), os.Getenv("WEATHER_API_KEY"), tracer)
+	if os.Getenv("PROVIDER_MODE") == "mock" {
+		cepClient, weatherClient = mock.NewCEPClient(tracer), mock.NewWeatherClient(tracer)
+	}
+
+	results := make([]client.BatchResult, 0, len(ceps))
+	for _, c := range ceps {
+		w, err := standaloneWeather(ctx, cepClient, weatherClient, c)
+		results = append(results, client.BatchResult{CEP: c, Weather: w, Err: err})
+	}
+	return results
+}
+
+func standaloneWeather(ctx context.Context, cepClient httpapi.CEPResolver, weatherClient httpapi.WeatherProvider, code string) (*client.Weather, error) {
+	if !cep.Validate(code) {
+		return nil, errors.New("invalid zipcode")
+	}
+
+	address, err := cepClient.Lookup(ctx, code)
+	if err != nil {
+		return nil, errors.New("can not find zipcode")
+	}
+
+	current, err := weatherClient.Current(ctx, address.Localidade)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get weather data: %w", err)
+	}
+
+	tempC := current.TempC
+	in := func(u temperature.Unit) *float64 {
+		v := temperature.Round(u.FromCelsius(tempC), standalonePrecision)
+		return &v
+	}
+	return &client.Weather{
+		City:  address.Localidade,
+		TempC: temperature.Round(tempC, standalonePrecision),
+		TempF: in(temperature.Fahrenheit),
+		TempK: in(temperature.Kelvin),
+		TempR: in(temperature.Rankine),
+
+		Condition: string(current.Condition),
+		Icon:      current.Condition.Icon(),
+	}, nil
+}
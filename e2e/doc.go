@@ -0,0 +1,7 @@
+// Package e2e runs service A and service B as built, against stand-ins for
+// ViaCEP and WeatherAPI and a Zipkin-compatible collector, and checks the
+// answers a client gets and the trace the lookup leaves. The tests need the
+// e2e build tag and the ports 8080 and 8081:
+//
+//	go test -tags e2e ./e2e
+package e2e
@@ -0,0 +1,226 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	serviceA = "http://localhost:8080"
+	serviceB = "http://localhost:8081"
+)
+
+const forecast = `{
+  "location": {"name": "Sao Paulo", "lat": -23.53, "lon": -46.62},
+  "current": {"last_updated_epoch": 1760428800, "temp_c": 28.5, "humidity": 60, "wind_kph": 12.2, "condition": {"code": 1000}},
+  "forecast": {"forecastday": [{"day": {"maxtemp_c": 31, "mintemp_c": 19, "daily_chance_of_rain": 10}, "hour": []}]}
+}`
+
+// providers stands in for ViaCEP and WeatherAPI: CEP 01001000 is São Paulo,
+// and every other CEP is unknown
+func providers(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/ws/01001000/json/":
+			io.WriteString(w, `{"cep":"01001-000","logradouro":"Praça da Sé","bairro":"Sé","localidade":"São Paulo","uf":"SP","ibge":"3550308"}`)
+		case strings.HasPrefix(r.URL.Path, "/ws/"):
+			io.WriteString(w, `{"erro":true}`)
+		case r.URL.Path == "/v1/forecast.json" && r.URL.Query().Get("key") == "e2e-key":
+			io.WriteString(w, forecast)
+		default:
+			t.Errorf("unexpected provider call %s", r.URL.Redacted())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// span is the part of a Zipkin v2 span the tests look at
+type span struct {
+	TraceID       string `json:"traceId"`
+	Name          string `json:"name"`
+	LocalEndpoint struct {
+		ServiceName string `json:"serviceName"`
+	} `json:"localEndpoint"`
+}
+
+// collector accepts spans the way Zipkin's /api/v2/spans does
+type collector struct {
+	mu    sync.Mutex
+	spans []span
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var spans []span
+	if r.URL.Path != "/api/v2/spans" || json.NewDecoder(r.Body).Decode(&spans) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	c.spans = append(c.spans, spans...)
+	c.mu.Unlock()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// trace returns the services and names of traceID's spans collected so far
+func (c *collector) trace(traceID string) (services map[string]bool, names map[string]bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	services, names = map[string]bool{}, map[string]bool{}
+	for _, s := range c.spans {
+		if s.TraceID == traceID {
+			services[s.LocalEndpoint.ServiceName] = true
+			names[s.Name] = true
+		}
+	}
+	return services, names
+}
+
+// start builds and runs the named command with env, stopping it when the
+// test ends and logging its output if the test failed
+func start(t *testing.T, bin, name string, env ...string) {
+	t.Helper()
+	var output bytes.Buffer
+	cmd := exec.Command(filepath.Join(bin, name))
+	cmd.Env = append([]string{"PATH=" + os.Getenv("PATH"), "HOME=" + t.TempDir()}, env...)
+	cmd.Stdout, cmd.Stderr = &output, &output
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start %s: %v", name, err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+		if t.Failed() {
+			t.Logf("%s output:\n%s", name, output.String())
+		}
+	})
+}
+
+// waitHealthy polls url's health check until it answers or time runs out
+func waitHealthy(t *testing.T, url string) {
+	t.Helper()
+	deadline := time.Now().Add(15 * time.Second)
+	for time.Now().Before(deadline) {
+		if resp, err := http.Get(url + "/health"); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("%s never became healthy", url)
+}
+
+func TestLookup(t *testing.T) {
+	for _, addr := range []string{":8080", ":8081"} {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			t.Fatalf("port %s is taken: %v", addr, err)
+		}
+		ln.Close()
+	}
+
+	bin := t.TempDir()
+	build := exec.Command("go", "build", "-o", bin, "../cmd/service-a", "../cmd/service-b")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("build: %v\n%s", err, out)
+	}
+
+	upstream := providers(t)
+	spans := &collector{}
+	zipkin := httptest.NewServer(spans)
+	t.Cleanup(zipkin.Close)
+	tracing := "OTEL_EXPORTER_ZIPKIN_ENDPOINT=" + zipkin.URL + "/api/v2/spans"
+
+	start(t, bin, "service-b", tracing,
+		"PROVIDER_MODE=live",
+		"VIACEP_BASE_URL="+upstream.URL+"/ws/",
+		"WEATHER_API_BASE_URL="+upstream.URL+"/v1/",
+		"WEATHER_API_KEY=e2e-key",
+	)
+	waitHealthy(t, serviceB)
+	start(t, bin, "service-a", tracing, "SERVICE_B_URL="+serviceB)
+	waitHealthy(t, serviceA)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCity   string
+		wantCode   string
+	}{
+		{"lookup", `{"cep":"01001000"}`, http.StatusOK, "São Paulo", ""},
+		// Service A's OpenAPI contract turns away malformed CEPs before its handler
+		{"invalid CEP", `{"cep":"123"}`, http.StatusUnprocessableEntity, "", "validation_failed"},
+		{"unknown CEP", `{"cep":"99999999"}`, http.StatusNotFound, "", "zipcode_not_found"},
+	}
+	var traceID string
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(serviceA+"/weather", "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("Post: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, body)
+			}
+
+			var got struct {
+				City  string  `json:"city"`
+				TempC float64 `json:"temp_C"`
+				Code  string  `json:"code"`
+			}
+			if err := json.Unmarshal(body, &got); err != nil {
+				t.Fatalf("answer %s: %v", body, err)
+			}
+			if got.City != tt.wantCity || got.Code != tt.wantCode {
+				t.Errorf("answer = %s, want city %q and code %q", body, tt.wantCity, tt.wantCode)
+			}
+			if tt.wantCity != "" {
+				if got.TempC != 28.5 {
+					t.Errorf("temp_C = %v, want WeatherAPI's 28.5", got.TempC)
+				}
+				traceID = resp.Header.Get("X-Trace-Id")
+			}
+		})
+	}
+	if traceID == "" {
+		t.Fatal("the lookup has no X-Trace-Id")
+	}
+
+	// Spans are exported in batches, every few seconds
+	want := []string{"weather-handler", "forward-to-service-b", "get-city-from-cep", "get-weather"}
+	deadline := time.Now().Add(15 * time.Second)
+	for {
+		services, names := spans.trace(traceID)
+		missing := !services["service-a"] || !services["service-b"]
+		for _, name := range want {
+			missing = missing || !names[name]
+		}
+		if !missing {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("trace %s has spans from %v named %v, want both services and %v", traceID, services, names, want)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
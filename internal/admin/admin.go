@@ -0,0 +1,264 @@
+// Package admin serves the runtime configuration endpoint: the effective
+// configuration with secrets masked, and a few settings that may be changed
+// without a restart.
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/offerni/weathercheck/internal/audit"
+	"github.com/offerni/weathercheck/internal/httpapi"
+)
+
+// Setting is one runtime-adjustable value. Parse validates a new value and
+// returns a func that applies it, so a PATCH is either applied whole or not
+// at all.
+type Setting struct {
+	Get   func() any
+	Parse func(raw json.RawMessage) (apply func(), err error)
+}
+
+// Handler serves GET and PATCH /admin/config for callers presenting one of
+// the admin tokens as a bearer token. Changes and rejected calls are written
+// to the audit log.
+type Handler struct {
+	tokens    map[string]string
+	effective func() any
+	settings  map[string]Setting
+	audit     *audit.Log
+	logger    *log.Logger
+}
+
+// NewHandler builds the handler. tokens maps each actor to its token, as
+// returned by ParseTokens. effective returns the configuration to show and
+// must already have its secrets masked. auditLog may be nil.
+func NewHandler(tokens map[string]string, effective func() any, settings map[string]Setting, auditLog *audit.Log, logger *log.Logger) *Handler {
+	return &Handler{tokens: tokens, effective: effective, settings: settings, audit: auditLog, logger: logger}
+}
+
+// ParseTokens reads ADMIN_TOKEN: either a single token, used by the actor
+// "admin", or a comma-separated list of actor:token pairs.
+func ParseTokens(spec string) (map[string]string, error) {
+	if !strings.Contains(spec, ":") {
+		return map[string]string{"admin": spec}, nil
+	}
+
+	tokens := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		actor, token, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || actor == "" || token == "" {
+			return nil, fmt.Errorf("expected actor:token, got %q", pair)
+		}
+		tokens[actor] = token
+	}
+	return tokens, nil
+}
+
+type configResponse struct {
+	Config  any            `json:"config"`
+	Runtime map[string]any `json:"runtime"`
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	actor, ok := authorize(h.tokens, r)
+	if !ok {
+		h.record(audit.Entry{Actor: "unknown", Action: "admin.unauthorized", RemoteAddr: r.RemoteAddr})
+		w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+		writeJSON(w, http.StatusUnauthorized, httpapi.ErrorResponse{Message: "unauthorized", Code: "unauthorized"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPatch:
+		if status, resp := h.patch(r, actor); resp != nil {
+			writeJSON(w, status, resp)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PATCH")
+		writeJSON(w, http.StatusMethodNotAllowed, httpapi.ErrorResponse{Message: "method not allowed", Code: "method_not_allowed"})
+		return
+	}
+
+	runtime := make(map[string]any, len(h.settings))
+	for name, s := range h.settings {
+		runtime[name] = s.Get()
+	}
+	writeJSON(w, http.StatusOK, configResponse{Config: h.effective(), Runtime: runtime})
+}
+
+// authorize returns the actor whose token, among tokens, r presents
+func authorize(tokens map[string]string, r *http.Request) (string, bool) {
+	got := []byte(r.Header.Get("Authorization"))
+	actor := ""
+	for name, token := range tokens {
+		// Compare against every token so timing does not reveal which matched
+		if subtle.ConstantTimeCompare(got, []byte("Bearer "+token)) == 1 {
+			actor = name
+		}
+	}
+	return actor, actor != ""
+}
+
+// RequireToken serves next only to callers presenting one of tokens as a
+// bearer token, for read-only pages such as /debug/requests that don't
+// check it themselves. Rejected calls are written to auditLog, which may
+// be nil.
+func RequireToken(tokens map[string]string, next http.Handler, auditLog *audit.Log, logger *log.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := authorize(tokens, r); !ok {
+			if err := auditLog.Record(audit.Entry{Actor: "unknown", Action: "admin.unauthorized", RemoteAddr: r.RemoteAddr}); err != nil {
+				logger.Printf("Failed to write audit log: %v", err)
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			writeJSON(w, http.StatusUnauthorized, httpapi.ErrorResponse{Message: "unauthorized", Code: "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// patch applies the requested changes, auditing rejected requests too
+func (h *Handler) patch(r *http.Request, actor string) (int, *httpapi.ErrorResponse) {
+	status, resp := h.update(r, actor)
+	if resp != nil {
+		h.record(audit.Entry{Actor: actor, Action: "config.update", RemoteAddr: r.RemoteAddr, Error: resp.Message})
+	}
+	return status, resp
+}
+
+// update validates every requested change before applying any of them
+func (h *Handler) update(r *http.Request, actor string) (int, *httpapi.ErrorResponse) {
+	var changes map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&changes); err != nil {
+		return http.StatusUnprocessableEntity, &httpapi.ErrorResponse{Message: "invalid settings", Code: "invalid_setting"}
+	}
+
+	names := make([]string, 0, len(changes))
+	for name := range changes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	applies := make([]func(), 0, len(names))
+	for _, name := range names {
+		s, ok := h.settings[name]
+		if !ok {
+			return http.StatusUnprocessableEntity, &httpapi.ErrorResponse{Message: fmt.Sprintf("unknown setting %q", name), Code: "unknown_setting"}
+		}
+		apply, err := s.Parse(changes[name])
+		if err != nil {
+			return http.StatusUnprocessableEntity, &httpapi.ErrorResponse{Message: fmt.Sprintf("invalid %s: %v", name, err), Code: "invalid_setting"}
+		}
+		applies = append(applies, apply)
+	}
+
+	audited := make(map[string]audit.Change, len(names))
+	for i, apply := range applies {
+		before := h.settings[names[i]].Get()
+		apply()
+		audited[names[i]] = audit.Change{Before: before, After: h.settings[names[i]].Get()}
+		h.logger.Printf("Admin %s changed %s to %s", actor, names[i], changes[names[i]])
+	}
+	h.record(audit.Entry{Actor: actor, Action: "config.update", RemoteAddr: r.RemoteAddr, Changes: audited})
+	return http.StatusOK, nil
+}
+
+func (h *Handler) record(e audit.Entry) {
+	if err := h.audit.Record(e); err != nil {
+		h.logger.Printf("Failed to write audit log: %v", err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Mask hides a secret, keeping only whether it is set.
+func Mask(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "****"
+}
+
+// MaskURL hides the credentials in a connection URL, keeping the rest
+// readable: the password, or the user name when it is the only userinfo,
+// as tokens often are, and every query value. A URL that doesn't parse is
+// masked whole.
+func MaskURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return Mask(raw)
+	}
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+		} else {
+			u.User = url.User("xxxxx")
+		}
+	}
+	if u.RawQuery != "" {
+		query := u.Query()
+		for name := range query {
+			query[name] = []string{"xxxxx"}
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// MaskURLs masks each URL of a comma-separated list with MaskURL.
+func MaskURLs(list string) string {
+	if list == "" {
+		return ""
+	}
+	urls := strings.Split(list, ",")
+	for i, raw := range urls {
+		urls[i] = MaskURL(strings.TrimSpace(raw))
+	}
+	return strings.Join(urls, ",")
+}
+
+// Ratio is a setting holding a value between 0 and 1.
+func Ratio(get func() float64, set func(float64)) Setting {
+	return Setting{
+		Get: func() any { return get() },
+		Parse: func(raw json.RawMessage) (func(), error) {
+			var v float64
+			if err := json.Unmarshal(raw, &v); err != nil || v < 0 || v > 1 {
+				return nil, fmt.Errorf("expected a number between 0 and 1")
+			}
+			return func() { set(v) }, nil
+		},
+	}
+}
+
+// Duration is a setting holding a non-negative duration such as "5m".
+func Duration(get func() time.Duration, set func(time.Duration)) Setting {
+	return Setting{
+		Get: func() any { return get().String() },
+		Parse: func(raw json.RawMessage) (func(), error) {
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return nil, fmt.Errorf("expected a duration string")
+			}
+			d, err := time.ParseDuration(s)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("expected a non-negative duration")
+			}
+			return func() { set(d) }, nil
+		},
+	}
+}
@@ -0,0 +1,102 @@
+package admin
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/offerni/weathercheck/internal/audit"
+	"github.com/offerni/weathercheck/internal/cep"
+	"github.com/offerni/weathercheck/internal/httpapi"
+)
+
+// DataPath is where DataHandler is mounted.
+const DataPath = "/admin/data"
+
+// Forget deletes what one store keeps about a CEP, returning how many
+// entries went.
+type Forget func(cep string) (int, error)
+
+// DataHandler deletes everything the service stores about a CEP, on
+// request of its owner:
+//
+//	DELETE /admin/data/{cep}
+//
+// It answers with how many entries each store dropped. Deletions are
+// written to the audit log under the CEP as redact leaves it, so the log
+// doesn't keep what was asked to be forgotten.
+type DataHandler struct {
+	tokens map[string]string
+	stores map[string]Forget
+	redact func(string) string
+	audit  *audit.Log
+	logger *log.Logger
+}
+
+// NewDataHandler builds the handler over stores, by name. redact hides
+// the CEP in the audit log and the logs; auditLog may be nil.
+func NewDataHandler(tokens map[string]string, stores map[string]Forget, redact func(string) string, auditLog *audit.Log, logger *log.Logger) *DataHandler {
+	return &DataHandler{tokens: tokens, stores: stores, redact: redact, audit: auditLog, logger: logger}
+}
+
+type dataResponse struct {
+	CEP     string         `json:"cep"`
+	Deleted map[string]int `json:"deleted"`
+}
+
+func (h *DataHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	actor, ok := authorize(h.tokens, r)
+	if !ok {
+		h.record(audit.Entry{Actor: "unknown", Action: "admin.unauthorized", RemoteAddr: r.RemoteAddr})
+		w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+		writeJSON(w, http.StatusUnauthorized, httpapi.ErrorResponse{Message: "unauthorized", Code: "unauthorized"})
+		return
+	}
+
+	code := strings.ReplaceAll(strings.Trim(strings.TrimPrefix(r.URL.Path, DataPath), "/"), "-", "")
+	switch {
+	case r.Method != http.MethodDelete || code == "":
+		writeJSON(w, http.StatusNotFound, httpapi.ErrorResponse{Message: "not found", Code: "not_found"})
+		return
+	case !cep.Validate(code):
+		writeJSON(w, http.StatusUnprocessableEntity, httpapi.ErrorResponse{Message: "invalid zipcode", Code: "invalid_zipcode"})
+		return
+	}
+
+	// Try every store even if one fails, so as much as possible goes
+	names := make([]string, 0, len(h.stores))
+	for name := range h.stores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	deleted := make(map[string]int, len(names))
+	changes := make(map[string]audit.Change, len(names))
+	var failed []string
+	for _, name := range names {
+		n, err := h.stores[name](code)
+		if err != nil {
+			h.logger.Printf("Failed to delete data for CEP %s from %s: %v", h.redact(code), name, err)
+			failed = append(failed, name)
+		}
+		deleted[name] = n
+		changes[name] = audit.Change{Before: n, After: 0}
+	}
+
+	entry := audit.Entry{Actor: actor, Action: "data.delete", Target: h.redact(code), RemoteAddr: r.RemoteAddr, Changes: changes}
+	if len(failed) > 0 {
+		entry.Error = "failed to delete from " + strings.Join(failed, ", ")
+		h.record(entry)
+		writeJSON(w, http.StatusInternalServerError, httpapi.ErrorResponse{Message: entry.Error, Code: "internal_error"})
+		return
+	}
+	h.record(entry)
+	h.logger.Printf("Admin %s deleted the data for CEP %s: %v", actor, h.redact(code), deleted)
+	writeJSON(w, http.StatusOK, dataResponse{CEP: code, Deleted: deleted})
+}
+
+func (h *DataHandler) record(e audit.Entry) {
+	if err := h.audit.Record(e); err != nil {
+		h.logger.Printf("Failed to write audit log: %v", err)
+	}
+}
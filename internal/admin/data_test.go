@@ -0,0 +1,82 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/offerni/weathercheck/internal/audit"
+)
+
+func TestDataHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	auditLog, err := audit.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer auditLog.Close()
+
+	var asked []string
+	cepCache := func(code string) (int, error) {
+		asked = append(asked, code)
+		return 1, nil
+	}
+	failures := func(code string) (int, error) { return 2, nil }
+	redact := func(code string) string { return code[:5] + "***" }
+	h := NewDataHandler(map[string]string{"ops": "t0ken"}, map[string]Forget{"cep_cache": cepCache, "failed_lookups": failures}, redact, auditLog, log.New(io.Discard, "", 0))
+
+	tests := []struct {
+		name, method, path, auth string
+		want                     int
+	}{
+		{"no token", http.MethodDelete, "/admin/data/01001000", "", http.StatusUnauthorized},
+		{"not a CEP", http.MethodDelete, "/admin/data/0100", "Bearer t0ken", http.StatusUnprocessableEntity},
+		{"not a delete", http.MethodGet, "/admin/data/01001000", "Bearer t0ken", http.StatusNotFound},
+		{"delete", http.MethodDelete, "/admin/data/01001-000", "Bearer t0ken", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+		if tt.want != http.StatusOK {
+			continue
+		}
+		var resp dataResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if resp.CEP != "01001000" || resp.Deleted["cep_cache"] != 1 || resp.Deleted["failed_lookups"] != 2 {
+			t.Errorf("%s: response = %s", tt.name, rec.Body)
+		}
+	}
+	if len(asked) != 1 || asked[0] != "01001000" {
+		t.Errorf("stores asked for %q, want the CEP once", asked)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"action":"data.delete","target":"01001***"`) || strings.Contains(string(data), "01001000") {
+		t.Errorf("audit log doesn't have the redacted deletion:\n%s", data)
+	}
+
+	// A failing store fails the request, after the others had their go
+	asked = nil
+	h.stores["failed_lookups"] = func(string) (int, error) { return 0, errors.New("disk full") }
+	req := httptest.NewRequest(http.MethodDelete, "/admin/data/01001000", nil)
+	req.Header.Set("Authorization", "Bearer t0ken")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError || len(asked) != 1 {
+		t.Errorf("with a failing store: status = %d, other stores asked %d times", rec.Code, len(asked))
+	}
+}
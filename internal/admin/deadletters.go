@@ -0,0 +1,110 @@
+package admin
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/offerni/weathercheck/internal/audit"
+	"github.com/offerni/weathercheck/internal/httpapi"
+	"github.com/offerni/weathercheck/internal/queue"
+)
+
+// DeadLettersPath is where DeadLetterHandler is mounted.
+const DeadLettersPath = "/admin/async/dead-letters"
+
+// DeadLetterHandler lets admins inspect failed async jobs and retry or
+// discard them:
+//
+//	GET    /admin/async/dead-letters            lists them, oldest first
+//	POST   /admin/async/dead-letters/{id}/retry runs one again
+//	DELETE /admin/async/dead-letters/{id}       discards one
+//
+// Retries and discards are written to the audit log.
+type DeadLetterHandler struct {
+	tokens      map[string]string
+	deadLetters *queue.DeadLetters
+	retry       func(ctx context.Context, letter queue.DeadLetter) error
+	audit       *audit.Log
+	logger      *log.Logger
+}
+
+// NewDeadLetterHandler builds the handler; retry runs a dead letter again
+// and fails if it fails again. auditLog may be nil.
+func NewDeadLetterHandler(tokens map[string]string, deadLetters *queue.DeadLetters, retry func(ctx context.Context, letter queue.DeadLetter) error, auditLog *audit.Log, logger *log.Logger) *DeadLetterHandler {
+	return &DeadLetterHandler{tokens: tokens, deadLetters: deadLetters, retry: retry, audit: auditLog, logger: logger}
+}
+
+type deadLettersResponse struct {
+	DeadLetters []queue.DeadLetter `json:"dead_letters"`
+}
+
+func (h *DeadLetterHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	actor, ok := authorize(h.tokens, r)
+	if !ok {
+		h.record(audit.Entry{Actor: "unknown", Action: "admin.unauthorized", RemoteAddr: r.RemoteAddr})
+		w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+		writeJSON(w, http.StatusUnauthorized, httpapi.ErrorResponse{Message: "unauthorized", Code: "unauthorized"})
+		return
+	}
+
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, DeadLettersPath), "/")
+	id, action, _ := strings.Cut(rest, "/")
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, deadLettersResponse{DeadLetters: h.deadLetters.List()})
+	case id != "" && action == "retry" && r.Method == http.MethodPost:
+		h.retryOne(w, r, actor, id)
+	case id != "" && action == "" && r.Method == http.MethodDelete:
+		h.discard(w, r, actor, id)
+	default:
+		writeJSON(w, http.StatusNotFound, httpapi.ErrorResponse{Message: "not found", Code: "not_found"})
+	}
+}
+
+func (h *DeadLetterHandler) retryOne(w http.ResponseWriter, r *http.Request, actor, id string) {
+	letter, ok := h.deadLetters.Get(id)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, httpapi.ErrorResponse{Message: "no dead letter for job " + id, Code: "dead_letter_not_found"})
+		return
+	}
+
+	entry := audit.Entry{Actor: actor, Action: "dead_letter.retry", RemoteAddr: r.RemoteAddr, Changes: map[string]audit.Change{
+		id: {Before: "dead_letter", After: "delivered"},
+	}}
+	if err := h.retry(r.Context(), letter); err != nil {
+		entry.Changes, entry.Error = nil, err.Error()
+		h.record(entry)
+		writeJSON(w, http.StatusBadGateway, httpapi.ErrorResponse{Message: "retry failed: " + err.Error(), Code: "retry_failed"})
+		return
+	}
+	h.record(entry)
+	h.logger.Printf("Admin %s retried job %s", actor, id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *DeadLetterHandler) discard(w http.ResponseWriter, r *http.Request, actor, id string) {
+	removed, err := h.deadLetters.Remove(id)
+	if err != nil {
+		h.logger.Printf("Failed to discard dead letter for job %s: %v", id, err)
+		writeJSON(w, http.StatusInternalServerError, httpapi.ErrorResponse{Message: "failed to discard dead letter", Code: "internal_error"})
+		return
+	}
+	if !removed {
+		writeJSON(w, http.StatusNotFound, httpapi.ErrorResponse{Message: "no dead letter for job " + id, Code: "dead_letter_not_found"})
+		return
+	}
+
+	h.record(audit.Entry{Actor: actor, Action: "dead_letter.discard", RemoteAddr: r.RemoteAddr, Changes: map[string]audit.Change{
+		id: {Before: "dead_letter", After: "discarded"},
+	}})
+	h.logger.Printf("Admin %s discarded job %s", actor, id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *DeadLetterHandler) record(e audit.Entry) {
+	if err := h.audit.Record(e); err != nil {
+		h.logger.Printf("Failed to write audit log: %v", err)
+	}
+}
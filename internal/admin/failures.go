@@ -0,0 +1,117 @@
+package admin
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/offerni/weathercheck/internal/audit"
+	"github.com/offerni/weathercheck/internal/httpapi"
+)
+
+// FailuresPath is where FailuresHandler is mounted.
+const FailuresPath = "/admin/failures"
+
+// FailuresHandler lets admins inspect the latest failed lookups, with the
+// upstream answers behind them, and run them again once a fix is out:
+//
+//	GET    /admin/failures             lists them, oldest first
+//	GET    /admin/failures/{id}        shows one
+//	POST   /admin/failures/{id}/replay runs one again and returns the new answer
+//	DELETE /admin/failures/{id}        discards one
+//
+// Replays and discards are written to the audit log.
+type FailuresHandler struct {
+	tokens   map[string]string
+	failures *httpapi.Failures
+	lookups  http.Handler
+	audit    *audit.Log
+	logger   *log.Logger
+}
+
+// NewFailuresHandler builds the handler; lookups serves the replays, and
+// should not record failures itself. auditLog may be nil.
+func NewFailuresHandler(tokens map[string]string, failures *httpapi.Failures, lookups http.Handler, auditLog *audit.Log, logger *log.Logger) *FailuresHandler {
+	return &FailuresHandler{tokens: tokens, failures: failures, lookups: lookups, audit: auditLog, logger: logger}
+}
+
+type failuresResponse struct {
+	Failures []httpapi.FailedLookup `json:"failures"`
+}
+
+func (h *FailuresHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	actor, ok := authorize(h.tokens, r)
+	if !ok {
+		h.record(audit.Entry{Actor: "unknown", Action: "admin.unauthorized", RemoteAddr: r.RemoteAddr})
+		w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+		writeJSON(w, http.StatusUnauthorized, httpapi.ErrorResponse{Message: "unauthorized", Code: "unauthorized"})
+		return
+	}
+
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, FailuresPath), "/")
+	id, action, _ := strings.Cut(rest, "/")
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, failuresResponse{Failures: h.failures.List()})
+	case id != "" && action == "" && r.Method == http.MethodGet:
+		lookup, ok := h.failures.Get(id)
+		if !ok {
+			h.notFound(w, id)
+			return
+		}
+		writeJSON(w, http.StatusOK, lookup)
+	case id != "" && action == "replay" && r.Method == http.MethodPost:
+		h.replay(w, r, actor, id)
+	case id != "" && action == "" && r.Method == http.MethodDelete:
+		h.discard(w, r, actor, id)
+	default:
+		writeJSON(w, http.StatusNotFound, httpapi.ErrorResponse{Message: "not found", Code: "not_found"})
+	}
+}
+
+func (h *FailuresHandler) replay(w http.ResponseWriter, r *http.Request, actor, id string) {
+	lookup, ok := h.failures.Get(id)
+	if !ok {
+		h.notFound(w, id)
+		return
+	}
+
+	replayed, err := httpapi.Replay(r.Context(), h.lookups, lookup)
+	entry := audit.Entry{Actor: actor, Action: "failure.replay", RemoteAddr: r.RemoteAddr}
+	if err != nil {
+		entry.Error = err.Error()
+		h.record(entry)
+		writeJSON(w, http.StatusInternalServerError, httpapi.ErrorResponse{Message: "replay failed: " + err.Error(), Code: "replay_failed"})
+		return
+	}
+	outcome := "failed"
+	if replayed.Status < http.StatusInternalServerError {
+		outcome = "succeeded"
+	}
+	entry.Changes = map[string]audit.Change{id: {Before: "failed", After: outcome}}
+	h.record(entry)
+	h.logger.Printf("Admin %s replayed failed lookup %s: %d", actor, id, replayed.Status)
+	writeJSON(w, http.StatusOK, replayed)
+}
+
+func (h *FailuresHandler) discard(w http.ResponseWriter, r *http.Request, actor, id string) {
+	if !h.failures.Remove(id) {
+		h.notFound(w, id)
+		return
+	}
+	h.record(audit.Entry{Actor: actor, Action: "failure.discard", RemoteAddr: r.RemoteAddr, Changes: map[string]audit.Change{
+		id: {Before: "failed", After: "discarded"},
+	}})
+	h.logger.Printf("Admin %s discarded failed lookup %s", actor, id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *FailuresHandler) notFound(w http.ResponseWriter, id string) {
+	writeJSON(w, http.StatusNotFound, httpapi.ErrorResponse{Message: "no failed lookup " + id, Code: "failure_not_found"})
+}
+
+func (h *FailuresHandler) record(e audit.Entry) {
+	if err := h.audit.Record(e); err != nil {
+		h.logger.Printf("Failed to write audit log: %v", err)
+	}
+}
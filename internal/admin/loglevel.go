@@ -0,0 +1,74 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// Log levels, from most to least verbose
+var logLevels = map[string]int32{"debug": 0, "info": 1, "warn": 2, "error": 3}
+
+// LogLevel controls the request log. At debug and info every request is
+// logged; at warn and error the request log is off.
+type LogLevel struct {
+	name  atomic.Value
+	level atomic.Int32
+}
+
+// NewLogLevel starts at level, which must be debug, info, warn or error.
+func NewLogLevel(level string) (*LogLevel, error) {
+	l := &LogLevel{}
+	if err := l.set(level); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *LogLevel) set(level string) error {
+	v, ok := logLevels[level]
+	if !ok {
+		return fmt.Errorf("unknown log level %q (expected debug, info, warn or error)", level)
+	}
+	l.name.Store(level)
+	l.level.Store(v)
+	return nil
+}
+
+// String returns the current level.
+func (l *LogLevel) String() string {
+	return l.name.Load().(string)
+}
+
+// RequestLogger wraps logged, a request-logging middleware such as
+// httpapi.AccessLog, so it only runs at info or more verbose.
+func (l *LogLevel) RequestLogger(logged func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		withLog := logged(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if l.level.Load() <= logLevels["info"] {
+				withLog.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Setting exposes the level on the admin endpoint.
+func (l *LogLevel) Setting() Setting {
+	return Setting{
+		Get: func() any { return l.String() },
+		Parse: func(raw json.RawMessage) (func(), error) {
+			var level string
+			if err := json.Unmarshal(raw, &level); err != nil {
+				return nil, fmt.Errorf("expected a string")
+			}
+			if _, ok := logLevels[level]; !ok {
+				return nil, fmt.Errorf("unknown log level %q (expected debug, info, warn or error)", level)
+			}
+			return func() { l.set(level) }, nil
+		},
+	}
+}
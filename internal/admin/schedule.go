@@ -0,0 +1,24 @@
+package admin
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/offerni/weathercheck/internal/audit"
+	"github.com/offerni/weathercheck/internal/schedule"
+)
+
+// SchedulePath is where ScheduleHandler is mounted.
+const SchedulePath = "/admin/schedule"
+
+type scheduleResponse struct {
+	Groups []schedule.GroupStatus `json:"groups"`
+}
+
+// ScheduleHandler lists each scheduled group with its CEPs, its next run
+// and how its last run went, to callers holding one of tokens.
+func ScheduleHandler(tokens map[string]string, scheduler *schedule.Scheduler, auditLog *audit.Log, logger *log.Logger) http.Handler {
+	return RequireToken(tokens, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, scheduleResponse{Groups: scheduler.Status()})
+	}), auditLog, logger)
+}
@@ -0,0 +1,144 @@
+// Package audit keeps an append-only record of administrative actions,
+// trimmed only by age.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Change is the value of one setting before and after an action.
+type Change struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// Entry is one audited action.
+type Entry struct {
+	Time   time.Time `json:"time"`
+	Actor  string    `json:"actor"`
+	Action string    `json:"action"`
+	// Target is what the action applied to, when it isn't a setting
+	Target     string            `json:"target,omitempty"`
+	RemoteAddr string            `json:"remote_addr,omitempty"`
+	Changes    map[string]Change `json:"changes,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// Log appends entries to a file as JSON lines, syncing each one to disk
+// before returning. Entries are only ever removed by Purge. A nil *Log
+// records nothing.
+type Log struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// Open opens path for appending, creating it if needed.
+func Open(path string) (*Log, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening audit log: %w", err)
+	}
+	return &Log{path: path, file: file}, nil
+}
+
+// Record appends e, stamping it with the current time if unset.
+func (l *Log) Record(e Entry) error {
+	if l == nil {
+		return nil
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding audit entry: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing audit entry: %w", err)
+	}
+	return l.file.Sync()
+}
+
+// Purge drops the entries older than maxAge, rewriting the file through a
+// temporary one so a crash never leaves it half written. It returns how
+// many entries went.
+func (l *Log) Purge(maxAge time.Duration) (int, error) {
+	if l == nil {
+		return 0, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(l.path)
+	if err != nil {
+		return 0, fmt.Errorf("reading audit log: %w", err)
+	}
+	defer f.Close()
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".tmp")
+	if err != nil {
+		return 0, fmt.Errorf("purging audit log: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	since := time.Now().Add(-maxAge)
+	purged := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var e struct {
+			Time time.Time `json:"time"`
+		}
+		// Lines that don't parse are kept; an audit log loses nothing by
+		// accident
+		if json.Unmarshal(scanner.Bytes(), &e) == nil && e.Time.Before(since) {
+			purged++
+			continue
+		}
+		if _, err := tmp.Write(append(scanner.Bytes(), '\n')); err != nil {
+			tmp.Close()
+			return 0, fmt.Errorf("purging audit log: %w", err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		tmp.Close()
+		return 0, fmt.Errorf("reading audit log: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, fmt.Errorf("purging audit log: %w", err)
+	}
+	if purged == 0 {
+		return 0, nil
+	}
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		return 0, fmt.Errorf("purging audit log: %w", err)
+	}
+
+	// Keep appending to the new file
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return purged, fmt.Errorf("reopening audit log: %w", err)
+	}
+	l.file.Close()
+	l.file = file
+	return purged, nil
+}
+
+// Close closes the underlying file.
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.file.Close()
+}
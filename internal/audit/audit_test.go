@@ -0,0 +1,43 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPurge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	now := time.Now().UTC()
+	l.Record(Entry{Time: now.Add(-50 * 24 * time.Hour), Actor: "ops", Action: "config.update"})
+	l.Record(Entry{Time: now.Add(-10 * 24 * time.Hour), Actor: "ops", Action: "failure.replay"})
+	l.Record(Entry{Actor: "ops", Action: "data.delete", Target: "cep:1234"})
+
+	purged, err := l.Purge(30 * 24 * time.Hour)
+	if err != nil || purged != 1 {
+		t.Fatalf("Purge() = %d, %v; want 1 entry purged", purged, err)
+	}
+	// Entries after a purge go to the rewritten file
+	l.Record(Entry{Actor: "ops", Action: "config.update"})
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], "failure.replay") || !strings.Contains(lines[1], `"target":"cep:1234"`) {
+		t.Errorf("audit log after purge:\n%s", data)
+	}
+
+	if purged, err := l.Purge(30 * 24 * time.Hour); err != nil || purged != 0 {
+		t.Errorf("second Purge() = %d, %v; want nothing purged", purged, err)
+	}
+}
@@ -0,0 +1,188 @@
+// Package cache provides bounded in-memory caches shared by the services.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// entryOverhead is roughly what the bookkeeping of one value costs
+const entryOverhead = 64
+
+// Sizer is implemented by values that know roughly how many bytes they
+// take; other values are sized by their JSON encoding.
+type Sizer interface {
+	Size() int
+}
+
+// Cache keeps up to a number of values and of bytes, evicting by its
+// Policy when either runs out. With a TTL set, values older than the TTL
+// are treated as missing. It is safe for concurrent use.
+type Cache[V any] struct {
+	mu     sync.Mutex
+	opts   Options
+	ttl    time.Duration
+	items  map[string]*entry[V]
+	policy policy
+	bytes  int64
+
+	hits, misses atomic.Uint64
+	evictions    metric.Int64Counter
+	expirations  metric.Int64Counter
+	name         attribute.KeyValue
+}
+
+type entry[V any] struct {
+	value    V
+	storedAt time.Time
+	size     int64
+}
+
+// New builds a cache bounded by opts; name tells it apart in the metrics.
+func New[V any](name string, opts Options) *Cache[V] {
+	c := &Cache[V]{
+		opts:   opts,
+		items:  make(map[string]*entry[V]),
+		policy: newPolicy(opts.Policy, opts.MaxEntries),
+		name:   attribute.String("cache", name),
+	}
+
+	meter := otel.Meter("github.com/offerni/weathercheck/internal/cache")
+	c.evictions, _ = meter.Int64Counter("cache.evictions",
+		metric.WithDescription("Values dropped to make room, by cache and the bound that ran out"))
+	c.expirations, _ = meter.Int64Counter("cache.expirations",
+		metric.WithDescription("Values found past their TTL and dropped, by cache"))
+	entries, _ := meter.Int64ObservableGauge("cache.entries",
+		metric.WithDescription("Values held, by cache"))
+	size, _ := meter.Int64ObservableGauge("cache.size",
+		metric.WithDescription("Approximate memory the values take, by cache"),
+		metric.WithUnit("By"))
+	meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		c.mu.Lock()
+		n, bytes := len(c.items), c.bytes
+		c.mu.Unlock()
+		o.ObserveInt64(entries, int64(n), metric.WithAttributes(c.name))
+		o.ObserveInt64(size, bytes, metric.WithAttributes(c.name))
+		return nil
+	}, entries, size)
+	return c
+}
+
+// Get returns the value stored under key and when it was stored.
+func (c *Cache[V]) Get(key string) (V, time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		c.misses.Add(1)
+		var zero V
+		return zero, time.Time{}, false
+	}
+	if c.ttl > 0 && time.Since(e.storedAt) > c.ttl {
+		c.policy.remove(key)
+		c.drop(key, e)
+		c.expirations.Add(context.Background(), 1, metric.WithAttributes(c.name))
+		c.misses.Add(1)
+		var zero V
+		return zero, time.Time{}, false
+	}
+	c.policy.touch(key)
+	c.hits.Add(1)
+	return e.value, e.storedAt, true
+}
+
+// Stats returns how many lookups found a value and how many did not.
+func (c *Cache[V]) Stats() (hits, misses uint64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
+// TTL returns how long values stay valid; zero means forever.
+func (c *Cache[V]) TTL() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ttl
+}
+
+// SetTTL changes how long values stay valid, including those already stored.
+func (c *Cache[V]) SetTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl = ttl
+}
+
+// Set stores value under key, replacing any previous value. A value larger
+// than the byte bound on its own isn't stored.
+func (c *Cache[V]) Set(key string, value V) {
+	size := sizeOf(key, value)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		c.bytes += size - e.size
+		e.value, e.storedAt, e.size = value, time.Now(), size
+		c.policy.touch(key)
+		// A bigger value may not fit any more
+		for len(c.items) > 0 && c.opts.MaxBytes > 0 && c.bytes > c.opts.MaxBytes {
+			c.evict("", "bytes")
+		}
+		return
+	}
+	if c.opts.MaxBytes > 0 && size > c.opts.MaxBytes {
+		return
+	}
+
+	for len(c.items) > 0 && c.opts.MaxEntries > 0 && len(c.items) >= c.opts.MaxEntries {
+		c.evict(key, "entries")
+	}
+	for len(c.items) > 0 && c.opts.MaxBytes > 0 && c.bytes+size > c.opts.MaxBytes {
+		c.evict(key, "bytes")
+	}
+	c.items[key] = &entry[V]{value: value, storedAt: time.Now(), size: size}
+	c.bytes += size
+	c.policy.add(key)
+}
+
+// DeleteFunc removes the values whose key match picks, returning how many
+// went.
+func (c *Cache[V]) DeleteFunc(match func(key string) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for key, e := range c.items {
+		if match(key) {
+			c.policy.remove(key)
+			c.drop(key, e)
+			n++
+		}
+	}
+	return n
+}
+
+// evict drops the value the policy picks to make room for incoming
+func (c *Cache[V]) evict(incoming, bound string) {
+	key := c.policy.victim(incoming)
+	c.drop(key, c.items[key])
+	c.evictions.Add(context.Background(), 1, metric.WithAttributes(c.name, attribute.String("bound", bound)))
+}
+
+func (c *Cache[V]) drop(key string, e *entry[V]) {
+	delete(c.items, key)
+	c.bytes -= e.size
+}
+
+func sizeOf[V any](key string, value V) int64 {
+	n := len(key) + entryOverhead
+	if s, ok := any(value).(Sizer); ok {
+		return int64(n + s.Size())
+	}
+	b, _ := json.Marshal(value)
+	return int64(n + len(b))
+}
@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// BenchmarkCache looks up CEPs drawn from a Zipf distribution, the way a few
+// busy regions dominate real traffic, storing each one it misses, for each
+// eviction policy; hit_% is the share answered from the cache
+func BenchmarkCache(b *testing.B) {
+	const cached, distinct = 1000, 100000
+	keys := make([]string, distinct)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%08d", i*997%100000000)
+	}
+
+	for _, policy := range []Policy{PolicyLRU, PolicyLFU, PolicyARC} {
+		b.Run(string(policy), func(b *testing.B) {
+			c := New[string]("bench", Options{MaxEntries: cached, Policy: policy})
+			zipf := rand.NewZipf(rand.New(rand.NewSource(1)), 1.1, 1, distinct-1)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				key := keys[zipf.Uint64()]
+				if _, _, ok := c.Get(key); !ok {
+					c.Set(key, "São Paulo")
+				}
+			}
+			hits, misses := c.Stats()
+			b.ReportMetric(100*float64(hits)/float64(hits+misses), "hit_%")
+		})
+	}
+}
+
+func BenchmarkCacheGetParallel(b *testing.B) {
+	c := New[string]("bench", Options{MaxEntries: 1000})
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%08d", i)
+		c.Set(keys[i], "São Paulo")
+	}
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.Get(keys[i%len(keys)])
+			i++
+		}
+	})
+}
@@ -0,0 +1,191 @@
+package cache
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Sealer encrypts values before they are written and decrypts them once
+// read, bound to the key they are stored under.
+type Sealer interface {
+	Seal(plaintext, additional []byte) ([]byte, error)
+	Open(sealed, additional []byte) ([]byte, error)
+}
+
+// Disk keeps values in a bbolt file, so they outlive restarts; a single
+// process can hold the file at a time. With a TTL set, values older than
+// the TTL are treated as missing and deleted. It is safe for concurrent use.
+type Disk[V any] struct {
+	db     *bolt.DB
+	bucket []byte
+	sealer Sealer
+
+	mu  sync.Mutex
+	ttl time.Duration
+
+	hits, misses atomic.Uint64
+}
+
+// diskEntry is how values are written to the file
+type diskEntry[V any] struct {
+	StoredAt time.Time `json:"stored_at"`
+	Value    V         `json:"value"`
+}
+
+// OpenDisk opens, or creates, the file at path and keeps values in its
+// bucket, so several caches can share a file. With a sealer, values are
+// encrypted at rest, and those that don't open, written in plain or under
+// another key, are treated as missing.
+func OpenDisk[V any](path, bucket string, sealer Sealer) (*Disk[V], error) {
+	// Fail rather than hang when another process holds the file
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Disk[V]{db: db, bucket: []byte(bucket), sealer: sealer}, nil
+}
+
+// Get returns the value stored under key and when it was stored.
+func (d *Disk[V]) Get(key string) (V, time.Time, bool) {
+	var zero V
+	var e diskEntry[V]
+	var found bool
+	err := d.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(d.bucket).Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return d.decode(key, data, &e)
+	})
+	if err != nil || !found {
+		d.misses.Add(1)
+		return zero, time.Time{}, false
+	}
+	if ttl := d.TTL(); ttl > 0 && time.Since(e.StoredAt) > ttl {
+		d.delete(key)
+		d.misses.Add(1)
+		return zero, time.Time{}, false
+	}
+	d.hits.Add(1)
+	return e.Value, e.StoredAt, true
+}
+
+// Set stores value under key, replacing any previous value.
+func (d *Disk[V]) Set(key string, value V) error {
+	data, err := json.Marshal(diskEntry[V]{StoredAt: time.Now().UTC(), Value: value})
+	if err != nil {
+		return err
+	}
+	if d.sealer != nil {
+		if data, err = d.sealer.Seal(data, []byte(key)); err != nil {
+			return err
+		}
+	}
+	return d.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(d.bucket).Put([]byte(key), data)
+	})
+}
+
+// decode opens data stored under key into v
+func (d *Disk[V]) decode(key string, data []byte, v any) error {
+	if d.sealer != nil {
+		var err error
+		if data, err = d.sealer.Open(data, []byte(key)); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (d *Disk[V]) delete(key string) {
+	d.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(d.bucket).Delete([]byte(key))
+	})
+}
+
+// Delete removes the value stored under key, expired or not, reporting
+// whether there was one.
+func (d *Disk[V]) Delete(key string) (bool, error) {
+	var found bool
+	err := d.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(d.bucket)
+		found = b.Get([]byte(key)) != nil
+		return b.Delete([]byte(key))
+	})
+	return found && err == nil, err
+}
+
+// Len returns how many values the file holds, expired ones included.
+func (d *Disk[V]) Len() int {
+	var n int
+	d.db.View(func(tx *bolt.Tx) error {
+		n = tx.Bucket(d.bucket).Stats().KeyN
+		return nil
+	})
+	return n
+}
+
+// Prune deletes the values past the TTL, and those that don't open,
+// returning how many went.
+func (d *Disk[V]) Prune() (int, error) {
+	ttl := d.TTL()
+	var pruned int
+	err := d.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(d.bucket)
+		// Deleting while iterating skips keys, so collect them first
+		var expired [][]byte
+		b.ForEach(func(k, v []byte) error {
+			var e struct {
+				StoredAt time.Time `json:"stored_at"`
+			}
+			if err := d.decode(string(k), v, &e); err != nil || (ttl > 0 && time.Since(e.StoredAt) > ttl) {
+				expired = append(expired, k)
+			}
+			return nil
+		})
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		pruned = len(expired)
+		return nil
+	})
+	return pruned, err
+}
+
+// Stats returns how many lookups found a value and how many did not.
+func (d *Disk[V]) Stats() (hits, misses uint64) {
+	return d.hits.Load(), d.misses.Load()
+}
+
+// TTL returns how long values stay valid; zero means forever.
+func (d *Disk[V]) TTL() time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.ttl
+}
+
+// SetTTL changes how long values stay valid, including those already stored.
+func (d *Disk[V]) SetTTL(ttl time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.ttl = ttl
+}
+
+// Close releases the file.
+func (d *Disk[V]) Close() error {
+	return d.db.Close()
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Policy picks which value a full cache evicts.
+type Policy string
+
+const (
+	// PolicyLRU evicts the least recently used value.
+	PolicyLRU Policy = "lru"
+	// PolicyLFU evicts the least frequently used value, the least recently
+	// used among equals.
+	PolicyLFU Policy = "lfu"
+	// PolicyARC balances recency and frequency (Adaptive Replacement
+	// Cache), keeping values read more than once from being flushed by a
+	// burst of one-off ones.
+	PolicyARC Policy = "arc"
+)
+
+// Options bound a cache. A zero bound leaves that dimension unbounded.
+type Options struct {
+	MaxEntries int
+	// MaxBytes is approximate: values are sized by their JSON encoding
+	// unless they implement Sizer
+	MaxBytes int64
+	// Policy defaults to PolicyLRU
+	Policy Policy
+}
+
+// FromEnv reads prefix_SIZE, the most values kept (entries when unset),
+// prefix_MAX_BYTES, roughly how much memory they may take, as a byte count
+// or with a KiB, MiB or GiB suffix, and prefix_EVICTION, one of lru, lfu
+// and arc (lru when unset).
+func FromEnv(prefix string, entries int) (Options, error) {
+	opts := Options{MaxEntries: entries, Policy: PolicyLRU}
+
+	if v := os.Getenv(prefix + "_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return Options{}, fmt.Errorf("%s_SIZE: expected a number of entries, got %q", prefix, v)
+		}
+		opts.MaxEntries = n
+	}
+
+	if v := os.Getenv(prefix + "_MAX_BYTES"); v != "" {
+		n, err := parseBytes(v)
+		if err != nil {
+			return Options{}, fmt.Errorf("%s_MAX_BYTES: %w", prefix, err)
+		}
+		opts.MaxBytes = n
+	}
+
+	if v := os.Getenv(prefix + "_EVICTION"); v != "" {
+		switch p := Policy(strings.ToLower(v)); p {
+		case PolicyLRU, PolicyLFU, PolicyARC:
+			opts.Policy = p
+		default:
+			return Options{}, fmt.Errorf("%s_EVICTION: expected lru, lfu or arc, got %q", prefix, v)
+		}
+	}
+	return opts, nil
+}
+
+// parseBytes reads a byte count, optionally with a KiB, MiB or GiB suffix
+func parseBytes(s string) (int64, error) {
+	digits, multiplier := s, int64(1)
+	for suffix, m := range map[string]int64{"KiB": 1 << 10, "MiB": 1 << 20, "GiB": 1 << 30} {
+		if strings.HasSuffix(s, suffix) {
+			digits, multiplier = strings.TrimSuffix(s, suffix), m
+			break
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(digits), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("expected a size like 67108864 or 64MiB, got %q", s)
+	}
+	return n * multiplier, nil
+}
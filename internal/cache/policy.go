@@ -0,0 +1,228 @@
+package cache
+
+import (
+	"container/heap"
+	"container/list"
+)
+
+// policy tracks the keys a cache holds and picks which one to evict
+type policy interface {
+	// add records a newly stored key
+	add(key string)
+	// touch records a read or replacement of a stored key
+	touch(key string)
+	// remove forgets a key dropped for another reason than eviction
+	remove(key string)
+	// victim picks a stored key to evict to make room for incoming, empty
+	// when none is arriving, and forgets it
+	victim(incoming string) string
+}
+
+func newPolicy(p Policy, entries int) policy {
+	switch p {
+	case PolicyLFU:
+		return newLFU()
+	case PolicyARC:
+		return newARC(entries)
+	default:
+		return newLRU()
+	}
+}
+
+// lru orders keys from most to least recently used
+type lru struct {
+	order *list.List
+	items map[string]*list.Element
+}
+
+func newLRU() *lru {
+	return &lru{order: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (l *lru) add(key string) {
+	l.items[key] = l.order.PushFront(key)
+}
+
+func (l *lru) touch(key string) {
+	if el, ok := l.items[key]; ok {
+		l.order.MoveToFront(el)
+	}
+}
+
+func (l *lru) remove(key string) {
+	if el, ok := l.items[key]; ok {
+		l.order.Remove(el)
+		delete(l.items, key)
+	}
+}
+
+func (l *lru) victim(string) string {
+	oldest := l.order.Back()
+	key := oldest.Value.(string)
+	l.order.Remove(oldest)
+	delete(l.items, key)
+	return key
+}
+
+func (l *lru) has(key string) bool {
+	_, ok := l.items[key]
+	return ok
+}
+
+func (l *lru) len() int {
+	return l.order.Len()
+}
+
+// lfu keeps keys in a heap by use count, then by last use
+type lfu struct {
+	heap  lfuHeap
+	items map[string]*lfuItem
+	clock uint64
+}
+
+type lfuItem struct {
+	key   string
+	uses  uint64
+	used  uint64
+	index int
+}
+
+func newLFU() *lfu {
+	return &lfu{items: make(map[string]*lfuItem)}
+}
+
+func (l *lfu) add(key string) {
+	l.clock++
+	item := &lfuItem{key: key, uses: 1, used: l.clock}
+	l.items[key] = item
+	heap.Push(&l.heap, item)
+}
+
+func (l *lfu) touch(key string) {
+	if item, ok := l.items[key]; ok {
+		l.clock++
+		item.uses++
+		item.used = l.clock
+		heap.Fix(&l.heap, item.index)
+	}
+}
+
+func (l *lfu) remove(key string) {
+	if item, ok := l.items[key]; ok {
+		heap.Remove(&l.heap, item.index)
+		delete(l.items, key)
+	}
+}
+
+func (l *lfu) victim(string) string {
+	item := heap.Pop(&l.heap).(*lfuItem)
+	delete(l.items, item.key)
+	return item.key
+}
+
+type lfuHeap []*lfuItem
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].uses != h[j].uses {
+		return h[i].uses < h[j].uses
+	}
+	return h[i].used < h[j].used
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+func (h *lfuHeap) Push(x any) {
+	item := x.(*lfuItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *lfuHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return item
+}
+
+// arc is the Adaptive Replacement Cache of Megiddo and Modha: t1 holds keys
+// seen once recently and t2 keys seen more than once, while the ghost lists
+// b1 and b2 remember keys recently evicted from each. A miss on a ghost
+// shifts the target size p of t1 towards the list that would have kept it.
+type arc struct {
+	entries        int
+	p              int
+	t1, t2, b1, b2 *lru
+}
+
+func newARC(entries int) *arc {
+	return &arc{entries: entries, t1: newLRU(), t2: newLRU(), b1: newLRU(), b2: newLRU()}
+}
+
+// capacity is the entry bound, or what is held now when only bytes bound
+// the cache
+func (a *arc) capacity() int {
+	if a.entries > 0 {
+		return a.entries
+	}
+	return max(a.t1.len()+a.t2.len(), 1)
+}
+
+func (a *arc) add(key string) {
+	switch {
+	case a.b1.has(key):
+		a.p = min(a.p+max(a.b2.len()/max(a.b1.len(), 1), 1), a.capacity())
+		a.b1.remove(key)
+		a.t2.add(key)
+	case a.b2.has(key):
+		a.p = max(a.p-max(a.b1.len()/max(a.b2.len(), 1), 1), 0)
+		a.b2.remove(key)
+		a.t2.add(key)
+	default:
+		a.t1.add(key)
+	}
+	a.trimGhosts()
+}
+
+func (a *arc) touch(key string) {
+	if a.t1.has(key) {
+		a.t1.remove(key)
+		a.t2.add(key)
+		return
+	}
+	a.t2.touch(key)
+}
+
+func (a *arc) remove(key string) {
+	a.t1.remove(key)
+	a.t2.remove(key)
+}
+
+func (a *arc) victim(incoming string) string {
+	var key string
+	if a.t1.len() > 0 && (a.t1.len() > a.p || (a.t1.len() == a.p && a.b2.has(incoming)) || a.t2.len() == 0) {
+		key = a.t1.victim("")
+		a.b1.add(key)
+	} else {
+		key = a.t2.victim("")
+		a.b2.add(key)
+	}
+	a.trimGhosts()
+	return key
+}
+
+// trimGhosts keeps the ghost lists within the cache's capacity
+func (a *arc) trimGhosts() {
+	c := a.capacity()
+	for a.b1.len() > 0 && a.t1.len()+a.b1.len() > c {
+		a.b1.victim("")
+	}
+	for a.b2.len() > 0 && a.t1.len()+a.t2.len()+a.b1.len()+a.b2.len() > 2*c {
+		a.b2.victim("")
+	}
+}
@@ -0,0 +1,50 @@
+package cep
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// Store keeps resolved addresses by CEP.
+type Store interface {
+	Get(cep string) (Address, time.Time, bool)
+	Set(cep string, address Address) error
+}
+
+// Cached answers lookups from the addresses its store kept from earlier
+// ones and asks next for the rest, keeping what it finds. Addresses
+// guessed from the CEP's prefix aren't kept.
+type Cached struct {
+	store Store
+	next  Resolver
+}
+
+func NewCached(next Resolver, store Store) *Cached {
+	return &Cached{store: store, next: next}
+}
+
+// Lookup returns the kept address for cep, or asks next.
+func (c *Cached) Lookup(ctx context.Context, cep string) (*Address, error) {
+	if address, storedAt, ok := c.store.Get(cep); ok {
+		oteltrace.SpanFromContext(ctx).AddEvent("cache_hit", oteltrace.WithAttributes(
+			attribute.String("cache", "cep"),
+			attribute.Int64("age_s", int64(time.Since(storedAt).Seconds())),
+		))
+		return &address, nil
+	}
+
+	address, err := c.next.Lookup(ctx, cep)
+	if err != nil {
+		return nil, err
+	}
+	if !address.Approximate {
+		// The address is good whether or not it could be kept
+		if err := c.store.Set(cep, *address); err != nil {
+			oteltrace.SpanFromContext(ctx).RecordError(err)
+		}
+	}
+	return address, nil
+}
@@ -0,0 +1,119 @@
+// Package cep validates Brazilian postal codes and resolves them to
+// addresses through ViaCEP.
+package cep
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// ErrNotFound is returned when ViaCEP has no address for the CEP.
+	ErrNotFound = errors.New("CEP not found")
+	// ErrInvalid is returned when ViaCEP rejects the CEP's format.
+	ErrInvalid = errors.New("CEP rejected as malformed")
+)
+
+var cepPattern = regexp.MustCompile(`^\d{8}$`)
+
+type Address struct {
+	CEP         string `json:"cep"`
+	Logradouro  string `json:"logradouro"`
+	Complemento string `json:"complemento"`
+	Bairro      string `json:"bairro"`
+	Localidade  string `json:"localidade"`
+	UF          string `json:"uf"`
+	IBGE        string `json:"ibge"`
+	GIA         string `json:"gia"`
+	DDD         string `json:"ddd"`
+	SIAFI       string `json:"siafi"`
+	Erro        bool   `json:"erro,omitempty"`
+
+	// Approximate is set on addresses guessed from the CEP's prefix, which
+	// only have the municipality
+	Approximate bool `json:"approximate,omitempty"`
+}
+
+// Validate reports whether cep has exactly 8 digits.
+func Validate(cep string) bool {
+	return cepPattern.MatchString(cep)
+}
+
+// DefaultBaseURL is ViaCEP's public API.
+const DefaultBaseURL = "https://viacep.com.br/ws/"
+
+// Client resolves CEPs through the ViaCEP API.
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	tracer     oteltrace.Tracer
+}
+
+// NewClient builds a client for the ViaCEP API at baseURL, normally
+// DefaultBaseURL; a local mock or a mirror works as long as it serves the
+// same paths.
+func NewClient(httpClient *http.Client, baseURL string, tracer oteltrace.Tracer) *Client {
+	return &Client{httpClient: httpClient, baseURL: strings.TrimRight(baseURL, "/"), tracer: tracer}
+}
+
+// Lookup resolves cep to its address.
+func (c *Client) Lookup(ctx context.Context, cep string) (*Address, error) {
+	ctx, span := c.tracer.Start(ctx, "get-city-from-cep")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("cep", cep))
+
+	url := fmt.Sprintf("%s/%s/json/", c.baseURL, cep)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusBadRequest:
+		span.RecordError(ErrInvalid)
+		return nil, ErrInvalid
+	case resp.StatusCode != http.StatusOK:
+		err := fmt.Errorf("viacep returned %d", resp.StatusCode)
+		span.RecordError(err)
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	var address Address
+	if err := json.Unmarshal(body, &address); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	if address.Erro {
+		span.SetAttributes(attribute.Bool("cep.not_found", true))
+		return nil, ErrNotFound
+	}
+
+	span.SetAttributes(attribute.String("city", address.Localidade))
+	return &address, nil
+}
@@ -0,0 +1,194 @@
+package cep
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"go.opentelemetry.io/otel"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		cep  string
+		want bool
+	}{
+		{"01001000", true},
+		{"99999999", true},
+		{"", false},
+		{"0100100", false},
+		{"010010000", false},
+		{"01001-000", false},
+		{" 01001000", false},
+		{"01001000\n", false},
+		{"0100100a", false},
+		{"０１００１０００", false},
+		{"٠١٠٠١٠٠٠", false},
+	}
+	for _, tt := range tests {
+		if got := Validate(tt.cep); got != tt.want {
+			t.Errorf("Validate(%q) = %v, want %v", tt.cep, got, tt.want)
+		}
+	}
+}
+
+func TestValidateProperties(t *testing.T) {
+	// Every number below 10⁸, zero-padded, is a CEP
+	padded := func(n uint32) bool { return Validate(fmt.Sprintf("%08d", n%100000000)) }
+	if err := quick.Check(padded, nil); err != nil {
+		t.Error(err)
+	}
+
+	// The dashed form isn't valid, and stripping the dash makes it valid again
+	dashed := func(n uint32) bool {
+		cep := fmt.Sprintf("%08d", n%100000000)
+		formatted := cep[:5] + "-" + cep[5:]
+		return !Validate(formatted) && strings.ReplaceAll(formatted, "-", "") == cep && Validate(cep)
+	}
+	if err := quick.Check(dashed, nil); err != nil {
+		t.Error(err)
+	}
+
+	// Changing the length or any one character to a non-digit breaks it
+	broken := func(n uint32, at uint8, c rune) bool {
+		cep := []rune(fmt.Sprintf("%08d", n%100000000))
+		i := int(at) % len(cep)
+		if c < '0' || c > '9' {
+			mangled := append(append([]rune{}, cep[:i]...), c)
+			if Validate(string(append(mangled, cep[i+1:]...))) {
+				return false
+			}
+		}
+		return !Validate(string(cep[:i])) && !Validate(string(cep)+string(cep[i]))
+	}
+	if err := quick.Check(broken, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// viaCEP stands in for ViaCEP, answering every lookup with status and body
+func viaCEP(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ws/01001000/json/" {
+			t.Errorf("path = %s, want /ws/01001000/json/", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// errOther stands for any error but the package's own
+var errOther = errors.New("other error")
+
+func FuzzValidate(f *testing.F) {
+	for _, seed := range []string{
+		"01001000", "01001-000", "0100100", "010010000", "", " 01001000", "01001000\n",
+		"０１００１０００", "٠١٠٠١٠٠٠", "01001\x00000", strings.Repeat("9", 1<<12),
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		want := len(s) == 8
+		for i := 0; i < len(s); i++ {
+			want = want && s[i] >= '0' && s[i] <= '9'
+		}
+		if got := Validate(s); got != want {
+			t.Errorf("Validate(%q) = %v, want %v", s, got, want)
+		}
+	})
+}
+
+func TestClientLookup(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		wantCity string
+		wantErr  error
+	}{
+		{"found", http.StatusOK, `{"cep":"01001-000","localidade":"São Paulo","uf":"SP"}`, "São Paulo", nil},
+		{"unknown CEP", http.StatusOK, `{"erro":true}`, "", ErrNotFound},
+		{"malformed CEP", http.StatusBadRequest, `<h1>Bad Request</h1>`, "", ErrInvalid},
+		{"server error", http.StatusInternalServerError, ``, "", errOther},
+		{"garbled answer", http.StatusOK, `{"cep":`, "", errOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := viaCEP(t, tt.status, tt.body)
+			client := NewClient(srv.Client(), srv.URL+"/ws/", otel.Tracer("test"))
+
+			address, err := client.Lookup(context.Background(), "01001000")
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Fatalf("Lookup: %v", err)
+			case tt.wantErr != nil && err == nil:
+				t.Fatalf("Lookup = %+v, want error %v", address, tt.wantErr)
+			case tt.wantErr != errOther && !errors.Is(err, tt.wantErr):
+				t.Fatalf("Lookup error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && address.Localidade != tt.wantCity {
+				t.Errorf("city = %q, want %q", address.Localidade, tt.wantCity)
+			}
+		})
+	}
+}
+
+// resolverFunc adapts a function to Resolver
+type resolverFunc func(ctx context.Context, cep string) (*Address, error)
+
+func (f resolverFunc) Lookup(ctx context.Context, cep string) (*Address, error) {
+	return f(ctx, cep)
+}
+
+func TestPrefixFallback(t *testing.T) {
+	down := errors.New("viacep down")
+	tests := []struct {
+		name            string
+		cep             string
+		nextErr         error
+		wantCity        string
+		wantApproximate bool
+		wantErr         error
+	}{
+		{"next answers", "01001000", nil, "Sé", false, nil},
+		{"next down, known prefix", "01310100", down, "São Paulo", true, nil},
+		{"next down, known prefix of another city", "07010000", down, "Guarulhos", true, nil},
+		{"next down, unknown prefix", "06000000", down, "", false, down},
+		{"unknown CEP isn't guessed", "01310100", ErrNotFound, "", false, ErrNotFound},
+		{"malformed CEP isn't guessed", "01310100", ErrInvalid, "", false, ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fallback := NewPrefixFallback(resolverFunc(func(ctx context.Context, cep string) (*Address, error) {
+				if tt.nextErr != nil {
+					return nil, tt.nextErr
+				}
+				return &Address{CEP: cep, Localidade: "Sé"}, nil
+			}))
+
+			address, err := fallback.Lookup(context.Background(), tt.cep)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Lookup error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if address.Localidade != tt.wantCity || address.Approximate != tt.wantApproximate {
+				t.Errorf("Lookup = %+v, want %s (approximate: %v)", address, tt.wantCity, tt.wantApproximate)
+			}
+			if tt.wantApproximate && address.CEP != tt.cep[:5]+"-"+tt.cep[5:] {
+				t.Errorf("CEP = %q, want it formatted", address.CEP)
+			}
+		})
+	}
+}
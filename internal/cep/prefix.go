@@ -0,0 +1,114 @@
+package cep
+
+import (
+	"context"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// prefixesCSV maps CEP ranges, by their first five digits, to the
+// municipality they belong to
+//
+//go:embed prefixes.csv
+var prefixesCSV string
+
+// prefixRange is one municipality's range of CEP prefixes
+type prefixRange struct {
+	first, last string
+	address     Address
+}
+
+// PrefixFallback resolves CEPs through next and, when next fails for any
+// reason other than the CEP being unknown or malformed, falls back to the
+// municipality its prefix belongs to in an embedded dataset of the larger
+// municipalities. Those addresses only have the municipality and are marked
+// Approximate.
+type PrefixFallback struct {
+	ranges []prefixRange
+	next   Resolver
+
+	hits, misses atomic.Uint64
+}
+
+// NewPrefixFallback builds the fallback in front of next.
+func NewPrefixFallback(next Resolver) *PrefixFallback {
+	ranges, err := parsePrefixes(prefixesCSV)
+	if err != nil {
+		panic("cep: invalid embedded prefixes: " + err.Error())
+	}
+	return &PrefixFallback{ranges: ranges, next: next}
+}
+
+func parsePrefixes(data string) ([]prefixRange, error) {
+	r := csv.NewReader(strings.NewReader(data))
+	r.Comment = '#'
+	r.FieldsPerRecord = 5
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	ranges := make([]prefixRange, 0, len(records))
+	for _, rec := range records {
+		if len(rec[0]) != 5 || len(rec[1]) != 5 || rec[0] > rec[1] {
+			return nil, fmt.Errorf("invalid range %s-%s", rec[0], rec[1])
+		}
+		ranges = append(ranges, prefixRange{first: rec[0], last: rec[1], address: Address{
+			IBGE: rec[2], Localidade: rec[3], UF: rec[4], Approximate: true,
+		}})
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].first < ranges[j].first })
+	for i := 1; i < len(ranges); i++ {
+		if ranges[i].first <= ranges[i-1].last {
+			return nil, fmt.Errorf("range %s-%s overlaps %s-%s", ranges[i].first, ranges[i].last, ranges[i-1].first, ranges[i-1].last)
+		}
+	}
+	return ranges, nil
+}
+
+// Len returns how many ranges the dataset holds.
+func (f *PrefixFallback) Len() int {
+	return len(f.ranges)
+}
+
+// Lookup resolves cep through the next resolver, or from its prefix when
+// that fails.
+func (f *PrefixFallback) Lookup(ctx context.Context, cep string) (*Address, error) {
+	address, err := f.next.Lookup(ctx, cep)
+	if err == nil || errors.Is(err, ErrNotFound) || errors.Is(err, ErrInvalid) {
+		return address, err
+	}
+
+	if approx, ok := f.prefix(cep); ok {
+		f.hits.Add(1)
+		return approx, nil
+	}
+	f.misses.Add(1)
+	return nil, err
+}
+
+// prefix returns the municipality cep's prefix belongs to
+func (f *PrefixFallback) prefix(cep string) (*Address, bool) {
+	if !Validate(cep) {
+		return nil, false
+	}
+	p := cep[:5]
+	i := sort.Search(len(f.ranges), func(i int) bool { return f.ranges[i].last >= p })
+	if i == len(f.ranges) || f.ranges[i].first > p {
+		return nil, false
+	}
+	address := f.ranges[i].address
+	address.CEP = cep[:5] + "-" + cep[5:]
+	return &address, true
+}
+
+// Stats returns how many failed lookups the dataset answered and how many
+// it couldn't.
+func (f *PrefixFallback) Stats() (hits, misses uint64) {
+	return f.hits.Load(), f.misses.Load()
+}
@@ -0,0 +1,89 @@
+package cep
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Resolver resolves a CEP to its address.
+type Resolver interface {
+	Lookup(ctx context.Context, cep string) (*Address, error)
+}
+
+// Snapshot answers lookups from addresses resolved ahead of time (see
+// cmd/cepimport) and hands every other CEP to its fallback resolver.
+type Snapshot struct {
+	addresses map[string]Address
+	next      Resolver
+
+	hits, misses atomic.Uint64
+}
+
+// LoadSnapshot reads a file of JSON lines, one ViaCEP address per line, as
+// written by WriteSnapshot.
+func LoadSnapshot(path string, next Resolver) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s := &Snapshot{addresses: map[string]Address{}, next: next}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var address Address
+		if err := json.Unmarshal(scanner.Bytes(), &address); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		code := strings.ReplaceAll(address.CEP, "-", "")
+		if !Validate(code) {
+			return nil, fmt.Errorf("%s:%d: invalid CEP %q", path, line, address.CEP)
+		}
+		s.addresses[code] = address
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// WriteSnapshot writes address as one line of a snapshot file.
+func WriteSnapshot(w io.Writer, address *Address) error {
+	return json.NewEncoder(w).Encode(address)
+}
+
+// Len returns how many CEPs the snapshot holds.
+func (s *Snapshot) Len() int {
+	return len(s.addresses)
+}
+
+// Has reports whether the snapshot holds cep.
+func (s *Snapshot) Has(cep string) bool {
+	_, ok := s.addresses[cep]
+	return ok
+}
+
+// Lookup returns the preloaded address for cep, or asks the fallback.
+func (s *Snapshot) Lookup(ctx context.Context, cep string) (*Address, error) {
+	if address, ok := s.addresses[cep]; ok {
+		s.hits.Add(1)
+		return &address, nil
+	}
+	s.misses.Add(1)
+	return s.next.Lookup(ctx, cep)
+}
+
+// Stats returns how many lookups the snapshot answered and how many it
+// passed on.
+func (s *Snapshot) Stats() (hits, misses uint64) {
+	return s.hits.Load(), s.misses.Load()
+}
@@ -0,0 +1,125 @@
+// Package chaos injects latency, errors and dropped connections into
+// incoming requests and upstream calls, for exercising retries and timeouts
+// outside production.
+package chaos
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/offerni/weathercheck/internal/httpapi"
+)
+
+// ErrInjected is returned by Transport for calls it decided to fail.
+var ErrInjected = errors.New("chaos: injected upstream failure")
+
+// Config holds the fault rates, each between 0 and 1.
+type Config struct {
+	Latency      time.Duration
+	LatencyRate  float64
+	ErrorRate    float64
+	DropRate     float64
+	UpstreamRate float64
+}
+
+// Enabled reports whether any fault is configured.
+func (c Config) Enabled() bool {
+	return (c.Latency > 0 && c.LatencyRate > 0) || c.ErrorRate > 0 || c.DropRate > 0 || c.UpstreamRate > 0
+}
+
+// FromEnv reads CHAOS_LATENCY, CHAOS_LATENCY_RATE, CHAOS_ERROR_RATE,
+// CHAOS_DROP_RATE and CHAOS_UPSTREAM_ERROR_RATE. Unset values disable the
+// corresponding fault, and everything stays off when APP_ENV=production.
+func FromEnv() (Config, error) {
+	var cfg Config
+	var err error
+
+	if os.Getenv("APP_ENV") == "production" {
+		return cfg, nil
+	}
+
+	if v := os.Getenv("CHAOS_LATENCY"); v != "" {
+		if cfg.Latency, err = time.ParseDuration(v); err != nil {
+			return Config{}, fmt.Errorf("CHAOS_LATENCY: %w", err)
+		}
+	}
+
+	rates := []struct {
+		key string
+		dst *float64
+	}{
+		{"CHAOS_LATENCY_RATE", &cfg.LatencyRate},
+		{"CHAOS_ERROR_RATE", &cfg.ErrorRate},
+		{"CHAOS_DROP_RATE", &cfg.DropRate},
+		{"CHAOS_UPSTREAM_ERROR_RATE", &cfg.UpstreamRate},
+	}
+	for _, r := range rates {
+		v := os.Getenv(r.key)
+		if v == "" {
+			continue
+		}
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return Config{}, fmt.Errorf("%s: expected a rate between 0 and 1, got %q", r.key, v)
+		}
+		*r.dst = rate
+	}
+
+	return cfg, nil
+}
+
+// Middleware delays, fails or drops incoming requests at the configured rates.
+func Middleware(cfg Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if hit(cfg.LatencyRate) {
+				select {
+				case <-time.After(cfg.Latency):
+				case <-r.Context().Done():
+					return
+				}
+			}
+
+			if hit(cfg.DropRate) {
+				// Aborts the response without writing anything
+				panic(http.ErrAbortHandler)
+			}
+
+			if hit(cfg.ErrorRate) {
+				httpapi.WriteResponse(w, r, http.StatusServiceUnavailable, httpapi.ErrorResponse{Message: "injected fault", Code: "injected_fault"})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Transport fails outgoing requests at cfg.UpstreamRate before they reach next.
+type Transport struct {
+	cfg  Config
+	next http.RoundTripper
+}
+
+func NewTransport(cfg Config, next http.RoundTripper) *Transport {
+	return &Transport{cfg: cfg, next: next}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if hit(t.cfg.UpstreamRate) {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, ErrInjected
+	}
+	return t.next.RoundTrip(req)
+}
+
+func hit(rate float64) bool {
+	return rate > 0 && rand.Float64() < rate
+}
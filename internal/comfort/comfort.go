@@ -0,0 +1,62 @@
+// Package comfort derives apparent temperature and dew point from air
+// temperature, relative humidity and wind speed.
+package comfort
+
+import "math"
+
+// Magnus formula coefficients (Alduchov & Eskridge, 1996)
+const (
+	magnusB = 17.625
+	magnusC = 243.04
+)
+
+// DewPoint returns the dew point in °C for tempC and relative humidity in
+// percent, using the Magnus approximation.
+func DewPoint(tempC, humidity float64) float64 {
+	gamma := math.Log(humidity/100) + magnusB*tempC/(magnusC+tempC)
+	return magnusC * gamma / (magnusB - gamma)
+}
+
+// HeatIndex returns the NWS heat index in °C (Rothfusz regression with the
+// standard low-humidity and high-humidity adjustments).
+func HeatIndex(tempC, humidity float64) float64 {
+	t := tempC*1.8 + 32
+	rh := humidity
+
+	// Steadman's simple formula is used below ~80 °F
+	hi := 0.5 * (t + 61 + (t-68)*1.2 + rh*0.094)
+	if (hi+t)/2 >= 80 {
+		hi = -42.379 + 2.04901523*t + 10.14333127*rh -
+			0.22475541*t*rh - 0.00683783*t*t - 0.05481717*rh*rh +
+			0.00122874*t*t*rh + 0.00085282*t*rh*rh - 0.00000199*t*t*rh*rh
+
+		switch {
+		case rh < 13 && t >= 80 && t <= 112:
+			hi -= (13 - rh) / 4 * math.Sqrt((17-math.Abs(t-95))/17)
+		case rh > 85 && t >= 80 && t <= 87:
+			hi += (rh - 85) / 10 * (87 - t) / 5
+		}
+	}
+
+	return (hi - 32) / 1.8
+}
+
+// WindChill returns the wind chill in °C for tempC and wind speed in km/h
+// (Environment Canada / NWS 2001 formula).
+func WindChill(tempC, windKph float64) float64 {
+	v := math.Pow(windKph, 0.16)
+	return 13.12 + 0.6215*tempC - 11.37*v + 0.3965*tempC*v
+}
+
+// FeelsLike returns the apparent temperature in °C: wind chill when cold and
+// windy, heat index when warm, and the air temperature otherwise.
+func FeelsLike(tempC, humidity, windKph float64) float64 {
+	switch {
+	case tempC <= 10 && windKph > 4.8:
+		return WindChill(tempC, windKph)
+	case tempC >= 26.7:
+		return HeatIndex(tempC, humidity)
+	default:
+		return tempC
+	}
+}
@@ -0,0 +1,108 @@
+package comfort
+
+import (
+	"math"
+	"testing"
+)
+
+func fahrenheit(c float64) float64 { return c*1.8 + 32 }
+func celsius(f float64) float64    { return (f - 32) / 1.8 }
+
+// TestHeatIndex checks values from the NWS heat index chart, which is in
+// °F and rounded to the degree
+func TestHeatIndex(t *testing.T) {
+	tests := []struct {
+		tempF, humidity, wantF float64
+	}{
+		{80, 40, 80},
+		{86, 50, 88},
+		{90, 40, 91},
+		{90, 60, 100},
+		{90, 70, 105},
+		{94, 50, 103},
+		{96, 65, 121},
+		{100, 40, 109},
+		{104, 50, 131},
+		// Dry air: the low-humidity adjustment
+		{100, 10, 95},
+		// Humid and not so hot: the high-humidity adjustment
+		{84, 95, 100},
+		// Below 80 °F the chart follows Steadman's simple formula
+		{70, 50, 69},
+	}
+	for _, tt := range tests {
+		got := fahrenheit(HeatIndex(celsius(tt.tempF), tt.humidity))
+		if math.Abs(got-tt.wantF) > 1 {
+			t.Errorf("HeatIndex(%v °F, %v%%) = %.1f °F, want %v ±1", tt.tempF, tt.humidity, got, tt.wantF)
+		}
+	}
+}
+
+// TestWindChill checks values from Environment Canada's wind chill chart,
+// in °C and km/h, and the NWS chart, in °F and mph, both rounded to the
+// degree
+func TestWindChill(t *testing.T) {
+	const kphPerMph = 1.609344
+	tests := []struct {
+		name           string
+		tempC, windKph float64
+		wantC          float64
+	}{
+		{"0 °C, 10 km/h", 0, 10, -3},
+		{"-10 °C, 20 km/h", -10, 20, -18},
+		{"-20 °C, 30 km/h", -20, 30, -33},
+		{"-30 °C, 50 km/h", -30, 50, -49},
+		{"5 °C, 40 km/h", 5, 40, -1},
+		{"40 °F, 5 mph", celsius(40), 5 * kphPerMph, celsius(36)},
+		{"20 °F, 10 mph", celsius(20), 10 * kphPerMph, celsius(9)},
+		{"0 °F, 15 mph", celsius(0), 15 * kphPerMph, celsius(-19)},
+		{"-10 °F, 20 mph", celsius(-10), 20 * kphPerMph, celsius(-35)},
+		{"30 °F, 30 mph", celsius(30), 30 * kphPerMph, celsius(15)},
+	}
+	for _, tt := range tests {
+		if got := WindChill(tt.tempC, tt.windKph); math.Abs(got-tt.wantC) > 0.6 {
+			t.Errorf("WindChill(%s) = %.2f °C, want %.2f ±0.6", tt.name, got, tt.wantC)
+		}
+	}
+}
+
+// TestDewPoint checks values from the formula behind the NWS dew point
+// calculator (Bolton's vapor pressure constants), rounded to a tenth of a
+// degree; the Magnus coefficients used here differ by a fraction of that
+func TestDewPoint(t *testing.T) {
+	tests := []struct {
+		tempC, humidity, wantC float64
+	}{
+		{25, 60, 16.7},
+		{30, 70, 23.9},
+		{20, 50, 9.3},
+		{35, 30, 14.9},
+		{10, 80, 6.7},
+		{-5, 70, -9.6},
+		{15, 100, 15},
+	}
+	for _, tt := range tests {
+		if got := DewPoint(tt.tempC, tt.humidity); math.Abs(got-tt.wantC) > 0.3 {
+			t.Errorf("DewPoint(%v °C, %v%%) = %.2f °C, want %v ±0.3", tt.tempC, tt.humidity, got, tt.wantC)
+		}
+	}
+}
+
+func TestFeelsLike(t *testing.T) {
+	tests := []struct {
+		name                     string
+		tempC, humidity, windKph float64
+		want                     float64
+	}{
+		{"cold and windy", -10, 50, 20, WindChill(-10, 20)},
+		{"cold and calm", -10, 50, 3, -10},
+		{"mild", 20, 90, 30, 20},
+		{"hot", 32, 65, 10, HeatIndex(32, 65)},
+		{"at the heat threshold", 26.7, 80, 0, HeatIndex(26.7, 80)},
+	}
+	for _, tt := range tests {
+		if got := FeelsLike(tt.tempC, tt.humidity, tt.windKph); got != tt.want {
+			t.Errorf("FeelsLike(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Package contract pins the interface between service A and service B as
+// golden HTTP exchanges: the requests service A sends and the answers it
+// relies on. Service A's tests check it sends these requests and can handle
+// these answers, and service B's tests replay the requests and check its
+// answers still have the same shape, so neither side can change the
+// interface without the other's tests failing.
+package contract
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+)
+
+//go:embed interactions/*.json
+var files embed.FS
+
+// Interaction is one request service A sends service B and the answer it
+// expects back.
+type Interaction struct {
+	Description string   `json:"description"`
+	Request     Request  `json:"request"`
+	Response    Response `json:"response"`
+}
+
+type Request struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Query   string            `json:"query,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// URL returns the request's path with its query, if any
+func (r Request) URL() string {
+	if r.Query == "" {
+		return r.Path
+	}
+	return r.Path + "?" + r.Query
+}
+
+// Response is the answer service A expects. Its body is an example: values
+// may differ, but every field in it must be there with the same JSON type.
+type Response struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// Interactions returns every recorded interaction, ordered by file name.
+func Interactions() ([]Interaction, error) {
+	names, err := files.ReadDir("interactions")
+	if err != nil {
+		return nil, err
+	}
+
+	var interactions []Interaction
+	for _, entry := range names {
+		data, err := files.ReadFile(path.Join("interactions", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var i Interaction
+		if err := json.Unmarshal(data, &i); err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		interactions = append(interactions, i)
+	}
+	return interactions, nil
+}
+
+// MatchShape reports how got differs in shape from the example body want:
+// a missing field, or a value of another JSON type. Fields want doesn't
+// have are allowed, and each element of an array is matched against the
+// example's first.
+func MatchShape(want, got []byte) error {
+	var w, g any
+	if err := json.Unmarshal(want, &w); err != nil {
+		return fmt.Errorf("example: %w", err)
+	}
+	if err := json.Unmarshal(got, &g); err != nil {
+		return err
+	}
+	return matchShape("", w, g)
+}
+
+func matchShape(at string, want, got any) error {
+	if jsonType(want) != jsonType(got) {
+		return fmt.Errorf("%s is %s, want %s", pointer(at), jsonType(got), jsonType(want))
+	}
+
+	switch want := want.(type) {
+	case map[string]any:
+		got := got.(map[string]any)
+		keys := make([]string, 0, len(want))
+		for k := range want {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			v, ok := got[k]
+			if !ok {
+				return fmt.Errorf("%s is missing", pointer(at+"/"+k))
+			}
+			if err := matchShape(at+"/"+k, want[k], v); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if len(want) == 0 {
+			return nil
+		}
+		for i, v := range got.([]any) {
+			if err := matchShape(fmt.Sprintf("%s/%d", at, i), want[0], v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func jsonType(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return "a string"
+	case float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	default:
+		return "null"
+	}
+}
+
+func pointer(at string) string {
+	if at == "" {
+		return "the body"
+	}
+	return at
+}
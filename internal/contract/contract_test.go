@@ -0,0 +1,54 @@
+package contract
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestInteractions(t *testing.T) {
+	interactions, err := Interactions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(interactions) == 0 {
+		t.Fatal("no interactions recorded")
+	}
+	for _, i := range interactions {
+		if i.Description == "" || i.Request.Method == "" || !strings.HasPrefix(i.Request.Path, "/") || i.Response.Status == 0 {
+			t.Errorf("incomplete interaction %+v", i)
+		}
+		if _, err := url.ParseQuery(i.Request.Query); err != nil {
+			t.Errorf("%s: query: %v", i.Description, err)
+		}
+	}
+}
+
+func TestMatchShape(t *testing.T) {
+	const example = `{"city":"São Paulo","temp_C":28.5,"cities":[{"city":"São Paulo","temp_C":28.5}]}`
+	tests := []struct {
+		name    string
+		got     string
+		wantErr string
+	}{
+		{"same shape, other values", `{"city":"Recife","temp_C":31,"cities":[{"city":"Recife","temp_C":31},{"city":"Olinda","temp_C":30.2}]}`, ""},
+		{"extra fields", `{"city":"Recife","temp_C":31,"temp_F":87.8,"cities":[]}`, ""},
+		{"missing field", `{"city":"Recife","cities":[]}`, "/temp_C is missing"},
+		{"other type", `{"city":"Recife","temp_C":"31","cities":[]}`, "/temp_C is a string, want a number"},
+		{"element of another shape", `{"city":"Recife","temp_C":31,"cities":[{"city":"Recife"}]}`, "/cities/0/temp_C is missing"},
+		{"null field", `{"city":null,"temp_C":31,"cities":[]}`, "/city is null, want a string"},
+		{"not an object", `[]`, "the body is an array, want an object"},
+		{"not JSON", `São Paulo`, "invalid character"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := MatchShape([]byte(example), []byte(tt.got))
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("MatchShape = %v, want a match", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("MatchShape = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+// Package ddd maps Brazilian area codes (DDD) to the principal cities they
+// serve.
+package ddd
+
+import (
+	"regexp"
+	"slices"
+)
+
+var dddPattern = regexp.MustCompile(`^[1-9]\d$`)
+
+// principalCities lists, per DDD, its largest or best-known cities. ViaCEP
+// only goes from CEP to DDD, so the reverse mapping is kept here.
+var principalCities = map[string][]string{
+	"11": {"São Paulo"},
+	"12": {"São José dos Campos", "Taubaté"},
+	"13": {"Santos"},
+	"14": {"Bauru", "Marília"},
+	"15": {"Sorocaba"},
+	"16": {"Ribeirão Preto", "São Carlos", "Araraquara"},
+	"17": {"São José do Rio Preto"},
+	"18": {"Presidente Prudente", "Araçatuba"},
+	"19": {"Campinas", "Piracicaba"},
+	"21": {"Rio de Janeiro", "Niterói"},
+	"22": {"Campos dos Goytacazes", "Macaé"},
+	"24": {"Petrópolis", "Volta Redonda"},
+	"27": {"Vitória", "Vila Velha"},
+	"28": {"Cachoeiro de Itapemirim"},
+	"31": {"Belo Horizonte", "Contagem"},
+	"32": {"Juiz de Fora"},
+	"33": {"Governador Valadares"},
+	"34": {"Uberlândia", "Uberaba"},
+	"35": {"Poços de Caldas", "Pouso Alegre"},
+	"37": {"Divinópolis"},
+	"38": {"Montes Claros"},
+	"41": {"Curitiba"},
+	"42": {"Ponta Grossa"},
+	"43": {"Londrina"},
+	"44": {"Maringá"},
+	"45": {"Cascavel", "Foz do Iguaçu"},
+	"46": {"Pato Branco", "Francisco Beltrão"},
+	"47": {"Joinville", "Blumenau"},
+	"48": {"Florianópolis", "Criciúma"},
+	"49": {"Chapecó", "Lages"},
+	"51": {"Porto Alegre", "Canoas"},
+	"53": {"Pelotas"},
+	"54": {"Caxias do Sul"},
+	"55": {"Santa Maria"},
+	"61": {"Brasília"},
+	"62": {"Goiânia", "Anápolis"},
+	"63": {"Palmas"},
+	"64": {"Rio Verde"},
+	"65": {"Cuiabá"},
+	"66": {"Rondonópolis"},
+	"67": {"Campo Grande"},
+	"68": {"Rio Branco"},
+	"69": {"Porto Velho"},
+	"71": {"Salvador"},
+	"73": {"Ilhéus", "Itabuna"},
+	"74": {"Juazeiro"},
+	"75": {"Feira de Santana"},
+	"77": {"Vitória da Conquista"},
+	"79": {"Aracaju"},
+	"81": {"Recife"},
+	"82": {"Maceió"},
+	"83": {"João Pessoa", "Campina Grande"},
+	"84": {"Natal"},
+	"85": {"Fortaleza"},
+	"86": {"Teresina"},
+	"87": {"Petrolina"},
+	"88": {"Juazeiro do Norte"},
+	"89": {"Picos"},
+	"91": {"Belém"},
+	"92": {"Manaus"},
+	"93": {"Santarém"},
+	"94": {"Marabá"},
+	"95": {"Boa Vista"},
+	"96": {"Macapá"},
+	"97": {"Coari"},
+	"98": {"São Luís"},
+	"99": {"Imperatriz"},
+}
+
+// Validate reports whether code looks like a DDD: two digits, not starting
+// with zero.
+func Validate(code string) bool {
+	return dddPattern.MatchString(code)
+}
+
+// Cities returns the principal cities of the DDD, or false when no such
+// area code is in use.
+func Cities(code string) ([]string, bool) {
+	cities, ok := principalCities[code]
+	return slices.Clone(cities), ok
+}
@@ -0,0 +1,133 @@
+package discovery
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const healthCheckTimeout = 2 * time.Second
+
+// Balancer periodically re-resolves a service's endpoints, health-checks
+// them and hands them out round-robin.
+type Balancer struct {
+	resolver   Resolver
+	healthPath string
+	interval   time.Duration
+	httpClient *http.Client
+	logger     *log.Logger
+
+	mu       sync.RWMutex
+	healthy  []*url.URL
+	position atomic.Uint64
+}
+
+func NewBalancer(resolver Resolver, healthPath string, interval time.Duration, httpClient *http.Client, logger *log.Logger) *Balancer {
+	return &Balancer{
+		resolver:   resolver,
+		healthPath: healthPath,
+		interval:   interval,
+		httpClient: httpClient,
+		logger:     logger,
+	}
+}
+
+// Start resolves the endpoints once and then keeps refreshing them in the
+// background until ctx is cancelled.
+func (b *Balancer) Start(ctx context.Context) {
+	b.refresh(ctx)
+
+	go func() {
+		ticker := time.NewTicker(b.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				b.refresh(ctx)
+			}
+		}
+	}()
+}
+
+// Next returns the next healthy endpoint in rotation.
+func (b *Balancer) Next() (*url.URL, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if len(b.healthy) == 0 {
+		return nil, ErrNoEndpoints
+	}
+
+	i := b.position.Add(1) - 1
+	return b.healthy[i%uint64(len(b.healthy))], nil
+}
+
+// MarkDown takes an endpoint out of rotation until the next refresh, e.g.
+// after a request to it failed.
+func (b *Balancer) MarkDown(endpoint *url.URL) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Keep the last endpoint rather than leaving nothing to try
+	if len(b.healthy) <= 1 {
+		return
+	}
+
+	remaining := make([]*url.URL, 0, len(b.healthy))
+	for _, u := range b.healthy {
+		if u.String() != endpoint.String() {
+			remaining = append(remaining, u)
+		}
+	}
+	b.healthy = remaining
+}
+
+func (b *Balancer) refresh(ctx context.Context) {
+	endpoints, err := b.resolver.Resolve(ctx)
+	if err != nil {
+		b.logger.Printf("Failed to resolve endpoints, keeping previous set: %v", err)
+		return
+	}
+
+	healthy := make([]*url.URL, 0, len(endpoints))
+	for _, u := range endpoints {
+		if b.check(ctx, u) {
+			healthy = append(healthy, u)
+		}
+	}
+
+	// Fail open so a broken health check doesn't take every endpoint out
+	if len(healthy) == 0 && len(endpoints) > 0 {
+		b.logger.Printf("No endpoint passed its health check, using all %d resolved endpoints", len(endpoints))
+		healthy = endpoints
+	}
+
+	b.mu.Lock()
+	b.healthy = healthy
+	b.mu.Unlock()
+}
+
+func (b *Balancer) check(ctx context.Context, endpoint *url.URL) bool {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.JoinPath(b.healthPath).String(), nil)
+	if err != nil {
+		return false
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode == http.StatusOK
+}
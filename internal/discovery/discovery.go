@@ -0,0 +1,148 @@
+// Package discovery locates service endpoints from static configuration,
+// DNS SRV records or Consul, and rotates requests across the healthy ones.
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ErrNoEndpoints is returned when no healthy endpoint is known.
+var ErrNoEndpoints = errors.New("no healthy endpoints available")
+
+// Resolver returns the current set of base URLs for a service.
+type Resolver interface {
+	Resolve(ctx context.Context) ([]*url.URL, error)
+}
+
+// StaticResolver always returns the same endpoints.
+type StaticResolver struct {
+	endpoints []*url.URL
+}
+
+// NewStaticResolver parses a comma-separated list of base URLs.
+func NewStaticResolver(rawURLs string) (*StaticResolver, error) {
+	var endpoints []*url.URL
+	for _, raw := range strings.Split(rawURLs, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endpoint %q: %w", raw, err)
+		}
+		endpoints = append(endpoints, u)
+	}
+
+	if len(endpoints) == 0 {
+		return nil, errors.New("no endpoints configured")
+	}
+
+	return &StaticResolver{endpoints: endpoints}, nil
+}
+
+func (r *StaticResolver) Resolve(ctx context.Context) ([]*url.URL, error) {
+	return r.endpoints, nil
+}
+
+// SRVResolver looks endpoints up from a DNS SRV record such as
+// "_http._tcp.service-b.service.consul".
+type SRVResolver struct {
+	name     string
+	scheme   string
+	resolver *net.Resolver
+}
+
+func NewSRVResolver(name, scheme string) *SRVResolver {
+	return &SRVResolver{name: name, scheme: scheme, resolver: net.DefaultResolver}
+}
+
+func (r *SRVResolver) Resolve(ctx context.Context) ([]*url.URL, error) {
+	_, records, err := r.resolver.LookupSRV(ctx, "", "", r.name)
+	if err != nil {
+		return nil, fmt.Errorf("looking up SRV %s: %w", r.name, err)
+	}
+
+	endpoints := make([]*url.URL, 0, len(records))
+	for _, rec := range records {
+		host := strings.TrimSuffix(rec.Target, ".")
+		endpoints = append(endpoints, &url.URL{
+			Scheme: r.scheme,
+			Host:   net.JoinHostPort(host, strconv.Itoa(int(rec.Port))),
+		})
+	}
+
+	return endpoints, nil
+}
+
+// ConsulResolver asks a Consul agent for the passing instances of a service.
+type ConsulResolver struct {
+	addr       string
+	service    string
+	scheme     string
+	httpClient *http.Client
+}
+
+func NewConsulResolver(addr, service, scheme string, httpClient *http.Client) *ConsulResolver {
+	return &ConsulResolver{
+		addr:       strings.TrimRight(addr, "/"),
+		service:    service,
+		scheme:     scheme,
+		httpClient: httpClient,
+	}
+}
+
+func (r *ConsulResolver) Resolve(ctx context.Context) ([]*url.URL, error) {
+	endpoint := fmt.Sprintf("%s/v1/health/service/%s?passing=true", r.addr, url.PathEscape(r.service))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("consul returned %d for service %s", resp.StatusCode, r.service)
+	}
+
+	var entries []struct {
+		Node struct {
+			Address string `json:"Address"`
+		} `json:"Node"`
+		Service struct {
+			Address string `json:"Address"`
+			Port    int    `json:"Port"`
+		} `json:"Service"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, err
+	}
+
+	endpoints := make([]*url.URL, 0, len(entries))
+	for _, e := range entries {
+		// Services registered without an address live on the node's address
+		host := e.Service.Address
+		if host == "" {
+			host = e.Node.Address
+		}
+		endpoints = append(endpoints, &url.URL{
+			Scheme: r.scheme,
+			Host:   net.JoinHostPort(host, strconv.Itoa(e.Service.Port)),
+		})
+	}
+
+	return endpoints, nil
+}
@@ -0,0 +1,122 @@
+// Package dnscache resolves upstream hosts with a small TTL cache, optional
+// static pins and an optional dedicated DNS server, so outbound calls don't
+// pay for a DNS round trip each time.
+package dnscache
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Resolver caches host lookups for a fixed TTL. Pinned hosts never hit DNS.
+// When a refresh fails, the last known addresses keep being used.
+type Resolver struct {
+	ttl    time.Duration
+	pins   map[string][]string
+	dns    *net.Resolver
+	logger *log.Logger
+
+	mu      sync.Mutex
+	entries map[string]entry
+
+	hits, misses atomic.Uint64
+}
+
+type entry struct {
+	addrs     []string
+	expiresAt time.Time
+}
+
+// New returns a Resolver caching lookups for ttl (zero disables the cache,
+// leaving only pins). server, when set, is the host:port of the DNS server
+// to query instead of the system's, for split-horizon setups.
+func New(ttl time.Duration, pins map[string][]string, server string, logger *log.Logger) *Resolver {
+	dns := net.DefaultResolver
+	if server != "" {
+		dns = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, server)
+			},
+		}
+	}
+
+	return &Resolver{
+		ttl:     ttl,
+		pins:    pins,
+		dns:     dns,
+		logger:  logger,
+		entries: map[string]entry{},
+	}
+}
+
+// ParsePins reads "host=ip[|ip...],host=ip" into a pin table.
+func ParsePins(spec string) (map[string][]string, error) {
+	pins := map[string][]string{}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		host, ips, ok := strings.Cut(pair, "=")
+		if !ok || host == "" {
+			return nil, fmt.Errorf("invalid pin %q (expected host=ip)", pair)
+		}
+		for _, ip := range strings.Split(ips, "|") {
+			if net.ParseIP(ip) == nil {
+				return nil, fmt.Errorf("invalid IP %q for %s", ip, host)
+			}
+			pins[strings.ToLower(host)] = append(pins[strings.ToLower(host)], ip)
+		}
+	}
+	return pins, nil
+}
+
+// LookupHost returns the addresses for host from the pins, the cache or DNS.
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	host = strings.ToLower(host)
+	if addrs, ok := r.pins[host]; ok {
+		return addrs, nil
+	}
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+
+	r.mu.Lock()
+	cached, ok := r.entries[host]
+	r.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		r.hits.Add(1)
+		return cached.addrs, nil
+	}
+	r.misses.Add(1)
+
+	addrs, err := r.dns.LookupHost(ctx, host)
+	if err != nil {
+		if ok {
+			r.logger.Printf("DNS lookup for %s failed, reusing cached addresses: %v", host, err)
+			return cached.addrs, nil
+		}
+		return nil, err
+	}
+
+	if r.ttl > 0 {
+		r.mu.Lock()
+		r.entries[host] = entry{addrs: addrs, expiresAt: time.Now().Add(r.ttl)}
+		r.mu.Unlock()
+	}
+	return addrs, nil
+}
+
+// Stats returns how many lookups the cache answered and how many went to
+// DNS; pinned hosts count as neither.
+func (r *Resolver) Stats() (hits, misses uint64) {
+	return r.hits.Load(), r.misses.Load()
+}
@@ -0,0 +1,79 @@
+package flags
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/open-feature/go-sdk/openfeature"
+)
+
+// TenantHeader names the caller for per-tenant flag targeting.
+const TenantHeader = "X-Tenant-ID"
+
+// Names of the flags the services consult.
+const (
+	CanaryRouting      = "canary-routing"
+	ShadowTraffic      = "shadow-traffic"
+	ProviderComparison = "provider-comparison"
+)
+
+type tenantKey struct{}
+
+// Client evaluates boolean flags for a request.
+type Client struct {
+	client *openfeature.Client
+}
+
+// Init registers the flags file (if any) as the OpenFeature provider for
+// environment and returns a client for serviceName. Without a file every
+// flag evaluates to its default.
+func Init(ctx context.Context, serviceName, path, environment string, logger *log.Logger) *Client {
+	if path != "" {
+		provider := NewFileProvider(path, 10*time.Second, logger)
+		provider.Start(ctx)
+		if err := openfeature.SetProvider(provider); err != nil {
+			logger.Printf("Failed to register flags provider: %v", err)
+		}
+	}
+
+	openfeature.SetEvaluationContext(openfeature.NewTargetlessEvaluationContext(map[string]any{
+		EnvironmentKey: environment,
+	}))
+
+	return &Client{client: openfeature.NewClient(serviceName)}
+}
+
+// Enabled evaluates flag for the tenant stored in ctx, falling back to
+// defaultValue when the flag is missing or invalid.
+func (c *Client) Enabled(ctx context.Context, flag string, defaultValue bool) bool {
+	evalCtx := openfeature.NewTargetlessEvaluationContext(nil)
+	if tenant, _ := ctx.Value(tenantKey{}).(string); tenant != "" {
+		evalCtx = openfeature.NewEvaluationContext(tenant, nil)
+	}
+
+	v, _ := c.client.BooleanValue(ctx, flag, defaultValue, evalCtx)
+	return v
+}
+
+// Middleware stores the request's tenant for later flag evaluations.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tenant := r.Header.Get(TenantHeader); tenant != "" {
+			r = r.WithContext(context.WithValue(r.Context(), tenantKey{}, tenant))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Gate serves enabled while flag is on for the request, and fallback otherwise.
+func (c *Client) Gate(flag string, defaultValue bool, enabled, fallback http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c.Enabled(r.Context(), flag, defaultValue) {
+			enabled.ServeHTTP(w, r)
+			return
+		}
+		fallback.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,198 @@
+// Package flags evaluates feature flags through OpenFeature, backed by a
+// JSON file that is re-read while the services run.
+package flags
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/open-feature/go-sdk/openfeature"
+)
+
+// EnvironmentKey and TenantKey are the evaluation context attributes the
+// file provider targets on.
+const (
+	EnvironmentKey = "environment"
+	TenantKey      = openfeature.TargetingKey
+)
+
+// Flag is one entry of the flags file. The most specific match wins:
+// tenant, then environment, then default.
+//
+//	{"canary-routing": {"default": false, "environments": {"staging": true}, "tenants": {"acme": true}}}
+type Flag struct {
+	Default      any            `json:"default"`
+	Environments map[string]any `json:"environments,omitempty"`
+	Tenants      map[string]any `json:"tenants,omitempty"`
+}
+
+// FileProvider is an OpenFeature provider serving flags from a JSON file.
+// Changes to the file are picked up on the next poll.
+type FileProvider struct {
+	path     string
+	interval time.Duration
+	logger   *log.Logger
+
+	mu      sync.RWMutex
+	flags   map[string]Flag
+	modTime time.Time
+}
+
+func NewFileProvider(path string, interval time.Duration, logger *log.Logger) *FileProvider {
+	return &FileProvider{path: path, interval: interval, logger: logger, flags: map[string]Flag{}}
+}
+
+// Start loads the file once and then keeps polling it until ctx is
+// cancelled. A missing or invalid file keeps the previous flags.
+func (p *FileProvider) Start(ctx context.Context) {
+	p.reload()
+
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.reload()
+			}
+		}
+	}()
+}
+
+func (p *FileProvider) reload() {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		p.logger.Printf("Failed to read flags file, keeping previous flags: %v", err)
+		return
+	}
+
+	p.mu.RLock()
+	unchanged := info.ModTime().Equal(p.modTime)
+	p.mu.RUnlock()
+	if unchanged {
+		return
+	}
+
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		p.logger.Printf("Failed to read flags file, keeping previous flags: %v", err)
+		return
+	}
+
+	var flags map[string]Flag
+	if err := json.Unmarshal(data, &flags); err != nil {
+		p.logger.Printf("Invalid flags file %s, keeping previous flags: %v", p.path, err)
+		return
+	}
+
+	p.mu.Lock()
+	p.flags, p.modTime = flags, info.ModTime()
+	p.mu.Unlock()
+	p.logger.Printf("Loaded %d feature flags from %s", len(flags), p.path)
+}
+
+func (p *FileProvider) Metadata() openfeature.Metadata {
+	return openfeature.Metadata{Name: "file"}
+}
+
+func (p *FileProvider) Hooks() []openfeature.Hook {
+	return nil
+}
+
+func (p *FileProvider) BooleanEvaluation(ctx context.Context, flag string, defaultValue bool, evalCtx openfeature.FlattenedContext) openfeature.BoolResolutionDetail {
+	value, detail := p.resolve(flag, evalCtx)
+	v, ok := value.(bool)
+	if detail.ResolutionError == (openfeature.ResolutionError{}) && !ok {
+		detail = mismatch(flag, value)
+	}
+	if detail.ResolutionError != (openfeature.ResolutionError{}) {
+		v = defaultValue
+	}
+	return openfeature.BoolResolutionDetail{Value: v, ProviderResolutionDetail: detail}
+}
+
+func (p *FileProvider) StringEvaluation(ctx context.Context, flag string, defaultValue string, evalCtx openfeature.FlattenedContext) openfeature.StringResolutionDetail {
+	value, detail := p.resolve(flag, evalCtx)
+	v, ok := value.(string)
+	if detail.ResolutionError == (openfeature.ResolutionError{}) && !ok {
+		detail = mismatch(flag, value)
+	}
+	if detail.ResolutionError != (openfeature.ResolutionError{}) {
+		v = defaultValue
+	}
+	return openfeature.StringResolutionDetail{Value: v, ProviderResolutionDetail: detail}
+}
+
+func (p *FileProvider) FloatEvaluation(ctx context.Context, flag string, defaultValue float64, evalCtx openfeature.FlattenedContext) openfeature.FloatResolutionDetail {
+	value, detail := p.resolve(flag, evalCtx)
+	v, ok := value.(float64)
+	if detail.ResolutionError == (openfeature.ResolutionError{}) && !ok {
+		detail = mismatch(flag, value)
+	}
+	if detail.ResolutionError != (openfeature.ResolutionError{}) {
+		v = defaultValue
+	}
+	return openfeature.FloatResolutionDetail{Value: v, ProviderResolutionDetail: detail}
+}
+
+func (p *FileProvider) IntEvaluation(ctx context.Context, flag string, defaultValue int64, evalCtx openfeature.FlattenedContext) openfeature.IntResolutionDetail {
+	value, detail := p.resolve(flag, evalCtx)
+	// JSON numbers decode as float64
+	f, ok := value.(float64)
+	if detail.ResolutionError == (openfeature.ResolutionError{}) && (!ok || f != float64(int64(f))) {
+		detail = mismatch(flag, value)
+	}
+	v := int64(f)
+	if detail.ResolutionError != (openfeature.ResolutionError{}) {
+		v = defaultValue
+	}
+	return openfeature.IntResolutionDetail{Value: v, ProviderResolutionDetail: detail}
+}
+
+func (p *FileProvider) ObjectEvaluation(ctx context.Context, flag string, defaultValue any, evalCtx openfeature.FlattenedContext) openfeature.InterfaceResolutionDetail {
+	value, detail := p.resolve(flag, evalCtx)
+	if detail.ResolutionError != (openfeature.ResolutionError{}) {
+		value = defaultValue
+	}
+	return openfeature.InterfaceResolutionDetail{Value: value, ProviderResolutionDetail: detail}
+}
+
+// resolve picks the flag's value for evalCtx and says which rule matched.
+func (p *FileProvider) resolve(flag string, evalCtx openfeature.FlattenedContext) (any, openfeature.ProviderResolutionDetail) {
+	p.mu.RLock()
+	f, ok := p.flags[flag]
+	p.mu.RUnlock()
+	if !ok {
+		return nil, openfeature.ProviderResolutionDetail{
+			ResolutionError: openfeature.NewFlagNotFoundResolutionError(fmt.Sprintf("flag %q is not defined", flag)),
+			Reason:          openfeature.ErrorReason,
+		}
+	}
+
+	if tenant, _ := evalCtx[TenantKey].(string); tenant != "" {
+		if v, ok := f.Tenants[tenant]; ok {
+			return v, openfeature.ProviderResolutionDetail{Reason: openfeature.TargetingMatchReason, Variant: "tenant:" + tenant}
+		}
+	}
+	if env, _ := evalCtx[EnvironmentKey].(string); env != "" {
+		if v, ok := f.Environments[env]; ok {
+			return v, openfeature.ProviderResolutionDetail{Reason: openfeature.TargetingMatchReason, Variant: "environment:" + env}
+		}
+	}
+	return f.Default, openfeature.ProviderResolutionDetail{Reason: openfeature.StaticReason, Variant: "default"}
+}
+
+func mismatch(flag string, value any) openfeature.ProviderResolutionDetail {
+	return openfeature.ProviderResolutionDetail{
+		ResolutionError: openfeature.NewTypeMismatchResolutionError(fmt.Sprintf("flag %q has value %v of type %T", flag, value, value)),
+		Reason:          openfeature.ErrorReason,
+	}
+}
@@ -0,0 +1,44 @@
+// Package geohash encodes coordinates as geohashes, strings naming a cell
+// of the globe that nearby points share.
+package geohash
+
+// MaxPrecision is the longest geohash Encode returns, a cell a few
+// centimetres across.
+const MaxPrecision = 12
+
+const base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Encode returns the geohash of lat, lon with precision characters, from 1
+// to MaxPrecision. Each extra character shrinks the cell about 32 times: 4
+// is about 39km by 20km, 5 about 5km by 5km and 6 about 1.2km by 600m.
+func Encode(lat, lon float64, precision int) string {
+	precision = max(1, min(precision, MaxPrecision))
+	latRange := [2]float64{-90, 90}
+	lonRange := [2]float64{-180, 180}
+
+	hash := make([]byte, 0, precision)
+	even := true
+	var bits, ch int
+	for len(hash) < precision {
+		// Bits alternate between longitude and latitude, longitude first
+		r, v := &latRange, lat
+		if even {
+			r, v = &lonRange, lon
+		}
+		mid := (r[0] + r[1]) / 2
+		ch <<= 1
+		if v >= mid {
+			ch |= 1
+			r[0] = mid
+		} else {
+			r[1] = mid
+		}
+		even = !even
+
+		if bits++; bits == 5 {
+			hash = append(hash, base32[ch])
+			bits, ch = 0, 0
+		}
+	}
+	return string(hash)
+}
@@ -0,0 +1,223 @@
+// Package handoff lets a service be upgraded in place without dropping
+// connections. Each listening socket comes from systemd socket activation,
+// from the process being replaced or, failing both, is opened anew; Upgrade
+// starts a new copy of the binary on the same sockets and returns once it
+// is serving, so the old one can drain and exit.
+package handoff
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Set for the new process by Upgrade: the names of the listeners passed,
+// in descriptor order, and the descriptor of the pipe it reports readiness
+// on
+const (
+	listenNamesEnv = "HANDOFF_LISTEN_FDNAMES"
+	readyFDEnv     = "HANDOFF_READY_FD"
+)
+
+// listenFDsStart is the first descriptor listeners are passed on, by
+// systemd and by Upgrade alike
+const listenFDsStart = 3
+
+var (
+	inheritOnce sync.Once
+	// inherited are the descriptors passed to this process, by name
+	inherited map[string]int
+
+	mu sync.Mutex
+	// listeners are the ones opened with Listen, handed over by Upgrade
+	listeners []named
+)
+
+type named struct {
+	name string
+	ln   net.Listener
+}
+
+// Listen returns the listener called name: the one inherited from the
+// process this one replaces, the socket systemd passed under that name (or
+// the only one it passed, for "http"), or a new one on network and addr.
+// A stale unix socket file at addr is replaced.
+func Listen(name, network, addr string) (net.Listener, error) {
+	inheritOnce.Do(func() { inherited = inheritedFDs() })
+
+	var ln net.Listener
+	if fd, ok := inherited[name]; ok {
+		f := os.NewFile(uintptr(fd), name)
+		defer f.Close()
+		var err error
+		if ln, err = net.FileListener(f); err != nil {
+			return nil, fmt.Errorf("inherited %s listener: %w", name, err)
+		}
+	} else {
+		if network == "unix" {
+			removeSocket(addr)
+		}
+		var err error
+		if ln, err = net.Listen(network, addr); err != nil {
+			return nil, err
+		}
+	}
+
+	mu.Lock()
+	listeners = append(listeners, named{name: name, ln: ln})
+	mu.Unlock()
+	return ln, nil
+}
+
+// inheritedFDs returns the listener descriptors handed to this process,
+// clearing the variables naming them so they don't leak to children
+func inheritedFDs() map[string]int {
+	if v := os.Getenv(listenNamesEnv); v != "" {
+		os.Unsetenv(listenNamesEnv)
+		return byName(strings.Split(v, ":"))
+	}
+
+	// systemd socket activation; LISTEN_PID tells the variables are ours
+	// and not left over from a parent
+	if os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+	if err != nil || n < 1 {
+		return nil
+	}
+	// Unnamed sockets are called after their unit, so a lone one is taken
+	// for the main listener whatever its name
+	if n == 1 {
+		return map[string]int{"http": listenFDsStart}
+	}
+	if len(names) > n {
+		names = names[:n]
+	}
+	return byName(names)
+}
+
+func byName(names []string) map[string]int {
+	fds := make(map[string]int, len(names))
+	for i, name := range names {
+		fds[name] = listenFDsStart + i
+	}
+	return fds
+}
+
+// removeSocket deletes the socket file a previous run left at path
+func removeSocket(path string) {
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+}
+
+// Ready tells the process this one replaces that it is serving, so that one
+// can drain and exit. It does nothing for a process started any other way.
+func Ready() error {
+	v := os.Getenv(readyFDEnv)
+	if v == "" {
+		return nil
+	}
+	os.Unsetenv(readyFDEnv)
+	fd, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q", readyFDEnv, v)
+	}
+	f := os.NewFile(uintptr(fd), "ready")
+	defer f.Close()
+	_, err = f.Write([]byte{1})
+	return err
+}
+
+// Upgrade starts the running binary again, with the same arguments and
+// environment, handing it every listener opened with Listen, and waits up
+// to timeout for it to call Ready. On error the new process is gone and the
+// caller should keep serving.
+func Upgrade(timeout time.Duration) error {
+	mu.Lock()
+	handed := append([]named(nil), listeners...)
+	mu.Unlock()
+
+	var files []*os.File
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	names := make([]string, len(handed))
+	for i, l := range handed {
+		filer, ok := l.ln.(interface{ File() (*os.File, error) })
+		if !ok {
+			return fmt.Errorf("can't hand over %s listener, a %T", l.name, l.ln)
+		}
+		f, err := filer.File()
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+		names[i] = l.name
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	ready, readyW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer ready.Close()
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	// ExtraFiles start at descriptor 3, the ready pipe after the listeners
+	cmd.ExtraFiles = append(append([]*os.File(nil), files...), readyW)
+	cmd.Env = append(os.Environ(),
+		listenNamesEnv+"="+strings.Join(names, ":"),
+		readyFDEnv+"="+strconv.Itoa(listenFDsStart+len(files)),
+	)
+	err = cmd.Start()
+	readyW.Close()
+	if err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		// EOF when the new process exits without calling Ready
+		_, err := ready.Read(make([]byte, 1))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			cmd.Wait()
+			return errors.New("new process exited before it was ready")
+		}
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		cmd.Wait()
+		return fmt.Errorf("new process not ready after %s", timeout)
+	}
+
+	// The new process serves on the unix sockets' files now; closing ours
+	// mustn't remove them
+	for _, l := range handed {
+		if ul, ok := l.ln.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+	// It outlives this process from here on
+	return cmd.Process.Release()
+}
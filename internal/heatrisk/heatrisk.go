@@ -0,0 +1,186 @@
+// Package heatrisk rates the heat stress risk at a configured set of CEPs,
+// reassessing them periodically, for worker-safety alerting.
+package heatrisk
+
+import (
+	"context"
+	"encoding/xml"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/offerni/weathercheck/internal/cep"
+	"github.com/offerni/weathercheck/internal/comfort"
+	"github.com/offerni/weathercheck/internal/weather"
+)
+
+// Level is a heat index risk category, as used by the US National Weather
+// Service.
+type Level string
+
+const (
+	LevelNone           Level = "none"
+	LevelCaution        Level = "caution"
+	LevelExtremeCaution Level = "extreme_caution"
+	LevelDanger         Level = "danger"
+	LevelExtremeDanger  Level = "extreme_danger"
+)
+
+// levels are the categories in increasing order of risk; the index of each
+// is what the heat.risk.level gauge reports
+var levels = []Level{LevelNone, LevelCaution, LevelExtremeCaution, LevelDanger, LevelExtremeDanger}
+
+// LevelFor returns the risk category of a heat index in °C.
+func LevelFor(heatIndexC float64) Level {
+	switch {
+	case heatIndexC >= 54:
+		return LevelExtremeDanger
+	case heatIndexC >= 41:
+		return LevelDanger
+	case heatIndexC >= 32:
+		return LevelExtremeCaution
+	case heatIndexC >= 27:
+		return LevelCaution
+	default:
+		return LevelNone
+	}
+}
+
+// Assessment is the latest risk rating of a CEP.
+type Assessment struct {
+	XMLName    xml.Name  `json:"-" xml:"risk"`
+	CEP        string    `json:"cep" xml:"cep"`
+	City       string    `json:"city" xml:"city"`
+	TempC      float64   `json:"temp_C" xml:"temp_C"`
+	Humidity   float64   `json:"humidity" xml:"humidity"`
+	HeatIndexC float64   `json:"heat_index_C" xml:"heat_index_C"`
+	Level      Level     `json:"level" xml:"level"`
+	AssessedAt time.Time `json:"assessed_at" xml:"assessed_at"`
+}
+
+// CEPResolver resolves a CEP to its address.
+type CEPResolver interface {
+	Lookup(ctx context.Context, code string) (*cep.Address, error)
+}
+
+// Monitor keeps the latest assessment of each of its CEPs. A CEP whose
+// reassessment fails keeps its previous assessment.
+type Monitor struct {
+	ceps    []string
+	cep     CEPResolver
+	weather weather.Provider
+	logger  *log.Logger
+
+	mu          sync.RWMutex
+	assessments map[string]Assessment
+}
+
+// NewMonitor builds a monitor for ceps and registers the heat.risk.level
+// gauge, for alert rules to fire on.
+func NewMonitor(ceps []string, cepResolver CEPResolver, provider weather.Provider, logger *log.Logger) *Monitor {
+	m := &Monitor{ceps: ceps, cep: cepResolver, weather: provider, logger: logger, assessments: make(map[string]Assessment)}
+
+	meter := otel.Meter("github.com/offerni/weathercheck/internal/heatrisk")
+	gauge, _ := meter.Int64ObservableGauge("heat.risk.level",
+		metric.WithDescription("Heat risk level per monitored CEP: 0 none, 1 caution, 2 extreme caution, 3 danger, 4 extreme danger"))
+	meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		for _, a := range m.assessments {
+			o.ObserveInt64(gauge, int64(levelIndex(a.Level)), metric.WithAttributes(
+				attribute.String("cep", a.CEP),
+				attribute.String("city", a.City),
+			))
+		}
+		return nil
+	}, gauge)
+
+	return m
+}
+
+func levelIndex(l Level) int {
+	for i, level := range levels {
+		if level == l {
+			return i
+		}
+	}
+	return 0
+}
+
+// Monitors reports whether code is one of the monitored CEPs.
+func (m *Monitor) Monitors(code string) bool {
+	for _, c := range m.ceps {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the latest assessment of code, if there is one.
+func (m *Monitor) Get(code string) (Assessment, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	a, ok := m.assessments[code]
+	return a, ok
+}
+
+// Run assesses every CEP right away and then every interval, until ctx is
+// done.
+func (m *Monitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.assessAll(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (m *Monitor) assessAll(ctx context.Context) {
+	for _, code := range m.ceps {
+		a, err := m.assess(ctx, code)
+		if err != nil {
+			m.logger.Printf("Failed to assess heat risk for CEP %s: %v", code, err)
+			continue
+		}
+		m.mu.Lock()
+		m.assessments[code] = a
+		m.mu.Unlock()
+	}
+}
+
+func (m *Monitor) assess(ctx context.Context, code string) (Assessment, error) {
+	address, err := m.cep.Lookup(ctx, code)
+	if err != nil {
+		return Assessment{}, err
+	}
+	current, err := m.weather.Current(ctx, address.Localidade)
+	if err != nil {
+		return Assessment{}, err
+	}
+
+	// The heat index only applies to warm air, and needs humidity; the air
+	// temperature stands in otherwise
+	heatIndex := current.TempC
+	if current.TempC >= 26.7 && current.Humidity > 0 {
+		heatIndex = math.Round(comfort.HeatIndex(current.TempC, current.Humidity)*10) / 10
+	}
+	return Assessment{
+		CEP:        code,
+		City:       address.Localidade,
+		TempC:      current.TempC,
+		Humidity:   current.Humidity,
+		HeatIndexC: heatIndex,
+		Level:      LevelFor(heatIndex),
+		AssessedAt: time.Now().UTC(),
+	}, nil
+}
@@ -0,0 +1,100 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AccessEntry is the access log line written for each request. The stage
+// timings are there when the request went through a weather lookup.
+type AccessEntry struct {
+	Time           time.Time `json:"time"`
+	Method         string    `json:"method"`
+	URI            string    `json:"uri"`
+	Status         int       `json:"status"`
+	Bytes          int64     `json:"bytes"`
+	DurationMS     float64   `json:"duration_ms"`
+	CEPLookupMS    float64   `json:"cep_lookup_ms,omitempty"`
+	WeatherFetchMS float64   `json:"weather_fetch_ms,omitempty"`
+	Cache          string    `json:"cache,omitempty"`
+	RemoteAddr     string    `json:"remote_addr"`
+	TraceID        string    `json:"trace_id,omitempty"`
+}
+
+// AccessLog writes one JSON AccessEntry line per request to out. Cache is
+// the response's X-Cache, lowercased, or "stale" for a stored reading
+// served under X-Observed-At and X-Degraded-Reason.
+func AccessLog(out io.Writer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			// Shared with Timeout, which fills in the stages
+			stages, ok := r.Context().Value(stageLogKey{}).(*stageLog)
+			if !ok {
+				stages = &stageLog{}
+				r = r.WithContext(context.WithValue(r.Context(), stageLogKey{}, stages))
+			}
+			aw := &accessWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(aw, r)
+
+			entry := AccessEntry{
+				Time:       start.UTC(),
+				Method:     r.Method,
+				URI:        r.URL.RequestURI(),
+				Status:     aw.status,
+				Bytes:      aw.bytes,
+				DurationMS: milliseconds(time.Since(start)),
+				Cache:      strings.ToLower(w.Header().Get(CacheHeader)),
+				RemoteAddr: r.RemoteAddr,
+				TraceID:    traceID(r.Context()),
+			}
+			// Fresh answers carry X-Observed-At too, but no degraded reason
+			if entry.Cache == "" && w.Header().Get(ObservedAtHeader) != "" && w.Header().Get(DegradedReasonHeader) != "" {
+				entry.Cache = "stale"
+			}
+			_, timings := stages.cut()
+			for _, t := range timings {
+				switch t.Name {
+				case stageCEPLookup:
+					entry.CEPLookupMS += t.DurationMS
+				case stageWeatherFetch:
+					entry.WeatherFetchMS += t.DurationMS
+				}
+			}
+
+			line, err := json.Marshal(entry)
+			if err != nil {
+				return
+			}
+			// One write per line, so lines from concurrent requests don't
+			// interleave
+			out.Write(append(line, '\n'))
+		})
+	}
+}
+
+// accessWriter counts what is written through it
+type accessWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *accessWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *accessWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,46 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAccessLogCache(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"fresh answer", map[string]string{ObservedAtHeader: "2026-10-14T08:00:00Z"}, ""},
+		{"stored reading", map[string]string{ObservedAtHeader: "2026-10-14T08:00:00Z", DegradedReasonHeader: ReasonQuotaExceeded}, "stale"},
+		{"degraded answer", map[string]string{DegradedReasonHeader: ReasonWeatherUnavailable}, ""},
+		{"response cache hit", map[string]string{CacheHeader: "HIT", ObservedAtHeader: "2026-10-14T08:00:00Z"}, "hit"},
+		{"response cache miss", map[string]string{CacheHeader: "MISS"}, "miss"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			h := AccessLog(&out)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for name, v := range tt.headers {
+					w.Header().Set(name, v)
+				}
+				w.Write([]byte("{}"))
+			}))
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/weather", nil))
+
+			var entry AccessEntry
+			if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+				t.Fatalf("log line %q: %v", out.String(), err)
+			}
+			if entry.Cache != tt.want {
+				t.Errorf("cache = %q, want %q", entry.Cache, tt.want)
+			}
+			if entry.Status != http.StatusOK || entry.Bytes != 2 || entry.URI != "/weather" {
+				t.Errorf("entry = %+v", entry)
+			}
+		})
+	}
+}
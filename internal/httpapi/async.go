@@ -0,0 +1,223 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/offerni/weathercheck/internal/queue"
+)
+
+// callbackAttempts is how many times a result is posted before giving up
+const callbackAttempts = 3
+
+// AsyncHandler serves POST /weather/async: it validates the lookup and its
+// callback URL, queues it and answers 202 with the job ID right away.
+// Callbacks to addresses callbacks doesn't allow are refused up front when
+// the URL names the address; the delivery transport checks the rest.
+type AsyncHandler struct {
+	queue     queue.Queue
+	callbacks CallbackPolicy
+	tracer    oteltrace.Tracer
+}
+
+func NewAsyncHandler(q queue.Queue, callbacks CallbackPolicy, tracer oteltrace.Tracer) *AsyncHandler {
+	return &AsyncHandler{queue: q, callbacks: callbacks, tracer: tracer}
+}
+
+func (h *AsyncHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "async-handler")
+	defer span.End()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		span.RecordError(err)
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	var req AsyncRequest
+	if status, errResp := decodeStrict(r, body, &req); errResp != nil {
+		WriteResponse(w, r, status, *errResp)
+		return
+	}
+	if errResp := validateLocation(req.CEPRequest); errResp != nil {
+		WriteResponse(w, r, http.StatusUnprocessableEntity, *errResp)
+		return
+	}
+	if u, err := url.Parse(req.CallbackURL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		WriteResponse(w, r, http.StatusUnprocessableEntity, ErrorResponse{Message: "callback_url must be an http or https URL", Code: "invalid_callback_url"})
+		return
+	} else if !h.callbacks.allowsURL(u.Hostname()) {
+		WriteResponse(w, r, http.StatusUnprocessableEntity, ErrorResponse{Message: "callback_url must be a public address", Code: "invalid_callback_url"})
+		return
+	}
+
+	job := queue.Job{ID: newJobID(), CEP: req.CEP, IBGE: req.IBGE, CallbackURL: req.CallbackURL}
+	span.SetAttributes(attribute.String("job.id", job.ID))
+	if err := h.queue.Publish(ctx, job); err != nil {
+		span.RecordError(err)
+		if errors.Is(err, queue.ErrFull) {
+			w.Header().Set("Retry-After", "1")
+			WriteResponse(w, r, http.StatusServiceUnavailable, ErrorResponse{Message: "too many pending lookups", Code: "queue_full"})
+			return
+		}
+		WriteResponse(w, r, http.StatusServiceUnavailable, ErrorResponse{Message: "failed to queue lookup", Code: "queue_unavailable"})
+		return
+	}
+
+	WriteResponse(w, r, http.StatusAccepted, AsyncAccepted{ID: job.ID, Status: "queued"})
+}
+
+// newJobID returns a random 128-bit hex identifier
+func newJobID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// AsyncWorker runs queued lookups against service B and posts each result
+// to its callback URL, signed with secret when it is set. Jobs that fail
+// are kept in deadLetters for an admin to retry or discard.
+type AsyncWorker struct {
+	forwarder   Forwarder
+	callbacks   *http.Client
+	secret      string
+	deadLetters *queue.DeadLetters
+	tracer      oteltrace.Tracer
+	logger      *log.Logger
+}
+
+func NewAsyncWorker(forwarder Forwarder, callbacks *http.Client, secret string, deadLetters *queue.DeadLetters, tracer oteltrace.Tracer, logger *log.Logger) *AsyncWorker {
+	return &AsyncWorker{forwarder: forwarder, callbacks: callbacks, secret: secret, deadLetters: deadLetters, tracer: tracer, logger: logger}
+}
+
+// Handle runs job and dead-letters it if it fails. It only fails itself
+// when the dead letter can't be stored, so the queue may deliver the job
+// again.
+func (w *AsyncWorker) Handle(ctx context.Context, job queue.Job) error {
+	if letter := w.run(ctx, job); letter != nil {
+		return w.bury(*letter)
+	}
+	return nil
+}
+
+// Retry runs a dead letter again: a result that couldn't be delivered is
+// posted once more, a lookup that failed is rerun. On success the dead
+// letter is dropped; otherwise it is kept with the new error.
+func (w *AsyncWorker) Retry(ctx context.Context, letter queue.DeadLetter) error {
+	if letter.Redacted {
+		return queue.ErrRedacted
+	}
+	if letter.Result == nil {
+		failed := w.run(ctx, letter.Job)
+		if failed == nil {
+			_, err := w.deadLetters.Remove(letter.Job.ID)
+			return err
+		}
+		failed.Attempts += letter.Attempts
+		w.bury(*failed)
+		return errors.New(failed.Error)
+	}
+
+	if err := w.deliver(ctx, letter.Job.CallbackURL, letter.Result); err != nil {
+		letter.Attempts++
+		letter.Error, letter.FailedAt = err.Error(), time.Now().UTC()
+		w.bury(letter)
+		return err
+	}
+	_, err := w.deadLetters.Remove(letter.Job.ID)
+	return err
+}
+
+// run looks job up and delivers its result, returning the dead letter to
+// keep if either fails
+func (w *AsyncWorker) run(ctx context.Context, job queue.Job) *queue.DeadLetter {
+	ctx, span := w.tracer.Start(ctx, "async-lookup", oteltrace.WithAttributes(attribute.String("job.id", job.ID)))
+	defer span.End()
+
+	failed := func(err error, result []byte, attempts int) *queue.DeadLetter {
+		span.RecordError(err)
+		return &queue.DeadLetter{Job: job, Result: result, Error: err.Error(), Attempts: attempts, FailedAt: time.Now().UTC()}
+	}
+
+	resp, err := w.forwarder.Forward(ctx, CEPRequest{CEP: job.CEP, IBGE: job.IBGE})
+	if err != nil {
+		return failed(err, nil, 1)
+	}
+	result, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return failed(err, nil, 1)
+	}
+	if !json.Valid(result) {
+		result, _ = json.Marshal(ErrorResponse{Message: "failed to get weather data", Code: "weather_unavailable"})
+	}
+	payload, _ := json.Marshal(AsyncResult{ID: job.ID, Status: resp.StatusCode, Result: result})
+
+	for attempt := 1; ; attempt++ {
+		err = w.deliver(ctx, job.CallbackURL, payload)
+		if err == nil {
+			return nil
+		}
+		if attempt == callbackAttempts {
+			return failed(err, payload, attempt)
+		}
+		select {
+		case <-time.After(time.Duration(attempt) * time.Second):
+		case <-ctx.Done():
+			return failed(ctx.Err(), payload, attempt)
+		}
+	}
+}
+
+// bury keeps letter for later inspection
+func (w *AsyncWorker) bury(letter queue.DeadLetter) error {
+	w.logger.Printf("Job %s failed after %d attempts, dead-lettered: %s", letter.Job.ID, letter.Attempts, letter.Error)
+	if err := w.deadLetters.Add(letter); err != nil {
+		w.logger.Printf("Failed to store dead letter for job %s: %v", letter.Job.ID, err)
+		return err
+	}
+	return nil
+}
+
+func (w *AsyncWorker) deliver(ctx context.Context, callbackURL string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("invalid callback URL %s", queue.RedactURL(callbackURL))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.secret != "" {
+		ts := time.Now().Unix()
+		req.Header.Set(CallbackTimestampHeader, strconv.FormatInt(ts, 10))
+		req.Header.Set(CallbackSignatureHeader, signCallback(w.secret, ts, payload))
+	}
+
+	resp, err := w.callbacks.Do(req)
+	if err != nil {
+		// The error names the URL, and goes to the log and the dead letters
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = queue.RedactURL(callbackURL)
+		}
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("callback answered %s", resp.Status)
+	}
+	return nil
+}
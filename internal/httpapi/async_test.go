@@ -0,0 +1,56 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+
+	"github.com/offerni/weathercheck/internal/queue"
+)
+
+func TestAsyncWorkerRetryHidesCallbackTokens(t *testing.T) {
+	// A callback that can't be reached, so the error names its URL
+	srv := httptest.NewServer(http.NotFoundHandler())
+	callback := srv.URL + "/hook?token=s3cret"
+	srv.Close()
+
+	path := filepath.Join(t.TempDir(), "dead-letters.jsonl")
+	deadLetters, err := queue.OpenDeadLetters(path, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logs bytes.Buffer
+	w := NewAsyncWorker(nil, &http.Client{}, "", deadLetters, otel.Tracer("test"), log.New(&logs, "", 0))
+
+	letter := queue.DeadLetter{Job: queue.Job{ID: "job-1", CEP: "01001000", CallbackURL: callback}, Result: json.RawMessage(`{}`)}
+	err = w.Retry(context.Background(), letter)
+	if err == nil {
+		t.Fatal("Retry to a closed callback succeeded")
+	}
+	buried, _ := deadLetters.Get("job-1")
+	file, _ := os.ReadFile(path)
+	for name, text := range map[string]string{"error": err.Error(), "log": logs.String(), "dead letter": buried.Error, "file": string(file)} {
+		if strings.Contains(text, "s3cret") {
+			t.Errorf("%s has the callback token: %s", name, text)
+		}
+	}
+	if !strings.Contains(buried.Error, srv.URL+"/hook") {
+		t.Errorf("dead letter error = %q, want the callback without its query", buried.Error)
+	}
+
+	// Letters reloaded without their token can't be delivered again
+	letter.Redacted = true
+	if err := w.Retry(context.Background(), letter); !errors.Is(err, queue.ErrRedacted) {
+		t.Errorf("Retry of a redacted letter = %v, want %v", err, queue.ErrRedacted)
+	}
+}
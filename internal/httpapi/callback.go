@@ -0,0 +1,125 @@
+package httpapi
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Sent with every callback when a signing secret is set: the Unix time it
+// was signed at and the HMAC-SHA256 of "<timestamp>.<body>", as
+// "sha256=<hex>"
+const (
+	CallbackTimestampHeader = "X-Weathercheck-Timestamp"
+	CallbackSignatureHeader = "X-Weathercheck-Signature"
+)
+
+// ErrCallbackAddress is returned for callbacks to an address CallbackPolicy
+// doesn't allow.
+var ErrCallbackAddress = errors.New("callback address not allowed")
+
+// nonPublicNets are reserved ranges that pass as global unicast but aren't
+// reachable on the internet
+var nonPublicNets = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+}
+
+// CallbackPolicy keeps callbacks, whose URLs come from unauthenticated
+// requests, off addresses only reachable from inside our network:
+// loopback, private, link-local (cloud metadata endpoints included),
+// multicast and other reserved ones, unless allowed lists them.
+type CallbackPolicy struct {
+	allowed []netip.Prefix
+}
+
+func NewCallbackPolicy(allowed []netip.Prefix) CallbackPolicy {
+	return CallbackPolicy{allowed: allowed}
+}
+
+// ParseCallbackNets parses a comma-separated list of CIDR prefixes.
+func ParseCallbackNets(s string) ([]netip.Prefix, error) {
+	var nets []netip.Prefix
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(part)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, prefix.Masked())
+	}
+	return nets, nil
+}
+
+// Allows reports whether callbacks may be delivered to ip.
+func (p CallbackPolicy) Allows(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	for _, prefix := range p.allowed {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicNets {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// allowsURL reports whether a callback URL can pass the policy at all: its
+// host has to be a name, left for the dialer to check once resolved, or an
+// allowed address.
+func (p CallbackPolicy) allowsURL(host string) bool {
+	ip, err := netip.ParseAddr(strings.Trim(host, "[]"))
+	return err != nil || p.Allows(ip)
+}
+
+// Transport dials only the addresses p allows. The check runs on the
+// address being connected to, after DNS and on every redirect, so a name
+// can't be pointed at an internal host. Proxy settings are ignored, as the
+// proxy would connect on the callback's behalf.
+func (p CallbackPolicy) Transport() *http.Transport {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second, Control: p.control}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	t.DialContext = dialer.DialContext
+	return t
+}
+
+func (p CallbackPolicy) control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !p.Allows(ip) {
+		return fmt.Errorf("%w: %s", ErrCallbackAddress, ip)
+	}
+	return nil
+}
+
+// signCallback returns the CallbackSignatureHeader value for payload sent
+// at ts
+func signCallback(secret string, ts int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.%s", ts, payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
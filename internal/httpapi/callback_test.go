@@ -0,0 +1,135 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+
+	"github.com/offerni/weathercheck/internal/queue"
+)
+
+func TestCallbackPolicyAllows(t *testing.T) {
+	tests := []struct {
+		addr    string
+		allowed string
+		want    bool
+	}{
+		{"8.8.8.8", "", true},
+		{"2001:4860:4860::8888", "", true},
+		{"127.0.0.1", "", false},
+		{"::1", "", false},
+		{"10.1.2.3", "", false},
+		{"172.16.0.1", "", false},
+		{"192.168.1.1", "", false},
+		{"169.254.169.254", "", false},
+		{"fe80::1", "", false},
+		{"fd00::1", "", false},
+		{"0.0.0.0", "", false},
+		{"100.64.0.1", "", false},
+		{"224.0.0.1", "", false},
+		{"::ffff:127.0.0.1", "", false},
+		{"::ffff:10.0.0.1", "", false},
+		{"10.1.2.3", "10.0.0.0/8", true},
+		{"10.1.2.3", "192.168.0.0/16, 10.1.2.0/24", true},
+		{"169.254.169.254", "10.0.0.0/8", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr+" "+tt.allowed, func(t *testing.T) {
+			nets, err := ParseCallbackNets(tt.allowed)
+			if err != nil {
+				t.Fatalf("ParseCallbackNets(%q): %v", tt.allowed, err)
+			}
+			if got := NewCallbackPolicy(nets).Allows(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Errorf("Allows(%s) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCallbackNetsRejectsGarbage(t *testing.T) {
+	for _, s := range []string{"10.0.0.0", "not-a-net", "10.0.0.0/33"} {
+		if _, err := ParseCallbackNets(s); err == nil {
+			t.Errorf("ParseCallbackNets(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestAsyncHandlerRefusesInternalCallbackURLs(t *testing.T) {
+	tests := []struct {
+		url    string
+		status int
+	}{
+		{"http://127.0.0.1:8081/", http.StatusUnprocessableEntity},
+		{"http://169.254.169.254/latest/meta-data/", http.StatusUnprocessableEntity},
+		{"http://[::1]/", http.StatusUnprocessableEntity},
+		{"http://10.0.0.5/hook", http.StatusUnprocessableEntity},
+		{"ftp://example.com/", http.StatusUnprocessableEntity},
+		{"https://example.com/hook", http.StatusAccepted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			h := NewAsyncHandler(queue.NewMemory(10), NewCallbackPolicy(nil), otel.Tracer("test"))
+			body := `{"cep":"01001000","callback_url":"` + tt.url + `"}`
+			r := httptest.NewRequest(http.MethodPost, "/weather/async", strings.NewReader(body))
+			r.Header.Set("Content-Type", FormatJSON)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+}
+
+func TestCallbackDelivery(t *testing.T) {
+	var got *http.Request
+	var gotBody []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+	}))
+	defer receiver.Close()
+	payload := []byte(`{"id":"1","status":200}`)
+
+	t.Run("loopback refused by default", func(t *testing.T) {
+		w := &AsyncWorker{callbacks: &http.Client{Transport: NewCallbackPolicy(nil).Transport()}}
+		err := w.deliver(context.Background(), receiver.URL, payload)
+		if !errors.Is(err, ErrCallbackAddress) {
+			t.Fatalf("deliver to %s = %v, want ErrCallbackAddress", receiver.URL, err)
+		}
+	})
+
+	t.Run("allowed and signed", func(t *testing.T) {
+		nets, _ := ParseCallbackNets("127.0.0.0/8")
+		w := &AsyncWorker{callbacks: &http.Client{Transport: NewCallbackPolicy(nets).Transport()}, secret: "s3cret"}
+		if err := w.deliver(context.Background(), receiver.URL, payload); err != nil {
+			t.Fatalf("deliver: %v", err)
+		}
+		if string(gotBody) != string(payload) {
+			t.Errorf("body = %s, want %s", gotBody, payload)
+		}
+		ts, err := strconv.ParseInt(got.Header.Get(CallbackTimestampHeader), 10, 64)
+		if err != nil {
+			t.Fatalf("%s: %v", CallbackTimestampHeader, err)
+		}
+		if sig := got.Header.Get(CallbackSignatureHeader); sig != signCallback("s3cret", ts, payload) {
+			t.Errorf("%s = %q, want the HMAC of the body", CallbackSignatureHeader, sig)
+		}
+	})
+}
+
+func TestSignCallback(t *testing.T) {
+	// printf '1700000000.{"id":"1"}' | openssl dgst -sha256 -hmac key
+	const want = "sha256=9e040cb90cefc5a04ab9a9848a74e2ff0489b4d8837b38d54555cb67e4f8e76e"
+	if got := signCallback("key", 1700000000, []byte(`{"id":"1"}`)); got != want {
+		t.Errorf("signCallback = %q, want %q", got, want)
+	}
+}
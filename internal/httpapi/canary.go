@@ -0,0 +1,68 @@
+package httpapi
+
+import (
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// CanaryHeader forces a request onto the canary backend when set to "true".
+const CanaryHeader = "X-Canary"
+
+// CanaryHandler splits traffic between a primary and a canary service B,
+// either on request via CanaryHeader or for a configured share of requests.
+type CanaryHandler struct {
+	primary  http.Handler
+	canary   http.Handler
+	percent  float64
+	requests metric.Int64Counter
+	duration metric.Float64Histogram
+}
+
+// NewCanaryHandler routes percent (0-100) of requests, plus any request with
+// CanaryHeader, to canary.
+func NewCanaryHandler(primary, canary http.Handler, percent float64) *CanaryHandler {
+	meter := otel.Meter("github.com/offerni/weathercheck/internal/httpapi")
+	requests, _ := meter.Int64Counter("service_b.requests",
+		metric.WithDescription("Requests forwarded to service B, by backend and status"))
+	duration, _ := meter.Float64Histogram("service_b.duration",
+		metric.WithDescription("Time spent forwarding to service B, by backend"),
+		metric.WithUnit("s"))
+
+	return &CanaryHandler{primary: primary, canary: canary, percent: percent, requests: requests, duration: duration}
+}
+
+func (h *CanaryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	backend, next := "primary", h.primary
+	if strings.EqualFold(r.Header.Get(CanaryHeader), "true") || rand.Float64()*100 < h.percent {
+		backend, next = "canary", h.canary
+	}
+
+	start := time.Now()
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	next.ServeHTTP(sw, r)
+
+	attrs := metric.WithAttributes(attribute.String("backend", backend), attribute.Int("status", sw.status))
+	h.requests.Add(r.Context(), 1, attrs)
+	h.duration.Record(r.Context(), time.Since(start).Seconds(), attrs)
+}
+
+// statusWriter remembers the status code written through it
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
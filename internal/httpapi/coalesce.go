@@ -0,0 +1,93 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// Coalescer merges identical weather lookups that are in flight at the same
+// time into a single call to service B: the first request goes through and
+// the others wait for its response and get a copy of it. Requests are
+// matched like ResponseCache's entries.
+type Coalescer struct {
+	next    http.Handler
+	shared  metric.Int64Counter
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// flight is a lookup on its way to service B
+type flight struct {
+	done     chan struct{}
+	complete bool
+	status   int
+	header   http.Header
+	body     []byte
+}
+
+func NewCoalescer(next http.Handler) *Coalescer {
+	meter := otel.Meter("github.com/offerni/weathercheck/internal/httpapi")
+	shared, _ := meter.Int64Counter("service_b.coalesced",
+		metric.WithDescription("Weather lookups answered by an identical request already in flight to service B"))
+
+	return &Coalescer{next: next, shared: shared, flights: map[string]*flight{}}
+}
+
+func (c *Coalescer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	span := oteltrace.SpanFromContext(r.Context())
+
+	key, ok, err := requestKey(r)
+	if err != nil {
+		span.RecordError(err)
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	if !ok {
+		c.next.ServeHTTP(w, r)
+		return
+	}
+
+	c.mu.Lock()
+	if f, ok := c.flights[key]; ok {
+		c.mu.Unlock()
+		span.SetAttributes(attribute.Bool("service_b.coalesced", true))
+		c.shared.Add(r.Context(), 1)
+		select {
+		case <-f.done:
+		case <-r.Context().Done():
+			return
+		}
+		if !f.complete {
+			http.Error(w, "Failed to forward request", http.StatusInternalServerError)
+			return
+		}
+		for name, values := range f.header {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(f.status)
+		w.Write(f.body)
+		return
+	}
+	f := &flight{done: make(chan struct{})}
+	c.flights[key] = f
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.flights, key)
+		c.mu.Unlock()
+		close(f.done)
+	}()
+
+	// Others may be waiting on this call, so the caller hanging up must not
+	// cancel it
+	rec := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+	c.next.ServeHTTP(rec, r.WithContext(context.WithoutCancel(r.Context())))
+	f.complete, f.status, f.header, f.body = true, rec.status, replayHeader(w.Header()), rec.body.Bytes()
+}
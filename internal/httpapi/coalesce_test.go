@@ -0,0 +1,85 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// inFlight reports how many lookups c has on their way to service B
+func (c *Coalescer) inFlight() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.flights)
+}
+
+func TestCoalescerReplaysAcrossEncodings(t *testing.T) {
+	tests := []struct {
+		name             string
+		leader, follower string
+	}{
+		{"gzip leader, plain follower", "gzip", ""},
+		{"plain leader, gzip follower", "", "gzip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			var calls atomic.Int32
+			upstream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				<-release
+				jsonUpstream(weatherBody).ServeHTTP(w, r)
+			})
+			c := NewCoalescer(upstream)
+			h := middleware.Compress(5, FormatJSON)(c)
+
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				h.ServeHTTP(httptest.NewRecorder(), weatherRequest(tt.leader))
+			}()
+			waitFor(t, func() bool { return c.inFlight() == 1 })
+
+			rec := httptest.NewRecorder()
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				h.ServeHTTP(rec, weatherRequest(tt.follower))
+			}()
+			// There is no hook for the follower joining the flight, so give it
+			// a moment before the leader's answer comes back
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+			wg.Wait()
+
+			if n := calls.Load(); n != 1 {
+				t.Fatalf("service B called %d times, want 1", n)
+			}
+			resp := rec.Result()
+			if got := resp.Header.Get("Content-Encoding"); got != tt.follower {
+				t.Errorf("follower Content-Encoding = %q, want %q", got, tt.follower)
+			}
+			if got := readBody(t, resp); got != weatherBody {
+				t.Errorf("follower body = %q, want %q", got, weatherBody)
+			}
+		})
+	}
+}
+
+// waitFor polls cond until it holds, failing the test after a second
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
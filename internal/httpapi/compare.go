@@ -0,0 +1,91 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"path"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/offerni/weathercheck/internal/cep"
+	"github.com/offerni/weathercheck/internal/snapshot"
+	"github.com/offerni/weathercheck/internal/temperature"
+)
+
+// yesterdayTolerance is how far from exactly 24 hours ago a snapshot may be
+// and still count as the same time yesterday
+const yesterdayTolerance = time.Hour
+
+// CompareHandler serves GET /compare/{cep}: the current temperature beside
+// the snapshot taken at about the same time yesterday, and the difference.
+type CompareHandler struct {
+	cep       CEPResolver
+	weather   WeatherProvider
+	snapshots *snapshot.Store
+	precision int
+	tracer    oteltrace.Tracer
+	logger    *log.Logger
+}
+
+// NewCompareHandler builds the handler. snapshots must be fed the readings
+// of weather, as snapshot-recording providers do.
+func NewCompareHandler(cep CEPResolver, weather WeatherProvider, snapshots *snapshot.Store, precision int, tracer oteltrace.Tracer, logger *log.Logger) *CompareHandler {
+	return &CompareHandler{cep: cep, weather: weather, snapshots: snapshots, precision: precision, tracer: tracer, logger: logger}
+}
+
+func (h *CompareHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "compare-handler")
+	defer span.End()
+
+	// The CEP is the last path segment
+	code := path.Base(r.URL.Path)
+	if !cep.Validate(code) {
+		writeError(w, r, http.StatusUnprocessableEntity, ErrorResponse{Message: "invalid zipcode", Code: "invalid_zipcode"})
+		return
+	}
+	span.SetAttributes(attribute.String("cep", code))
+
+	precision, _, errResp := temperatureOptions(r, h.precision)
+	if errResp != nil {
+		writeError(w, r, http.StatusUnprocessableEntity, *errResp)
+		return
+	}
+
+	cepData, err := h.cep.Lookup(ctx, code)
+	if err != nil {
+		span.RecordError(err)
+		status, resp := cepError(err)
+		if status >= http.StatusInternalServerError {
+			Logf(ctx, h.logger, "Failed to look up CEP %s: %v", code, err)
+		}
+		writeError(w, r, status, resp)
+		return
+	}
+	city := cepData.Localidade
+
+	yesterday, found := h.snapshots.Nearest(city, time.Now().Add(-24*time.Hour), yesterdayTolerance)
+
+	current, err := h.weather.Current(ctx, city)
+	if err != nil {
+		span.RecordError(err)
+		Logf(ctx, h.logger, "Failed to get weather for %s: %v", city, err)
+		status, resp := weatherError(err)
+		writeError(w, r, status, resp)
+		return
+	}
+
+	resp := CompareResponse{City: city, TempC: temperature.Round(current.TempC, precision)}
+	if found {
+		yesterdayC := temperature.Round(yesterday.TempC, precision)
+		delta := temperature.Round(current.TempC-yesterday.TempC, precision)
+		resp.YesterdayTempC, resp.YesterdayAt, resp.DeltaC = &yesterdayC, &yesterday.At, &delta
+	}
+	span.SetAttributes(attribute.Bool("compare.yesterday_found", found))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,89 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"path"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/offerni/weathercheck/internal/ddd"
+)
+
+// DDDHandler serves GET /ddd/{ddd}: the current temperatures of the
+// principal cities behind a Brazilian area code.
+type DDDHandler struct {
+	weather   WeatherProvider
+	precision int
+	tracer    oteltrace.Tracer
+	logger    *log.Logger
+}
+
+func NewDDDHandler(weather WeatherProvider, precision int, tracer oteltrace.Tracer, logger *log.Logger) *DDDHandler {
+	return &DDDHandler{weather: weather, precision: precision, tracer: tracer, logger: logger}
+}
+
+func (h *DDDHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "ddd-handler")
+	defer span.End()
+
+	// The DDD is the last path segment
+	code := path.Base(r.URL.Path)
+	if !ddd.Validate(code) {
+		writeError(w, r, http.StatusUnprocessableEntity, ErrorResponse{Message: "invalid area code", Code: "invalid_ddd"})
+		return
+	}
+	span.SetAttributes(attribute.String("ddd", code))
+
+	cities, ok := ddd.Cities(code)
+	if !ok {
+		writeError(w, r, http.StatusNotFound, ErrorResponse{Message: "can not find area code", Code: "ddd_not_found"})
+		return
+	}
+
+	precision, units, errResp := temperatureOptions(r, h.precision)
+	if errResp != nil {
+		writeError(w, r, http.StatusUnprocessableEntity, *errResp)
+		return
+	}
+
+	// Look the cities up in parallel; one failing city doesn't fail the area
+	resp := DDDResponse{DDD: code, Cities: make([]CityWeather, len(cities))}
+	var wg sync.WaitGroup
+	for i, city := range cities {
+		wg.Add(1)
+		go func(i int, city string) {
+			defer wg.Done()
+			resp.Cities[i].City = city
+			data, err := h.weather.Current(ctx, city)
+			if err != nil {
+				Logf(ctx, h.logger, "Failed to get weather for %s: %v", city, err)
+				_, errResp := weatherError(err)
+				resp.Cities[i].Error = errResp.Message
+				return
+			}
+			temps := NewTemperatures(data.TempC, units, precision)
+			resp.Cities[i].Temperatures = &temps
+		}(i, city)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, c := range resp.Cities {
+		if c.Temperatures == nil {
+			failed++
+		}
+	}
+	span.SetAttributes(attribute.Int("ddd.cities", len(cities)), attribute.Int("ddd.failed", failed))
+	if failed == len(cities) {
+		writeError(w, r, http.StatusBadGateway, ErrorResponse{Message: "failed to get weather data", Code: "weather_unavailable"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,84 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/offerni/weathercheck/internal/cep"
+	"github.com/offerni/weathercheck/internal/ibge"
+)
+
+// decodeCEPRequest strictly decodes a JSON CEPRequest body. On failure it
+// returns the status and error body to answer with.
+func decodeCEPRequest(r *http.Request, body []byte) (CEPRequest, int, *ErrorResponse) {
+	var req CEPRequest
+	status, errResp := decodeStrict(r, body, &req)
+	return req, status, errResp
+}
+
+// decodeStrict decodes a JSON body into v, rejecting other content types,
+// unknown fields and trailing data.
+func decodeStrict(r *http.Request, body []byte, v any) (int, *ErrorResponse) {
+	if !isJSONContent(r.Header.Get("Content-Type")) {
+		return http.StatusUnsupportedMediaType, &ErrorResponse{
+			Message: "content type must be application/json",
+			Code:    "unsupported_media_type",
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	// Anything after the object is garbage too
+	if err == nil && dec.Decode(&struct{}{}) != io.EOF {
+		err = errors.New("unexpected data after JSON object")
+	}
+	if err != nil {
+		return http.StatusUnprocessableEntity, &ErrorResponse{
+			Message: "invalid zipcode",
+			Code:    "invalid_zipcode",
+			Errors:  []FieldError{decodeFieldError(err)},
+		}
+	}
+
+	return 0, nil
+}
+
+// validateLocation checks that req names exactly one well-formed CEP or
+// IBGE code, returning the error body to answer with otherwise.
+func validateLocation(req CEPRequest) *ErrorResponse {
+	switch {
+	case req.CEP != "" && req.IBGE != "":
+		return &ErrorResponse{Message: "send either cep or ibge, not both", Code: "ambiguous_location"}
+	case req.IBGE != "":
+		if !ibge.Validate(req.IBGE) {
+			return &ErrorResponse{Message: "invalid municipality code", Code: "invalid_ibge"}
+		}
+	case !cep.Validate(req.CEP):
+		return &ErrorResponse{Message: "invalid zipcode", Code: "invalid_zipcode"}
+	}
+	return nil
+}
+
+// decodeFieldError describes a json decoding error in FieldError terms.
+func decodeFieldError(err error) FieldError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return FieldError{
+			Path:   "/" + strings.ReplaceAll(typeErr.Field, ".", "/"),
+			Reason: fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value),
+		}
+	}
+
+	// encoding/json reports unknown fields only as text
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return FieldError{Path: "/" + strings.Trim(field, `"`), Reason: "unknown field"}
+	}
+
+	return FieldError{Path: "/", Reason: err.Error()}
+}
@@ -0,0 +1,167 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeCEPRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        CEPRequest
+		wantStatus  int
+		wantErrors  []FieldError
+	}{
+		{name: "cep", contentType: "application/json", body: `{"cep":"01001000"}`, want: CEPRequest{CEP: "01001000"}},
+		{name: "ibge", contentType: "application/json; charset=utf-8", body: `{"ibge":"3550308"}`, want: CEPRequest{IBGE: "3550308"}},
+		{name: "surrounding blanks", contentType: "application/json", body: " \n{\"cep\":\"01001000\"}\n", want: CEPRequest{CEP: "01001000"}},
+		{
+			name:        "not JSON content",
+			contentType: "text/plain",
+			body:        `{"cep":"01001000"}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "unknown field",
+			contentType: "application/json",
+			body:        `{"cep":"01001000","zip":"x"}`,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantErrors:  []FieldError{{Path: "/zip", Reason: "unknown field"}},
+		},
+		{
+			name:        "wrong type",
+			contentType: "application/json",
+			body:        `{"cep":1001000}`,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantErrors:  []FieldError{{Path: "/cep", Reason: "expected string, got number"}},
+		},
+		{
+			name:        "trailing data",
+			contentType: "application/json",
+			body:        `{"cep":"01001000"}{"cep":"02002000"}`,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantErrors:  []FieldError{{Path: "/", Reason: "unexpected data after JSON object"}},
+		},
+		{
+			name:        "truncated object",
+			contentType: "application/json",
+			body:        `{"cep":"0100`,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantErrors:  []FieldError{{Path: "/", Reason: "unexpected EOF"}},
+		},
+		{
+			name:        "empty body",
+			contentType: "application/json",
+			body:        ``,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantErrors:  []FieldError{{Path: "/", Reason: "EOF"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/weather", nil)
+			r.Header.Set("Content-Type", tt.contentType)
+
+			got, status, errResp := decodeCEPRequest(r, []byte(tt.body))
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%+v)", status, tt.wantStatus, errResp)
+			}
+			if tt.wantStatus == 0 {
+				if errResp != nil || got != tt.want {
+					t.Errorf("decoded %+v, %+v; want %+v", got, errResp, tt.want)
+				}
+				return
+			}
+			if errResp == nil {
+				t.Fatal("no error body")
+			}
+			if !reflect.DeepEqual(errResp.Errors, tt.wantErrors) {
+				t.Errorf("errors = %+v, want %+v", errResp.Errors, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestValidateLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      CEPRequest
+		wantCode string
+	}{
+		{"valid cep", CEPRequest{CEP: "01001000"}, ""},
+		{"valid ibge", CEPRequest{IBGE: "3550308"}, ""},
+		{"short cep", CEPRequest{CEP: "0100100"}, "invalid_zipcode"},
+		{"formatted cep", CEPRequest{CEP: "01001-000"}, "invalid_zipcode"},
+		{"nothing sent", CEPRequest{}, "invalid_zipcode"},
+		{"both sent", CEPRequest{CEP: "01001000", IBGE: "3550308"}, "ambiguous_location"},
+		{"short ibge", CEPRequest{IBGE: "355030"}, "invalid_ibge"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errResp := validateLocation(tt.req)
+			var got string
+			if errResp != nil {
+				got = errResp.Code
+			}
+			if got != tt.wantCode {
+				t.Errorf("validateLocation(%+v) = %q, want %q", tt.req, got, tt.wantCode)
+			}
+		})
+	}
+}
+
+func FuzzDecodeCEPRequest(f *testing.F) {
+	for _, seed := range []struct{ contentType, body string }{
+		{"application/json", `{"cep":"01001000"}`},
+		{"application/json; charset=utf-8", `{"ibge":"3550308"}`},
+		{"application/json", `{"cep":"01001000","ibge":"3550308"}`},
+		{"application/json", `{"CEP":"01001000"}`},
+		{"application/json", `{"cep":"٠١٠٠١٠٠٠"}`},
+		{"application/json", `{"cep":"01001000\u0000"}`},
+		{"application/json", `{"cep":null}`},
+		{"application/json", `{"cep":{"cep":[[["01001000"]]]}}`},
+		{"application/json", strings.Repeat(`[`, 10001) + strings.Repeat(`]`, 10001)},
+		{"application/json", `{"cep":"` + strings.Repeat("0", 1<<12) + `"}`},
+		{"application/json", `{"cep":"01001000"} trailing`},
+		{"text/plain", `{"cep":"01001000"}`},
+		{"application/json;;", ``},
+	} {
+		f.Add(seed.contentType, seed.body)
+	}
+	f.Fuzz(func(t *testing.T, contentType, body string) {
+		r := httptest.NewRequest(http.MethodPost, "/weather", nil)
+		r.Header.Set("Content-Type", contentType)
+
+		req, status, errResp := decodeCEPRequest(r, []byte(body))
+		switch {
+		case errResp == nil && status != 0:
+			t.Fatalf("status %d without an error body", status)
+		case errResp != nil && status != http.StatusUnsupportedMediaType && status != http.StatusUnprocessableEntity:
+			t.Fatalf("status = %d for %+v", status, errResp)
+		case status == http.StatusUnprocessableEntity && (len(errResp.Errors) != 1 || !strings.HasPrefix(errResp.Errors[0].Path, "/")):
+			t.Fatalf("errors = %+v, want one with a JSON pointer", errResp.Errors)
+		case errResp != nil:
+			return
+		}
+
+		// What decodes once decodes again to the same request
+		encoded, err := json.Marshal(req)
+		if err != nil {
+			t.Fatalf("Marshal(%+v): %v", req, err)
+		}
+		again, _, errResp := decodeCEPRequest(r, encoded)
+		if errResp != nil || again != req {
+			t.Fatalf("round trip of %s = %+v, %+v; want %+v", encoded, again, errResp, req)
+		}
+
+		if validateLocation(req) == nil && (req.CEP != "") == (req.IBGE != "") {
+			t.Fatalf("validateLocation accepted %+v", req)
+		}
+	})
+}
@@ -0,0 +1,167 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// ProfileV2 is the Accept profile parameter, as in
+// `application/json; profile="v2"`, that asks for the v2 envelope on the
+// unversioned routes.
+const ProfileV2 = "v2"
+
+// Cache status reported in the v2 envelope
+const (
+	CacheHit    = "hit"
+	CacheMiss   = "miss"
+	CacheBypass = "bypass"
+)
+
+// Envelope is the v2 response body: the v1 answer under data, along with
+// who answered, when the reading was taken, whether service A's cache was
+// used, why the answer is degraded if it is, and the ID to quote when
+// reporting the request.
+type Envelope struct {
+	XMLName        xml.Name   `json:"-" xml:"response"`
+	Data           any        `json:"data"`
+	Provider       string     `json:"provider,omitempty" xml:"provider,omitempty"`
+	ObservedAt     *time.Time `json:"observed_at,omitempty" xml:"observed_at,omitempty"`
+	Cache          string     `json:"cache" xml:"cache"`
+	DegradedReason string     `json:"degraded_reason,omitempty" xml:"degraded_reason,omitempty"`
+	RequestID      string     `json:"request_id,omitempty" xml:"request_id,omitempty"`
+}
+
+// V2 wraps the weather answers of requests to /v2/weather, or that accept
+// ProfileV2, in an Envelope. next always serves the v1 answer in JSON at the
+// unversioned path, so caching and contract validation see v1 requests;
+// other requests pass through untouched and keep their v1 bytes.
+func V2(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		versioned := strings.HasPrefix(r.URL.Path, "/v2/")
+		if !versioned && !acceptsV2(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		format := NegotiateFormat(r)
+		oteltrace.SpanFromContext(r.Context()).SetAttributes(attribute.String("response.schema", "v2"))
+
+		inner := r.Clone(r.Context())
+		inner.Header.Set("Accept", FormatJSON)
+		if versioned {
+			inner.URL.Path = strings.TrimPrefix(r.URL.Path, "/v2")
+			inner.URL.RawPath = ""
+		}
+		rec := &bufferingWriter{header: http.Header{}, status: http.StatusOK}
+		next.ServeHTTP(rec, inner)
+
+		for name, values := range rec.header {
+			w.Header()[name] = values
+		}
+		// Bodies that aren't ours, like the proxy's plain text failures, go
+		// out as they are
+		if !isJSONContent(rec.header.Get("Content-Type")) {
+			w.WriteHeader(rec.status)
+			w.Write(rec.body.Bytes())
+			return
+		}
+
+		var v any = &ErrorResponse{}
+		if rec.status == http.StatusOK {
+			v = envelope(r, rec)
+		}
+		if err := json.Unmarshal(rec.body.Bytes(), envelopeData(v)); err != nil {
+			w.WriteHeader(rec.status)
+			w.Write(rec.body.Bytes())
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := Encode(&buf, format, v); err != nil {
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", format)
+		w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+		w.WriteHeader(rec.status)
+		w.Write(buf.Bytes())
+	})
+}
+
+// envelope builds the Envelope of the 200 answer rec holds, its data still
+// to be decoded
+func envelope(r *http.Request, rec *bufferingWriter) *Envelope {
+	var data any = &WeatherResponse{}
+	if isDegraded(rec.body.Bytes()) {
+		data = &DegradedResponse{}
+	}
+
+	env := &Envelope{
+		Data:           data,
+		Provider:       rec.header.Get(ProviderHeader),
+		Cache:          CacheBypass,
+		DegradedReason: rec.header.Get(DegradedReasonHeader),
+		RequestID:      traceID(r.Context()),
+	}
+	if at, err := time.Parse(time.RFC3339, rec.header.Get(ObservedAtHeader)); err == nil {
+		env.ObservedAt = &at
+	}
+	switch rec.header.Get(CacheHeader) {
+	case "HIT":
+		env.Cache = CacheHit
+	case "MISS":
+		env.Cache = CacheMiss
+	}
+	return env
+}
+
+// envelopeData returns what the answer's JSON decodes into: an Envelope's
+// data, or v itself
+func envelopeData(v any) any {
+	if env, ok := v.(*Envelope); ok {
+		return env.Data
+	}
+	return v
+}
+
+// acceptsV2 reports whether any media type in r's Accept header carries
+// ProfileV2
+func acceptsV2(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if _, ok := acceptedMediaTypes[mediaType]; ok && params["profile"] == ProfileV2 {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferingWriter holds a whole response back so it can be rewritten
+type bufferingWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferingWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferingWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *bufferingWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
@@ -0,0 +1,132 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// v1Weather stands in for the v1 weather route, recording the path and
+// Accept header it was asked with
+type v1Weather struct {
+	status      int
+	contentType string
+	body        string
+
+	path, accept string
+}
+
+func (h *v1Weather) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.path, h.accept = r.URL.Path, r.Header.Get("Accept")
+	w.Header().Set("Content-Type", h.contentType)
+	w.Header().Set(ProviderHeader, "weatherapi")
+	w.Header().Set(ObservedAtHeader, "2023-11-14T22:13:20Z")
+	w.Header().Set(CacheHeader, "HIT")
+	w.WriteHeader(h.status)
+	io.WriteString(w, h.body)
+}
+
+func TestV2(t *testing.T) {
+	const weatherBody = `{"city":"São Paulo","temp_C":28.5,"condition":"rain","icon":"cloud-rain"}`
+	tests := []struct {
+		name        string
+		path        string
+		accept      string
+		status      int
+		contentType string
+		body        string
+		wantPath    string
+		wantBody    string
+	}{
+		{
+			name:        "unversioned passes through",
+			path:        "/weather",
+			status:      http.StatusOK,
+			contentType: FormatJSON,
+			body:        weatherBody,
+			wantPath:    "/weather",
+			wantBody:    weatherBody,
+		},
+		{
+			name:        "versioned path",
+			path:        "/v2/weather",
+			status:      http.StatusOK,
+			contentType: FormatJSON,
+			body:        weatherBody,
+			wantPath:    "/weather",
+			wantBody:    `{"data":{"city":"São Paulo","temp_C":28.5,"condition":"rain","icon":"cloud-rain"},"provider":"weatherapi","observed_at":"2023-11-14T22:13:20Z","cache":"hit"}`,
+		},
+		{
+			name:        "v2 profile",
+			path:        "/weather",
+			accept:      `application/json; profile="v2"`,
+			status:      http.StatusOK,
+			contentType: FormatJSON,
+			body:        weatherBody,
+			wantPath:    "/weather",
+			wantBody:    `{"data":{"city":"São Paulo","temp_C":28.5,"condition":"rain","icon":"cloud-rain"},"provider":"weatherapi","observed_at":"2023-11-14T22:13:20Z","cache":"hit"}`,
+		},
+		{
+			name:        "degraded answer",
+			path:        "/v2/weather",
+			status:      http.StatusOK,
+			contentType: FormatJSON,
+			body:        `{"city":"São Paulo","weather_available":false}`,
+			wantPath:    "/weather",
+			wantBody:    `{"data":{"city":"São Paulo","weather_available":false},"provider":"weatherapi","observed_at":"2023-11-14T22:13:20Z","cache":"hit"}`,
+		},
+		{
+			name:        "errors aren't wrapped",
+			path:        "/v2/weather",
+			status:      http.StatusNotFound,
+			contentType: FormatJSON,
+			body:        `{"message":"can not find zipcode","code":"zipcode_not_found"}`,
+			wantPath:    "/weather",
+			wantBody:    `{"message":"can not find zipcode","code":"zipcode_not_found"}`,
+		},
+		{
+			name:        "foreign bodies go out as they are",
+			path:        "/v2/weather",
+			status:      http.StatusInternalServerError,
+			contentType: "text/plain; charset=utf-8",
+			body:        "Failed to forward request\n",
+			wantPath:    "/weather",
+			wantBody:    "Failed to forward request\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &v1Weather{status: tt.status, contentType: tt.contentType, body: tt.body}
+			r := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			V2(next).ServeHTTP(rec, r)
+
+			if next.path != tt.wantPath {
+				t.Errorf("v1 route asked for %s, want %s", next.path, tt.wantPath)
+			}
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Body.String(); !sameJSON(got, tt.wantBody) {
+				t.Errorf("body = %s\nwant %s", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+// sameJSON reports whether a and b hold the same JSON value, or are the
+// same text if either isn't JSON
+func sameJSON(a, b string) bool {
+	var va, vb any
+	if json.Unmarshal([]byte(a), &va) != nil || json.Unmarshal([]byte(b), &vb) != nil {
+		return a == b
+	}
+	ja, _ := json.Marshal(va)
+	jb, _ := json.Marshal(vb)
+	return string(ja) == string(jb)
+}
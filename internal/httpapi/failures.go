@@ -0,0 +1,181 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/offerni/weathercheck/internal/upstream"
+)
+
+// FailedLookup is a lookup that got a 5xx, kept with what was asked and
+// what each upstream answered so it can be inspected and replayed.
+type FailedLookup struct {
+	ID          string          `json:"id"`
+	FailedAt    time.Time       `json:"failed_at"`
+	Method      string          `json:"method"`
+	URI         string          `json:"uri"`
+	ContentType string          `json:"content_type,omitempty"`
+	Input       string          `json:"input,omitempty"`
+	Status      int             `json:"status"`
+	Response    json.RawMessage `json:"response,omitempty"`
+	TraceID     string          `json:"trace_id,omitempty"`
+	Calls       []upstream.Call `json:"upstream_calls"`
+}
+
+// Replayed is the outcome of running a FailedLookup again.
+type Replayed struct {
+	Status   int             `json:"status"`
+	Response json.RawMessage `json:"response,omitempty"`
+	TraceID  string          `json:"trace_id,omitempty"`
+	Calls    []upstream.Call `json:"upstream_calls"`
+}
+
+// Failures keeps the last size failed lookups, dropping the oldest when
+// full. It is safe for concurrent use.
+type Failures struct {
+	size int
+
+	mu      sync.Mutex
+	lookups []FailedLookup
+}
+
+func NewFailures(size int) *Failures {
+	return &Failures{size: size}
+}
+
+// Middleware records the requests next fails with a 5xx, along with their
+// upstream calls.
+func (f *Failures) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		input, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(input))
+		// Taken before validation fills in the query's defaults
+		uri := r.URL.RequestURI()
+
+		ctx, recording := upstream.WithRecording(r.Context())
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+		if rec.status < http.StatusInternalServerError {
+			return
+		}
+
+		f.add(FailedLookup{
+			ID:          newJobID(),
+			FailedAt:    time.Now().UTC(),
+			Method:      r.Method,
+			URI:         uri,
+			ContentType: r.Header.Get("Content-Type"),
+			Input:       string(input),
+			Status:      rec.status,
+			Response:    rawJSON(rec.body.Bytes()),
+			TraceID:     traceID(r.Context()),
+			Calls:       recording.Calls(),
+		})
+	})
+}
+
+func (f *Failures) add(lookup FailedLookup) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lookups = append(f.lookups, lookup)
+	if len(f.lookups) > f.size {
+		f.lookups = f.lookups[len(f.lookups)-f.size:]
+	}
+}
+
+// List returns the failed lookups, oldest first.
+func (f *Failures) List() []FailedLookup {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.lookups)
+}
+
+// Get returns the failed lookup with id.
+func (f *Failures) Get(id string) (FailedLookup, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := slices.IndexFunc(f.lookups, func(l FailedLookup) bool { return l.ID == id })
+	if i < 0 {
+		return FailedLookup{}, false
+	}
+	return f.lookups[i], true
+}
+
+// Remove drops the failed lookup with id, reporting whether it was there.
+func (f *Failures) Remove(id string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := len(f.lookups)
+	f.lookups = slices.DeleteFunc(f.lookups, func(l FailedLookup) bool { return l.ID == id })
+	return len(f.lookups) < n
+}
+
+// RemoveFunc drops the failed lookups match picks, returning how many went.
+func (f *Failures) RemoveFunc(match func(FailedLookup) bool) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := len(f.lookups)
+	f.lookups = slices.DeleteFunc(f.lookups, match)
+	return n - len(f.lookups)
+}
+
+// Mentions reports whether s shows up anywhere in l: its URI, its request
+// or response body, or one of its upstream calls.
+func (l FailedLookup) Mentions(s string) bool {
+	if strings.Contains(l.URI, s) || strings.Contains(l.Input, s) || bytes.Contains(l.Response, []byte(s)) {
+		return true
+	}
+	for _, c := range l.Calls {
+		if strings.Contains(c.URL, s) || strings.Contains(c.Body, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Replay runs lookup through handler again, as it was first asked, and
+// returns the new answer with its upstream calls.
+func Replay(ctx context.Context, handler http.Handler, lookup FailedLookup) (Replayed, error) {
+	ctx, recording := upstream.WithRecording(ctx)
+	req, err := http.NewRequestWithContext(ctx, lookup.Method, lookup.URI, bytes.NewBufferString(lookup.Input))
+	if err != nil {
+		return Replayed{}, err
+	}
+	if lookup.ContentType != "" {
+		req.Header.Set("Content-Type", lookup.ContentType)
+	}
+	req.Header.Set("Accept", FormatJSON)
+
+	rec := &bufferingWriter{header: http.Header{}, status: http.StatusOK}
+	handler.ServeHTTP(rec, req)
+	return Replayed{
+		Status:   rec.status,
+		Response: rawJSON(rec.body.Bytes()),
+		TraceID:  traceID(ctx),
+		Calls:    recording.Calls(),
+	}, nil
+}
+
+// rawJSON returns body as JSON, quoting it when it is something else
+func rawJSON(body []byte) json.RawMessage {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil
+	}
+	if json.Valid(body) {
+		return body
+	}
+	quoted, _ := json.Marshal(string(body))
+	return quoted
+}
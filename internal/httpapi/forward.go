@@ -0,0 +1,314 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/offerni/weathercheck/internal/heatrisk"
+)
+
+// Forwarder sends a validated lookup on to service B.
+type Forwarder interface {
+	Forward(ctx context.Context, req CEPRequest) (*http.Response, error)
+}
+
+// Summarizer asks service B for a CEP's or a city's weather summary in lang.
+type Summarizer interface {
+	Summarize(ctx context.Context, cep, lang string) (*http.Response, error)
+	SummarizeCity(ctx context.Context, city, lang string) (*http.Response, error)
+}
+
+// Endpoints hands out service B base URLs and takes failing ones out of
+// rotation.
+type Endpoints interface {
+	Next() (*url.URL, error)
+	MarkDown(endpoint *url.URL)
+}
+
+// serviceBPath is the service B route every lookup is sent to
+const serviceBPath = "/weather"
+
+// ServiceBClient forwards lookups to service B's /weather and /summary
+// endpoints.
+type ServiceBClient struct {
+	endpoints  Endpoints
+	httpClient *http.Client
+	tracer     oteltrace.Tracer
+}
+
+func NewServiceBClient(endpoints Endpoints, httpClient *http.Client, tracer oteltrace.Tracer) *ServiceBClient {
+	return &ServiceBClient{endpoints: endpoints, httpClient: httpClient, tracer: tracer}
+}
+
+func (c *ServiceBClient) Forward(ctx context.Context, req CEPRequest) (*http.Response, error) {
+	ctx, span := c.tracer.Start(ctx, "forward-to-service-b")
+	defer span.End()
+
+	endpoint, err := c.endpoints.Next()
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	reqBody, _ := json.Marshal(req)
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint.JoinPath(serviceBPath).String(), bytes.NewBuffer(reqBody))
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		span.RecordError(err)
+		c.endpoints.MarkDown(endpoint)
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (c *ServiceBClient) Summarize(ctx context.Context, cep, lang string) (*http.Response, error) {
+	return c.summarize(ctx, path.Join("/summary", cep), url.Values{"lang": {lang}})
+}
+
+func (c *ServiceBClient) SummarizeCity(ctx context.Context, city, lang string) (*http.Response, error) {
+	return c.summarize(ctx, "/summary", url.Values{"city": {city}, "lang": {lang}})
+}
+
+func (c *ServiceBClient) summarize(ctx context.Context, summaryPath string, query url.Values) (*http.Response, error) {
+	ctx, span := c.tracer.Start(ctx, "summarize-with-service-b")
+	defer span.End()
+
+	endpoint, err := c.endpoints.Next()
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	target := endpoint.JoinPath(summaryPath)
+	target.RawQuery = query.Encode()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		span.RecordError(err)
+		c.endpoints.MarkDown(endpoint)
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ServiceBProxy is a reverse proxy to service B. Every request is sent to
+// the same route on the next healthy service B endpoint, upstream headers
+// and trailers are preserved and the response body is streamed back,
+// re-encoded only when the client negotiated a format other than JSON.
+type ServiceBProxy struct {
+	endpoints Endpoints
+	proxy     *httputil.ReverseProxy
+	logger    *log.Logger
+}
+
+func NewServiceBProxy(endpoints Endpoints, transport http.RoundTripper, logger *log.Logger) *ServiceBProxy {
+	p := &ServiceBProxy{endpoints: endpoints, logger: logger}
+	p.proxy = &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			target := pr.In.Context().Value(endpointKey{}).(*url.URL)
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			pr.Out.URL.Path = strings.TrimRight(target.Path, "/") + pr.In.URL.Path
+			pr.Out.URL.RawPath = ""
+			// Service B always answers in JSON; negotiation happens here
+			format := NegotiateFormat(pr.In)
+			pr.Out = pr.Out.WithContext(context.WithValue(pr.Out.Context(), formatKey{}, format))
+			pr.Out.Header.Del("Accept")
+			// Compression is applied by service A's own middleware
+			pr.Out.Header.Del("Accept-Encoding")
+		},
+		Transport:      transport,
+		FlushInterval:  -1,
+		ModifyResponse: reencodeResponse,
+		ErrorHandler:   p.handleError,
+	}
+	return p
+}
+
+func (p *ServiceBProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	endpoint, err := p.endpoints.Next()
+	if err != nil {
+		p.handleError(w, r, err)
+		return
+	}
+
+	r = r.WithContext(context.WithValue(r.Context(), endpointKey{}, endpoint))
+	p.proxy.ServeHTTP(w, r)
+}
+
+func (p *ServiceBProxy) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	oteltrace.SpanFromContext(r.Context()).RecordError(err)
+	if endpoint, ok := r.Context().Value(endpointKey{}).(*url.URL); ok {
+		p.endpoints.MarkDown(endpoint)
+	}
+	Logf(r.Context(), p.logger, "Failed to forward request to service B: %v", err)
+	http.Error(w, "Failed to forward request", http.StatusInternalServerError)
+}
+
+// endpointKey carries the service B endpoint chosen for a request
+type endpointKey struct{}
+
+// formatKey carries the client's negotiated format on the outbound request
+type formatKey struct{}
+
+// isDegraded reports whether a 200 body from service B is a DegradedResponse
+func isDegraded(body []byte) bool {
+	var probe struct {
+		WeatherAvailable *bool `json:"weather_available"`
+	}
+	return json.Unmarshal(body, &probe) == nil && probe.WeatherAvailable != nil
+}
+
+// reencodeResponse converts service B's JSON body into the format the
+// client negotiated; JSON responses pass through untouched.
+func reencodeResponse(resp *http.Response) error {
+	resp.Header.Add("Vary", "Accept")
+	// Service A already reports the trace ID, which service B shares
+	resp.Header.Del(TraceIDHeader)
+
+	format, _ := resp.Request.Context().Value(formatKey{}).(string)
+	if format == "" || format == FormatJSON {
+		return nil
+	}
+	// Problem details are only defined in JSON
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == ProblemContentType {
+		return nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("reading service B response: %w", err)
+	}
+
+	var v any
+	switch {
+	case resp.StatusCode != http.StatusOK:
+		v = &ErrorResponse{}
+	case strings.Contains(resp.Request.URL.Path, "/summary"):
+		v = &SummaryResponse{}
+	case strings.Contains(resp.Request.URL.Path, "/ddd/"):
+		v = &DDDResponse{}
+	case strings.Contains(resp.Request.URL.Path, "/compare/"):
+		v = &CompareResponse{}
+	case strings.Contains(resp.Request.URL.Path, "/rain/"):
+		v = &RainResponse{}
+	case strings.Contains(resp.Request.URL.Path, "/marine/"):
+		v = &MarineResponse{}
+	case strings.Contains(resp.Request.URL.Path, "/pollen/"):
+		v = &PollenResponse{}
+	case strings.Contains(resp.Request.URL.Path, "/risk/"):
+		v = &heatrisk.Assessment{}
+	case isDegraded(body):
+		v = &DegradedResponse{}
+	default:
+		v = &WeatherResponse{}
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("decoding service B response: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, format, v); err != nil {
+		return err
+	}
+
+	resp.Body = io.NopCloser(&buf)
+	resp.ContentLength = int64(buf.Len())
+	resp.Header.Set("Content-Type", format)
+	resp.Header.Set("Content-Length", strconv.Itoa(buf.Len()))
+	return nil
+}
+
+// ValidationHandler serves service A's weather endpoint: it validates the
+// CEP or IBGE code and hands the request to the upstream proxy.
+type ValidationHandler struct {
+	upstream http.Handler
+	stages   stageTimer
+	tracer   oteltrace.Tracer
+}
+
+func NewValidationHandler(upstream http.Handler, tracer oteltrace.Tracer) *ValidationHandler {
+	return &ValidationHandler{upstream: upstream, stages: newStageTimer(), tracer: tracer}
+}
+
+func (h *ValidationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "weather-handler")
+	defer span.End()
+
+	// Parse request body
+	start := h.stages.begin(ctx, stageParsing)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		span.RecordError(err)
+		h.stages.observe(ctx, stageParsing, start, true)
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	span.SetAttributes(attribute.Int("request.body_bytes", len(body)))
+
+	req, status, errResp := decodeCEPRequest(r, body)
+	if errResp != nil {
+		span.SetAttributes(attribute.String("request.error", errResp.Code))
+		h.stages.observe(ctx, stageParsing, start, true)
+		WriteResponse(w, r, status, *errResp)
+		return
+	}
+	h.stages.observe(ctx, stageParsing, start, false)
+
+	// Validate the CEP or IBGE code format
+	start = h.stages.begin(ctx, stageValidation)
+	if errResp := validateLocation(req); errResp != nil {
+		span.SetAttributes(attribute.String("request.error", errResp.Code))
+		h.stages.observe(ctx, stageValidation, start, true)
+		WriteResponse(w, r, http.StatusUnprocessableEntity, *errResp)
+		return
+	}
+	h.stages.observe(ctx, stageValidation, start, false)
+
+	if req.IBGE != "" {
+		span.SetAttributes(attribute.String("ibge.valid", req.IBGE))
+	} else {
+		span.SetAttributes(attribute.String("cep.valid", req.CEP))
+	}
+
+	// Forward to Service B
+	forwardCtx, forwardSpan := h.tracer.Start(ctx, "forward-to-service-b")
+	defer forwardSpan.End()
+
+	r = r.WithContext(forwardCtx)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	h.upstream.ServeHTTP(w, r)
+}
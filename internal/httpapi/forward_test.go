@@ -0,0 +1,128 @@
+package httpapi
+
+import (
+	"encoding/xml"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+)
+
+// fixedEndpoint always hands out one endpoint and counts how often it was
+// marked down
+type fixedEndpoint struct {
+	url  *url.URL
+	down atomic.Int32
+}
+
+func (e *fixedEndpoint) Next() (*url.URL, error) { return e.url, nil }
+func (e *fixedEndpoint) MarkDown(*url.URL)       { e.down.Add(1) }
+
+// serviceB stands in for service B: 01001000 is São Paulo and any other CEP
+// is unknown. It counts the lookups it answered.
+func serviceB(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path != "/weather" {
+			t.Errorf("path = %s, want /weather", r.URL.Path)
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", FormatJSON)
+		if !strings.Contains(string(body), "01001000") {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"message":"can not find zipcode","code":"zipcode_not_found"}`)
+			return
+		}
+		io.WriteString(w, `{"city":"São Paulo","temp_C":28.5,"temp_F":83.3,"condition":"clear","icon":"sun"}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func newTestValidationHandler(endpoint *fixedEndpoint) *ValidationHandler {
+	proxy := NewServiceBProxy(endpoint, http.DefaultTransport, log.New(io.Discard, "", 0))
+	return NewValidationHandler(proxy, otel.Tracer("test"))
+}
+
+func TestValidationHandlerForwards(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		accept      string
+		body        string
+		wantStatus  int
+		wantCalls   int32
+		wantType    string
+		wantBody    string
+	}{
+		{"valid CEP", FormatJSON, "", `{"cep":"01001000"}`, http.StatusOK, 1, FormatJSON, `"city":"São Paulo"`},
+		{"valid CEP in XML", FormatJSON, FormatXML, `{"cep":"01001000"}`, http.StatusOK, 1, FormatXML, `<city>São Paulo</city>`},
+		{"unknown CEP", FormatJSON, "", `{"cep":"99999999"}`, http.StatusNotFound, 1, FormatJSON, `"code":"zipcode_not_found"`},
+		{"unknown CEP in XML", FormatJSON, FormatXML, `{"cep":"99999999"}`, http.StatusNotFound, 1, FormatXML, `<code>zipcode_not_found</code>`},
+		{"invalid CEP", FormatJSON, "", `{"cep":"0100100"}`, http.StatusUnprocessableEntity, 0, FormatJSON, `"code":"invalid_zipcode"`},
+		{"unknown field", FormatJSON, "", `{"zip":"01001000"}`, http.StatusUnprocessableEntity, 0, FormatJSON, `"path":"/zip"`},
+		{"not JSON", "text/plain", "", `01001000`, http.StatusUnsupportedMediaType, 0, FormatJSON, `"code":"unsupported_media_type"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := serviceB(t)
+			target, _ := url.Parse(srv.URL)
+			h := newTestValidationHandler(&fixedEndpoint{url: target})
+
+			r := httptest.NewRequest(http.MethodPost, "/weather", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("service B answered %d lookups, want %d", got, tt.wantCalls)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.wantType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantType)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", rec.Body, tt.wantBody)
+			}
+			if tt.wantType == FormatXML {
+				var v struct{}
+				if err := xml.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+					t.Errorf("body isn't XML: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidationHandlerServiceBDown(t *testing.T) {
+	srv, _ := serviceB(t)
+	target, _ := url.Parse(srv.URL)
+	srv.Close()
+	endpoint := &fixedEndpoint{url: target}
+	h := newTestValidationHandler(endpoint)
+
+	r := httptest.NewRequest(http.MethodPost, "/weather", strings.NewReader(`{"cep":"01001000"}`))
+	r.Header.Set("Content-Type", FormatJSON)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500: %s", rec.Code, rec.Body)
+	}
+	if endpoint.down.Load() != 1 {
+		t.Errorf("endpoint marked down %d times, want once", endpoint.down.Load())
+	}
+}
@@ -0,0 +1,50 @@
+package httpapi
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestInProcessTransport(t *testing.T) {
+	var seen *http.Request
+	transport := NewInProcessTransport(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", FormatJSON)
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"echo":`+string(body)+`}`)
+	}))
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Post("http://service-b/weather?units=C", FormatJSON, strings.NewReader(`"01001000"`))
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusCreated || resp.Status != "201 Created" {
+		t.Errorf("status = %q", resp.Status)
+	}
+	if string(body) != `{"echo":"01001000"}` {
+		t.Errorf("body = %s", body)
+	}
+	if resp.ContentLength != int64(len(body)) || resp.Header.Get("Content-Length") != "19" {
+		t.Errorf("length = %d (%s), want %d", resp.ContentLength, resp.Header.Get("Content-Length"), len(body))
+	}
+	if seen.RequestURI != "/weather?units=C" || seen.Host != "service-b" || seen.Method != http.MethodPost {
+		t.Errorf("handler got %s %s on %s", seen.Method, seen.RequestURI, seen.Host)
+	}
+
+	// Bodiless requests still read as empty bodies
+	resp, err = client.Get("http://service-b/health")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+	if seen.Body == nil {
+		t.Error("handler got a nil body")
+	}
+}
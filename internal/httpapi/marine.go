@@ -0,0 +1,89 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"path"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/offerni/weathercheck/internal/cep"
+	"github.com/offerni/weathercheck/internal/weather"
+)
+
+// MarineProvider returns the tides and swell off a coastal city.
+type MarineProvider interface {
+	Marine(ctx context.Context, city string) (*weather.Marine, error)
+}
+
+// MarineHandler serves GET /marine/{cep}: today's tides and the current
+// swell, for CEPs in one of the configured coastal municipalities.
+type MarineHandler struct {
+	cep     CEPResolver
+	marine  MarineProvider
+	coastal map[string]bool
+	tracer  oteltrace.Tracer
+	logger  *log.Logger
+}
+
+// NewMarineHandler builds the handler; coastal holds the IBGE codes of the
+// municipalities served.
+func NewMarineHandler(cep CEPResolver, marine MarineProvider, coastal map[string]bool, tracer oteltrace.Tracer, logger *log.Logger) *MarineHandler {
+	return &MarineHandler{cep: cep, marine: marine, coastal: coastal, tracer: tracer, logger: logger}
+}
+
+func (h *MarineHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "marine-handler")
+	defer span.End()
+
+	// The CEP is the last path segment
+	code := path.Base(r.URL.Path)
+	if !cep.Validate(code) {
+		writeError(w, r, http.StatusUnprocessableEntity, ErrorResponse{Message: "invalid zipcode", Code: "invalid_zipcode"})
+		return
+	}
+	span.SetAttributes(attribute.String("cep", code))
+
+	cepData, err := h.cep.Lookup(ctx, code)
+	if err != nil {
+		span.RecordError(err)
+		status, resp := cepError(err)
+		if status >= http.StatusInternalServerError {
+			Logf(ctx, h.logger, "Failed to look up CEP %s: %v", code, err)
+		}
+		writeError(w, r, status, resp)
+		return
+	}
+	if !h.coastal[cepData.IBGE] {
+		writeError(w, r, http.StatusNotFound, ErrorResponse{Message: "zipcode is not in a coastal municipality", Code: "not_coastal"})
+		return
+	}
+	city := cepData.Localidade
+
+	marine, err := h.marine.Marine(ctx, city)
+	if err != nil {
+		span.RecordError(err)
+		Logf(ctx, h.logger, "Failed to get marine data for %s: %v", city, err)
+		status, resp := weatherError(err)
+		writeError(w, r, status, resp)
+		return
+	}
+
+	resp := MarineResponse{City: city, Tides: make([]TideResponse, len(marine.Tides))}
+	for i, tide := range marine.Tides {
+		resp.Tides[i] = TideResponse{Time: tide.Time, Type: "low", HeightM: tide.HeightM}
+		if tide.High {
+			resp.Tides[i].Type = "high"
+		}
+	}
+	if s := marine.Swell; s != nil {
+		resp.Swell = &SwellResponse{HeightM: s.HeightM, PeriodSecs: s.PeriodSecs, Direction: s.Direction}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
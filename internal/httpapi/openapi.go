@@ -0,0 +1,242 @@
+package httpapi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8" />
+  <title>%s</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css" />
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+// Contract is a loaded OpenAPI document used to document and validate the
+// routes it describes.
+type Contract struct {
+	spec   []byte
+	doc    *openapi3.T
+	logger *log.Logger
+}
+
+// LoadContract parses and validates spec, exiting if it is not a valid
+// OpenAPI 3 document. Responses that drift from it are logged to logger.
+func LoadContract(spec []byte, logger *log.Logger) *Contract {
+	doc, err := openapi3.NewLoader().LoadFromData(spec)
+	if err != nil {
+		log.Fatalf("Failed to load OpenAPI spec: %v", err)
+	}
+
+	if err := doc.Validate(openapi3.NewLoader().Context); err != nil {
+		log.Fatalf("Invalid OpenAPI spec: %v", err)
+	}
+
+	return &Contract{spec: spec, doc: doc, logger: logger}
+}
+
+// SpecHandler serves the raw OpenAPI document.
+func (c *Contract) SpecHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(c.spec)
+}
+
+// DocsHandler serves a Swagger UI page rendering the OpenAPI document.
+func (c *Contract) DocsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, swaggerUIPage, html.EscapeString(c.doc.Info.Title))
+}
+
+// Validate checks requests against the spec, rejecting violations with a 422
+// carrying invalidMessage, and logs responses that drift from the contract.
+func (c *Contract) Validate(invalidMessage string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, pathParams := c.findRoute(r)
+			if route == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			input := &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: pathParams,
+				Route:      route,
+				Options: &openapi3filter.Options{
+					MultiError: true,
+					// Bodies declared in other media types are left to the handler
+					ExcludeRequestBody: !isJSONContent(r.Header.Get("Content-Type")),
+				},
+			}
+
+			span := oteltrace.SpanFromContext(r.Context())
+			if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+				span.RecordError(err)
+				WriteResponse(w, r, http.StatusUnprocessableEntity, ErrorResponse{
+					Message: invalidMessage,
+					Code:    "validation_failed",
+					Errors:  fieldErrors(err),
+				})
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			if !isJSONContent(rec.Header().Get("Content-Type")) {
+				return
+			}
+
+			responseInput := &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: input,
+				Status:                 rec.status,
+				Header:                 rec.Header(),
+			}
+			responseInput.SetBodyBytes(rec.body.Bytes())
+
+			if err := openapi3filter.ValidateResponse(r.Context(), responseInput); err != nil {
+				span.RecordError(err)
+				Logf(r.Context(), c.logger, "Response for %s %s does not match OpenAPI spec: %v", r.Method, r.URL.Path, err)
+			}
+		})
+	}
+}
+
+func (c *Contract) findRoute(r *http.Request) (*routers.Route, map[string]string) {
+	path, pathItem, pathParams := c.matchPath(r.URL.Path)
+	if pathItem == nil {
+		return nil, nil
+	}
+
+	operation := pathItem.GetOperation(r.Method)
+	if operation == nil {
+		return nil, nil
+	}
+
+	return &routers.Route{
+		Spec:      c.doc,
+		Path:      path,
+		PathItem:  pathItem,
+		Method:    r.Method,
+		Operation: operation,
+	}, pathParams
+}
+
+// matchPath finds the spec path urlPath belongs to, such as /summary/{cep}
+// for /summary/01001000, along with the values of its path parameters.
+func (c *Contract) matchPath(urlPath string) (string, *openapi3.PathItem, map[string]string) {
+	if pathItem := c.doc.Paths.Find(urlPath); pathItem != nil {
+		return urlPath, pathItem, nil
+	}
+
+	segments := strings.Split(urlPath, "/")
+	for path, pathItem := range c.doc.Paths {
+		templateSegments := strings.Split(path, "/")
+		if len(templateSegments) != len(segments) {
+			continue
+		}
+
+		params := make(map[string]string)
+		for i, seg := range templateSegments {
+			if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") && segments[i] != "" {
+				params[seg[1:len(seg)-1]] = segments[i]
+			} else if seg != segments[i] {
+				params = nil
+				break
+			}
+		}
+		if params != nil {
+			return path, pathItem, params
+		}
+	}
+	return "", nil, nil
+}
+
+// fieldErrors flattens a kin-openapi validation error into one entry per
+// offending field, keyed by JSON pointer.
+func fieldErrors(err error) []FieldError {
+	// Parameters are reported by name rather than by schema pointer
+	if paramErr, ok := err.(*openapi3filter.RequestError); ok && paramErr.Parameter != nil {
+		reason := paramErr.Reason
+		var paramSchemaErr *openapi3.SchemaError
+		if errors.As(paramErr.Err, &paramSchemaErr) {
+			reason = paramSchemaErr.Reason
+		} else if paramErr.Err != nil {
+			reason = paramErr.Err.Error()
+		}
+		return []FieldError{{Path: paramErr.Parameter.Name, Reason: reason}}
+	}
+
+	var multi openapi3.MultiError
+	if errors.As(err, &multi) {
+		var result []FieldError
+		for _, e := range multi {
+			result = append(result, fieldErrors(e)...)
+		}
+		return result
+	}
+
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		return []FieldError{{
+			Path:   "/" + strings.Join(schemaErr.JSONPointer(), "/"),
+			Reason: schemaErr.Reason,
+		}}
+	}
+
+	var requestErr *openapi3filter.RequestError
+	if errors.As(err, &requestErr) && requestErr.Err != nil {
+		return fieldErrors(requestErr.Err)
+	}
+
+	return []FieldError{{Path: "/", Reason: err.Error()}}
+}
+
+func isJSONContent(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
+
+// responseRecorder passes writes through while keeping a copy of the body
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush streamed responses.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
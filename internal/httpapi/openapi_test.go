@@ -0,0 +1,76 @@
+package httpapi
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/offerni/weathercheck/internal/privacy"
+)
+
+const summarySpec = `{
+  "openapi": "3.0.3",
+  "info": {"title": "test", "version": "1"},
+  "paths": {
+    "/summary/{cep}": {
+      "get": {
+        "parameters": [{"name": "cep", "in": "path", "required": true, "schema": {"type": "string", "pattern": "^\\d{8}$"}}],
+        "responses": {
+          "200": {
+            "description": "ok",
+            "content": {"application/json": {"schema": {
+              "type": "object",
+              "required": ["city"],
+              "properties": {"city": {"type": "string"}}
+            }}}
+          }
+        }
+      }
+    }
+  }
+}`
+
+func TestContractValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		status   int
+		wantLogs bool
+	}{
+		{"matching response", "/summary/01001000", `{"city":"São Paulo"}`, http.StatusOK, false},
+		{"drifting response", "/summary/01001000", `{"town":"São Paulo"}`, http.StatusOK, true},
+		{"invalid request", "/summary/123", `{"city":"São Paulo"}`, http.StatusUnprocessableEntity, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redactor, _ := privacy.New(privacy.Truncate, "salt")
+			var logs bytes.Buffer
+			contract := LoadContract([]byte(summarySpec), log.New(redactor.Writer(&logs), "", 0))
+			h := contract.Validate("invalid zipcode")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", FormatJSON)
+				io.WriteString(w, tt.body)
+			}))
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if got := logs.Len() > 0; got != tt.wantLogs {
+				t.Errorf("logged %q, want logs: %v", logs.String(), tt.wantLogs)
+			}
+			// The drift warning names the path, through the redacting logger
+			if strings.Contains(logs.String(), "01001000") {
+				t.Errorf("log has the raw CEP: %s", logs.String())
+			}
+			if tt.wantLogs && !strings.Contains(logs.String(), "/summary/01001***") {
+				t.Errorf("log = %q, want the redacted path", logs.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"path"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/offerni/weathercheck/internal/cache"
+	"github.com/offerni/weathercheck/internal/cep"
+	"github.com/offerni/weathercheck/internal/pollen"
+	"github.com/offerni/weathercheck/internal/weather"
+)
+
+// PollenHandler serves GET /pollen/{cep}: today's pollen index for the
+// CEP's city. Answers are reused per city for ttl, and an older one is
+// served, marked stale, when the provider fails.
+type PollenHandler struct {
+	cep     CEPResolver
+	pollen  pollen.Provider
+	answers *cache.Cache[PollenResponse]
+	ttl     time.Duration
+	tracer  oteltrace.Tracer
+	logger  *log.Logger
+}
+
+// NewPollenHandler builds the handler. answers must have no TTL of its own,
+// so expired answers stay available when the provider fails.
+func NewPollenHandler(cep CEPResolver, provider pollen.Provider, answers *cache.Cache[PollenResponse], ttl time.Duration, tracer oteltrace.Tracer, logger *log.Logger) *PollenHandler {
+	return &PollenHandler{cep: cep, pollen: provider, answers: answers, ttl: ttl, tracer: tracer, logger: logger}
+}
+
+func (h *PollenHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "pollen-handler")
+	defer span.End()
+
+	// The CEP is the last path segment
+	code := path.Base(r.URL.Path)
+	if !cep.Validate(code) {
+		writeError(w, r, http.StatusUnprocessableEntity, ErrorResponse{Message: "invalid zipcode", Code: "invalid_zipcode"})
+		return
+	}
+	span.SetAttributes(attribute.String("cep", code))
+
+	cepData, err := h.cep.Lookup(ctx, code)
+	if err != nil {
+		span.RecordError(err)
+		status, resp := cepError(err)
+		if status >= http.StatusInternalServerError {
+			Logf(ctx, h.logger, "Failed to look up CEP %s: %v", code, err)
+		}
+		writeError(w, r, status, resp)
+		return
+	}
+	city := cepData.Localidade
+
+	cached, storedAt, found := h.answers.Get(city)
+	fresh := found && time.Since(storedAt) <= h.ttl
+	span.SetAttributes(attribute.Bool("pollen.cached", fresh))
+	if fresh {
+		span.AddEvent("cache_hit", oteltrace.WithAttributes(
+			attribute.String("cache", "pollen"),
+			attribute.Int64("age_s", int64(time.Since(storedAt).Seconds())),
+		))
+	}
+
+	resp := cached
+	if !fresh {
+		index, err := h.pollen.Index(ctx, city)
+		switch {
+		case err == nil:
+			resp = PollenResponse{City: city, Types: make([]PollenType, len(index.Types)), UpdatedAt: time.Now().UTC()}
+			for i, t := range index.Types {
+				resp.Types[i] = PollenType{Code: t.Code, Value: t.Value, Category: t.Category, InSeason: t.InSeason}
+			}
+			h.answers.Set(city, resp)
+		case found:
+			span.RecordError(err)
+			Logf(ctx, h.logger, "Failed to get pollen for %s, serving the answer from %s: %v", city, storedAt.Format(time.RFC3339), err)
+			resp.Stale = true
+			span.AddEvent("cache_stale", oteltrace.WithAttributes(
+				attribute.String("cache", "pollen"),
+				attribute.Int64("age_s", int64(time.Since(storedAt).Seconds())),
+				attribute.String("reason", "pollen_unavailable"),
+			))
+		default:
+			span.RecordError(err)
+			Logf(ctx, h.logger, "Failed to get pollen for %s: %v", city, err)
+			status, errResp := pollenError(err)
+			writeError(w, r, status, errResp)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// pollenError maps a pollen provider failure to its status and error body.
+func pollenError(err error) (int, ErrorResponse) {
+	if errors.Is(err, weather.ErrLocationNotFound) {
+		return http.StatusNotFound, ErrorResponse{Message: "can not find pollen data for city", Code: "location_not_found"}
+	}
+	return http.StatusBadGateway, ErrorResponse{Message: "failed to get pollen data", Code: "pollen_unavailable"}
+}
@@ -0,0 +1,126 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/offerni/weathercheck/internal/cep"
+	"github.com/offerni/weathercheck/internal/weather"
+)
+
+const (
+	defaultRainHours = 6
+	// maxRainHours is as far as every provider's hourly forecast reaches
+	maxRainHours = 24
+)
+
+// RainHandler serves GET /rain/{cep}?hours=N: whether rain is expected in
+// the next N hours, and the first window of hours whose chance of rain
+// reaches the threshold.
+type RainHandler struct {
+	cep       CEPResolver
+	weather   WeatherProvider
+	threshold float64
+	tracer    oteltrace.Tracer
+	logger    *log.Logger
+}
+
+// NewRainHandler builds the handler; threshold is the chance of rain, in
+// percent, from which an hour counts as rainy.
+func NewRainHandler(cep CEPResolver, weather WeatherProvider, threshold float64, tracer oteltrace.Tracer, logger *log.Logger) *RainHandler {
+	return &RainHandler{cep: cep, weather: weather, threshold: threshold, tracer: tracer, logger: logger}
+}
+
+func (h *RainHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "rain-handler")
+	defer span.End()
+
+	// The CEP is the last path segment
+	code := path.Base(r.URL.Path)
+	if !cep.Validate(code) {
+		writeError(w, r, http.StatusUnprocessableEntity, ErrorResponse{Message: "invalid zipcode", Code: "invalid_zipcode"})
+		return
+	}
+	span.SetAttributes(attribute.String("cep", code))
+
+	hours := defaultRainHours
+	if v := r.URL.Query().Get("hours"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxRainHours {
+			writeError(w, r, http.StatusUnprocessableEntity, ErrorResponse{
+				Message: "invalid hours",
+				Code:    "invalid_hours",
+				Errors:  []FieldError{{Path: "hours", Reason: "must be an integer between 1 and " + strconv.Itoa(maxRainHours)}},
+			})
+			return
+		}
+		hours = n
+	}
+
+	cepData, err := h.cep.Lookup(ctx, code)
+	if err != nil {
+		span.RecordError(err)
+		status, resp := cepError(err)
+		if status >= http.StatusInternalServerError {
+			Logf(ctx, h.logger, "Failed to look up CEP %s: %v", code, err)
+		}
+		writeError(w, r, status, resp)
+		return
+	}
+	city := cepData.Localidade
+
+	current, err := h.weather.Current(ctx, city)
+	if err != nil {
+		span.RecordError(err)
+		Logf(ctx, h.logger, "Failed to get weather for %s: %v", city, err)
+		status, resp := weatherError(err)
+		writeError(w, r, status, resp)
+		return
+	}
+	if len(current.Hourly) == 0 {
+		writeError(w, r, http.StatusBadGateway, ErrorResponse{Message: "weather provider sent no hourly forecast", Code: "hourly_forecast_unavailable"})
+		return
+	}
+
+	resp := RainResponse{City: city, Hours: hours, Threshold: h.threshold}
+	resp.Window = rainWindow(current.Hourly, time.Now(), hours, h.threshold)
+	resp.WillRain = resp.Window != nil
+	span.SetAttributes(attribute.Bool("rain.expected", resp.WillRain))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// rainWindow returns the first run of hours, among the hours from the one
+// now falls in, whose chance of rain reaches threshold
+func rainWindow(hourly []weather.Hour, now time.Time, hours int, threshold float64) *RainWindow {
+	from := now.Truncate(time.Hour)
+	until := from.Add(time.Duration(hours) * time.Hour)
+
+	var window *RainWindow
+	for _, hour := range hourly {
+		if hour.Start.Before(from) || !hour.Start.Before(until) {
+			continue
+		}
+		if hour.ChanceOfRain < threshold {
+			if window != nil {
+				break
+			}
+			continue
+		}
+		if window == nil {
+			window = &RainWindow{Start: hour.Start}
+		}
+		window.End = hour.Start.Add(time.Hour)
+		window.MaxChanceOfRain = max(window.MaxChanceOfRain, hour.ChanceOfRain)
+	}
+	return window
+}
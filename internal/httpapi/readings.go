@@ -0,0 +1,64 @@
+package httpapi
+
+import (
+	"time"
+
+	"github.com/offerni/weathercheck/internal/cache"
+	"github.com/offerni/weathercheck/internal/geohash"
+	"github.com/offerni/weathercheck/internal/weather"
+)
+
+// Readings keeps the last answer per place for stale and degraded answers.
+// Once the provider has said where a city is, its answers are kept under
+// the geohash of that spot, so neighbouring cities in a metro area share
+// one entry; until then, and without a precision, under the city's name.
+// Answers are kept in celsius as the provider gave them, unrounded, and
+// expressed in each request's units and precision when served.
+type Readings struct {
+	*cache.Cache[WeatherResponse]
+	precision int
+	// cells maps city names to the geohash their readings are kept under
+	cells *cache.Cache[string]
+}
+
+// NewReadings keeps readings within opts, keyed by geohashes of precision
+// characters; a precision of 0 keys them by city alone.
+func NewReadings(opts cache.Options, precision int) *Readings {
+	// Brazil has some 5,600 municipalities, so cells take little room
+	// even without an entry bound
+	cells := cache.Options{MaxEntries: opts.MaxEntries, Policy: opts.Policy}
+	return &Readings{
+		Cache:     cache.New[WeatherResponse]("readings", opts),
+		precision: precision,
+		cells:     cache.New[string]("readings-cells", cells),
+	}
+}
+
+// Last returns the reading kept for city and when it was taken, under the
+// city's name even when it came from a neighbour.
+func (r *Readings) Last(city string) (WeatherResponse, time.Time, bool) {
+	last, storedAt, ok := r.Get(r.key(city))
+	if ok {
+		last.City = city
+	}
+	return last, storedAt, ok
+}
+
+// Store keeps resp as city's reading; at is where the provider placed the
+// city, nil if it didn't say.
+func (r *Readings) Store(city string, at *weather.Coordinates, resp WeatherResponse) {
+	if r.precision > 0 && at != nil {
+		r.cells.Set(city, geohash.Encode(at.Lat, at.Lon, r.precision))
+	}
+	r.Set(r.key(city), resp)
+}
+
+// key is the geohash city's readings are kept under, or its name
+func (r *Readings) key(city string) string {
+	if r.precision > 0 {
+		if cell, _, ok := r.cells.Get(city); ok {
+			return "geohash:" + cell
+		}
+	}
+	return city
+}
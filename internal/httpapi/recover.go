@@ -0,0 +1,72 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// ProblemContentType is the media type of Problem bodies.
+const ProblemContentType = "application/problem+json"
+
+// Recoverer turns a handler panic into a 500 Problem carrying the request's
+// trace ID, instead of a bare status, and records the panic with its stack
+// on the request span, in the log and in the http.server.panics counter. It
+// must run inside the OpenTelemetry middleware so the span exists.
+func Recoverer(logger *log.Logger) func(http.Handler) http.Handler {
+	meter := otel.Meter("github.com/offerni/weathercheck/internal/httpapi")
+	panics, _ := meter.Int64Counter("http.server.panics",
+		metric.WithDescription("Handler panics recovered into 500 responses, by method"))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				// The standard library aborts responses this way on purpose
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				err, ok := rec.(error)
+				if !ok {
+					err = fmt.Errorf("%v", rec)
+				}
+				err = fmt.Errorf("panic: %w", err)
+				stack := debug.Stack()
+
+				span := oteltrace.SpanFromContext(r.Context())
+				span.RecordError(err, oteltrace.WithAttributes(attribute.String("exception.stacktrace", string(stack))))
+				span.SetStatus(codes.Error, "panic")
+				panics.Add(r.Context(), 1, metric.WithAttributes(attribute.String("method", r.Method)))
+				logger.Printf("Recovered from %v serving %s %s trace_id=%s\n%s", err, r.Method, r.URL.Path, traceID(r.Context()), stack)
+
+				// An upgraded connection has no response left to write
+				if r.Header.Get("Connection") == "Upgrade" {
+					return
+				}
+				w.Header().Set("Content-Type", ProblemContentType)
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(Problem{
+					Type:      "about:blank",
+					Title:     http.StatusText(http.StatusInternalServerError),
+					Status:    http.StatusInternalServerError,
+					Detail:    "the server hit an unexpected error",
+					Instance:  r.URL.Path,
+					RequestID: traceID(r.Context()),
+				})
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
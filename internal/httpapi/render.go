@@ -0,0 +1,100 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Response formats supported by WriteResponse
+const (
+	FormatJSON    = "application/json"
+	FormatXML     = "application/xml"
+	FormatMsgPack = "application/msgpack"
+)
+
+// Media types accepted in the Accept header, mapped to the format we render
+var acceptedMediaTypes = map[string]string{
+	"application/json":        FormatJSON,
+	"application/xml":         FormatXML,
+	"text/xml":                FormatXML,
+	"application/msgpack":     FormatMsgPack,
+	"application/x-msgpack":   FormatMsgPack,
+	"application/vnd.msgpack": FormatMsgPack,
+	"*/*":                     FormatJSON,
+	"application/*":           FormatJSON,
+}
+
+// NegotiateFormat picks the response format with the highest q-value from
+// the Accept header, falling back to JSON.
+func NegotiateFormat(r *http.Request) string {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return FormatJSON
+	}
+
+	best, bestQ := FormatJSON, -1.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		format, ok := acceptedMediaTypes[mediaType]
+		if !ok {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+
+		if q > 0 && q > bestQ {
+			best, bestQ = format, q
+		}
+	}
+
+	return best
+}
+
+// WriteResponse encodes v in the format negotiated from the request. Error
+// responses are stamped with the request's trace ID.
+func WriteResponse(w http.ResponseWriter, r *http.Request, status int, v any) {
+	format := NegotiateFormat(r)
+	if resp, ok := v.(ErrorResponse); ok && resp.TraceID == "" {
+		resp.TraceID = traceID(r.Context())
+		v = resp
+	}
+
+	w.Header().Set("Content-Type", format)
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(status)
+
+	Encode(w, format, v)
+}
+
+// Encode writes v to w in one of the supported response formats.
+func Encode(w io.Writer, format string, v any) error {
+	switch format {
+	case FormatXML:
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return err
+		}
+		return xml.NewEncoder(w).Encode(v)
+	case FormatMsgPack:
+		enc := msgpack.NewEncoder(w)
+		enc.SetCustomStructTag("json")
+		return enc.Encode(v)
+	default:
+		return json.NewEncoder(w).Encode(v)
+	}
+}
@@ -0,0 +1,110 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", FormatJSON},
+		{"application/json", FormatJSON},
+		{"application/xml", FormatXML},
+		{"text/xml", FormatXML},
+		{"application/x-msgpack", FormatMsgPack},
+		{"*/*", FormatJSON},
+		{"text/html", FormatJSON},
+		{"application/xml;q=0.5, application/msgpack;q=0.9", FormatMsgPack},
+		{"application/json;q=0.1, application/xml", FormatXML},
+		{"application/xml;q=0, text/html", FormatJSON},
+		{"application/xml;q=bogus", FormatXML},
+		{"garbage;;;, application/xml", FormatXML},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", tt.accept)
+		if got := NegotiateFormat(r); got != tt.want {
+			t.Errorf("NegotiateFormat(%q) = %s, want %s", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tempF := 83.3
+	v := WeatherResponse{City: "São Paulo", Temperatures: Temperatures{TempC: 28.5, TempF: &tempF}, Condition: "rain", Icon: "cloud-rain"}
+	decoders := map[string]func([]byte, any) error{
+		FormatJSON: json.Unmarshal,
+		FormatXML: func(b []byte, v any) error {
+			if !bytes.HasPrefix(b, []byte(xml.Header)) {
+				return errors.New("missing XML declaration")
+			}
+			return xml.Unmarshal(b, v)
+		},
+		FormatMsgPack: func(b []byte, v any) error {
+			dec := msgpack.NewDecoder(bytes.NewReader(b))
+			dec.SetCustomStructTag("json")
+			return dec.Decode(v)
+		},
+	}
+	for format, decode := range decoders {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, format, v); err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			var got WeatherResponse
+			if err := decode(buf.Bytes(), &got); err != nil {
+				t.Fatalf("decode %q: %v", buf.String(), err)
+			}
+			if got.City != v.City || got.TempC != v.TempC || got.TempF == nil || *got.TempF != tempF || got.TempK != nil || got.Icon != v.Icon {
+				t.Errorf("round trip = %+v, want %+v", got, v)
+			}
+		})
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	tempF, tempK := 83.3, 301.65
+	v := WeatherResponse{City: "São Paulo", Temperatures: Temperatures{TempC: 28.5, TempF: &tempF, TempK: &tempK}, Condition: "rain", Icon: "cloud-rain"}
+	for _, format := range []string{FormatJSON, FormatXML, FormatMsgPack} {
+		b.Run(format, func(b *testing.B) {
+			b.ReportAllocs()
+			var buf bytes.Buffer
+			for i := 0; i < b.N; i++ {
+				buf.Reset()
+				if err := Encode(&buf, format, v); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.SetBytes(int64(buf.Len()))
+		})
+	}
+}
+
+func TestWriteResponse(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/weather", nil)
+	r.Header.Set("Accept", FormatXML)
+	rec := httptest.NewRecorder()
+	WriteResponse(rec, r, http.StatusNotFound, ErrorResponse{Message: "can not find zipcode", Code: "zipcode_not_found"})
+
+	if rec.Code != http.StatusNotFound || rec.Header().Get("Content-Type") != FormatXML {
+		t.Errorf("answer = %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Header().Get("Vary"), "Accept") {
+		t.Errorf("Vary = %q, want Accept", rec.Header().Get("Vary"))
+	}
+	if !strings.Contains(rec.Body.String(), "<code>zipcode_not_found</code>") {
+		t.Errorf("body = %s", rec.Body)
+	}
+}
@@ -0,0 +1,44 @@
+package httpapi
+
+import (
+	"net/http"
+	"time"
+
+	"golang.org/x/net/trace"
+)
+
+// RequestsPath is where RequestsHandler is mounted.
+const RequestsPath = "/debug/requests"
+
+// RequestTrace keeps each request in the in-process view served by
+// RequestsHandler, under family, with its stage timings and outcome, for
+// triage when the tracing backend is down too. Paths go through redact
+// before they are kept.
+func RequestTrace(family string, redact func(string) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tr := trace.New(family, r.Method+" "+redact(r.URL.Path))
+			defer tr.Finish()
+			if id := traceID(r.Context()); id != "" {
+				tr.LazyPrintf("trace %s", id)
+			}
+
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r.WithContext(trace.NewContext(r.Context(), tr)))
+			tr.LazyPrintf("%d in %s", sw.status, time.Since(start))
+			if sw.status >= http.StatusInternalServerError {
+				tr.SetError()
+			}
+		})
+	}
+}
+
+// RequestsHandler serves the recent and active requests kept by
+// RequestTrace, the slowest and failed ones included. It shows them to
+// anyone who reaches it, so it belongs behind the admin token.
+func RequestsHandler() http.Handler {
+	// The default only lets localhost in
+	trace.AuthRequest = func(*http.Request) (any, sensitive bool) { return true, true }
+	return http.HandlerFunc(trace.Traces)
+}
@@ -0,0 +1,219 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/offerni/weathercheck/internal/cache"
+	"github.com/offerni/weathercheck/internal/temperature"
+)
+
+// CacheHeader tells clients whether service A answered from its cache.
+const CacheHeader = "X-Cache"
+
+// CachedResponse is a successful service B answer kept by ResponseCache.
+type CachedResponse struct {
+	header http.Header
+	body   []byte
+}
+
+// Size is roughly how many bytes the response takes.
+func (r CachedResponse) Size() int {
+	n := len(r.body)
+	for name, values := range r.header {
+		n += len(name)
+		for _, v := range values {
+			n += len(v)
+		}
+	}
+	return n
+}
+
+// ResponseCache answers repeated weather lookups without calling service B.
+// Entries are keyed by the CEP or IBGE code, the normalized requested units
+// and precision and the negotiated format; only fresh 200 responses are
+// stored.
+type ResponseCache struct {
+	next     http.Handler
+	entries  *cache.Cache[CachedResponse]
+	requests metric.Int64Counter
+}
+
+// NewResponseCache serves POST /weather bodies from entries, passing misses
+// on to next.
+func NewResponseCache(next http.Handler, entries *cache.Cache[CachedResponse]) *ResponseCache {
+	meter := otel.Meter("github.com/offerni/weathercheck/internal/httpapi")
+	requests, _ := meter.Int64Counter("response_cache.requests",
+		metric.WithDescription("Weather lookups seen by service A's response cache, by result (hit, miss or bypass)"))
+
+	return &ResponseCache{next: next, entries: entries, requests: requests}
+}
+
+func (c *ResponseCache) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	span := oteltrace.SpanFromContext(r.Context())
+
+	key, ok, err := requestKey(r)
+	if err != nil {
+		span.RecordError(err)
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	if !ok {
+		c.observe(r, span, "bypass")
+		c.next.ServeHTTP(w, r)
+		return
+	}
+
+	if cached, storedAt, ok := c.entries.Get(key); ok {
+		c.observe(r, span, "hit")
+		span.AddEvent("cache_hit", oteltrace.WithAttributes(
+			attribute.String("cache", "responses"),
+			attribute.Int64("age_s", int64(time.Since(storedAt).Seconds())),
+		))
+		for name, values := range cached.header {
+			w.Header()[name] = values
+		}
+		w.Header().Set(CacheHeader, "HIT")
+		w.Header().Set("Age", strconv.Itoa(int(time.Since(storedAt).Seconds())))
+		w.WriteHeader(http.StatusOK)
+		w.Write(cached.body)
+		return
+	}
+
+	c.observe(r, span, "miss")
+	w.Header().Set(CacheHeader, "MISS")
+	rec := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+	c.next.ServeHTTP(rec, r)
+	// Stale answers would outlive the reason they were given for
+	if rec.status == http.StatusOK && w.Header().Get(DegradedReasonHeader) == "" {
+		c.entries.Set(key, CachedResponse{header: replayHeader(w.Header()), body: rec.body.Bytes()})
+	}
+}
+
+func (c *ResponseCache) observe(r *http.Request, span oteltrace.Span, result string) {
+	span.SetAttributes(attribute.String("response_cache.result", result))
+	c.requests.Add(r.Context(), 1, metric.WithAttributes(attribute.String("result", result)))
+}
+
+// requestKey reads r's body, leaving it in place for the next handler, and
+// returns its cacheKey
+func requestKey(r *http.Request) (string, bool, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", false, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	key, ok := cacheKey(r, body)
+	return key, ok, nil
+}
+
+// replayHeader copies the response headers worth handing to another client;
+// per-request ones are left out. So are the ones the compress middleware set
+// for the first client: the recorded body is the uncompressed one, and the
+// middleware compresses it again for clients that ask.
+func replayHeader(h http.Header) http.Header {
+	header := h.Clone()
+	header.Del("Date")
+	header.Del(CacheHeader)
+	header.Del(TraceIDHeader)
+	header.Del("Content-Encoding")
+	header.Del("Content-Length")
+
+	var vary []string
+	for _, v := range header.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" && !strings.EqualFold(name, "Accept-Encoding") {
+				vary = append(vary, name)
+			}
+		}
+	}
+	header.Del("Vary")
+	if len(vary) > 0 {
+		header.Set("Vary", strings.Join(vary, ", "))
+	}
+	return header
+}
+
+// cacheKey identifies the answer r would get. Requests whose answer can
+// differ for the same location (degraded answers, the canary, unknown
+// options) or that service B will reject are not cached.
+func cacheKey(r *http.Request, body []byte) (string, bool) {
+	if strings.EqualFold(r.Header.Get(CanaryHeader), "true") {
+		return "", false
+	}
+	query := r.URL.Query()
+	if query.Get("degraded") == "true" {
+		return "", false
+	}
+	for name := range query {
+		if name != "units" && name != "precision" && name != "degraded" {
+			return "", false
+		}
+	}
+
+	// -1 stands for service B's default precision
+	precision, units, errResp := temperatureOptions(r, -1)
+	if errResp != nil {
+		return "", false
+	}
+	symbols := make([]string, len(units))
+	for i, u := range units {
+		symbols[i] = string(u)
+	}
+	// The response lists units in a fixed order, whatever the query's
+	slices.Sort(symbols)
+	if len(symbols) == 0 {
+		symbols = []string{string(temperature.Celsius)}
+	}
+
+	var req CEPRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return "", false
+	}
+	location := "cep:" + req.CEP
+	if req.IBGE != "" {
+		location = "ibge:" + req.IBGE
+	}
+
+	return strings.Join([]string{location, strings.Join(symbols, ","), strconv.Itoa(precision), NegotiateFormat(r)}, "|"), true
+}
+
+// ForgetCEP drops every cached answer for cep from responses, whatever its
+// units, precision or format, returning how many went.
+func ForgetCEP(responses *cache.Cache[CachedResponse], cep string) int {
+	prefix := "cep:" + cep + "|"
+	return responses.DeleteFunc(func(key string) bool { return strings.HasPrefix(key, prefix) })
+}
+
+// recordingWriter keeps a copy of the status and body written through it
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets the reverse proxy flush through to the client
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,156 @@
+package httpapi
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/offerni/weathercheck/internal/cache"
+)
+
+const weatherBody = `{"city":"São Paulo","temp_C":21}` + "\n"
+
+// jsonUpstream stands in for service B, answering every lookup with body
+func jsonUpstream(body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", FormatJSON)
+		w.Header().Add("Vary", "Accept")
+		io.WriteString(w, body)
+	})
+}
+
+func weatherRequest(acceptEncoding string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/weather", strings.NewReader(`{"cep":"01001000"}`))
+	r.Header.Set("Content-Type", FormatJSON)
+	if acceptEncoding != "" {
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	return r
+}
+
+// readBody returns the response body, gunzipped when it says it's gzip
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	body := resp.Body
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			t.Fatalf("gzip body: %v", err)
+		}
+		body = gz
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	return string(b)
+}
+
+func TestResponseCacheReplaysAcrossEncodings(t *testing.T) {
+	tests := []struct {
+		name         string
+		first, again string
+	}{
+		{"gzip then plain", "gzip", ""},
+		{"plain then gzip", "", "gzip"},
+		{"gzip then gzip", "gzip", "gzip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := cache.New[CachedResponse]("test", cache.Options{MaxEntries: 10})
+			h := middleware.Compress(5, FormatJSON)(NewResponseCache(jsonUpstream(weatherBody), entries))
+
+			miss := httptest.NewRecorder()
+			h.ServeHTTP(miss, weatherRequest(tt.first))
+			if got := miss.Header().Get(CacheHeader); got != "MISS" {
+				t.Fatalf("first %s = %q, want MISS", CacheHeader, got)
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, weatherRequest(tt.again))
+			resp := rec.Result()
+			if got := resp.Header.Get(CacheHeader); got != "HIT" {
+				t.Fatalf("second %s = %q, want HIT", CacheHeader, got)
+			}
+			wantEncoding := tt.again
+			if got := resp.Header.Get("Content-Encoding"); got != wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, wantEncoding)
+			}
+			if got := readBody(t, resp); got != weatherBody {
+				t.Errorf("body = %q, want %q", got, weatherBody)
+			}
+		})
+	}
+}
+
+func TestReplayHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   http.Header
+		wantVary string
+	}{
+		{
+			name:     "compressed",
+			header:   http.Header{"Content-Encoding": {"gzip"}, "Vary": {"Accept", "Accept-Encoding"}},
+			wantVary: "Accept",
+		},
+		{
+			name:     "joined vary",
+			header:   http.Header{"Vary": {"Accept-Encoding, Accept"}},
+			wantVary: "Accept",
+		},
+		{
+			name:     "only accept-encoding",
+			header:   http.Header{"Content-Encoding": {"gzip"}, "Vary": {"accept-encoding"}},
+			wantVary: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.header.Set("Content-Length", "42")
+			tt.header.Set(CacheHeader, "MISS")
+			tt.header.Set(TraceIDHeader, "abc")
+			got := replayHeader(tt.header)
+			for _, name := range []string{"Content-Encoding", "Content-Length", CacheHeader, TraceIDHeader} {
+				if v := got.Get(name); v != "" {
+					t.Errorf("%s = %q, want it dropped", name, v)
+				}
+			}
+			if v := got.Get("Vary"); v != tt.wantVary {
+				t.Errorf("Vary = %q, want %q", v, tt.wantVary)
+			}
+		})
+	}
+}
+
+func TestForgetCEP(t *testing.T) {
+	entries := cache.New[CachedResponse]("test", cache.Options{MaxEntries: 10})
+	h := NewResponseCache(jsonUpstream(weatherBody), entries)
+	lookup := func(cep, query string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/weather"+query, strings.NewReader(`{"cep":"`+cep+`"}`))
+		r.Header.Set("Content-Type", FormatJSON)
+		return r
+	}
+	h.ServeHTTP(httptest.NewRecorder(), lookup("01001000", ""))
+	h.ServeHTTP(httptest.NewRecorder(), lookup("01001000", "?units=c,f"))
+	h.ServeHTTP(httptest.NewRecorder(), lookup("13015904", ""))
+
+	if n := ForgetCEP(entries, "01001000"); n != 2 {
+		t.Errorf("ForgetCEP() = %d, want both answers for the CEP", n)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, lookup("01001000", ""))
+	if got := rec.Header().Get(CacheHeader); got != "MISS" {
+		t.Errorf("forgotten CEP: %s = %q, want MISS", CacheHeader, got)
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, lookup("13015904", ""))
+	if got := rec.Header().Get(CacheHeader); got != "HIT" {
+		t.Errorf("other CEP: %s = %q, want HIT", CacheHeader, got)
+	}
+}
@@ -0,0 +1,54 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/offerni/weathercheck/internal/cep"
+	"github.com/offerni/weathercheck/internal/heatrisk"
+)
+
+// RiskHandler serves GET /risk/{cep}: the latest heat risk assessment of a
+// monitored CEP. It never calls the providers itself; the monitor does, on
+// its own schedule.
+type RiskHandler struct {
+	monitor *heatrisk.Monitor
+	tracer  oteltrace.Tracer
+}
+
+func NewRiskHandler(monitor *heatrisk.Monitor, tracer oteltrace.Tracer) *RiskHandler {
+	return &RiskHandler{monitor: monitor, tracer: tracer}
+}
+
+func (h *RiskHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_, span := h.tracer.Start(r.Context(), "risk-handler")
+	defer span.End()
+
+	// The CEP is the last path segment
+	code := path.Base(r.URL.Path)
+	if !cep.Validate(code) {
+		writeError(w, r, http.StatusUnprocessableEntity, ErrorResponse{Message: "invalid zipcode", Code: "invalid_zipcode"})
+		return
+	}
+	span.SetAttributes(attribute.String("cep", code))
+
+	if !h.monitor.Monitors(code) {
+		writeError(w, r, http.StatusNotFound, ErrorResponse{Message: "zipcode is not monitored for heat risk", Code: "risk_not_monitored"})
+		return
+	}
+	assessment, ok := h.monitor.Get(code)
+	if !ok {
+		w.Header().Set("Retry-After", "60")
+		writeError(w, r, http.StatusServiceUnavailable, ErrorResponse{Message: "heat risk not assessed yet", Code: "risk_unavailable"})
+		return
+	}
+	span.SetAttributes(attribute.String("risk.level", string(assessment.Level)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(assessment)
+}
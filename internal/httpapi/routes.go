@@ -0,0 +1,57 @@
+package httpapi
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Route groups a deployment can turn off to expose less
+const (
+	// RoutesForecast is GET /rain, /marine, /pollen and /risk
+	RoutesForecast = "forecast"
+	// RoutesHistory is GET /compare, which compares with yesterday
+	RoutesHistory = "history"
+	// RoutesAsync is POST /weather/async
+	RoutesAsync = "async"
+	// RoutesIntegrations is the SMS, Slack and voice assistant webhooks
+	RoutesIntegrations = "integrations"
+	// RoutesDocs is /openapi.json, /docs and /ui
+	RoutesDocs = "docs"
+	// RoutesAdmin is everything under /admin
+	RoutesAdmin = "admin"
+)
+
+var routeGroups = []string{RoutesForecast, RoutesHistory, RoutesAsync, RoutesIntegrations, RoutesDocs, RoutesAdmin}
+
+// Routes holds the route groups turned off.
+type Routes map[string]bool
+
+// ParseRoutes parses a comma-separated list of route groups to turn off,
+// e.g. "history,admin".
+func ParseRoutes(disabled string) (Routes, error) {
+	routes := Routes{}
+	if disabled == "" {
+		return routes, nil
+	}
+	for _, group := range strings.Split(disabled, ",") {
+		group = strings.TrimSpace(group)
+		if !slices.Contains(routeGroups, group) {
+			return nil, fmt.Errorf("unknown route group %q (expected %s)", group, strings.Join(routeGroups, ", "))
+		}
+		routes[group] = true
+	}
+	return routes, nil
+}
+
+// Enabled reports whether group's routes are served.
+func (r Routes) Enabled(group string) bool {
+	return !r[group]
+}
+
+// NotFound answers requests for routes that don't exist or are turned off,
+// alike, so a disabled group can't be told from one that was never there.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	WriteResponse(w, r, http.StatusNotFound, ErrorResponse{Message: "not found", Code: "not_found"})
+}
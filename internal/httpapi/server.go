@@ -0,0 +1,197 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"github.com/offerni/weathercheck/internal/handoff"
+)
+
+// ServerConfig describes a service listener. When both TLS files are set the
+// listener serves HTTPS with HTTP/2 negotiated via ALPN; otherwise it serves
+// plain HTTP/1.1 and cleartext HTTP/2 (h2c) on the same port.
+type ServerConfig struct {
+	Addr        string
+	TLSCertFile string
+	TLSKeyFile  string
+	// AdminAddr, a host:port or unix:/path, serves the admin and metrics
+	// endpoints on their own plain HTTP listener instead of Addr
+	AdminAddr string
+	// Upgrades hands the listeners to a new copy of the binary on SIGHUP and
+	// drains this one, to upgrade without dropping connections
+	Upgrades bool
+}
+
+// After a handoff, connections already accepted get handoffGrace to send
+// their request, since Shutdown would drop them unanswered, and requests in
+// flight get drainTimeout to finish
+const (
+	handoffGrace = time.Second
+	drainTimeout = 30 * time.Second
+)
+
+// listening is a server with the listener it serves on
+type listening struct {
+	server   *http.Server
+	ln       net.Listener
+	certFile string
+	keyFile  string
+}
+
+func (l *listening) serve() error {
+	if l.certFile != "" {
+		return l.server.ServeTLS(l.ln, l.certFile, l.keyFile)
+	}
+	return l.server.Serve(l.ln)
+}
+
+// ListenAndServe runs handler on the configured listener, and admin on
+// AdminAddr when set, until one fails, or returns nil once they have been
+// handed over to a new process and drained. The listeners are the ones
+// systemd or the previous process passed, if any.
+func ListenAndServe(cfg ServerConfig, handler, admin http.Handler, logger *log.Logger) error {
+	ln, err := handoff.Listen("http", "tcp", cfg.Addr)
+	if err != nil {
+		return err
+	}
+	public := &listening{server: &http.Server{Handler: handler}, ln: ln}
+	if cfg.TLSCertFile != "" && cfg.TLSKeyFile != "" {
+		public.certFile, public.keyFile = cfg.TLSCertFile, cfg.TLSKeyFile
+	} else {
+		h2s := &http2.Server{}
+		// Lets Shutdown tell h2c connections to go away too
+		if err := http2.ConfigureServer(public.server, h2s); err != nil {
+			return err
+		}
+		public.server.Handler = h2c.NewHandler(handler, h2s)
+	}
+	servers := []*listening{public}
+
+	if cfg.AdminAddr != "" {
+		network, addr := "tcp", cfg.AdminAddr
+		if path, ok := strings.CutPrefix(cfg.AdminAddr, "unix:"); ok {
+			network, addr = "unix", path
+		}
+		ln, err := handoff.Listen("admin", network, addr)
+		if err != nil {
+			return fmt.Errorf("admin listener: %w", err)
+		}
+		servers = append(servers, &listening{server: &http.Server{Handler: admin}, ln: ln})
+	}
+
+	drained := make(chan struct{})
+	if cfg.Upgrades {
+		for _, s := range servers {
+			s.ln = &handedListener{Listener: s.ln, closed: make(chan struct{})}
+		}
+		go upgradeOnHangup(servers, drained, logger)
+	}
+	if err := handoff.Ready(); err != nil {
+		return err
+	}
+
+	errs := make(chan error, len(servers))
+	for _, s := range servers {
+		go func(s *listening) { errs <- s.serve() }(s)
+	}
+	for range servers {
+		if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+	}
+	<-drained
+	return nil
+}
+
+// upgradeOnHangup starts a new process on the servers' listeners at each
+// SIGHUP until one is ready, then stops the servers, closing drained once
+// their requests are done
+func upgradeOnHangup(servers []*listening, drained chan<- struct{}, logger *log.Logger) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	for range hangup {
+		logger.Printf("Upgrading: starting new process")
+		if err := handoff.Upgrade(drainTimeout); err != nil {
+			logger.Printf("Upgrade failed, still serving: %v", err)
+			continue
+		}
+		break
+	}
+	signal.Stop(hangup)
+
+	logger.Printf("Upgrade handed over, draining")
+	for _, s := range servers {
+		s.ln.(*handedListener).stopAccepting()
+	}
+	time.Sleep(handoffGrace)
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	for _, s := range servers {
+		if err := s.server.Shutdown(ctx); err != nil {
+			logger.Printf("Drain cut short: %v", err)
+		}
+	}
+	close(drained)
+}
+
+// handedListener leaves new connections to the process it was handed to
+// once stopAccepting is called, while Serve keeps waiting on it until
+// Shutdown closes it
+type handedListener struct {
+	net.Listener
+	stopped atomic.Bool
+	once    sync.Once
+	closed  chan struct{}
+}
+
+// stopAccepting closes this process's copy of the socket; the new process
+// keeps its own
+func (l *handedListener) stopAccepting() {
+	l.stopped.Store(true)
+	l.Listener.Close()
+}
+
+func (l *handedListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil && l.stopped.Load() {
+		<-l.closed
+		return nil, net.ErrClosed
+	}
+	return conn, err
+}
+
+func (l *handedListener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	if l.stopped.Load() {
+		return nil
+	}
+	return l.Listener.Close()
+}
+
+// NewH2CTransport returns a transport that speaks HTTP/2 with prior
+// knowledge over plain TCP, multiplexing requests to an h2c server over a
+// single connection per host.
+func NewH2CTransport() http.RoundTripper {
+	var dialer net.Dialer
+	return &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		},
+	}
+}
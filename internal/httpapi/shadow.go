@@ -0,0 +1,136 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+const shadowTimeout = 5 * time.Second
+
+// ShadowHandler serves requests from its primary handler and mirrors a share
+// of them to a secondary service B, logging any difference in the answers.
+// The shadow response is never returned to the caller.
+type ShadowHandler struct {
+	primary    http.Handler
+	target     *url.URL
+	percent    float64
+	httpClient *http.Client
+	logger     *log.Logger
+}
+
+// NewShadowHandler mirrors percent (0-100) of requests to target.
+func NewShadowHandler(primary http.Handler, target *url.URL, percent float64, httpClient *http.Client, logger *log.Logger) *ShadowHandler {
+	return &ShadowHandler{primary: primary, target: target, percent: percent, httpClient: httpClient, logger: logger}
+}
+
+func (h *ShadowHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if rand.Float64()*100 >= h.percent {
+		h.primary.ServeHTTP(w, r)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	rec := &responseRecorder{ResponseWriter: w}
+	h.primary.ServeHTTP(rec, r)
+
+	status := rec.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	primaryJSON := isJSONContent(rec.Header().Get("Content-Type"))
+
+	// Keep the trace but not the cancellation of the finished request
+	ctx := context.WithoutCancel(r.Context())
+	go h.compare(ctx, body, status, primaryJSON, rec.body.Bytes())
+}
+
+func (h *ShadowHandler) compare(ctx context.Context, reqBody []byte, status int, primaryJSON bool, primaryBody []byte) {
+	ctx, cancel := context.WithTimeout(ctx, shadowTimeout)
+	defer cancel()
+
+	target := *h.target
+	target.Path = strings.TrimRight(target.Path, "/") + serviceBPath
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.String(), bytes.NewReader(reqBody))
+	if err != nil {
+		Logf(ctx, h.logger, "Shadow request failed: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		Logf(ctx, h.logger, "Shadow request to %s failed: %v", h.target.Host, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	shadowBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		Logf(ctx, h.logger, "Shadow response from %s unreadable: %v", h.target.Host, err)
+		return
+	}
+
+	var diffs []string
+	if resp.StatusCode != status {
+		diffs = append(diffs, fmt.Sprintf("status: %d != %d", status, resp.StatusCode))
+	}
+	// Only JSON answers can be compared field by field; other formats were
+	// re-encoded by service A and would always differ
+	if primaryJSON {
+		diffs = append(diffs, diffJSON(primaryBody, shadowBody)...)
+	}
+
+	if len(diffs) > 0 {
+		Logf(ctx, h.logger, "Shadow mismatch for %s: %s", reqBody, strings.Join(diffs, "; "))
+	}
+}
+
+// diffJSON compares two top-level JSON objects field by field.
+func diffJSON(primary, shadow []byte) []string {
+	var a, b map[string]any
+	if err := json.Unmarshal(primary, &a); err != nil {
+		return []string{"primary body is not a JSON object"}
+	}
+	if err := json.Unmarshal(shadow, &b); err != nil {
+		return []string{"shadow body is not a JSON object"}
+	}
+
+	keys := make(map[string]struct{}, len(a)+len(b))
+	for k := range a {
+		keys[k] = struct{}{}
+	}
+	for k := range b {
+		keys[k] = struct{}{}
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var diffs []string
+	for _, k := range sorted {
+		if !reflect.DeepEqual(a[k], b[k]) {
+			diffs = append(diffs, fmt.Sprintf("%s: %v != %v", k, a[k], b[k]))
+		}
+	}
+	return diffs
+}
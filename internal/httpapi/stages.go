@@ -0,0 +1,119 @@
+package httpapi
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/trace"
+)
+
+// Stages of a weather lookup, as reported on the stage histogram
+const (
+	stageParsing       = "parsing"
+	stageValidation    = "validation"
+	stageCEPLookup     = "cep_lookup"
+	stageWeatherFetch  = "weather_fetch"
+	stageSerialization = "serialization"
+)
+
+// stageBuckets spans fast in-process work up to slow upstream calls, in seconds
+var stageBuckets = []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// stageTimer records how long each stage of a request took, on a histogram,
+// as an event on the request's span and in its RequestTrace entry.
+type stageTimer struct {
+	duration metric.Float64Histogram
+}
+
+func newStageTimer() stageTimer {
+	meter := otel.Meter("github.com/offerni/weathercheck/internal/httpapi")
+	duration, _ := meter.Float64Histogram("weather.stage.duration",
+		metric.WithDescription("Time spent in each stage of a weather lookup, by stage and outcome"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(stageBuckets...))
+	return stageTimer{duration: duration}
+}
+
+// begin marks stage as started and returns its start time.
+func (t stageTimer) begin(ctx context.Context, stage string) time.Time {
+	start := time.Now()
+	if l, ok := ctx.Value(stageLogKey{}).(*stageLog); ok {
+		l.begin(stage, start)
+	}
+	if tr, ok := trace.FromContext(ctx); ok {
+		tr.LazyPrintf("%s started", stage)
+	}
+	return start
+}
+
+// observe records stage as having run from start until now.
+func (t stageTimer) observe(ctx context.Context, stage string, start time.Time, failed bool) {
+	elapsed := time.Since(start)
+	outcome := "ok"
+	if failed {
+		outcome = "error"
+	}
+	if l, ok := ctx.Value(stageLogKey{}).(*stageLog); ok {
+		l.end(stage, elapsed, outcome)
+	}
+	if tr, ok := trace.FromContext(ctx); ok {
+		tr.LazyPrintf("%s %s in %s", stage, outcome, elapsed)
+	}
+
+	t.duration.Record(ctx, elapsed.Seconds(), metric.WithAttributes(
+		attribute.String("stage", stage),
+		attribute.String("outcome", outcome),
+	))
+	oteltrace.SpanFromContext(ctx).AddEvent(stage, oteltrace.WithAttributes(
+		attribute.Float64("duration_ms", milliseconds(elapsed)),
+		attribute.String("outcome", outcome),
+	))
+}
+
+type stageLogKey struct{}
+
+// stageLog mirrors the stage events of a request's span, which can't be
+// read back while the span is open, so Timeout can tell which stage was
+// running when the request's budget ran out and AccessLog can log them
+type stageLog struct {
+	mu      sync.Mutex
+	stages  []StageTiming
+	running string
+	started time.Time
+}
+
+func (l *stageLog) begin(stage string, start time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.running, l.started = stage, start
+}
+
+func (l *stageLog) end(stage string, elapsed time.Duration, outcome string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stages = append(l.stages, StageTiming{Name: stage, DurationMS: milliseconds(elapsed), Outcome: outcome})
+	if l.running == stage {
+		l.running = ""
+	}
+}
+
+// cut returns the stage still running and every stage's timing so far, the
+// running one included as timed out
+func (l *stageLog) cut() (string, []StageTiming) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stages := append([]StageTiming(nil), l.stages...)
+	if l.running != "" {
+		stages = append(stages, StageTiming{Name: l.running, DurationMS: milliseconds(time.Since(l.started)), Outcome: "timeout"})
+	}
+	return l.running, stages
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,105 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/offerni/weathercheck/internal/cep"
+	"github.com/offerni/weathercheck/internal/summary"
+)
+
+// SummaryHandler serves GET /summary/{cep}, and GET /summary?city= through
+// ServeCity: the weather as a short sentence in the language picked by
+// ?lang= or Accept-Language.
+type SummaryHandler struct {
+	cep     CEPResolver
+	weather WeatherProvider
+	tracer  oteltrace.Tracer
+	logger  *log.Logger
+}
+
+func NewSummaryHandler(cep CEPResolver, weather WeatherProvider, tracer oteltrace.Tracer, logger *log.Logger) *SummaryHandler {
+	return &SummaryHandler{cep: cep, weather: weather, tracer: tracer, logger: logger}
+}
+
+func (h *SummaryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "summary-handler")
+	defer span.End()
+
+	// The CEP is the last path segment
+	code := path.Base(r.URL.Path)
+	if !cep.Validate(code) {
+		writeError(w, r, http.StatusUnprocessableEntity, ErrorResponse{Message: "invalid zipcode", Code: "invalid_zipcode"})
+		return
+	}
+	span.SetAttributes(attribute.String("cep", code))
+
+	cepData, err := h.cep.Lookup(ctx, code)
+	if err != nil {
+		span.RecordError(err)
+		status, resp := cepError(err)
+		if status >= http.StatusInternalServerError {
+			Logf(ctx, h.logger, "Failed to look up CEP %s: %v", code, err)
+		}
+		writeError(w, r, status, resp)
+		return
+	}
+
+	h.respond(w, r.WithContext(ctx), cepData.Localidade)
+}
+
+// ServeCity serves GET /summary?city=, for callers that know the city but
+// not a CEP.
+func (h *SummaryHandler) ServeCity(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "summary-handler")
+	defer span.End()
+
+	city := strings.TrimSpace(r.URL.Query().Get("city"))
+	if city == "" {
+		writeError(w, r, http.StatusUnprocessableEntity, ErrorResponse{Message: "invalid city", Code: "invalid_city"})
+		return
+	}
+
+	h.respond(w, r.WithContext(ctx), city)
+}
+
+// respond looks up the weather for city and writes its summary
+func (h *SummaryHandler) respond(w http.ResponseWriter, r *http.Request, city string) {
+	ctx := r.Context()
+	span := oteltrace.SpanFromContext(ctx)
+
+	lang := r.URL.Query().Get("lang")
+	if lang == "" {
+		lang = r.Header.Get("Accept-Language")
+	}
+	tag := summary.Match(lang)
+
+	weatherData, err := h.weather.Current(ctx, city)
+	if err != nil {
+		span.RecordError(err)
+		Logf(ctx, h.logger, "Failed to get weather for %s: %v", city, err)
+		status, resp := weatherError(err)
+		writeError(w, r, status, resp)
+		return
+	}
+
+	text, err := summary.Text(tag, city, weatherData)
+	if err != nil {
+		span.RecordError(err)
+		writeError(w, r, http.StatusInternalServerError, ErrorResponse{Message: "failed to summarize weather", Code: "summary_failed"})
+		return
+	}
+
+	span.SetAttributes(attribute.String("summary.lang", tag.String()))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Language", tag.String())
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(SummaryResponse{City: city, Language: tag.String(), Summary: text})
+}
@@ -0,0 +1,116 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// Timeout gives each request budget to answer. Past it the request's
+// context is canceled, stopping the upstream calls still in flight, and the
+// client gets a 504 Problem naming the stage that was running, cep_lookup or
+// weather_fetch, and how long each stage took; timeouts are counted in
+// http.server.timeouts by stage. A budget of 0 turns the timeout off.
+func Timeout(budget time.Duration, logger *log.Logger) func(http.Handler) http.Handler {
+	meter := otel.Meter("github.com/offerni/weathercheck/internal/httpapi")
+	timeouts, _ := meter.Int64Counter("http.server.timeouts",
+		metric.WithDescription("Requests that ran out of their time budget, by the stage that was running"))
+
+	return func(next http.Handler) http.Handler {
+		if budget == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), budget)
+			defer cancel()
+			// AccessLog may have started the stage log already
+			stages, ok := ctx.Value(stageLogKey{}).(*stageLog)
+			if !ok {
+				stages = &stageLog{}
+				ctx = context.WithValue(ctx, stageLogKey{}, stages)
+			}
+
+			// The handler answers into a buffer, dropped if it is too late
+			rec := &bufferingWriter{header: http.Header{}, status: http.StatusOK}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- handlerPanic(p)
+						return
+					}
+					close(done)
+				}()
+				next.ServeHTTP(rec, r.WithContext(ctx))
+			}()
+
+			select {
+			case p := <-panicked:
+				// Raised again here so the Recoverer up the chain answers it
+				panic(p)
+			case <-done:
+				for name, values := range rec.header {
+					w.Header()[name] = values
+				}
+				w.WriteHeader(rec.status)
+				w.Write(rec.body.Bytes())
+				return
+			case <-ctx.Done():
+			}
+
+			// A client that hung up needs no answer
+			if r.Context().Err() != nil {
+				return
+			}
+
+			stage, timings := stages.cut()
+			detail := fmt.Sprintf("the request did not finish within %s", budget)
+			if stage != "" {
+				detail = fmt.Sprintf("%s did not finish within the request's %s budget", stage, budget)
+			}
+
+			span := oteltrace.SpanFromContext(r.Context())
+			span.AddEvent("timeout", oteltrace.WithAttributes(attribute.String("stage", stage)))
+			span.SetStatus(codes.Error, "timeout")
+			timeouts.Add(r.Context(), 1, metric.WithAttributes(attribute.String("stage", stage)))
+			Logf(r.Context(), logger, "Timed out serving %s %s: %s", r.Method, r.URL.Path, detail)
+
+			w.Header().Set("Content-Type", ProblemContentType)
+			w.WriteHeader(http.StatusGatewayTimeout)
+			json.NewEncoder(w).Encode(Problem{
+				Type:      "about:blank",
+				Title:     http.StatusText(http.StatusGatewayTimeout),
+				Status:    http.StatusGatewayTimeout,
+				Detail:    detail,
+				Instance:  r.URL.Path,
+				RequestID: traceID(r.Context()),
+				Stage:     stage,
+				Stages:    timings,
+			})
+		})
+	}
+}
+
+// handlerPanic keeps the stack of a panic caught in the handler's goroutine,
+// which is lost once it is raised again in the request's
+func handlerPanic(p any) any {
+	if p == http.ErrAbortHandler {
+		return p
+	}
+	err, ok := p.(error)
+	if !ok {
+		err = fmt.Errorf("%v", p)
+	}
+	return fmt.Errorf("%w\n\n%s", err, debug.Stack())
+}
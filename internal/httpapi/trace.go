@@ -0,0 +1,43 @@
+package httpapi
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// TraceIDHeader carries the request's trace ID back to the caller.
+const TraceIDHeader = "X-Trace-Id"
+
+// TraceID sets TraceIDHeader on every response. It must run inside the
+// OpenTelemetry middleware so the request span already exists.
+func TraceID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := traceID(r.Context()); id != "" {
+			w.Header().Set(TraceIDHeader, id)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// traceID returns the trace ID of the span in ctx, or "" without one.
+func traceID(ctx context.Context) string {
+	sc := oteltrace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// Logf logs to logger with the trace ID of the span in ctx appended, the
+// same one both services log for a request, so its lines can be found by
+// grepping for it.
+func Logf(ctx context.Context, logger *log.Logger, format string, args ...any) {
+	if id := traceID(ctx); id != "" {
+		format += " trace_id=%s"
+		args = append(args, id)
+	}
+	logger.Printf(format, args...)
+}
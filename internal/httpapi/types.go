@@ -0,0 +1,241 @@
+// Package httpapi holds the request/response types and HTTP plumbing shared
+// by the service binaries.
+package httpapi
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"time"
+
+	"github.com/offerni/weathercheck/internal/temperature"
+)
+
+// CEPRequest names the place to look up: a CEP, or an IBGE municipality
+// code instead.
+type CEPRequest struct {
+	CEP  string `json:"cep,omitempty"`
+	IBGE string `json:"ibge,omitempty"`
+}
+
+// AsyncRequest asks for a CEPRequest to be looked up in the background and
+// its result posted to CallbackURL.
+type AsyncRequest struct {
+	CEPRequest
+	CallbackURL string `json:"callback_url"`
+}
+
+// AsyncAccepted acknowledges a queued AsyncRequest.
+type AsyncAccepted struct {
+	XMLName xml.Name `json:"-" xml:"job"`
+	ID      string   `json:"id" xml:"id,attr"`
+	Status  string   `json:"status" xml:"status"`
+}
+
+// AsyncResult is posted to an AsyncRequest's callback URL: service B's status
+// code and JSON body for the lookup.
+type AsyncResult struct {
+	ID     string          `json:"id"`
+	Status int             `json:"status"`
+	Result json.RawMessage `json:"result"`
+}
+
+type ErrorResponse struct {
+	XMLName xml.Name     `json:"-" xml:"error"`
+	Message string       `json:"message" xml:"message"`
+	Code    string       `json:"code,omitempty" xml:"code,omitempty"`
+	TraceID string       `json:"trace_id,omitempty" xml:"trace_id,omitempty"`
+	Errors  []FieldError `json:"errors,omitempty" xml:"field,omitempty"`
+}
+
+type FieldError struct {
+	Path   string `json:"path" xml:"path"`
+	Reason string `json:"reason" xml:"reason"`
+}
+
+// Problem is an RFC 9457 problem details body, sent as
+// application/problem+json when a handler fails unexpectedly or runs out of
+// time.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// RequestID is the trace ID, as in X-Trace-Id
+	RequestID string `json:"request_id,omitempty"`
+	// Stage is the stage still running when the request timed out, and
+	// Stages how long each stage had taken by then
+	Stage  string        `json:"stage,omitempty"`
+	Stages []StageTiming `json:"stages,omitempty"`
+}
+
+// StageTiming is how long one stage of a timed out request ran, with its
+// outcome: ok, error, or timeout for the stage that was cut short.
+type StageTiming struct {
+	Name       string  `json:"name"`
+	DurationMS float64 `json:"duration_ms"`
+	Outcome    string  `json:"outcome"`
+}
+
+type WeatherResponse struct {
+	XMLName xml.Name `json:"-" xml:"weather"`
+	City    string   `json:"city" xml:"city"`
+	Temperatures
+
+	// Condition is one of the weather.Condition values, whatever the
+	// provider, and Icon the identifier of its icon
+	Condition string `json:"condition" xml:"condition"`
+	Icon      string `json:"icon" xml:"icon"`
+
+	// Only set when the provider reports humidity
+	FeelsLikeC *float64 `json:"feels_like_C,omitempty" xml:"feels_like_C,omitempty"`
+	DewPointC  *float64 `json:"dew_point_C,omitempty" xml:"dew_point_C,omitempty"`
+
+	// Approximate is set when the CEP couldn't be looked up and the city
+	// was guessed from its prefix
+	Approximate bool `json:"approximate,omitempty" xml:"approximate,omitempty"`
+}
+
+// DegradedResponse is returned instead of an error when the caller asked for
+// degraded answers and only the CEP could be resolved.
+type DegradedResponse struct {
+	XMLName          xml.Name `json:"-" xml:"weather"`
+	City             string   `json:"city" xml:"city"`
+	WeatherAvailable bool     `json:"weather_available" xml:"weather_available"`
+	LastReading      *Reading `json:"last_reading,omitempty" xml:"last_reading,omitempty"`
+
+	// Approximate is set when the city was guessed from the CEP's prefix
+	Approximate bool `json:"approximate,omitempty" xml:"approximate,omitempty"`
+}
+
+// Reading is a previously served set of temperatures.
+type Reading struct {
+	Temperatures
+	ObservedAt time.Time `json:"observed_at" xml:"observed_at"`
+}
+
+// Temperatures is a reading in Celsius plus the other scales the caller
+// asked for.
+type Temperatures struct {
+	TempC float64  `json:"temp_C" xml:"temp_C"`
+	TempF *float64 `json:"temp_F,omitempty" xml:"temp_F,omitempty"`
+	TempK *float64 `json:"temp_K,omitempty" xml:"temp_K,omitempty"`
+	TempR *float64 `json:"temp_R,omitempty" xml:"temp_R,omitempty"`
+}
+
+// NewTemperatures expresses celsius in units, rounded to precision.
+func NewTemperatures(celsius float64, units []temperature.Unit, precision int) Temperatures {
+	t := Temperatures{TempC: temperature.Round(celsius, precision)}
+	for _, u := range units {
+		v := temperature.Round(u.FromCelsius(celsius), precision)
+		switch u {
+		case temperature.Fahrenheit:
+			t.TempF = &v
+		case temperature.Kelvin:
+			t.TempK = &v
+		case temperature.Rankine:
+			t.TempR = &v
+		}
+	}
+	return t
+}
+
+// DDDResponse lists the weather in the principal cities of an area code.
+type DDDResponse struct {
+	XMLName xml.Name      `json:"-" xml:"area"`
+	DDD     string        `json:"ddd" xml:"ddd,attr"`
+	Cities  []CityWeather `json:"cities" xml:"city"`
+}
+
+// CityWeather is one city's temperatures, or why they are missing.
+type CityWeather struct {
+	City string `json:"city" xml:"name,attr"`
+	*Temperatures
+	Error string `json:"error,omitempty" xml:"error,omitempty"`
+}
+
+// SummaryResponse is a one-sentence description of a CEP's weather.
+type SummaryResponse struct {
+	XMLName  xml.Name `json:"-" xml:"summary"`
+	City     string   `json:"city" xml:"city"`
+	Language string   `json:"lang" xml:"lang"`
+	Summary  string   `json:"summary" xml:"text"`
+}
+
+// CompareResponse sets a city's current temperature beside the one recorded
+// at about the same time the day before. The yesterday fields are omitted
+// when there is no such snapshot.
+type CompareResponse struct {
+	XMLName        xml.Name   `json:"-" xml:"comparison"`
+	City           string     `json:"city" xml:"city"`
+	TempC          float64    `json:"temp_C" xml:"temp_C"`
+	YesterdayTempC *float64   `json:"yesterday_temp_C,omitempty" xml:"yesterday_temp_C,omitempty"`
+	YesterdayAt    *time.Time `json:"yesterday_at,omitempty" xml:"yesterday_at,omitempty"`
+	DeltaC         *float64   `json:"delta_C,omitempty" xml:"delta_C,omitempty"`
+}
+
+// RainResponse says whether rain is expected at a CEP in the next Hours
+// hours, and when.
+type RainResponse struct {
+	XMLName   xml.Name    `json:"-" xml:"rain"`
+	City      string      `json:"city" xml:"city"`
+	Hours     int         `json:"hours" xml:"hours"`
+	Threshold float64     `json:"threshold" xml:"threshold"`
+	WillRain  bool        `json:"will_rain" xml:"will_rain"`
+	Window    *RainWindow `json:"window,omitempty" xml:"window,omitempty"`
+}
+
+// RainWindow is a run of consecutive hours whose chance of rain reaches the
+// threshold.
+type RainWindow struct {
+	Start           time.Time `json:"start" xml:"start"`
+	End             time.Time `json:"end" xml:"end"`
+	MaxChanceOfRain float64   `json:"max_chance_of_rain" xml:"max_chance_of_rain"`
+}
+
+// MarineResponse is the sea state off a coastal CEP's city.
+type MarineResponse struct {
+	XMLName xml.Name       `json:"-" xml:"marine"`
+	City    string         `json:"city" xml:"city"`
+	Tides   []TideResponse `json:"tides" xml:"tide"`
+	Swell   *SwellResponse `json:"swell,omitempty" xml:"swell,omitempty"`
+}
+
+// TideResponse is one of today's tides.
+type TideResponse struct {
+	Time    time.Time `json:"time" xml:"time"`
+	Type    string    `json:"type" xml:"type"`
+	HeightM float64   `json:"height_m" xml:"height_m"`
+}
+
+// SwellResponse is the swell for the current hour.
+type SwellResponse struct {
+	HeightM    float64 `json:"height_m" xml:"height_m"`
+	PeriodSecs float64 `json:"period_s" xml:"period_s"`
+	Direction  string  `json:"direction" xml:"direction"`
+}
+
+// PollenResponse is today's pollen index in a CEP's city.
+type PollenResponse struct {
+	XMLName   xml.Name     `json:"-" xml:"pollen"`
+	City      string       `json:"city" xml:"city"`
+	Types     []PollenType `json:"types" xml:"type"`
+	UpdatedAt time.Time    `json:"updated_at" xml:"updated_at"`
+	// Stale is set when the provider failed and an older answer was served
+	Stale bool `json:"stale,omitempty" xml:"stale,omitempty"`
+}
+
+// PollenType is one pollen type on the Universal Pollen Index, 0 to 5.
+type PollenType struct {
+	Code     string `json:"code" xml:"code"`
+	Value    int    `json:"value" xml:"value"`
+	Category string `json:"category" xml:"category"`
+	InSeason bool   `json:"in_season" xml:"in_season"`
+}
+
+// Health answers liveness probes.
+func Health(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
@@ -0,0 +1,375 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/offerni/weathercheck/internal/cep"
+	"github.com/offerni/weathercheck/internal/comfort"
+	"github.com/offerni/weathercheck/internal/ibge"
+	"github.com/offerni/weathercheck/internal/temperature"
+	"github.com/offerni/weathercheck/internal/upstream"
+	"github.com/offerni/weathercheck/internal/weather"
+)
+
+// Headers on service B's weather answers telling where and when the reading
+// was taken, for service A's v2 envelope
+const (
+	ProviderHeader   = "X-Weather-Provider"
+	ObservedAtHeader = "X-Observed-At"
+)
+
+// DegradedReasonHeader tells why an answer isn't a fresh reading:
+// quota_exceeded when the provider's quota ran out, weather_unavailable when
+// it failed otherwise.
+const DegradedReasonHeader = "X-Degraded-Reason"
+
+// Reasons reported in DegradedReasonHeader
+const (
+	ReasonQuotaExceeded      = "quota_exceeded"
+	ReasonWeatherUnavailable = "weather_unavailable"
+)
+
+// CEPResolver resolves a CEP to its address.
+type CEPResolver interface {
+	Lookup(ctx context.Context, cep string) (*cep.Address, error)
+}
+
+// MunicipalityResolver resolves an IBGE municipality code.
+type MunicipalityResolver interface {
+	Lookup(ctx context.Context, code string) (*ibge.Municipality, error)
+}
+
+// WeatherProvider returns the current weather for a city.
+type WeatherProvider interface {
+	Current(ctx context.Context, city string) (*weather.Conditions, error)
+}
+
+// WeatherHandler serves service B's weather lookups: it resolves the CEP or
+// IBGE code to a city and returns that city's current temperatures.
+type WeatherHandler struct {
+	cep            CEPResolver
+	municipalities MunicipalityResolver
+	weather        WeatherProvider
+	readings       *Readings
+	precision      int
+	stages         stageTimer
+	tracer         oteltrace.Tracer
+	logger         *log.Logger
+}
+
+// NewWeatherHandler builds the handler. readings remembers the last answer
+// per place for degraded responses; precision is the default number of
+// decimal places in returned temperatures.
+func NewWeatherHandler(cep CEPResolver, municipalities MunicipalityResolver, weather WeatherProvider, readings *Readings, precision int, tracer oteltrace.Tracer, logger *log.Logger) *WeatherHandler {
+	return &WeatherHandler{
+		cep: cep, municipalities: municipalities, weather: weather, readings: readings,
+		precision: precision, stages: newStageTimer(), tracer: tracer, logger: logger,
+	}
+}
+
+func (h *WeatherHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "weather-handler")
+	defer span.End()
+
+	// Parse request body
+	start := h.stages.begin(ctx, stageParsing)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		span.RecordError(err)
+		h.stages.observe(ctx, stageParsing, start, true)
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	span.SetAttributes(attribute.Int("request.body_bytes", len(body)))
+
+	req, status, errResp := decodeCEPRequest(r, body)
+	if errResp != nil {
+		span.SetAttributes(attribute.String("request.error", errResp.Code))
+		h.stages.observe(ctx, stageParsing, start, true)
+		writeError(w, r, status, *errResp)
+		return
+	}
+	h.stages.observe(ctx, stageParsing, start, false)
+
+	start = h.stages.begin(ctx, stageValidation)
+	if errResp := validateLocation(req); errResp != nil {
+		span.SetAttributes(attribute.String("request.error", errResp.Code))
+		h.stages.observe(ctx, stageValidation, start, true)
+		writeError(w, r, http.StatusUnprocessableEntity, *errResp)
+		return
+	}
+
+	if req.IBGE != "" {
+		span.SetAttributes(attribute.String("ibge", req.IBGE))
+	} else {
+		span.SetAttributes(attribute.String("cep", req.CEP))
+	}
+
+	precision, units, errResp := temperatureOptions(r, h.precision)
+	if errResp != nil {
+		h.stages.observe(ctx, stageValidation, start, true)
+		writeError(w, r, http.StatusUnprocessableEntity, *errResp)
+		return
+	}
+
+	h.stages.observe(ctx, stageValidation, start, false)
+
+	// Get city from the CEP, or from the IBGE code when given instead
+	start = h.stages.begin(ctx, stageCEPLookup)
+	city, approximate, err := h.city(ctx, req)
+	h.stages.observe(ctx, stageCEPLookup, start, err != nil)
+	if err != nil {
+		span.RecordError(err)
+		status, resp := cepError(err)
+		subject := "CEP " + req.CEP
+		if req.IBGE != "" {
+			status, resp = municipalityError(err)
+			subject = "IBGE code " + req.IBGE
+		}
+		if status >= http.StatusInternalServerError {
+			Logf(ctx, h.logger, "Failed to look up %s: %v", subject, err)
+		}
+		writeError(w, r, status, resp)
+		return
+	}
+
+	// Get weather data
+	start = h.stages.begin(ctx, stageWeatherFetch)
+	weatherData, err := h.weather.Current(ctx, city)
+	h.stages.observe(ctx, stageWeatherFetch, start, err != nil)
+	if err != nil {
+		span.RecordError(err)
+		Logf(ctx, h.logger, "Failed to get weather for %s: %v", city, err)
+
+		reason := ReasonWeatherUnavailable
+		if errors.Is(err, weather.ErrQuotaExceeded) {
+			reason = ReasonQuotaExceeded
+			// Until the quota resets, the last reading beats an error
+			if last, storedAt, ok := h.readings.Last(city); ok {
+				span.SetAttributes(attribute.Bool("response.stale", true))
+				span.AddEvent("cache_stale", oteltrace.WithAttributes(
+					attribute.String("cache", "readings"),
+					attribute.Int64("age_s", int64(time.Since(storedAt).Seconds())),
+					attribute.String("reason", reason),
+				))
+				w.Header().Set(DegradedReasonHeader, reason)
+				w.Header().Set(ObservedAtHeader, storedAt.UTC().Format(time.RFC3339))
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				resp := stale(last, units, precision)
+				resp.Approximate = approximate
+				start = h.stages.begin(ctx, stageSerialization)
+				err := json.NewEncoder(w).Encode(resp)
+				h.stages.observe(ctx, stageSerialization, start, err != nil)
+				return
+			}
+		}
+
+		// Callers that opted in get the city and the last known reading
+		if r.URL.Query().Get("degraded") == "true" {
+			span.SetAttributes(attribute.Bool("response.degraded", true))
+			w.Header().Set(DegradedReasonHeader, reason)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			resp := h.degraded(city, units, precision)
+			resp.Approximate = approximate
+			start = h.stages.begin(ctx, stageSerialization)
+			err := json.NewEncoder(w).Encode(resp)
+			h.stages.observe(ctx, stageSerialization, start, err != nil)
+			return
+		}
+
+		status, resp := weatherError(err)
+		writeError(w, r, status, resp)
+		return
+	}
+
+	// Convert temperatures
+	response := WeatherResponse{
+		City:         city,
+		Temperatures: NewTemperatures(weatherData.TempC, units, precision),
+		Condition:    string(weatherData.Condition),
+		Icon:         weatherData.Condition.Icon(),
+	}
+
+	// The reading is kept unrounded, to be rounded for each request it is
+	// served to later
+	reading := response
+	reading.Temperatures = Temperatures{TempC: weatherData.TempC}
+
+	// Derived comfort values need humidity, which not every provider reports
+	if weatherData.Humidity > 0 {
+		rawFeelsLike := comfort.FeelsLike(weatherData.TempC, weatherData.Humidity, weatherData.WindKph)
+		rawDewPoint := comfort.DewPoint(weatherData.TempC, weatherData.Humidity)
+		reading.FeelsLikeC, reading.DewPointC = &rawFeelsLike, &rawDewPoint
+
+		feelsLike := temperature.Round(rawFeelsLike, precision)
+		dewPoint := temperature.Round(rawDewPoint, precision)
+		response.FeelsLikeC, response.DewPointC = &feelsLike, &dewPoint
+	}
+
+	h.readings.Store(response.City, weatherData.Coordinates, reading)
+	response.Approximate = approximate
+
+	span.SetAttributes(
+		attribute.String("response.city", response.City),
+		attribute.Float64("response.temp_c", response.TempC),
+	)
+	if approximate {
+		span.SetAttributes(attribute.Bool("response.approximate", true))
+	}
+	for key, v := range map[string]*float64{"response.temp_f": response.TempF, "response.temp_k": response.TempK, "response.temp_r": response.TempR} {
+		if v != nil {
+			span.SetAttributes(attribute.Float64(key, *v))
+		}
+	}
+
+	if weatherData.Provider != "" {
+		w.Header().Set(ProviderHeader, weatherData.Provider)
+	}
+	if !weatherData.ObservedAt.IsZero() {
+		w.Header().Set(ObservedAtHeader, weatherData.ObservedAt.Format(time.RFC3339))
+	}
+
+	start = h.stages.begin(ctx, stageSerialization)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(response)
+	h.stages.observe(ctx, stageSerialization, start, err != nil)
+}
+
+// city resolves the request's CEP or IBGE code to a city name, and whether
+// it was only guessed from the CEP's prefix
+func (h *WeatherHandler) city(ctx context.Context, req CEPRequest) (string, bool, error) {
+	if req.IBGE != "" {
+		municipality, err := h.municipalities.Lookup(ctx, req.IBGE)
+		if err != nil {
+			return "", false, err
+		}
+		return municipality.Name, false, nil
+	}
+
+	address, err := h.cep.Lookup(ctx, req.CEP)
+	if err != nil {
+		return "", false, err
+	}
+	return address.Localidade, address.Approximate, nil
+}
+
+func (h *WeatherHandler) degraded(city string, units []temperature.Unit, precision int) DegradedResponse {
+	resp := DegradedResponse{City: city}
+	if last, storedAt, ok := h.readings.Last(city); ok {
+		resp.LastReading = &Reading{
+			Temperatures: NewTemperatures(last.TempC, units, precision),
+			ObservedAt:   storedAt.UTC(),
+		}
+	}
+	return resp
+}
+
+// stale rebuilds the last answer for a city with the request's units and
+// precision
+func stale(last WeatherResponse, units []temperature.Unit, precision int) WeatherResponse {
+	last.Temperatures = NewTemperatures(last.TempC, units, precision)
+	if last.FeelsLikeC != nil {
+		feelsLike := temperature.Round(*last.FeelsLikeC, precision)
+		last.FeelsLikeC = &feelsLike
+	}
+	if last.DewPointC != nil {
+		dewPoint := temperature.Round(*last.DewPointC, precision)
+		last.DewPointC = &dewPoint
+	}
+	return last
+}
+
+// temperatureOptions reads the ?precision= and ?units= overrides, falling
+// back to precision and the default units.
+func temperatureOptions(r *http.Request, precision int) (int, []temperature.Unit, *ErrorResponse) {
+	if v := r.URL.Query().Get("precision"); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil || p < 0 || p > temperature.MaxPrecision {
+			return 0, nil, &ErrorResponse{
+				Message: "invalid precision",
+				Code:    "invalid_precision",
+				Errors:  []FieldError{{Path: "precision", Reason: fmt.Sprintf("must be an integer between 0 and %d", temperature.MaxPrecision)}},
+			}
+		}
+		precision = p
+	}
+
+	units := temperature.DefaultUnits
+	if v := r.URL.Query().Get("units"); v != "" {
+		var err error
+		if units, err = temperature.ParseUnits(v); err != nil {
+			return 0, nil, &ErrorResponse{
+				Message: "invalid units",
+				Code:    "invalid_units",
+				Errors:  []FieldError{{Path: "units", Reason: err.Error()}},
+			}
+		}
+	}
+	return precision, units, nil
+}
+
+// upstreamBusy answers calls refused by a full upstream bulkhead or an
+// adaptive concurrency limit
+var upstreamBusy = ErrorResponse{Message: "too many concurrent upstream calls, try again", Code: "upstream_busy"}
+
+// cepError maps a CEP lookup failure to its status and error body.
+func cepError(err error) (int, ErrorResponse) {
+	switch {
+	case errors.Is(err, cep.ErrNotFound):
+		return http.StatusNotFound, ErrorResponse{Message: "can not find zipcode", Code: "zipcode_not_found"}
+	case errors.Is(err, cep.ErrInvalid):
+		return http.StatusUnprocessableEntity, ErrorResponse{Message: "invalid zipcode", Code: "invalid_zipcode"}
+	case errors.Is(err, upstream.ErrBulkheadFull):
+		return http.StatusServiceUnavailable, upstreamBusy
+	default:
+		return http.StatusBadGateway, ErrorResponse{Message: "failed to look up zipcode", Code: "zipcode_lookup_failed"}
+	}
+}
+
+// municipalityError maps an IBGE lookup failure to its status and error body.
+func municipalityError(err error) (int, ErrorResponse) {
+	if errors.Is(err, ibge.ErrNotFound) {
+		return http.StatusNotFound, ErrorResponse{Message: "can not find municipality", Code: "municipality_not_found"}
+	}
+	if errors.Is(err, upstream.ErrBulkheadFull) {
+		return http.StatusServiceUnavailable, upstreamBusy
+	}
+	return http.StatusBadGateway, ErrorResponse{Message: "failed to look up municipality", Code: "municipality_lookup_failed"}
+}
+
+// weatherError maps a weather provider failure to its status and error body.
+func weatherError(err error) (int, ErrorResponse) {
+	switch {
+	case errors.Is(err, weather.ErrLocationNotFound):
+		return http.StatusNotFound, ErrorResponse{Message: "can not find weather for city", Code: "location_not_found"}
+	case errors.Is(err, weather.ErrUnauthorized):
+		return http.StatusBadGateway, ErrorResponse{Message: "weather provider rejected the request", Code: "provider_unauthorized"}
+	case errors.Is(err, weather.ErrQuotaExceeded):
+		return http.StatusServiceUnavailable, ErrorResponse{Message: "weather provider quota exceeded", Code: "provider_quota_exceeded"}
+	case errors.Is(err, upstream.ErrBulkheadFull), errors.Is(err, upstream.ErrConcurrencyLimit):
+		return http.StatusServiceUnavailable, upstreamBusy
+	default:
+		return http.StatusInternalServerError, ErrorResponse{Message: "failed to get weather data", Code: "weather_unavailable"}
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, resp ErrorResponse) {
+	resp.TraceID = traceID(r.Context())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,327 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+
+	"github.com/offerni/weathercheck/internal/cache"
+	"github.com/offerni/weathercheck/internal/cep"
+	"github.com/offerni/weathercheck/internal/ibge"
+	"github.com/offerni/weathercheck/internal/mock"
+	"github.com/offerni/weathercheck/internal/upstream"
+	"github.com/offerni/weathercheck/internal/weather"
+)
+
+// stubCEP resolves the CEPs it holds and reports the rest not found
+type stubCEP map[string]cep.Address
+
+func (s stubCEP) Lookup(ctx context.Context, code string) (*cep.Address, error) {
+	address, ok := s[code]
+	if !ok {
+		return nil, cep.ErrNotFound
+	}
+	return &address, nil
+}
+
+// stubWeather answers conditions, or err once it is set
+type stubWeather struct {
+	mu         sync.Mutex
+	conditions weather.Conditions
+	err        error
+}
+
+func (s *stubWeather) Current(ctx context.Context, city string) (*weather.Conditions, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	c := s.conditions
+	c.Location = city
+	return &c, nil
+}
+
+func (s *stubWeather) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+var testCEPs = stubCEP{
+	"01001000": {CEP: "01001-000", Localidade: "São Paulo", UF: "SP"},
+}
+
+func newTestWeatherHandler(provider WeatherProvider) *WeatherHandler {
+	readings := NewReadings(cache.Options{MaxEntries: 10}, 0)
+	return NewWeatherHandler(testCEPs, nil, provider, readings, 1, otel.Tracer("test"), log.New(io.Discard, "", 0))
+}
+
+// lookup posts body to h at target and decodes the answer into v
+func lookup(t *testing.T, h http.Handler, target, body string, v any) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", FormatJSON)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s answered %s: %v", target, rec.Body, err)
+		}
+	}
+	return rec
+}
+
+func TestStoredReadingsKeepFullPrecision(t *testing.T) {
+	const celsius = 21.456789
+	tests := []struct {
+		name   string
+		err    error
+		target string
+		want   float64
+	}{
+		{"stale answer at a finer precision", weather.ErrQuotaExceeded, "/?precision=6", 21.456789},
+		{"stale answer at a coarser precision", weather.ErrQuotaExceeded, "/?precision=2", 21.46},
+		{"degraded last reading", errors.New("provider down"), "/?degraded=true&precision=4", 21.4568},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &stubWeather{conditions: weather.Conditions{TempC: celsius, Humidity: 60, WindKph: 10}}
+			h := newTestWeatherHandler(provider)
+
+			// The first answer is rounded to whole degrees
+			var first WeatherResponse
+			lookup(t, h, "/?precision=0", `{"cep":"01001000"}`, &first)
+			if first.TempC != 21 {
+				t.Fatalf("first temp_C = %v, want 21", first.TempC)
+			}
+
+			provider.fail(tt.err)
+			var got struct {
+				WeatherResponse
+				LastReading *Reading `json:"last_reading"`
+			}
+			rec := lookup(t, h, tt.target, `{"cep":"01001000"}`, &got)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+			}
+			temp := got.TempC
+			if got.LastReading != nil {
+				temp = got.LastReading.TempC
+			}
+			if temp != tt.want {
+				t.Errorf("temp_C = %v, want %v", temp, tt.want)
+			}
+		})
+	}
+}
+
+func TestDegradedAnswer(t *testing.T) {
+	ceps := stubCEP{
+		"01001000": {Localidade: "São Paulo"},
+		"01999000": {Localidade: "São Paulo", Approximate: true},
+	}
+	tests := []struct {
+		name            string
+		cep             string
+		wantApproximate bool
+	}{
+		{"resolved CEP", "01001000", false},
+		{"CEP guessed from its prefix", "01999000", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &stubWeather{err: errors.New("provider down")}
+			readings := NewReadings(cache.Options{MaxEntries: 10}, 0)
+			h := NewWeatherHandler(ceps, nil, provider, readings, 1, otel.Tracer("test"), log.New(io.Discard, "", 0))
+
+			stages := &stageLog{}
+			r := httptest.NewRequest(http.MethodPost, "/?degraded=true", strings.NewReader(`{"cep":"`+tt.cep+`"}`))
+			r.Header.Set("Content-Type", FormatJSON)
+			r = r.WithContext(context.WithValue(r.Context(), stageLogKey{}, stages))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+
+			var got DegradedResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("answer %s: %v", rec.Body, err)
+			}
+			if got.City != "São Paulo" || got.WeatherAvailable {
+				t.Errorf("answer = %+v, want São Paulo without weather", got)
+			}
+			if got.Approximate != tt.wantApproximate {
+				t.Errorf("approximate = %v, want %v", got.Approximate, tt.wantApproximate)
+			}
+
+			var names []string
+			_, timings := stages.cut()
+			for _, timing := range timings {
+				names = append(names, timing.Name)
+			}
+			want := []string{stageParsing, stageValidation, stageCEPLookup, stageWeatherFetch, stageSerialization}
+			if strings.Join(names, ",") != strings.Join(want, ",") {
+				t.Errorf("stages = %v, want %v", names, want)
+			}
+		})
+	}
+}
+
+// upstreams stand in for ViaCEP and WeatherAPI: ViaCEP knows 01001000 and
+// answers 400 for 00000000, and WeatherAPI answers weatherStatus and
+// weatherBody
+func upstreams(t testing.TB, weatherStatus int, weatherBody string) (viaCEP, weatherAPI *httptest.Server) {
+	t.Helper()
+	viaCEP = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ws/01001000/json/":
+			io.WriteString(w, `{"cep":"01001-000","logradouro":"Praça da Sé","localidade":"São Paulo","uf":"SP"}`)
+		case "/ws/00000000/json/":
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			io.WriteString(w, `{"erro":true}`)
+		}
+	}))
+	weatherAPI = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("q"); got != "Sao Paulo, Brazil" {
+			t.Errorf("weather asked for %q", got)
+		}
+		w.WriteHeader(weatherStatus)
+		io.WriteString(w, weatherBody)
+	}))
+	t.Cleanup(viaCEP.Close)
+	t.Cleanup(weatherAPI.Close)
+	return viaCEP, weatherAPI
+}
+
+// forecast is WeatherAPI's answer for São Paulo
+const forecast = `{"location":{"name":"Sao Paulo"},"current":{"temp_c":28.5,"humidity":60,"wind_kph":10,"condition":{"code":1000}}}`
+
+func TestWeatherHandlerUpstreams(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		weatherStatus int
+		weatherBody   string
+		wantStatus    int
+		wantCode      string
+	}{
+		{"found", `{"cep":"01001000"}`, http.StatusOK, forecast, http.StatusOK, ""},
+		{"invalid CEP", `{"cep":"0100100"}`, http.StatusOK, forecast, http.StatusUnprocessableEntity, "invalid_zipcode"},
+		{"unknown CEP", `{"cep":"99999999"}`, http.StatusOK, forecast, http.StatusNotFound, "zipcode_not_found"},
+		{"CEP rejected by ViaCEP", `{"cep":"00000000"}`, http.StatusOK, forecast, http.StatusUnprocessableEntity, "invalid_zipcode"},
+		{"unknown location", `{"cep":"01001000"}`, http.StatusBadRequest, `{"error":{"code":1006,"message":"No matching location found."}}`, http.StatusNotFound, "location_not_found"},
+		{"key rejected", `{"cep":"01001000"}`, http.StatusUnauthorized, `{"error":{"code":2006,"message":"API key is invalid."}}`, http.StatusBadGateway, "provider_unauthorized"},
+		{"quota exceeded", `{"cep":"01001000"}`, http.StatusForbidden, `{"error":{"code":2007,"message":"API key has exceeded calls per month quota."}}`, http.StatusServiceUnavailable, "provider_quota_exceeded"},
+		{"provider failing", `{"cep":"01001000"}`, http.StatusInternalServerError, ``, http.StatusInternalServerError, "weather_unavailable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viaCEP, weatherAPI := upstreams(t, tt.weatherStatus, tt.weatherBody)
+			tracer := otel.Tracer("test")
+			h := NewWeatherHandler(
+				cep.NewClient(viaCEP.Client(), viaCEP.URL+"/ws/", tracer),
+				nil,
+				weather.NewClient(weatherAPI.Client(), weatherAPI.URL, "k", tracer),
+				NewReadings(cache.Options{MaxEntries: 10}, 0),
+				1, tracer, log.New(io.Discard, "", 0),
+			)
+
+			if tt.wantStatus == http.StatusOK {
+				var got WeatherResponse
+				rec := lookup(t, h, "/", tt.body, &got)
+				if rec.Code != http.StatusOK {
+					t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+				}
+				if got.City != "São Paulo" || got.TempC != 28.5 || got.TempF == nil || *got.TempF != 83.3 || got.TempK == nil || *got.TempK != 301.7 {
+					t.Errorf("answer = %s", rec.Body)
+				}
+				if got := rec.Header().Get(ProviderHeader); got != "weatherapi" {
+					t.Errorf("%s = %q", ProviderHeader, got)
+				}
+				return
+			}
+
+			var got ErrorResponse
+			rec := lookup(t, h, "/", tt.body, &got)
+			if rec.Code != tt.wantStatus || got.Code != tt.wantCode {
+				t.Errorf("answer = %d %s, want %d %s", rec.Code, rec.Body, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+// BenchmarkWeatherHandler times a lookup through the handler, with the
+// offline mocks and with real clients calling local upstreams over HTTP
+func BenchmarkWeatherHandler(b *testing.B) {
+	tracer := otel.Tracer("test")
+	viaCEP, weatherAPI := upstreams(b, http.StatusOK, forecast)
+	handlers := []struct {
+		name    string
+		handler http.Handler
+	}{
+		{"mock providers", NewWeatherHandler(
+			mock.NewCEPClient(tracer), mock.NewIBGEClient(tracer), mock.NewWeatherClient(tracer),
+			NewReadings(cache.Options{MaxEntries: 10}, 0), 2, tracer, log.New(io.Discard, "", 0),
+		)},
+		{"HTTP upstreams", NewWeatherHandler(
+			cep.NewClient(viaCEP.Client(), viaCEP.URL+"/ws/", tracer), nil,
+			weather.NewClient(weatherAPI.Client(), weatherAPI.URL, "k", tracer),
+			NewReadings(cache.Options{MaxEntries: 10}, 0), 2, tracer, log.New(io.Discard, "", 0),
+		)},
+	}
+	for _, h := range handlers {
+		b.Run(h.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				r := httptest.NewRequest(http.MethodPost, "/weather?units=C,F,K", strings.NewReader(`{"cep":"01001000"}`))
+				r.Header.Set("Content-Type", FormatJSON)
+				rec := httptest.NewRecorder()
+				h.handler.ServeHTTP(rec, r)
+				if rec.Code != http.StatusOK {
+					b.Fatalf("status = %d: %s", rec.Code, rec.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestErrorMapping(t *testing.T) {
+	tests := []struct {
+		name       string
+		mapping    func(error) (int, ErrorResponse)
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{"unknown CEP", cepError, cep.ErrNotFound, http.StatusNotFound, "zipcode_not_found"},
+		{"malformed CEP", cepError, cep.ErrInvalid, http.StatusUnprocessableEntity, "invalid_zipcode"},
+		{"CEP bulkhead full", cepError, upstream.ErrBulkheadFull, http.StatusServiceUnavailable, "upstream_busy"},
+		{"CEP lookup failing", cepError, errors.New("viacep returned 500"), http.StatusBadGateway, "zipcode_lookup_failed"},
+		{"unknown municipality", municipalityError, ibge.ErrNotFound, http.StatusNotFound, "municipality_not_found"},
+		{"IBGE bulkhead full", municipalityError, upstream.ErrBulkheadFull, http.StatusServiceUnavailable, "upstream_busy"},
+		{"IBGE lookup failing", municipalityError, errors.New("ibge down"), http.StatusBadGateway, "municipality_lookup_failed"},
+		{"unknown location", weatherError, &weather.APIError{StatusCode: 400, Code: 1006}, http.StatusNotFound, "location_not_found"},
+		{"key rejected", weatherError, weather.ErrUnauthorized, http.StatusBadGateway, "provider_unauthorized"},
+		{"quota exceeded", weatherError, weather.ErrQuotaExceeded, http.StatusServiceUnavailable, "provider_quota_exceeded"},
+		{"weather bulkhead full", weatherError, upstream.ErrBulkheadFull, http.StatusServiceUnavailable, "upstream_busy"},
+		{"concurrency limit", weatherError, upstream.ErrConcurrencyLimit, http.StatusServiceUnavailable, "upstream_busy"},
+		{"deadline", weatherError, context.DeadlineExceeded, http.StatusInternalServerError, "weather_unavailable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := tt.mapping(tt.err)
+			if status != tt.wantStatus || resp.Code != tt.wantCode {
+				t.Errorf("mapped %v to %d %s, want %d %s", tt.err, status, resp.Code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+// Package ibge validates IBGE municipality codes and resolves them to
+// municipalities through the IBGE localities API.
+package ibge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// ErrNotFound is returned when IBGE has no municipality for the code.
+var ErrNotFound = errors.New("municipality not found")
+
+var codePattern = regexp.MustCompile(`^\d{7}$`)
+
+type Municipality struct {
+	Code string
+	Name string
+	UF   string
+}
+
+// Validate reports whether code has exactly 7 digits.
+func Validate(code string) bool {
+	return codePattern.MatchString(code)
+}
+
+// Client resolves municipality codes through the IBGE localities API.
+type Client struct {
+	httpClient *http.Client
+	tracer     oteltrace.Tracer
+}
+
+func NewClient(httpClient *http.Client, tracer oteltrace.Tracer) *Client {
+	return &Client{httpClient: httpClient, tracer: tracer}
+}
+
+// Lookup resolves code to its municipality.
+func (c *Client) Lookup(ctx context.Context, code string) (*Municipality, error) {
+	ctx, span := c.tracer.Start(ctx, "get-city-from-ibge")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("ibge", code))
+
+	url := fmt.Sprintf("https://servicodados.ibge.gov.br/api/v1/localidades/municipios/%s", code)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("ibge returned %d", resp.StatusCode)
+		span.RecordError(err)
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	// Unknown codes come back as an empty list
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+		span.SetAttributes(attribute.Bool("ibge.not_found", true))
+		return nil, ErrNotFound
+	}
+
+	var municipality struct {
+		ID           int    `json:"id"`
+		Nome         string `json:"nome"`
+		Microrregiao struct {
+			Mesorregiao struct {
+				UF struct {
+					Sigla string `json:"sigla"`
+				} `json:"UF"`
+			} `json:"mesorregiao"`
+		} `json:"microrregiao"`
+	}
+	if err := json.Unmarshal(body, &municipality); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.String("city", municipality.Nome))
+	return &Municipality{
+		Code: fmt.Sprint(municipality.ID),
+		Name: municipality.Nome,
+		UF:   municipality.Microrregiao.Mesorregiao.UF.Sigla,
+	}, nil
+}
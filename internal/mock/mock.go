@@ -0,0 +1,229 @@
+// Package mock provides deterministic stand-ins for ViaCEP, IBGE and
+// WeatherAPI so the stack can run without internet access or an API key.
+package mock
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/offerni/weathercheck/internal/cep"
+	"github.com/offerni/weathercheck/internal/ibge"
+	"github.com/offerni/weathercheck/internal/pollen"
+	"github.com/offerni/weathercheck/internal/weather"
+)
+
+// NotFoundCEP and NotFoundIBGE are the codes the mocks report as unknown, to
+// exercise 404s.
+const (
+	NotFoundCEP  = "00000000"
+	NotFoundIBGE = "0000000"
+)
+
+var cities = []struct {
+	name string
+	uf   string
+	ibge string
+}{
+	{"São Paulo", "SP", "3550308"},
+	{"Rio de Janeiro", "RJ", "3304557"},
+	{"Belo Horizonte", "MG", "3106200"},
+	{"Curitiba", "PR", "4106902"},
+	{"Porto Alegre", "RS", "4314902"},
+	{"Salvador", "BA", "2927408"},
+	{"Recife", "PE", "2611606"},
+	{"Fortaleza", "CE", "2304400"},
+	{"Manaus", "AM", "1302603"},
+	{"Bauru", "SP", "3506003"},
+}
+
+// CEPClient resolves every CEP except NotFoundCEP to a city picked from the
+// CEP's hash, so the same CEP always lands on the same city.
+type CEPClient struct {
+	tracer oteltrace.Tracer
+}
+
+func NewCEPClient(tracer oteltrace.Tracer) *CEPClient {
+	return &CEPClient{tracer: tracer}
+}
+
+func (c *CEPClient) Lookup(ctx context.Context, code string) (*cep.Address, error) {
+	_, span := c.tracer.Start(ctx, "get-city-from-cep")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("cep", code), attribute.Bool("mock", true))
+
+	if code == NotFoundCEP || !cep.Validate(code) {
+		span.RecordError(cep.ErrNotFound)
+		return nil, cep.ErrNotFound
+	}
+
+	city := cities[seed(code)%uint64(len(cities))]
+	span.SetAttributes(attribute.String("city", city.name))
+	return &cep.Address{
+		CEP:        code[:5] + "-" + code[5:],
+		Localidade: city.name,
+		UF:         city.uf,
+		IBGE:       city.ibge,
+	}, nil
+}
+
+// IBGEClient resolves every municipality code except NotFoundIBGE to a city
+// picked from the code's hash.
+type IBGEClient struct {
+	tracer oteltrace.Tracer
+}
+
+func NewIBGEClient(tracer oteltrace.Tracer) *IBGEClient {
+	return &IBGEClient{tracer: tracer}
+}
+
+func (c *IBGEClient) Lookup(ctx context.Context, code string) (*ibge.Municipality, error) {
+	_, span := c.tracer.Start(ctx, "get-city-from-ibge")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("ibge", code), attribute.Bool("mock", true))
+
+	if code == NotFoundIBGE || !ibge.Validate(code) {
+		span.RecordError(ibge.ErrNotFound)
+		return nil, ibge.ErrNotFound
+	}
+
+	city := cities[seed(code)%uint64(len(cities))]
+	span.SetAttributes(attribute.String("city", city.name))
+	return &ibge.Municipality{Code: code, Name: city.name, UF: city.uf}, nil
+}
+
+// WeatherClient reports fixed conditions per city: 5-35 °C, 40-95% humidity
+// and 0-30 km/h wind, with a forecast high up to 6 °C above that and a low
+// up to 8 °C below.
+type WeatherClient struct {
+	tracer oteltrace.Tracer
+}
+
+func NewWeatherClient(tracer oteltrace.Tracer) *WeatherClient {
+	return &WeatherClient{tracer: tracer}
+}
+
+func (c *WeatherClient) Current(ctx context.Context, city string) (*weather.Conditions, error) {
+	_, span := c.tracer.Start(ctx, "get-weather")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("city", city), attribute.Bool("mock", true))
+
+	data := weather.Conditions{
+		Location: city,
+		// One decimal place, like WeatherAPI
+		TempC:     math.Round((5+float64(seed(city)%301)/10)*10) / 10,
+		Humidity:  float64(40 + seed(city+"/humidity")%56),
+		WindKph:   float64(seed(city+"/wind") % 31),
+		Condition: mockConditions[seed(city+"/condition")%uint64(len(mockConditions))],
+		Provider:  "mock",
+		// Updated every quarter hour, like WeatherAPI
+		ObservedAt: time.Now().UTC().Truncate(15 * time.Minute),
+	}
+	data.Today = &weather.Day{
+		MaxTempC:     data.TempC + float64(seed(city+"/high")%7),
+		MinTempC:     data.TempC - float64(2+seed(city+"/low")%7),
+		ChanceOfRain: float64(seed(city+"/rain") % 101),
+	}
+	// Two days of hours from midnight, like WeatherAPI; a city keeps its
+	// chances for a given hour of the day
+	midnight := time.Now().UTC().Truncate(24 * time.Hour)
+	for i := 0; i < 48; i++ {
+		data.Hourly = append(data.Hourly, weather.Hour{
+			Start:        midnight.Add(time.Duration(i) * time.Hour),
+			ChanceOfRain: float64(seed(fmt.Sprintf("%s/rain/%d", city, i%24)) % 101),
+		})
+	}
+
+	span.SetAttributes(attribute.Float64("temperature.celsius", data.TempC))
+	return &data, nil
+}
+
+// mockConditions are the sky conditions a city can get, every one of the
+// taxonomy but unknown
+var mockConditions = []weather.Condition{
+	weather.ConditionClear, weather.ConditionPartlyCloudy, weather.ConditionCloudy, weather.ConditionFog,
+	weather.ConditionDrizzle, weather.ConditionRain, weather.ConditionSnow, weather.ConditionThunderstorm,
+}
+
+// MarineClient reports two high and two low tides a day, about 6 hours
+// apart, and a 0.5-3 m swell, fixed per city.
+type MarineClient struct {
+	tracer oteltrace.Tracer
+}
+
+func NewMarineClient(tracer oteltrace.Tracer) *MarineClient {
+	return &MarineClient{tracer: tracer}
+}
+
+func (c *MarineClient) Marine(ctx context.Context, city string) (*weather.Marine, error) {
+	_, span := c.tracer.Start(ctx, "get-marine")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("city", city), attribute.Bool("mock", true))
+
+	data := weather.Marine{
+		Location: city,
+		Swell: &weather.Swell{
+			HeightM:    float64(5+seed(city+"/swell")%26) / 10,
+			PeriodSecs: float64(6 + seed(city+"/period")%9),
+			Direction:  compass[seed(city+"/direction")%uint64(len(compass))],
+		},
+	}
+	first := time.Now().UTC().Truncate(24 * time.Hour).Add(time.Duration(seed(city+"/tide")%360) * time.Minute)
+	for i := 0; i < 4; i++ {
+		high := i%2 == 0
+		decimeters := 2 + seed(fmt.Sprintf("%s/tide/%d", city, i))%4
+		if high {
+			decimeters += 12
+		}
+		data.Tides = append(data.Tides, weather.Tide{
+			Time:    first.Add(time.Duration(i) * (6*time.Hour + 12*time.Minute)),
+			HeightM: float64(decimeters) / 10,
+			High:    high,
+		})
+	}
+	return &data, nil
+}
+
+var compass = []string{"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE", "S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW"}
+
+// PollenClient reports a grass, tree and weed index of 0-5, fixed per city
+// and day of the year.
+type PollenClient struct {
+	tracer oteltrace.Tracer
+}
+
+func NewPollenClient(tracer oteltrace.Tracer) *PollenClient {
+	return &PollenClient{tracer: tracer}
+}
+
+func (c *PollenClient) Index(ctx context.Context, city string) (*pollen.Index, error) {
+	_, span := c.tracer.Start(ctx, "get-pollen")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("city", city), attribute.Bool("mock", true))
+
+	index := pollen.Index{Location: city}
+	day := time.Now().YearDay()
+	for _, code := range []string{"grass", "tree", "weed"} {
+		value := int(seed(fmt.Sprintf("%s/pollen/%s/%d", city, code, day)) % 6)
+		index.Types = append(index.Types, pollen.Type{Code: code, Value: value, Category: pollenCategories[value], InSeason: value > 0})
+	}
+	return &index, nil
+}
+
+var pollenCategories = []string{"None", "Very low", "Low", "Moderate", "High", "Very high"}
+
+func seed(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
@@ -0,0 +1,142 @@
+// Package pollen fetches today's pollen index, by pollen type, from the
+// Google Pollen API.
+package pollen
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/offerni/weathercheck/internal/weather"
+)
+
+// Index is today's pollen level in a city, one entry per pollen type.
+type Index struct {
+	Location string
+	Types    []Type
+}
+
+// Type is the level of one pollen type on the Universal Pollen Index, from
+// 0 (none) to 5 (very high).
+type Type struct {
+	// Code is grass, tree or weed
+	Code     string
+	Value    int
+	Category string
+	InSeason bool
+}
+
+// Provider returns today's pollen index for a city.
+type Provider interface {
+	Index(ctx context.Context, city string) (*Index, error)
+}
+
+// Geocoder places a city, since the Pollen API works on coordinates.
+type Geocoder interface {
+	Geocode(ctx context.Context, city string) (*weather.Place, error)
+}
+
+// GoogleClient queries the Google Pollen API.
+type GoogleClient struct {
+	httpClient *http.Client
+	apiKey     string
+	geocoder   Geocoder
+	tracer     oteltrace.Tracer
+}
+
+func NewGoogleClient(httpClient *http.Client, apiKey string, geocoder Geocoder, tracer oteltrace.Tracer) *GoogleClient {
+	return &GoogleClient{httpClient: httpClient, apiKey: apiKey, geocoder: geocoder, tracer: tracer}
+}
+
+// googleResponse is the part of the forecast:lookup answer we use
+type googleResponse struct {
+	DailyInfo []struct {
+		PollenTypeInfo []struct {
+			Code      string `json:"code"`
+			InSeason  bool   `json:"inSeason"`
+			IndexInfo *struct {
+				Value    int    `json:"value"`
+				Category string `json:"category"`
+			} `json:"indexInfo"`
+		} `json:"pollenTypeInfo"`
+	} `json:"dailyInfo"`
+}
+
+// Index returns today's pollen index for city.
+func (c *GoogleClient) Index(ctx context.Context, city string) (*Index, error) {
+	ctx, span := c.tracer.Start(ctx, "get-pollen")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("city", city))
+
+	if c.apiKey == "" {
+		err := fmt.Errorf("pollen API key not configured")
+		span.RecordError(err)
+		return nil, err
+	}
+
+	place, err := c.geocoder.Geocode(ctx, city)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	query := url.Values{
+		"key":                {c.apiKey},
+		"location.latitude":  {strconv.FormatFloat(place.Latitude, 'f', 4, 64)},
+		"location.longitude": {strconv.FormatFloat(place.Longitude, 'f', 4, 64)},
+		"days":               {"1"},
+		"plantsDescription":  {"false"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://pollen.googleapis.com/v1/forecast:lookup?"+query.Encode(), nil)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("pollen API returned %d", resp.StatusCode)
+		span.RecordError(err)
+		return nil, err
+	}
+
+	var data googleResponse
+	if err := json.Unmarshal(body, &data); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	index := &Index{Location: place.Name}
+	if len(data.DailyInfo) > 0 {
+		for _, info := range data.DailyInfo[0].PollenTypeInfo {
+			// Out of season types come without an index
+			t := Type{Code: strings.ToLower(info.Code), Category: "None", InSeason: info.InSeason}
+			if info.IndexInfo != nil {
+				t.Value, t.Category = info.IndexInfo.Value, info.IndexInfo.Category
+			}
+			index.Types = append(index.Types, t)
+		}
+	}
+	span.SetAttributes(attribute.Int("pollen.types", len(index.Types)))
+	return index, nil
+}
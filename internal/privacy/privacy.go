@@ -0,0 +1,195 @@
+// Package privacy redacts personal data, CEPs and the street addresses they
+// resolve to, before it reaches logs, traces and disk.
+package privacy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Mode says how CEPs and addresses are redacted.
+type Mode string
+
+const (
+	// Off leaves CEPs as they are.
+	Off Mode = "off"
+	// Hash replaces a CEP with a salted hash, so one CEP can still be
+	// followed across logs and traces without being readable.
+	Hash Mode = "hash"
+	// Truncate keeps the five-digit region prefix and masks the rest.
+	Truncate Mode = "truncate"
+)
+
+// cepPattern finds CEPs in free text, with or without the dash
+var cepPattern = regexp.MustCompile(`\b\d{5}-?\d{3}\b`)
+
+// addressFields are the JSON fields, as ViaCEP names them, that hold a
+// street address or part of one
+var addressFields = map[string]bool{
+	"logradouro":  true,
+	"complemento": true,
+	"bairro":      true,
+	"unidade":     true,
+}
+
+// Redactor rewrites CEPs and addresses according to its mode. The zero value and a nil
+// *Redactor leave text untouched.
+type Redactor struct {
+	mode Mode
+	salt string
+}
+
+// New returns a redactor for mode; salt keys the hashes in Hash mode and
+// the storage keys from Key in both Hash and Truncate, so those modes need
+// one: there are few enough CEPs to recover them all from unsalted hashes.
+func New(mode Mode, salt string) (*Redactor, error) {
+	switch mode {
+	case Off:
+	case Hash, Truncate:
+		if salt == "" {
+			return nil, fmt.Errorf("privacy mode %q needs a salt", mode)
+		}
+	default:
+		return nil, fmt.Errorf("unknown privacy mode %q (expected off, hash or truncate)", mode)
+	}
+	return &Redactor{mode: mode, salt: salt}, nil
+}
+
+// Enabled reports whether r changes anything.
+func (r *Redactor) Enabled() bool {
+	return r != nil && r.mode != "" && r.mode != Off
+}
+
+// CEP redacts a single CEP.
+func (r *Redactor) CEP(cep string) string {
+	if !r.Enabled() {
+		return cep
+	}
+
+	digits := strings.ReplaceAll(cep, "-", "")
+	switch r.mode {
+	case Hash:
+		sum := sha256.Sum256([]byte(r.salt + digits))
+		return "cep:" + hex.EncodeToString(sum[:6])
+	default:
+		if len(digits) < 5 {
+			return "*****"
+		}
+		return digits[:5] + "***"
+	}
+}
+
+// Address redacts a street address or part of one, such as a bairro. An
+// address has no coarse prefix like a CEP's region, so Truncate masks it
+// whole.
+func (r *Redactor) Address(s string) string {
+	if !r.Enabled() || s == "" {
+		return s
+	}
+	if r.mode == Hash {
+		sum := sha256.Sum256([]byte(r.salt + s))
+		return "addr:" + hex.EncodeToString(sum[:6])
+	}
+	return "***"
+}
+
+// Key returns what to store data about cep under: cep itself when r is
+// off, its full salted hash otherwise. Truncate mode hashes too, since
+// truncated CEPs would share keys.
+func (r *Redactor) Key(cep string) string {
+	if !r.Enabled() {
+		return cep
+	}
+	sum := sha256.Sum256([]byte(r.salt + strings.ReplaceAll(cep, "-", "")))
+	return "cep:" + hex.EncodeToString(sum[:])
+}
+
+// JSON redacts a JSON document, such as a ViaCEP answer: the address
+// fields with Address and CEPs wherever they appear. Anything that isn't
+// JSON is redacted as text with String.
+func (r *Redactor) JSON(data []byte) []byte {
+	if !r.Enabled() {
+		return data
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil || dec.More() {
+		return []byte(r.String(string(data)))
+	}
+
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(r.redactValue("", doc)); err != nil {
+		return []byte(r.String(string(data)))
+	}
+	return bytes.TrimSuffix(out.Bytes(), []byte("\n"))
+}
+
+// redactValue redacts v, found under the object key field
+func (r *Redactor) redactValue(field string, v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, item := range v {
+			v[k] = r.redactValue(k, item)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = r.redactValue(field, item)
+		}
+		return v
+	case string:
+		if addressFields[strings.ToLower(field)] {
+			return r.Address(v)
+		}
+		return r.String(v)
+	default:
+		return v
+	}
+}
+
+// String redacts every CEP found in s.
+func (r *Redactor) String(s string) string {
+	if !r.Enabled() {
+		return s
+	}
+	return cepPattern.ReplaceAllStringFunc(s, r.CEP)
+}
+
+// Writer redacts everything written through it to w, for use as a
+// log.Logger's output. Each Write is redacted on its own, which matches
+// log.Logger writing one line per call.
+func (r *Redactor) Writer(w io.Writer) io.Writer {
+	if !r.Enabled() {
+		return w
+	}
+	return writer{w: w, r: r}
+}
+
+// MarshalText reports only the mode, never the salt.
+func (r *Redactor) MarshalText() ([]byte, error) {
+	if r == nil || r.mode == "" {
+		return []byte(Off), nil
+	}
+	return []byte(r.mode), nil
+}
+
+type writer struct {
+	w io.Writer
+	r *Redactor
+}
+
+func (w writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, w.r.String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
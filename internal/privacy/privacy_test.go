@@ -0,0 +1,175 @@
+package privacy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+func mustNew(t *testing.T, mode Mode) *Redactor {
+	t.Helper()
+	r, err := New(mode, "salt")
+	if err != nil {
+		t.Fatalf("New(%q): %v", mode, err)
+	}
+	return r
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		mode    Mode
+		salt    string
+		wantErr bool
+	}{
+		{Off, "", false},
+		{Hash, "salt", false},
+		{Truncate, "salt", false},
+		// Unsalted hashes of the ~10⁸ CEPs can be reversed by brute force
+		{Hash, "", true},
+		{Truncate, "", true},
+		{"mask", "salt", true},
+		{"", "salt", true},
+	}
+	for _, tt := range tests {
+		if _, err := New(tt.mode, tt.salt); (err != nil) != tt.wantErr {
+			t.Errorf("New(%q, %q) error = %v, want error: %v", tt.mode, tt.salt, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCEP(t *testing.T) {
+	tests := []struct {
+		mode Mode
+		cep  string
+		want string
+	}{
+		{Off, "01001000", "01001000"},
+		{Truncate, "01001000", "01001***"},
+		{Truncate, "01001-000", "01001***"},
+		{Truncate, "010", "*****"},
+	}
+	for _, tt := range tests {
+		if got := mustNew(t, tt.mode).CEP(tt.cep); got != tt.want {
+			t.Errorf("%s CEP(%q) = %q, want %q", tt.mode, tt.cep, got, tt.want)
+		}
+	}
+
+	r := mustNew(t, Hash)
+	if got := r.CEP("01001000"); !strings.HasPrefix(got, "cep:") || strings.Contains(got, "01001") {
+		t.Errorf("hash CEP = %q, want an opaque cep: hash", got)
+	}
+	if r.CEP("01001000") != r.CEP("01001-000") {
+		t.Error("hash CEP differs with and without the dash")
+	}
+}
+
+func TestAddress(t *testing.T) {
+	tests := []struct {
+		mode    Mode
+		address string
+		want    string
+	}{
+		{Off, "Praça da Sé", "Praça da Sé"},
+		{Truncate, "Praça da Sé", "***"},
+		{Truncate, "", ""},
+		{Hash, "", ""},
+	}
+	for _, tt := range tests {
+		if got := mustNew(t, tt.mode).Address(tt.address); got != tt.want {
+			t.Errorf("%s Address(%q) = %q, want %q", tt.mode, tt.address, got, tt.want)
+		}
+	}
+
+	r := mustNew(t, Hash)
+	if got := r.Address("Praça da Sé"); !strings.HasPrefix(got, "addr:") || got != r.Address("Praça da Sé") {
+		t.Errorf("hash Address = %q, want a stable addr: hash", got)
+	}
+}
+
+func TestKey(t *testing.T) {
+	if got := mustNew(t, Off).Key("01001000"); got != "01001000" {
+		t.Errorf("off Key = %q, want the CEP", got)
+	}
+	for _, mode := range []Mode{Hash, Truncate} {
+		r := mustNew(t, mode)
+		// Same region, so truncated they'd be the same key
+		a, b := r.Key("01001000"), r.Key("01001001")
+		if a == b {
+			t.Errorf("%s Key gives %q for two CEPs", mode, a)
+		}
+		if strings.Contains(a, "01001") {
+			t.Errorf("%s Key = %q, want no digits of the CEP", mode, a)
+		}
+		if a != r.Key("01001-000") {
+			t.Errorf("%s Key differs with and without the dash", mode)
+		}
+	}
+}
+
+func TestCEPProperties(t *testing.T) {
+	for _, mode := range []Mode{Hash, Truncate} {
+		r := mustNew(t, mode)
+		// Formatting doesn't change what a CEP redacts to or is stored under,
+		// and redacted CEPs have nothing left to redact
+		property := func(n uint32) bool {
+			cep := fmt.Sprintf("%08d", n%100000000)
+			dashed := cep[:5] + "-" + cep[5:]
+			return r.CEP(dashed) == r.CEP(cep) && r.Key(dashed) == r.Key(cep) &&
+				r.String(r.String(dashed)) == r.String(dashed)
+		}
+		if err := quick.Check(property, nil); err != nil {
+			t.Errorf("%s: %v", mode, err)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	const viaCEP = `{"cep":"01001-000","logradouro":"Praça da Sé","complemento":"lado ímpar","bairro":"Sé","localidade":"São Paulo","uf":"SP","ibge":"3550308"}`
+	tests := []struct {
+		name string
+		mode Mode
+		in   string
+		want map[string]any
+	}{
+		{
+			name: "truncate",
+			mode: Truncate,
+			in:   viaCEP,
+			want: map[string]any{"cep": "01001***", "logradouro": "***", "complemento": "***", "bairro": "***", "localidade": "São Paulo", "uf": "SP", "ibge": "3550308"},
+		},
+		{
+			name: "address list",
+			mode: Truncate,
+			in:   `[{"cep":"01001-000","logradouro":"Praça da Sé"}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := mustNew(t, tt.mode).JSON([]byte(tt.in))
+			if strings.Contains(string(out), "Praça") || strings.Contains(string(out), "01001-000") {
+				t.Fatalf("JSON = %s, still has the address or CEP", out)
+			}
+			if tt.want == nil {
+				return
+			}
+			var got map[string]any
+			if err := json.Unmarshal(out, &got); err != nil {
+				t.Fatalf("JSON = %s, not JSON: %v", out, err)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("%s = %v, want %v", k, got[k], v)
+				}
+			}
+		})
+	}
+
+	if got := mustNew(t, Off).JSON([]byte(viaCEP)); string(got) != viaCEP {
+		t.Errorf("off JSON = %s, want it unchanged", got)
+	}
+	if got := mustNew(t, Truncate).JSON([]byte("not json 01001000")); string(got) != "not json 01001***" {
+		t.Errorf("JSON on text = %q, want the CEPs redacted as text", got)
+	}
+}
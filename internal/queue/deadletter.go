@@ -0,0 +1,197 @@
+package queue
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/offerni/weathercheck/internal/privacy"
+)
+
+// ErrRedacted is returned for dead letters reloaded from a file that only
+// has their redacted form, whose jobs can't be run again.
+var ErrRedacted = errors.New("dead letter was stored redacted and can't be run again")
+
+// DeadLetter is a job that failed for good: either its lookup or the
+// delivery of its result to the callback.
+type DeadLetter struct {
+	Job Job `json:"job"`
+	// Result is the payload that couldn't be delivered; it is empty when
+	// the lookup itself failed.
+	Result   json.RawMessage `json:"result,omitempty"`
+	Error    string          `json:"error"`
+	Attempts int             `json:"attempts"`
+	FailedAt time.Time       `json:"failed_at"`
+	// Redacted is set on letters whose job lost its CEP or callback
+	// credentials when saved, so they can be shown but not retried.
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+// DeadLetters keeps up to size failed jobs, dropping the oldest when full.
+// With a path, the letters are saved to that file on every change and
+// reloaded on start, so they survive restarts. The file never has callback
+// URLs' userinfo or query, which often carry tokens, and has CEPs redacted
+// by the privacy mode; letters that lost either are only whole in memory.
+type DeadLetters struct {
+	path     string
+	size     int
+	personal *privacy.Redactor
+
+	mu      sync.Mutex
+	letters []DeadLetter
+}
+
+// OpenDeadLetters loads the dead letters saved at path, if any; an empty
+// path keeps them in memory only. personal redacts CEPs in the file, and
+// may be nil.
+func OpenDeadLetters(path string, size int, personal *privacy.Redactor) (*DeadLetters, error) {
+	d := &DeadLetters{path: path, size: size, personal: personal}
+	if path == "" {
+		return d, nil
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return d, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		var letter DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &letter); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		d.letters = append(d.letters, letter)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(d.letters) > size {
+		d.letters = d.letters[len(d.letters)-size:]
+	}
+	return d, nil
+}
+
+// Add stores letter, replacing an earlier one for the same job.
+func (d *DeadLetters) Add(letter DeadLetter) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.letters = slices.DeleteFunc(d.letters, func(l DeadLetter) bool { return l.Job.ID == letter.Job.ID })
+	d.letters = append(d.letters, letter)
+	if len(d.letters) > d.size {
+		d.letters = slices.Delete(d.letters, 0, len(d.letters)-d.size)
+	}
+	return d.save()
+}
+
+// List returns the dead letters, oldest first.
+func (d *DeadLetters) List() []DeadLetter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return slices.Clone(d.letters)
+}
+
+// Get returns the dead letter for job id.
+func (d *DeadLetters) Get(id string) (DeadLetter, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, l := range d.letters {
+		if l.Job.ID == id {
+			return l, true
+		}
+	}
+	return DeadLetter{}, false
+}
+
+// Remove drops the dead letter for job id, reporting whether there was one.
+func (d *DeadLetters) Remove(id string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	n := len(d.letters)
+	d.letters = slices.DeleteFunc(d.letters, func(l DeadLetter) bool { return l.Job.ID == id })
+	if len(d.letters) == n {
+		return false, nil
+	}
+	return true, d.save()
+}
+
+// RemoveCEP drops the dead letters for jobs on cep, returning how many
+// went. Letters reloaded redacted are matched by their redacted CEP, so in
+// truncate mode those of CEPs sharing its prefix go too.
+func (d *DeadLetters) RemoveCEP(cep string) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	redacted := d.personal.CEP(cep)
+	n := len(d.letters)
+	d.letters = slices.DeleteFunc(d.letters, func(l DeadLetter) bool {
+		return l.Job.CEP == cep || (l.Redacted && l.Job.CEP == redacted)
+	})
+	if len(d.letters) == n {
+		return 0, nil
+	}
+	return n - len(d.letters), d.save()
+}
+
+// save rewrites the file through a temporary one, so a crash never leaves
+// it half written. The caller holds mu.
+func (d *DeadLetters) save() error {
+	if d.path == "" {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(d.path), filepath.Base(d.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	enc := json.NewEncoder(tmp)
+	for _, l := range d.letters {
+		if err := enc.Encode(d.stored(l)); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), d.path)
+}
+
+// stored returns letter as it is written to the file
+func (d *DeadLetters) stored(letter DeadLetter) DeadLetter {
+	job := letter.Job
+	job.CEP = d.personal.CEP(job.CEP)
+	job.CallbackURL = RedactURL(job.CallbackURL)
+	if job != letter.Job {
+		letter.Job, letter.Redacted = job, true
+	}
+	letter.Error = d.personal.String(letter.Error)
+	return letter
+}
+
+// RedactURL returns a callback URL without its userinfo, query and
+// fragment, where callers put tokens.
+func RedactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "[invalid URL]"
+	}
+	u.User, u.RawQuery, u.ForceQuery, u.Fragment, u.RawFragment = nil, "", false, "", ""
+	return u.String()
+}
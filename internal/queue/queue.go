@@ -0,0 +1,62 @@
+// Package queue carries asynchronous weather lookups from service A's
+// ingestion endpoint to the workers that run them, either in memory or
+// through RabbitMQ.
+package queue
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrFull is returned by Publish when the queue can't take more jobs.
+var ErrFull = errors.New("queue is full")
+
+// Job is a lookup to run in the background, whose result is posted to
+// CallbackURL.
+type Job struct {
+	ID          string `json:"id"`
+	CEP         string `json:"cep,omitempty"`
+	IBGE        string `json:"ibge,omitempty"`
+	CallbackURL string `json:"callback_url"`
+}
+
+// Queue hands jobs from publishers to consumers.
+type Queue interface {
+	// Publish enqueues job, or fails with ErrFull when there is no room.
+	Publish(ctx context.Context, job Job) error
+	// Consume runs handle for each job until ctx is done. A job whose
+	// handler fails may be delivered again.
+	Consume(ctx context.Context, handle func(ctx context.Context, job Job) error) error
+}
+
+// Memory is an in-process Queue bounded to a fixed number of pending jobs.
+// Jobs are lost when the process exits.
+type Memory struct {
+	jobs chan Job
+}
+
+func NewMemory(size int) *Memory {
+	return &Memory{jobs: make(chan Job, size)}
+}
+
+func (q *Memory) Publish(ctx context.Context, job Job) error {
+	select {
+	case q.jobs <- job:
+		return nil
+	default:
+		return ErrFull
+	}
+}
+
+// Consume runs handle for one job at a time; start several consumers to run
+// jobs in parallel. Failed jobs are not retried.
+func (q *Memory) Consume(ctx context.Context, handle func(ctx context.Context, job Job) error) error {
+	for {
+		select {
+		case job := <-q.jobs:
+			handle(ctx, job)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
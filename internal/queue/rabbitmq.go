@@ -0,0 +1,98 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// RabbitMQ is a Queue on a durable RabbitMQ queue, so pending jobs survive
+// restarts of service A and are shared by every replica.
+type RabbitMQ struct {
+	conn *amqp.Connection
+	name string
+
+	mu      sync.Mutex
+	channel *amqp.Channel
+}
+
+// DialRabbitMQ connects to url and declares the durable queue name.
+func DialRabbitMQ(url, name string) (*RabbitMQ, error) {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to RabbitMQ: %w", err)
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("opening RabbitMQ channel: %w", err)
+	}
+	if _, err := channel.QueueDeclare(name, true, false, false, false, nil); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("declaring queue %s: %w", name, err)
+	}
+
+	return &RabbitMQ{conn: conn, name: name, channel: channel}, nil
+}
+
+func (q *RabbitMQ) Publish(ctx context.Context, job Job) error {
+	body, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	// Channels aren't safe for concurrent publishing
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.channel.PublishWithContext(ctx, "", q.name, false, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    job.ID,
+		Body:         body,
+	})
+}
+
+// Consume takes one job at a time on its own channel. A job is acked once
+// handled and requeued once if its handler fails.
+func (q *RabbitMQ) Consume(ctx context.Context, handle func(ctx context.Context, job Job) error) error {
+	channel, err := q.conn.Channel()
+	if err != nil {
+		return fmt.Errorf("opening RabbitMQ channel: %w", err)
+	}
+	defer channel.Close()
+
+	if err := channel.Qos(1, 0, false); err != nil {
+		return err
+	}
+	deliveries, err := channel.ConsumeWithContext(ctx, q.name, "", false, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("consuming queue %s: %w", q.name, err)
+	}
+
+	for delivery := range deliveries {
+		var job Job
+		if err := json.Unmarshal(delivery.Body, &job); err != nil {
+			// A malformed message will never succeed
+			delivery.Nack(false, false)
+			continue
+		}
+		if err := handle(ctx, job); err != nil {
+			delivery.Nack(false, !delivery.Redelivered)
+			continue
+		}
+		delivery.Ack(false)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return amqp.ErrClosed
+}
+
+// Close closes the connection to RabbitMQ.
+func (q *RabbitMQ) Close() error {
+	return q.conn.Close()
+}
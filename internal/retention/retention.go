@@ -0,0 +1,47 @@
+// Package retention runs the jobs that purge stored data once it is past
+// its configured age.
+package retention
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Job purges one store, returning how many entries went.
+type Job struct {
+	Name  string
+	Purge func() (int, error)
+}
+
+// Run runs every job right away and then every interval, until ctx is
+// done. A job that fails is logged and tried again next time.
+func Run(ctx context.Context, interval time.Duration, jobs []Job, logger *log.Logger) {
+	meter := otel.Meter("github.com/offerni/weathercheck/internal/retention")
+	purged, _ := meter.Int64Counter("retention.purged",
+		metric.WithDescription("Stored entries purged past their retention, by store"))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, job := range jobs {
+			n, err := job.Purge()
+			if err != nil {
+				logger.Printf("Failed to purge %s: %v", job.Name, err)
+			}
+			if n > 0 {
+				purged.Add(ctx, int64(n), metric.WithAttributes(attribute.String("store", job.Name)))
+				logger.Printf("Purged %d expired entries from %s", n, job.Name)
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
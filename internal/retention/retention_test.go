@@ -0,0 +1,36 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	var snapshots, audit atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		Run(ctx, time.Millisecond, []Job{
+			{"snapshots", func() (int, error) { return int(snapshots.Add(1)), nil }},
+			// A failing job doesn't stop the others
+			{"audit log", func() (int, error) { audit.Add(1); return 0, errors.New("disk full") }},
+		}, log.New(io.Discard, "", 0))
+		close(done)
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for snapshots.Load() < 3 || audit.Load() < 3 {
+		select {
+		case <-deadline:
+			t.Fatalf("jobs ran %d and %d times, want at least 3", snapshots.Load(), audit.Load())
+		case <-time.After(time.Millisecond):
+		}
+	}
+	cancel()
+	<-done
+}
@@ -0,0 +1,176 @@
+// Package schedule runs periodic work on cron expressions, such as
+// refreshing the weather of groups of CEPs so their snapshots and caches
+// stay warm.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed five-field cron expression: minute, hour, day of month,
+// month and day of week (0 or 7 is Sunday). Each field is *, a value, a
+// range such as 1-5, any of those with a /step, or a comma-separated list
+// of them. As in cron, when both days are restricted either may match.
+type Cron struct {
+	expr              string
+	minute, hour, dom uint64
+	month, dow        uint64
+	domStar, dowStar  bool
+}
+
+// macros are the shorthands cron accepts for common schedules
+var macros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = []field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// horizon bounds how far Next looks, so expressions that never fire, such
+// as February 30th, don't loop forever
+const horizon = 5 * 366 * 24 * time.Hour
+
+// Parse parses a cron expression, or one of @hourly, @daily, @weekly,
+// @monthly and @yearly.
+func Parse(expr string) (*Cron, error) {
+	spec := strings.TrimSpace(expr)
+	if macro, ok := macros[spec]; ok {
+		spec = macro
+	}
+	parts := strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("cron expression %q: expected 5 fields, got %d", expr, len(parts))
+	}
+
+	c := &Cron{expr: strings.TrimSpace(expr)}
+	sets := []*uint64{&c.minute, &c.hour, &c.dom, &c.month, &c.dow}
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron expression %q: %w", expr, err)
+		}
+		*sets[i] = set
+	}
+	// Sunday is both 0 and 7
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domStar, c.dowStar = strings.HasPrefix(parts[2], "*"), strings.HasPrefix(parts[4], "*")
+
+	if c.Next(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)).IsZero() {
+		return nil, fmt.Errorf("cron expression %q never fires", expr)
+	}
+	return c, nil
+}
+
+// parseField returns the set of values part allows, one bit per value
+func parseField(part string, f field) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(part, ",") {
+		rng, stepText, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("%s: invalid step %q", f.name, stepText)
+			}
+			step = n
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			from, to, _ := strings.Cut(rng, "-")
+			var err error
+			if lo, err = value(from, f); err != nil {
+				return 0, err
+			}
+			if hi, err = value(to, f); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("%s: range %q is backwards", f.name, rng)
+			}
+		default:
+			n, err := value(rng, f)
+			if err != nil {
+				return 0, err
+			}
+			lo, hi = n, n
+			// 5/15 means from 5 on, every 15
+			if hasStep {
+				hi = f.max
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+func value(text string, f field) (int, error) {
+	n, err := strconv.Atoi(text)
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("%s: %q is not between %d and %d", f.name, text, f.min, f.max)
+	}
+	return n, nil
+}
+
+// String returns the expression as it was parsed.
+func (c *Cron) String() string { return c.expr }
+
+// Next returns the first time after after that c fires, in after's
+// location, or the zero time if it doesn't fire within five years.
+func (c *Cron) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := after.Add(horizon)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.domStar && c.dowStar:
+		return true
+	case c.domStar:
+		return dow
+	case c.dowStar:
+		return dom
+	default:
+		return dom || dow
+	}
+}
@@ -0,0 +1,63 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *",
+		"* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@every 10m", "0 0 30 2 *",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) accepted", expr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	// A Wednesday
+	at := time.Date(2026, 10, 14, 8, 7, 30, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 10, 14, 8, 8, 0, 0, time.UTC)},
+		{"*/10 * * * *", time.Date(2026, 10, 14, 8, 10, 0, 0, time.UTC)},
+		{"5/15 * * * *", time.Date(2026, 10, 14, 8, 20, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)},
+		{"0 6,18 * * *", time.Date(2026, 10, 14, 18, 0, 0, 0, time.UTC)},
+		{"30 9-17/4 * * *", time.Date(2026, 10, 14, 9, 30, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 1-5", time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2026, 10, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Either day matches when both are restricted: the 1st or a Friday
+		{"0 0 1 * 5", time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		c, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := c.Next(at); !got.Equal(tt.want) {
+			t.Errorf("%q.Next(%s) = %s, want %s", tt.expr, at, got, tt.want)
+		}
+	}
+}
+
+func TestNextLocation(t *testing.T) {
+	// Half-hour offsets still fire on the local hour
+	kolkata := time.FixedZone("IST", 5*3600+1800)
+	c, _ := Parse("0 * * * *")
+	at := time.Date(2026, 10, 14, 8, 10, 0, 0, kolkata)
+	if got, want := c.Next(at), time.Date(2026, 10, 14, 9, 0, 0, 0, kolkata); !got.Equal(want) {
+		t.Errorf("Next = %s, want %s", got, want)
+	}
+}
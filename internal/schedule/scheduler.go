@@ -0,0 +1,153 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Group is a set of CEPs refreshed together on one schedule.
+type Group struct {
+	Name string
+	Cron *Cron
+	CEPs []string
+}
+
+// ParseGroups reads groups from spec: name=cron:cep,cep entries separated
+// by semicolons, for example
+// "coastal=*/10 * * * *:11010000,88010000;inland=@hourly:13015904".
+// validCEP checks each CEP.
+func ParseGroups(spec string, validCEP func(string) bool) ([]Group, error) {
+	var groups []Group
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, rest, ok := strings.Cut(entry, "=")
+		expr, list, ok2 := strings.Cut(rest, ":")
+		name = strings.TrimSpace(name)
+		if !ok || !ok2 || name == "" {
+			return nil, fmt.Errorf("expected name=cron:ceps, got %q", entry)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("group %q is defined twice", name)
+		}
+		seen[name] = true
+
+		cron, err := Parse(expr)
+		if err != nil {
+			return nil, fmt.Errorf("group %q: %w", name, err)
+		}
+		g := Group{Name: name, Cron: cron}
+		for _, code := range strings.Split(list, ",") {
+			code = strings.TrimSpace(code)
+			if !validCEP(code) {
+				return nil, fmt.Errorf("group %q: %q is not a CEP", name, code)
+			}
+			g.CEPs = append(g.CEPs, code)
+		}
+		groups = append(groups, g)
+	}
+	return groups, nil
+}
+
+// GroupStatus is what a group last did and will do next, for the admin
+// endpoint.
+type GroupStatus struct {
+	Name     string    `json:"name"`
+	Schedule string    `json:"schedule"`
+	CEPs     []string  `json:"ceps"`
+	NextRun  time.Time `json:"next_run"`
+	// LastRun is nil until the group first runs
+	LastRun *time.Time `json:"last_run,omitempty"`
+	// LastFailures counts the CEPs whose refresh failed on the last run
+	LastFailures int `json:"last_failures"`
+}
+
+// Scheduler runs refresh for every CEP of each group whenever the group's
+// schedule fires. Runs of one group never overlap: a run that outlasts its
+// interval delays the next one.
+type Scheduler struct {
+	groups  []Group
+	refresh func(ctx context.Context, cep string) error
+	logger  *log.Logger
+
+	// now and after stand in for the clock in tests
+	now   func() time.Time
+	after func(time.Duration) <-chan time.Time
+
+	mu     sync.Mutex
+	status map[string]*GroupStatus
+}
+
+// New builds a scheduler for groups; nothing runs until Run.
+func New(groups []Group, refresh func(ctx context.Context, cep string) error, logger *log.Logger) *Scheduler {
+	s := &Scheduler{
+		groups:  groups,
+		refresh: refresh,
+		logger:  logger,
+		now:     time.Now,
+		after:   time.After,
+		status:  make(map[string]*GroupStatus, len(groups)),
+	}
+	for _, g := range groups {
+		s.status[g.Name] = &GroupStatus{Name: g.Name, Schedule: g.Cron.String(), CEPs: g.CEPs, NextRun: g.Cron.Next(s.now())}
+	}
+	return s
+}
+
+// Run runs every group on its schedule until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, g := range s.groups {
+		wg.Add(1)
+		go func(g Group) {
+			defer wg.Done()
+			s.loop(ctx, g)
+		}(g)
+	}
+	wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, g Group) {
+	for {
+		next := g.Cron.Next(s.now())
+		s.update(g.Name, func(st *GroupStatus) { st.NextRun = next })
+		select {
+		case <-s.after(next.Sub(s.now())):
+		case <-ctx.Done():
+			return
+		}
+
+		failures := 0
+		for _, code := range g.CEPs {
+			if err := s.refresh(ctx, code); err != nil {
+				failures++
+				s.logger.Printf("Scheduled refresh of CEP %s (group %s) failed: %v", code, g.Name, err)
+			}
+		}
+		s.update(g.Name, func(st *GroupStatus) { st.LastRun, st.LastFailures = &next, failures })
+	}
+}
+
+func (s *Scheduler) update(name string, change func(*GroupStatus)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	change(s.status[name])
+}
+
+// Status returns each group's schedule and runs, in configuration order.
+func (s *Scheduler) Status() []GroupStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]GroupStatus, 0, len(s.groups))
+	for _, g := range s.groups {
+		out = append(out, *s.status[g.Name])
+	}
+	return out
+}
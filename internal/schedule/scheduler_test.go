@@ -0,0 +1,116 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func validCEP(code string) bool { return len(code) == 8 }
+
+func TestParseGroups(t *testing.T) {
+	groups, err := ParseGroups("coastal=*/10 * * * *:11010000, 88010000; inland=@hourly:13015904;", validCEP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][]string
+	for _, g := range groups {
+		got = append(got, append([]string{g.Name, g.Cron.String()}, g.CEPs...))
+	}
+	want := [][]string{{"coastal", "*/10 * * * *", "11010000", "88010000"}, {"inland", "@hourly", "13015904"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("groups = %q, want %q", got, want)
+	}
+
+	for _, spec := range []string{
+		"coastal", "coastal=@hourly", "=@hourly:11010000", "coastal=@hourly:110",
+		"coastal=61 * * * *:11010000", "a=@hourly:11010000;a=@daily:13015904",
+	} {
+		if _, err := ParseGroups(spec, validCEP); err == nil {
+			t.Errorf("ParseGroups(%q) accepted", spec)
+		}
+	}
+}
+
+func TestSchedulerRun(t *testing.T) {
+	groups, _ := ParseGroups("coastal=*/10 * * * *:11010000,88010000;inland=@hourly:13015904", validCEP)
+
+	var mu sync.Mutex
+	var refreshed []string
+	clock := time.Date(2026, 10, 14, 8, 7, 0, 0, time.UTC)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := New(groups, func(ctx context.Context, code string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		refreshed = append(refreshed, code)
+		if code == "88010000" {
+			return errors.New("provider down")
+		}
+		return nil
+	}, log.New(io.Discard, "", 0))
+
+	// The clock jumps to each run as soon as it is waited for, and stops
+	// after the first run of each group
+	s.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return clock
+	}
+	waits := make(map[bool]int)
+	s.after = func(d time.Duration) <-chan time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		hourly := d > 10*time.Minute
+		waits[hourly]++
+		ch := make(chan time.Time)
+		if waits[hourly] == 1 {
+			ch = make(chan time.Time, 1)
+			ch <- clock.Add(d)
+		}
+		return ch
+	}
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for {
+		st := s.Status()
+		if st[0].LastRun != nil && st[1].LastRun != nil {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("groups didn't run: %+v", st)
+		case <-time.After(time.Millisecond):
+		}
+	}
+	cancel()
+	<-done
+
+	st := s.Status()
+	if want := time.Date(2026, 10, 14, 8, 10, 0, 0, time.UTC); !st[0].LastRun.Equal(want) || st[0].LastFailures != 1 {
+		t.Errorf("coastal = %+v, want a run at %s with 1 failure", st[0], want)
+	}
+	if want := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC); !st[1].LastRun.Equal(want) || st[1].LastFailures != 0 {
+		t.Errorf("inland = %+v, want a run at %s", st[1], want)
+	}
+	if !st[0].NextRun.Equal(time.Date(2026, 10, 14, 8, 10, 0, 0, time.UTC)) {
+		t.Errorf("coastal next run = %s", st[0].NextRun)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(refreshed) != 3 {
+		t.Errorf("refreshed %v, want every CEP once", refreshed)
+	}
+}
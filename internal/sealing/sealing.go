@@ -0,0 +1,101 @@
+// Package sealing encrypts data at rest with AES-256-GCM, under a key given
+// directly or as a data key only AWS KMS can decrypt.
+package sealing
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+// KeySize is the length of keys, in bytes.
+const KeySize = 32
+
+// ErrOpen is returned when sealed data doesn't open: it was sealed under
+// another key, for another place, or tampered with.
+var ErrOpen = errors.New("sealed data can't be opened with this key")
+
+// Sealer seals and opens data under one key.
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+// New builds a sealer for a KeySize-byte key.
+func New(key []byte) (*Sealer, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealer{aead: aead}, nil
+}
+
+// Seal encrypts plaintext, bound to additional so it only opens with the
+// same additional data, e.g. the key it is stored under.
+func (s *Sealer) Seal(plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize(), s.aead.NonceSize()+len(plaintext)+s.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+// Open decrypts what Seal returned for the same additional data.
+func (s *Sealer) Open(sealed, additional []byte) ([]byte, error) {
+	n := s.aead.NonceSize()
+	if len(sealed) < n {
+		return nil, ErrOpen
+	}
+	plaintext, err := s.aead.Open(nil, sealed[:n], sealed[n:], additional)
+	if err != nil {
+		return nil, ErrOpen
+	}
+	return plaintext, nil
+}
+
+// DecodeKey reads a base64-encoded key.
+func DecodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("key must be base64: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", KeySize, len(key))
+	}
+	return key, nil
+}
+
+// DecryptKMS asks AWS KMS, with the default credentials and region, for the
+// plaintext of a base64-encoded data key, as returned in CiphertextBlob by
+// `aws kms generate-data-key --key-spec AES_256`.
+func DecryptKMS(ctx context.Context, encoded string) ([]byte, error) {
+	blob, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("data key must be base64: %w", err)
+	}
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out, err := kms.NewFromConfig(cfg).Decrypt(ctx, &kms.DecryptInput{CiphertextBlob: blob})
+	if err != nil {
+		return nil, fmt.Errorf("kms decrypt: %w", err)
+	}
+	if len(out.Plaintext) != KeySize {
+		return nil, fmt.Errorf("data key must be %d bytes, got %d", KeySize, len(out.Plaintext))
+	}
+	return out.Plaintext, nil
+}
@@ -0,0 +1,198 @@
+// Package snapshot keeps hourly temperature readings per city, so current
+// conditions can be compared with the same time on an earlier day.
+package snapshot
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Snapshot is one city's temperature at a point in time.
+type Snapshot struct {
+	City  string    `json:"city"`
+	TempC float64   `json:"temp_C"`
+	At    time.Time `json:"at"`
+}
+
+// Store keeps the first reading of each hour per city for retention. With a
+// path, snapshots are appended to that file as JSON lines and reloaded on
+// start, when the expired ones are compacted away, as Purge does later on.
+type Store struct {
+	retention time.Duration
+	path      string
+
+	mu     sync.Mutex
+	file   *os.File
+	byCity map[string][]Snapshot // oldest first
+}
+
+// Open loads the snapshots saved at path, if any; an empty path keeps them
+// in memory only.
+func Open(path string, retention time.Duration) (*Store, error) {
+	s := &Store{retention: retention, path: path, byCity: make(map[string][]Snapshot)}
+	if path == "" {
+		return s, nil
+	}
+
+	var kept []Snapshot
+	f, err := os.Open(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		kept, err = load(f, time.Now().Add(-retention))
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if err := rewrite(path, kept); err != nil {
+		return nil, err
+	}
+	for _, snap := range kept {
+		s.byCity[snap.City] = append(s.byCity[snap.City], snap)
+	}
+
+	if s.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads the snapshots taken after since
+func load(f *os.File, since time.Time) ([]Snapshot, error) {
+	var kept []Snapshot
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		var snap Snapshot
+		if err := json.Unmarshal(scanner.Bytes(), &snap); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if snap.At.After(since) {
+			kept = append(kept, snap)
+		}
+	}
+	return kept, scanner.Err()
+}
+
+// rewrite replaces the file through a temporary one, so a crash never
+// leaves it half written
+func rewrite(path string, snaps []Snapshot) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	enc := json.NewEncoder(tmp)
+	for _, snap := range snaps {
+		if err := enc.Encode(snap); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Record stores a reading for city unless the city already has one for the
+// same hour.
+func (s *Store) Record(city string, tempC float64, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snaps := s.byCity[city]
+	if n := len(snaps); n > 0 && snaps[n-1].At.Truncate(time.Hour).Equal(at.Truncate(time.Hour)) {
+		return nil
+	}
+
+	// Drop what expired while we are at it
+	since := at.Add(-s.retention)
+	for len(snaps) > 0 && !snaps[0].At.After(since) {
+		snaps = snaps[1:]
+	}
+	snap := Snapshot{City: city, TempC: tempC, At: at.UTC()}
+	s.byCity[city] = append(snaps, snap)
+
+	if s.file == nil {
+		return nil
+	}
+	line, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+// Purge drops every snapshot older than the retention, of cities that are
+// no longer looked up too, and compacts the file. It returns how many
+// snapshots went.
+func (s *Store) Purge() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	since := time.Now().Add(-s.retention)
+	var kept []Snapshot
+	purged := 0
+	for city, snaps := range s.byCity {
+		n := len(snaps)
+		for len(snaps) > 0 && !snaps[0].At.After(since) {
+			snaps = snaps[1:]
+		}
+		purged += n - len(snaps)
+		if len(snaps) == 0 {
+			delete(s.byCity, city)
+			continue
+		}
+		s.byCity[city] = snaps
+		kept = append(kept, snaps...)
+	}
+	if s.file == nil || purged == 0 {
+		return purged, nil
+	}
+
+	// The file is in recording order; the order between cities doesn't
+	// matter to Open
+	if err := rewrite(s.path, kept); err != nil {
+		return purged, err
+	}
+	s.file.Close()
+	var err error
+	s.file, err = os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	return purged, err
+}
+
+// Nearest returns city's snapshot closest to at, if one is within
+// tolerance of it.
+func (s *Store) Nearest(city string, at time.Time, tolerance time.Duration) (Snapshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var best Snapshot
+	found := false
+	for _, snap := range s.byCity[city] {
+		off := snap.At.Sub(at).Abs()
+		if off <= tolerance && (!found || off < best.At.Sub(at).Abs()) {
+			best, found = snap, true
+		}
+	}
+	return best, found
+}
+
+// Close closes the underlying file, if any.
+func (s *Store) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
@@ -0,0 +1,50 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPurge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshots.jsonl")
+	s, err := Open(path, 48*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	s.Record("São Paulo", 25, now.Add(-time.Hour))
+	// Cities nobody looked up since
+	s.Record("Manaus", 31, now.Add(-50*time.Hour))
+	s.Record("Belém", 30, now.Add(-49*time.Hour))
+
+	purged, err := s.Purge()
+	if err != nil || purged != 2 {
+		t.Fatalf("Purge() = %d, %v; want 2 snapshots purged", purged, err)
+	}
+	if _, ok := s.Nearest("Manaus", now.Add(-50*time.Hour), time.Minute); ok {
+		t.Error("expired Manaus snapshot still there")
+	}
+
+	// Later snapshots are appended to the compacted file
+	s.Record("Curitiba", 15, now)
+	s.Close()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 2 || strings.Contains(string(data), "Manaus") || strings.Contains(string(data), "Belém") {
+		t.Errorf("file after purge:\n%s", data)
+	}
+
+	s, err = Open(path, 48*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if snap, ok := s.Nearest("São Paulo", now.Add(-time.Hour), time.Minute); !ok || snap.TempC != 25 {
+		t.Errorf("reloaded São Paulo snapshot = %+v, %v", snap, ok)
+	}
+}
@@ -0,0 +1,112 @@
+// Package startup holds a service's start until the dependencies it needs
+// accept connections, for deployments that bring them up in any order.
+package startup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Backoff between rounds of dials, doubling from the first to the last
+const (
+	firstBackoff = 100 * time.Millisecond
+	maxBackoff   = 5 * time.Second
+)
+
+// defaultPorts are used for URLs that don't name their port
+var defaultPorts = map[string]string{"http": "80", "https": "443", "amqp": "5672", "amqps": "5671"}
+
+// Dependency is something a service needs reachable before it serves.
+type Dependency struct {
+	Name string
+	// Addrs are the host:port of its replicas; it is up once any of them
+	// accepts a connection
+	Addrs []string
+}
+
+// FromURLs returns the dependency reached at any of rawURLs.
+func FromURLs(name string, rawURLs ...string) (Dependency, error) {
+	dep := Dependency{Name: name}
+	for _, rawURL := range rawURLs {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return Dependency{}, fmt.Errorf("%s: %w", name, err)
+		}
+		port := u.Port()
+		if port == "" {
+			port = defaultPorts[u.Scheme]
+		}
+		if u.Hostname() == "" || port == "" {
+			return Dependency{}, fmt.Errorf("%s: no host and port in %q", name, u.Redacted())
+		}
+		dep.Addrs = append(dep.Addrs, net.JoinHostPort(u.Hostname(), port))
+	}
+	return dep, nil
+}
+
+// Wait dials deps until every one accepts a TCP connection, backing off
+// between rounds, and fails naming those still unreachable once max has
+// passed.
+func Wait(ctx context.Context, deps []Dependency, max time.Duration, logger *log.Logger) error {
+	ctx, cancel := context.WithTimeout(ctx, max)
+	defer cancel()
+
+	start := time.Now()
+	pending := deps
+	backoff := firstBackoff
+	for attempt := 1; ; attempt++ {
+		var down []Dependency
+		var lastErr error
+		for _, dep := range pending {
+			if err := dialAny(ctx, dep.Addrs); err != nil {
+				down, lastErr = append(down, dep), err
+			}
+		}
+		if len(down) == 0 {
+			if attempt > 1 {
+				logger.Printf("Dependencies reachable after %s", time.Since(start).Round(time.Millisecond))
+			}
+			return nil
+		}
+		pending = down
+
+		// Jitter keeps replicas started together from dialing in step
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		logger.Printf("Waiting for %s (attempt %d): %v", names(down), attempt, lastErr)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s still unreachable after %s: %w", names(down), max, lastErr)
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// dialAny connects to the first of addrs that accepts
+func dialAny(ctx context.Context, addrs []string) error {
+	var err error
+	for _, addr := range addrs {
+		dialCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		var conn net.Conn
+		conn, err = (&net.Dialer{}).DialContext(dialCtx, "tcp", addr)
+		cancel()
+		if err == nil {
+			return conn.Close()
+		}
+	}
+	return err
+}
+
+func names(deps []Dependency) string {
+	list := make([]string, len(deps))
+	for i, dep := range deps {
+		list[i] = dep.Name + " (" + strings.Join(dep.Addrs, ", ") + ")"
+	}
+	return strings.Join(list, ", ")
+}
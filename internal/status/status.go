@@ -0,0 +1,234 @@
+// Package status serves a small HTML page summarizing a service's health:
+// its upstreams, cache hit rates, recent error rates and build version.
+package status
+
+import (
+	"embed"
+	"html/template"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+// windowMinutes is how far back the recent error rates look
+const windowMinutes = 5
+
+//go:embed templates/*.html
+var templates embed.FS
+
+var page = template.Must(template.ParseFS(templates, "templates/status.html"))
+
+// Board collects what the status page shows. It is safe for concurrent use.
+type Board struct {
+	service string
+	version string
+	started time.Time
+	logger  *log.Logger
+
+	mu        sync.Mutex
+	requests  window
+	upstreams map[string]*upstream
+	caches    map[string]func() (hits, misses uint64)
+}
+
+type upstream struct {
+	calls     window
+	lastOK    time.Time
+	lastFail  time.Time
+	lastError string
+}
+
+func NewBoard(service, version string, logger *log.Logger) *Board {
+	return &Board{
+		service:   service,
+		version:   version,
+		started:   time.Now(),
+		logger:    logger,
+		upstreams: make(map[string]*upstream),
+		caches:    make(map[string]func() (hits, misses uint64)),
+	}
+}
+
+// Observe records the outcome of one call to the named upstream. Callers
+// should pass a nil err for answers that are valid even if negative, such
+// as an unknown CEP.
+func (b *Board) Observe(name string, err error) {
+	now := time.Now()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	u, ok := b.upstreams[name]
+	if !ok {
+		u = &upstream{}
+		b.upstreams[name] = u
+	}
+	u.calls.add(now, err != nil)
+	if err != nil {
+		u.lastFail, u.lastError = now, err.Error()
+	} else {
+		u.lastOK = now
+	}
+}
+
+// AddCache shows the hit rate of a cache reporting its hits and misses.
+func (b *Board) AddCache(name string, stats func() (hits, misses uint64)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.caches[name] = stats
+}
+
+// Middleware counts requests, and 5xx responses as errors.
+func (b *Board) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+
+		b.mu.Lock()
+		b.requests.add(time.Now(), sw.status >= http.StatusInternalServerError)
+		b.mu.Unlock()
+	})
+}
+
+type pageData struct {
+	Service   string
+	Version   string
+	Revision  string
+	GoVersion string
+	Uptime    time.Duration
+	Requests  rate
+	Upstreams []upstreamView
+	Caches    []cacheView
+}
+
+type upstreamView struct {
+	Name      string
+	State     string
+	Calls     rate
+	LastOK    time.Time
+	LastFail  time.Time
+	LastError string
+}
+
+type cacheView struct {
+	Name    string
+	Hits    uint64
+	Misses  uint64
+	HitRate float64
+}
+
+func (b *Board) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := page.Execute(w, b.snapshot()); err != nil {
+		b.logger.Printf("Failed to render status page: %v", err)
+	}
+}
+
+func (b *Board) snapshot() pageData {
+	now := time.Now()
+	data := pageData{
+		Service: b.service,
+		Version: b.version,
+		Uptime:  now.Sub(b.started).Round(time.Second),
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		data.GoVersion = info.GoVersion
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				data.Revision = s.Value
+			}
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	data.Requests = b.requests.rate(now)
+	for name, u := range b.upstreams {
+		state := "up"
+		if u.lastFail.After(u.lastOK) {
+			state = "down"
+		}
+		data.Upstreams = append(data.Upstreams, upstreamView{
+			Name: name, State: state, Calls: u.calls.rate(now),
+			LastOK: u.lastOK, LastFail: u.lastFail, LastError: u.lastError,
+		})
+	}
+	sort.Slice(data.Upstreams, func(i, j int) bool { return data.Upstreams[i].Name < data.Upstreams[j].Name })
+
+	for name, stats := range b.caches {
+		hits, misses := stats()
+		view := cacheView{Name: name, Hits: hits, Misses: misses}
+		if total := hits + misses; total > 0 {
+			view.HitRate = float64(hits) / float64(total) * 100
+		}
+		data.Caches = append(data.Caches, view)
+	}
+	sort.Slice(data.Caches, func(i, j int) bool { return data.Caches[i].Name < data.Caches[j].Name })
+
+	return data
+}
+
+// window counts events and errors in one-minute buckets over the last
+// windowMinutes minutes
+type window struct {
+	buckets [windowMinutes]bucket
+}
+
+type bucket struct {
+	minute int64
+	total  uint64
+	errors uint64
+}
+
+func (w *window) add(now time.Time, failed bool) {
+	minute := now.Unix() / 60
+	b := &w.buckets[minute%windowMinutes]
+	if b.minute != minute {
+		*b = bucket{minute: minute}
+	}
+	b.total++
+	if failed {
+		b.errors++
+	}
+}
+
+// rate is a window's totals
+type rate struct {
+	Total     uint64
+	Errors    uint64
+	ErrorRate float64
+}
+
+func (w *window) rate(now time.Time) rate {
+	oldest := now.Unix()/60 - windowMinutes + 1
+	var r rate
+	for _, b := range w.buckets {
+		if b.minute >= oldest {
+			r.Total += b.total
+			r.Errors += b.errors
+		}
+	}
+	if r.Total > 0 {
+		r.ErrorRate = float64(r.Errors) / float64(r.Total) * 100
+	}
+	return r
+}
+
+// statusWriter remembers the status code written through it
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,98 @@
+// Package summary turns a weather reading into a short sentence, such as
+// "Mild and rainy in Campinas, high of 24°C", for voice and SMS clients.
+package summary
+
+import (
+	"bytes"
+	"math"
+	"text/template"
+
+	"golang.org/x/text/language"
+
+	"github.com/offerni/weathercheck/internal/weather"
+)
+
+// rainyChance is the chance of rain, in percent, from which a day is rainy
+const rainyChance = 50
+
+// Supported lists the summary languages; the first is the default.
+var Supported = []language.Tag{language.BrazilianPortuguese, language.English}
+
+var matcher = language.NewMatcher(Supported)
+
+type phrasing struct {
+	// feel names each temperature band, coldest first
+	feel     [5]string
+	template *template.Template
+}
+
+var phrasings = map[language.Tag]phrasing{
+	language.BrazilianPortuguese: {
+		feel: [5]string{"Frio", "Fresco", "Ameno", "Quente", "Muito quente"},
+		template: template.Must(template.New("pt-BR").Parse(
+			`{{.Feel}}{{if .Rainy}} e chuvoso{{end}} em {{.City}}, ` +
+				`{{if .HasForecast}}máxima de {{.High}}°C{{else}}agora {{.Current}}°C{{end}}`)),
+	},
+	language.English: {
+		feel: [5]string{"Cold", "Cool", "Mild", "Warm", "Hot"},
+		template: template.Must(template.New("en").Parse(
+			`{{.Feel}}{{if .Rainy}} and rainy{{end}} in {{.City}}, ` +
+				`{{if .HasForecast}}high of {{.High}}°C{{else}}currently {{.Current}}°C{{end}}`)),
+	},
+}
+
+// Match picks the supported language closest to the preferences in
+// acceptLanguage (an Accept-Language header or a single tag).
+func Match(acceptLanguage string) language.Tag {
+	prefs, _, _ := language.ParseAcceptLanguage(acceptLanguage)
+	_, index, _ := matcher.Match(prefs...)
+	return Supported[index]
+}
+
+// Text summarizes data for city in lang, which must be one of Supported.
+func Text(lang language.Tag, city string, data *weather.Conditions) (string, error) {
+	p, ok := phrasings[lang]
+	if !ok {
+		p = phrasings[Supported[0]]
+	}
+
+	view := struct {
+		Feel        string
+		Rainy       bool
+		City        string
+		HasForecast bool
+		High        int
+		Current     int
+	}{City: city, Current: int(math.Round(data.TempC))}
+
+	reference := data.TempC
+	if today := data.Today; today != nil {
+		view.HasForecast = true
+		view.High = int(math.Round(today.MaxTempC))
+		view.Rainy = today.ChanceOfRain >= rainyChance
+		reference = today.MaxTempC
+	}
+	view.Feel = p.feel[band(reference)]
+
+	var buf bytes.Buffer
+	if err := p.template.Execute(&buf, view); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// band places a temperature in one of the five feel bands
+func band(tempC float64) int {
+	switch {
+	case tempC < 12:
+		return 0
+	case tempC < 18:
+		return 1
+	case tempC < 24:
+		return 2
+	case tempC < 30:
+		return 3
+	default:
+		return 4
+	}
+}
@@ -0,0 +1,125 @@
+package telemetry
+
+import (
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric"
+)
+
+// unboundedKeys are attributes whose values have no practical limit. They
+// are fine on spans, but as metric labels each value would be a series of
+// its own, so the guard drops them from every instrument not listed in
+// boundedInstruments.
+var unboundedKeys = map[attribute.Key]bool{
+	"cep":     true,
+	"zipcode": true,
+	"city":    true,
+	"ibge":    true,
+}
+
+// boundedInstruments may carry unboundedKeys because something else bounds
+// their values: the configured CEP list for heat risk, TopValues for the
+// city lookups
+var boundedInstruments = map[string]bool{
+	"heat.risk.level":         true,
+	"weather.lookups.by_city": true,
+}
+
+// cardinalityGuard is a view that strips unboundedKeys from measurements
+func cardinalityGuard(inst metric.Instrument) (metric.Stream, bool) {
+	if boundedInstruments[inst.Name] {
+		return metric.Stream{}, false
+	}
+	return metric.Stream{
+		Name:        inst.Name,
+		Description: inst.Description,
+		Unit:        inst.Unit,
+		AttributeFilter: func(kv attribute.KeyValue) bool {
+			return !unboundedKeys[kv.Key]
+		},
+	}, true
+}
+
+// OtherValue labels the values TopValues doesn't give a label of their own.
+const OtherValue = "other"
+
+// topCandidates is how many more values than labels TopValues keeps
+// counting, to tell the frequent ones from the rest
+const topCandidates = 4
+
+// TopValues turns an unbounded dimension, such as city names, into a metric
+// label with at most size values besides OtherValue: the first values seen
+// at least minCount times, by a Space-Saving count. A value that got a
+// label keeps it, so series don't churn; the rest share OtherValue. It is
+// safe for concurrent use.
+type TopValues struct {
+	size     int
+	minCount int
+
+	mu         sync.Mutex
+	candidates map[string]*candidate
+	labeled    map[string]bool
+}
+
+// candidate is a Space-Saving counter: count may overestimate by up to err,
+// the count of the value it replaced
+type candidate struct {
+	count, err int
+}
+
+func NewTopValues(size, minCount int) *TopValues {
+	return &TopValues{
+		size:       size,
+		minCount:   minCount,
+		candidates: make(map[string]*candidate),
+		labeled:    make(map[string]bool, size),
+	}
+}
+
+// Label counts one occurrence of value and returns the label to record it
+// under.
+func (t *TopValues) Label(value string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.labeled[value] {
+		return value
+	}
+	if len(t.labeled) >= t.size {
+		return OtherValue
+	}
+
+	c := t.count(value)
+	if c.count-c.err >= t.minCount {
+		t.labeled[value] = true
+		delete(t.candidates, value)
+		return value
+	}
+	return OtherValue
+}
+
+// count adds one to value's candidate, taking the place of the least
+// counted one when there is no room
+func (t *TopValues) count(value string) *candidate {
+	if c, ok := t.candidates[value]; ok {
+		c.count++
+		return c
+	}
+	if len(t.candidates) < t.size*topCandidates {
+		c := &candidate{count: 1}
+		t.candidates[value] = c
+		return c
+	}
+
+	var minValue string
+	var minCand *candidate
+	for v, c := range t.candidates {
+		if minCand == nil || c.count < minCand.count {
+			minValue, minCand = v, c
+		}
+	}
+	delete(t.candidates, minValue)
+	c := &candidate{count: minCand.count + 1, err: minCand.count}
+	t.candidates[value] = c
+	return c
+}
@@ -0,0 +1,71 @@
+package telemetry
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric"
+)
+
+func TestTopValues(t *testing.T) {
+	top := NewTopValues(2, 3)
+	steps := []struct {
+		value, want string
+	}{
+		{"São Paulo", OtherValue},
+		{"São Paulo", OtherValue},
+		{"Curitiba", OtherValue},
+		{"São Paulo", "São Paulo"},
+		// A labeled value keeps its label
+		{"São Paulo", "São Paulo"},
+		{"Curitiba", OtherValue},
+		{"Curitiba", "Curitiba"},
+		// With every label taken, newcomers never get one
+		{"Manaus", OtherValue},
+		{"Manaus", OtherValue},
+		{"Manaus", OtherValue},
+		{"Manaus", OtherValue},
+	}
+	for i, step := range steps {
+		if got := top.Label(step.value); got != step.want {
+			t.Errorf("step %d: Label(%q) = %q, want %q", i, step.value, got, step.want)
+		}
+	}
+}
+
+func TestCardinalityGuard(t *testing.T) {
+	attrs := []attribute.KeyValue{
+		attribute.String("cep", "01001000"),
+		attribute.String("city", "São Paulo"),
+		attribute.String("ibge", "3550308"),
+		attribute.String("provider", "weatherapi"),
+		attribute.Int("status", 200),
+	}
+	tests := []struct {
+		instrument string
+		wantKept   []attribute.Key
+	}{
+		{"weather.lookups", []attribute.Key{"provider", "status"}},
+		{"heat.risk.level", []attribute.Key{"cep", "city", "ibge", "provider", "status"}},
+		{"weather.lookups.by_city", []attribute.Key{"cep", "city", "ibge", "provider", "status"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.instrument, func(t *testing.T) {
+			stream, ok := cardinalityGuard(metric.Instrument{Name: tt.instrument})
+			var kept []attribute.Key
+			for _, kv := range attrs {
+				if !ok || stream.AttributeFilter(kv) {
+					kept = append(kept, kv.Key)
+				}
+			}
+			if len(kept) != len(tt.wantKept) {
+				t.Fatalf("kept %v, want %v", kept, tt.wantKept)
+			}
+			for i := range kept {
+				if kept[i] != tt.wantKept[i] {
+					t.Errorf("kept %v, want %v", kept, tt.wantKept)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package telemetry
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	otelprom "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/sdk/metric"
+)
+
+// exemplarFlag turns on exemplars in the metrics SDK
+const exemplarFlag = "OTEL_GO_X_EXEMPLAR"
+
+// InitMeter installs a Prometheus-backed meter provider for serviceName as
+// the global provider. It returns the /metrics handler and a shutdown func.
+//
+// Histograms carry the trace and span IDs of a sampled request as
+// exemplars, served to scrapers that ask for OpenMetrics.
+// OTEL_METRICS_EXEMPLAR_FILTER picks which measurements become exemplars
+// (trace_based by default, always_on or always_off).
+func InitMeter(serviceName string) (http.Handler, func()) {
+	// The SDK keeps exemplars behind an experimental flag; opt in unless
+	// it was set either way
+	if _, ok := os.LookupEnv(exemplarFlag); !ok {
+		os.Setenv(exemplarFlag, "true")
+	}
+
+	// Create Prometheus exporter
+	exporter, err := otelprom.New()
+	if err != nil {
+		log.Fatalf("Failed to create Prometheus exporter: %v", err)
+	}
+
+	// Create meter provider; the guard keeps CEPs and cities out of labels
+	mp := metric.NewMeterProvider(
+		metric.WithReader(exporter),
+		metric.WithResource(newResource(serviceName)),
+		metric.WithView(cardinalityGuard),
+	)
+
+	otel.SetMeterProvider(mp)
+
+	// Exemplars only exist in the OpenMetrics format
+	handler := promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}))
+
+	return handler, func() {
+		if err := mp.Shutdown(context.Background()); err != nil {
+			log.Printf("Error shutting down meter provider: %v", err)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TestExemplars checks a histogram measurement taken inside a sampled span
+// is served with that span's IDs to OpenMetrics scrapers
+func TestExemplars(t *testing.T) {
+	handler, shutdown := InitMeter("telemetry-test")
+	defer shutdown()
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	duration, err := otel.Meter("telemetry-test").Float64Histogram("exemplar.test.duration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	duration.Record(ctx, 0.42)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	if !strings.Contains(body, `trace_id="4bf92f3577b34da6a3ce929d0e0e4736"`) || !strings.Contains(body, `span_id="00f067aa0ba902b7"`) {
+		t.Errorf("no exemplar for the sampled span in:\n%s", body)
+	}
+}
@@ -0,0 +1,32 @@
+package telemetry
+
+import (
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+)
+
+// StartProfiling serves the runtime profiles under /debug/pprof/ on addr, in
+// the background, for a continuous profiler such as Parca or Pyroscope to
+// scrape. They are kept off the public router so they are never exposed on
+// the service port.
+func StartProfiling(addr string, logger *log.Logger) {
+	// Sample a share of contended locks and blocking events as well
+	runtime.SetMutexProfileFraction(100)
+	runtime.SetBlockProfileRate(int(1e6))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	go func() {
+		logger.Printf("Profiling endpoints listening on %s", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			logger.Printf("Profiling server stopped: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,39 @@
+package telemetry
+
+import (
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// DefaultPropagators is used when OTEL_PROPAGATORS is not set.
+const DefaultPropagators = "tracecontext,baggage"
+
+// SetPropagators installs the comma-separated propagators in spec as the
+// global propagator, using the names from the OpenTelemetry OTEL_PROPAGATORS
+// convention: tracecontext, baggage, b3 (single header) and b3multi. Incoming
+// requests are read with all of them and outgoing requests carry all of them.
+func SetPropagators(spec string) error {
+	var propagators []propagation.TextMapPropagator
+	for _, name := range strings.Split(spec, ",") {
+		switch strings.TrimSpace(name) {
+		case "tracecontext":
+			propagators = append(propagators, propagation.TraceContext{})
+		case "baggage":
+			propagators = append(propagators, propagation.Baggage{})
+		case "b3":
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case "b3multi":
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		case "", "none":
+		default:
+			return fmt.Errorf("unknown propagator %q (expected tracecontext, baggage, b3, b3multi or none)", name)
+		}
+	}
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagators...))
+	return nil
+}
@@ -0,0 +1,62 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestSetPropagators(t *testing.T) {
+	defer otel.SetTextMapPropagator(otel.GetTextMapPropagator())
+
+	traceID, _ := oteltrace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := oteltrace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := oteltrace.ContextWithSpanContext(context.Background(), oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID: traceID, SpanID: spanID, TraceFlags: oteltrace.FlagsSampled,
+	}))
+
+	tests := []struct {
+		spec        string
+		wantHeaders []string
+		wantErr     bool
+	}{
+		{DefaultPropagators, []string{"Traceparent"}, false},
+		{"b3", []string{"B3"}, false},
+		{"b3multi", []string{"X-B3-Traceid", "X-B3-Spanid", "X-B3-Sampled"}, false},
+		{"tracecontext, b3", []string{"Traceparent", "B3"}, false},
+		{"none", nil, false},
+		{"jaeger", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			err := SetPropagators(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetPropagators(%q) error = %v, want error: %v", tt.spec, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			header := http.Header{}
+			otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+			if len(header) != len(tt.wantHeaders) {
+				t.Errorf("injected %v, want %v", header, tt.wantHeaders)
+			}
+			for _, name := range tt.wantHeaders {
+				if header.Get(name) == "" {
+					t.Errorf("no %s in %v", name, header)
+				}
+			}
+
+			// What was injected reads back as the same trace
+			got := oteltrace.SpanContextFromContext(otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(header)))
+			if len(tt.wantHeaders) > 0 && got.TraceID() != traceID {
+				t.Errorf("extracted trace %s, want %s", got.TraceID(), traceID)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package telemetry
+
+import (
+	"context"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+// redact rewrites string span attributes before export; nil leaves them be
+var redact atomic.Pointer[func(string) string]
+
+// SetRedactor makes every exported span pass its string attributes, such as
+// CEPs and the URLs that embed them, through fn.
+func SetRedactor(fn func(string) string) {
+	redact.Store(&fn)
+}
+
+// redactingExporter applies the redactor to spans on their way out
+type redactingExporter struct {
+	trace.SpanExporter
+}
+
+func (e redactingExporter) ExportSpans(ctx context.Context, spans []trace.ReadOnlySpan) error {
+	fn := redact.Load()
+	if fn == nil {
+		return e.SpanExporter.ExportSpans(ctx, spans)
+	}
+
+	redacted := make([]trace.ReadOnlySpan, len(spans))
+	for i, s := range spans {
+		redacted[i] = redactedSpan{ReadOnlySpan: s, redact: *fn}
+	}
+	return e.SpanExporter.ExportSpans(ctx, redacted)
+}
+
+type redactedSpan struct {
+	trace.ReadOnlySpan
+	redact func(string) string
+}
+
+func (s redactedSpan) Attributes() []attribute.KeyValue {
+	return s.redactAll(s.ReadOnlySpan.Attributes())
+}
+
+// Events covers recorded errors, whose messages may quote a CEP or URL
+func (s redactedSpan) Events() []trace.Event {
+	events := s.ReadOnlySpan.Events()
+	out := make([]trace.Event, len(events))
+	for i, e := range events {
+		e.Attributes = s.redactAll(e.Attributes)
+		out[i] = e
+	}
+	return out
+}
+
+func (s redactedSpan) redactAll(attrs []attribute.KeyValue) []attribute.KeyValue {
+	out := make([]attribute.KeyValue, len(attrs))
+	for i, kv := range attrs {
+		if kv.Value.Type() == attribute.STRING {
+			kv = kv.Key.String(s.redact(kv.Value.AsString()))
+		}
+		out[i] = kv
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRedactingExporter(t *testing.T) {
+	defer redact.Store(redact.Load())
+
+	exported := tracetest.NewInMemoryExporter()
+	provider := trace.NewTracerProvider(trace.WithSyncer(redactingExporter{SpanExporter: exported}))
+	defer provider.Shutdown(context.Background())
+
+	SetRedactor(func(s string) string { return strings.ReplaceAll(s, "01001000", "01001***") })
+	_, span := provider.Tracer("test").Start(context.Background(), "get-city-from-cep")
+	span.SetAttributes(attribute.String("cep", "01001000"), attribute.Int("attempt", 1))
+	span.RecordError(errors.New("GET https://viacep.com.br/ws/01001000/json/: timeout"))
+	span.End()
+
+	spans := exported.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("exported %d spans, want 1", len(spans))
+	}
+	for _, kv := range spans[0].Attributes {
+		if kv.Key == "cep" && kv.Value.AsString() != "01001***" {
+			t.Errorf("cep = %q, want it redacted", kv.Value.AsString())
+		}
+		if kv.Key == "attempt" && kv.Value.AsInt64() != 1 {
+			t.Errorf("attempt = %v, want it untouched", kv.Value)
+		}
+	}
+	for _, event := range spans[0].Events {
+		for _, kv := range event.Attributes {
+			if strings.Contains(kv.Value.Emit(), "01001000") {
+				t.Errorf("event %s has the raw CEP: %s", event.Name, kv.Value.Emit())
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package telemetry
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// ratioSampler samples a share of new traces, a share that can change while
+// running. Traces it passes over are still recorded, unsampled, so that
+// tailProcessor can keep the ones that turn out to fail or be slow.
+type ratioSampler struct {
+	current atomic.Pointer[ratio]
+}
+
+type ratio struct {
+	value   float64
+	sampler trace.Sampler
+}
+
+var sampler = newRatioSampler(1)
+
+func newRatioSampler(r float64) *ratioSampler {
+	s := &ratioSampler{}
+	s.set(r)
+	return s
+}
+
+func (s *ratioSampler) set(r float64) {
+	s.current.Store(&ratio{value: r, sampler: trace.TraceIDRatioBased(r)})
+}
+
+func (s *ratioSampler) ShouldSample(p trace.SamplingParameters) trace.SamplingResult {
+	parent := oteltrace.SpanContextFromContext(p.ParentContext)
+	if parent.IsSampled() {
+		return trace.SamplingResult{Decision: trace.RecordAndSample, Tracestate: parent.TraceState()}
+	}
+
+	// Only new traces roll the dice; children follow their unsampled parent
+	if !parent.IsValid() {
+		if res := s.current.Load().sampler.ShouldSample(p); res.Decision == trace.RecordAndSample {
+			return res
+		}
+	}
+	return trace.SamplingResult{Decision: trace.RecordOnly, Tracestate: parent.TraceState()}
+}
+
+func (s *ratioSampler) Description() string {
+	return fmt.Sprintf("DynamicRatio{%g}", s.current.Load().value)
+}
+
+// SampleRatio returns the share of new traces being sampled.
+func SampleRatio() float64 {
+	return sampler.current.Load().value
+}
+
+// SetSampleRatio changes the share (0-1) of new traces sampled. Requests
+// arriving with a sampled parent are always sampled, and failed or slow
+// requests are kept regardless (see SetSlowThreshold).
+func SetSampleRatio(r float64) {
+	sampler.set(r)
+}
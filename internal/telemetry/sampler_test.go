@@ -0,0 +1,51 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestRatioSampler(t *testing.T) {
+	traceID, _ := oteltrace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	parent := func(flags oteltrace.TraceFlags) context.Context {
+		spanID, _ := oteltrace.SpanIDFromHex("00f067aa0ba902b7")
+		return oteltrace.ContextWithSpanContext(context.Background(), oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+			TraceID: traceID, SpanID: spanID, TraceFlags: flags,
+		}))
+	}
+	tests := []struct {
+		name   string
+		ratio  float64
+		parent context.Context
+		want   trace.SamplingDecision
+	}{
+		{"new trace, all sampled", 1, context.Background(), trace.RecordAndSample},
+		{"new trace, none sampled", 0, context.Background(), trace.RecordOnly},
+		{"sampled parent", 0, parent(oteltrace.FlagsSampled), trace.RecordAndSample},
+		{"unsampled parent", 1, parent(0), trace.RecordOnly},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newRatioSampler(tt.ratio)
+			res := s.ShouldSample(trace.SamplingParameters{ParentContext: tt.parent, TraceID: traceID, Name: "test"})
+			if res.Decision != tt.want {
+				t.Errorf("decision = %v, want %v", res.Decision, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetSampleRatio(t *testing.T) {
+	defer SetSampleRatio(SampleRatio())
+
+	SetSampleRatio(0.25)
+	if got := SampleRatio(); got != 0.25 {
+		t.Errorf("SampleRatio() = %v, want 0.25", got)
+	}
+	if got := sampler.Description(); got != "DynamicRatio{0.25}" {
+		t.Errorf("Description() = %q", got)
+	}
+}
@@ -0,0 +1,142 @@
+package telemetry
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// maxPendingTraces bounds how many unsampled traces are buffered at once
+	maxPendingTraces = 10000
+	// pendingTTL drops spans whose local root ended long ago, such as
+	// background work that outlived its request
+	pendingTTL    = time.Minute
+	sweepInterval = 10 * time.Second
+)
+
+var slowThreshold atomic.Int64
+
+func init() {
+	slowThreshold.Store(int64(time.Second))
+}
+
+// SlowThreshold returns how long a request must take to always be traced.
+func SlowThreshold() time.Duration {
+	return time.Duration(slowThreshold.Load())
+}
+
+// SetSlowThreshold changes how long a request must take to always be traced;
+// zero turns slow-request sampling off.
+func SetSlowThreshold(d time.Duration) {
+	slowThreshold.Store(int64(d))
+}
+
+// tailProcessor forwards sampled spans straight on. Unsampled ones are held
+// until their local root span ends, and the whole batch is forwarded only if
+// a span failed or the root was slower than SlowThreshold.
+type tailProcessor struct {
+	next trace.SpanProcessor
+
+	mu        sync.Mutex
+	pending   map[oteltrace.TraceID]*pendingTrace
+	lastSweep time.Time
+}
+
+type pendingTrace struct {
+	spans   []trace.ReadOnlySpan
+	started time.Time
+}
+
+func newTailProcessor(next trace.SpanProcessor) *tailProcessor {
+	return &tailProcessor{next: next, pending: make(map[oteltrace.TraceID]*pendingTrace), lastSweep: time.Now()}
+}
+
+func (p *tailProcessor) OnStart(ctx context.Context, s trace.ReadWriteSpan) {
+	p.next.OnStart(ctx, s)
+}
+
+func (p *tailProcessor) OnEnd(s trace.ReadOnlySpan) {
+	if s.SpanContext().IsSampled() {
+		p.next.OnEnd(s)
+		return
+	}
+
+	id := s.SpanContext().TraceID()
+	localRoot := !s.Parent().IsValid() || s.Parent().IsRemote()
+
+	p.mu.Lock()
+	pt, ok := p.pending[id]
+	if !ok {
+		if len(p.pending) >= maxPendingTraces && !localRoot {
+			p.mu.Unlock()
+			return
+		}
+		pt = &pendingTrace{started: time.Now()}
+		p.pending[id] = pt
+	}
+	pt.spans = append(pt.spans, s)
+	if !localRoot {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.pending, id)
+	p.sweep()
+	p.mu.Unlock()
+
+	if !keep(s, pt.spans) {
+		return
+	}
+	for _, span := range pt.spans {
+		p.next.OnEnd(sampledSpan{span})
+	}
+}
+
+// keep reports whether a trace is worth exporting despite not being sampled
+func keep(root trace.ReadOnlySpan, spans []trace.ReadOnlySpan) bool {
+	if threshold := SlowThreshold(); threshold > 0 && root.EndTime().Sub(root.StartTime()) >= threshold {
+		return true
+	}
+	for _, s := range spans {
+		if s.Status().Code == codes.Error {
+			return true
+		}
+	}
+	return false
+}
+
+// sweep drops traces left pending for too long. p.mu must be held.
+func (p *tailProcessor) sweep() {
+	if time.Since(p.lastSweep) < sweepInterval {
+		return
+	}
+	p.lastSweep = time.Now()
+	for id, pt := range p.pending {
+		if time.Since(pt.started) > pendingTTL {
+			delete(p.pending, id)
+		}
+	}
+}
+
+func (p *tailProcessor) Shutdown(ctx context.Context) error {
+	return p.next.Shutdown(ctx)
+}
+
+func (p *tailProcessor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
+
+// sampledSpan marks a kept span as sampled so the exporter pipeline takes it
+type sampledSpan struct {
+	trace.ReadOnlySpan
+}
+
+func (s sampledSpan) SpanContext() oteltrace.SpanContext {
+	sc := s.ReadOnlySpan.SpanContext()
+	return sc.WithTraceFlags(sc.TraceFlags().WithSampled(true))
+}
@@ -0,0 +1,94 @@
+// Package telemetry configures OpenTelemetry tracing and metrics for the
+// services.
+package telemetry
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/zipkin"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+// DefaultZipkinEndpoint is the collector spans are exported to unless
+// OTEL_EXPORTER_ZIPKIN_ENDPOINT names another.
+const DefaultZipkinEndpoint = "http://zipkin:9411/api/v2/spans"
+
+// ZipkinEndpoint returns the collector spans are exported to.
+func ZipkinEndpoint() string {
+	if v := os.Getenv("OTEL_EXPORTER_ZIPKIN_ENDPOINT"); v != "" {
+		return v
+	}
+	return DefaultZipkinEndpoint
+}
+
+// ServiceVersion is reported on every span and metric, and on /status.
+const ServiceVersion = "1.0.0"
+
+// InitTracer installs a Zipkin-backed tracer provider for serviceName as the
+// global provider and returns a function that flushes and shuts it down.
+func InitTracer(serviceName string) func() {
+	// Create Zipkin exporter
+	exporter, err := zipkin.New(ZipkinEndpoint())
+	if err != nil {
+		log.Fatalf("Failed to create Zipkin exporter: %v", err)
+	}
+
+	// Create tracer provider
+	tp := trace.NewTracerProvider(
+		trace.WithSpanProcessor(newTailProcessor(trace.NewBatchSpanProcessor(redactingExporter{exporter}))),
+		trace.WithSampler(sampler),
+		trace.WithResource(newResource(serviceName)),
+	)
+
+	otel.SetTracerProvider(tp)
+
+	return func() {
+		if err := tp.Shutdown(context.Background()); err != nil {
+			log.Printf("Error shutting down tracer provider: %v", err)
+		}
+	}
+}
+
+// newResource describes serviceName, in the environment and region it is
+// deployed to, plus whatever OTEL_RESOURCE_ATTRIBUTES adds or overrides.
+// The service's name and version always come from the code.
+func newResource(serviceName string) *resource.Resource {
+	res, err := resource.New(context.Background(),
+		resource.WithAttributes(deploymentAttributes()...),
+		resource.WithFromEnv(),
+		resource.WithAttributes(
+			semconv.ServiceNameKey.String(serviceName),
+			semconv.ServiceVersionKey.String(ServiceVersion),
+		),
+	)
+	if err != nil {
+		log.Fatalf("Failed to create resource: %v", err)
+	}
+	return res
+}
+
+// deploymentAttributes tells staging from production from the variables
+// the services already get: APP_ENV, and CLOUD_REGION or the AWS_REGION
+// Lambda sets
+func deploymentAttributes() []attribute.KeyValue {
+	env := os.Getenv("APP_ENV")
+	if env == "" {
+		env = "development"
+	}
+	attrs := []attribute.KeyValue{semconv.DeploymentEnvironmentKey.String(env)}
+
+	region := os.Getenv("CLOUD_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region != "" {
+		attrs = append(attrs, semconv.CloudRegionKey.String(region))
+	}
+	return attrs
+}
@@ -0,0 +1,87 @@
+// Package temperature converts between temperature scales.
+package temperature
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Unit is a temperature scale, named by its symbol.
+type Unit string
+
+const (
+	Celsius    Unit = "C"
+	Fahrenheit Unit = "F"
+	Kelvin     Unit = "K"
+	Rankine    Unit = "R"
+)
+
+// Exact offsets of the absolute scales
+const (
+	absoluteZeroC = 273.15
+	absoluteZeroF = 459.67
+)
+
+// DefaultUnits are the scales returned when a request does not pick any.
+var DefaultUnits = []Unit{Celsius, Fahrenheit, Kelvin}
+
+// FromCelsius returns celsius expressed in u.
+func (u Unit) FromCelsius(celsius float64) float64 {
+	switch u {
+	case Fahrenheit:
+		return celsius*9/5 + 32
+	case Kelvin:
+		return celsius + absoluteZeroC
+	case Rankine:
+		return (celsius + absoluteZeroC) * 9 / 5
+	default:
+		return celsius
+	}
+}
+
+// ToCelsius returns v, expressed in u, in Celsius.
+func (u Unit) ToCelsius(v float64) float64 {
+	switch u {
+	case Fahrenheit:
+		return (v - 32) * 5 / 9
+	case Kelvin:
+		return v - absoluteZeroC
+	case Rankine:
+		return v*5/9 - absoluteZeroC
+	default:
+		return v
+	}
+}
+
+// ParseUnits reads a comma-separated list of unit symbols such as "C,F,R",
+// ignoring case, blanks and duplicates.
+func ParseUnits(spec string) ([]Unit, error) {
+	var units []Unit
+	seen := map[Unit]bool{}
+	for _, symbol := range strings.Split(spec, ",") {
+		u := Unit(strings.ToUpper(strings.TrimSpace(symbol)))
+		if u == "" {
+			continue
+		}
+		switch u {
+		case Celsius, Fahrenheit, Kelvin, Rankine:
+		default:
+			return nil, fmt.Errorf("unknown unit %q (expected C, F, K or R)", symbol)
+		}
+		if !seen[u] {
+			seen[u] = true
+			units = append(units, u)
+		}
+	}
+	return units, nil
+}
+
+// MaxPrecision is the most decimal places Round keeps.
+const MaxPrecision = 6
+
+// Round rounds v to the given number of decimal places.
+func Round(v float64, precision int) float64 {
+	p := math.Pow10(precision)
+	return math.Round(v*p) / p
+}
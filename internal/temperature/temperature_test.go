@@ -0,0 +1,179 @@
+package temperature
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+var units = []Unit{Celsius, Fahrenheit, Kelvin, Rankine}
+
+// physical draws temperatures from absolute zero to 10 000 °C, where
+// testing/quick's own floats would mostly overflow the scales
+func physical(args []reflect.Value, r *rand.Rand) {
+	for i := range args {
+		args[i] = reflect.ValueOf(r.Float64()*(10000+absoluteZeroC) - absoluteZeroC)
+	}
+}
+
+// near reports whether a and b agree to about nine significant digits
+func near(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+}
+
+func TestFromCelsius(t *testing.T) {
+	tests := []struct {
+		celsius float64
+		unit    Unit
+		want    float64
+	}{
+		{0, Celsius, 0},
+		{0, Fahrenheit, 32},
+		{0, Kelvin, 273.15},
+		{0, Rankine, 491.67},
+		{100, Fahrenheit, 212},
+		{100, Kelvin, 373.15},
+		{100, Rankine, 671.67},
+		{-40, Fahrenheit, -40},
+		{-273.15, Kelvin, 0},
+		{-273.15, Rankine, 0},
+		{28.5, Fahrenheit, 83.3},
+		{28.5, Kelvin, 301.65},
+	}
+	for _, tt := range tests {
+		if got := tt.unit.FromCelsius(tt.celsius); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s.FromCelsius(%v) = %v, want %v", tt.unit, tt.celsius, got, tt.want)
+		}
+	}
+}
+
+func TestConversionProperties(t *testing.T) {
+	cfg := &quick.Config{Values: physical}
+	for _, u := range units {
+		t.Run(string(u), func(t *testing.T) {
+			roundTrip := func(c float64) bool { return near(u.ToCelsius(u.FromCelsius(c)), c) }
+			if err := quick.Check(roundTrip, cfg); err != nil {
+				t.Errorf("C to %s and back: %v", u, err)
+			}
+			ordered := func(a, b float64) bool {
+				if a > b {
+					a, b = b, a
+				}
+				return u.FromCelsius(a) <= u.FromCelsius(b)
+			}
+			if err := quick.Check(ordered, cfg); err != nil {
+				t.Errorf("%s is not monotonic: %v", u, err)
+			}
+		})
+	}
+
+	// The absolute scales are the relative ones shifted to absolute zero
+	shifted := func(c float64) bool {
+		return near(Rankine.FromCelsius(c), Fahrenheit.FromCelsius(c)+absoluteZeroF) &&
+			near(Rankine.FromCelsius(c), Kelvin.FromCelsius(c)*9/5) &&
+			Kelvin.FromCelsius(c) >= 0
+	}
+	if err := quick.Check(shifted, cfg); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseUnits(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []Unit
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"C", []Unit{Celsius}, false},
+		{"c,f,k,r", []Unit{Celsius, Fahrenheit, Kelvin, Rankine}, false},
+		{" R , C ", []Unit{Rankine, Celsius}, false},
+		{"F,F,f", []Unit{Fahrenheit}, false},
+		{"C,,K", []Unit{Celsius, Kelvin}, false},
+		{"C,X", nil, true},
+		{"celsius", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseUnits(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseUnits(%q) error = %v, want error: %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseUnits(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParseUnitsProperties(t *testing.T) {
+	// Specs made of valid symbols in any case, spacing and repetition
+	symbols := []string{"c", "C", " F", "k ", "R", "r", ""}
+	cfg := &quick.Config{Values: func(args []reflect.Value, r *rand.Rand) {
+		parts := make([]string, r.Intn(8))
+		for i := range parts {
+			parts[i] = symbols[r.Intn(len(symbols))]
+		}
+		args[0] = reflect.ValueOf(strings.Join(parts, ","))
+	}}
+
+	// Parsing what was parsed changes nothing, and leaves no duplicates
+	idempotent := func(spec string) bool {
+		parsed, err := ParseUnits(spec)
+		if err != nil {
+			return false
+		}
+		names := make([]string, len(parsed))
+		seen := map[Unit]bool{}
+		for i, u := range parsed {
+			if seen[u] {
+				return false
+			}
+			seen[u] = true
+			names[i] = string(u)
+		}
+		again, err := ParseUnits(strings.Join(names, ","))
+		return err == nil && reflect.DeepEqual(again, parsed)
+	}
+	if err := quick.Check(idempotent, cfg); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		v         float64
+		precision int
+		want      float64
+	}{
+		{21.456789, 0, 21},
+		{21.456789, 1, 21.5},
+		{21.456789, 2, 21.46},
+		{21.456789, MaxPrecision, 21.456789},
+		{-0.05, 1, -0.1},
+		{0.5, 0, 1},
+		{294.65, 1, 294.7},
+	}
+	for _, tt := range tests {
+		if got := Round(tt.v, tt.precision); got != tt.want {
+			t.Errorf("Round(%v, %d) = %v, want %v", tt.v, tt.precision, got, tt.want)
+		}
+	}
+}
+
+func TestRoundProperties(t *testing.T) {
+	cfg := &quick.Config{Values: func(args []reflect.Value, r *rand.Rand) {
+		args[0] = reflect.ValueOf(r.Float64()*20000 - 10000)
+		args[1] = reflect.ValueOf(r.Intn(MaxPrecision + 1))
+	}}
+	// Rounding twice is rounding once, and moves v by at most half a step
+	property := func(v float64, precision int) bool {
+		once := Round(v, precision)
+		return Round(once, precision) == once && math.Abs(once-v) <= 0.5*math.Pow10(-precision)+1e-9
+	}
+	if err := quick.Check(property, cfg); err != nil {
+		t.Error(err)
+	}
+}
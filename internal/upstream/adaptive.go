@@ -0,0 +1,137 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// ErrConcurrencyLimit is returned when an upstream already has as many calls
+// in flight as its adaptive limit currently allows.
+var ErrConcurrencyLimit = errors.New("upstream concurrency limit reached")
+
+// baselineWindow is how long the fastest call sets the baseline latency, so
+// a provider that got slower for good is judged by its new pace a window or
+// two later
+const baselineWindow = time.Minute
+
+// AdaptiveLimits bounds an adaptive concurrency limit.
+type AdaptiveLimits struct {
+	Initial int
+	Min     int
+	Max     int
+	// Tolerance is how many times the provider's baseline latency a call may
+	// take before it counts as a sign of overload, e.g. 2.
+	Tolerance float64
+}
+
+// adaptive limits a provider's concurrent calls with AIMD: every call that
+// comes back in time while the limit is in use raises it by one, and every
+// overload sign (a failure, a 429 or 5xx, or a latency past Tolerance times
+// the baseline, the latency when unloaded) cuts it by a tenth. The limit so
+// follows what the provider can take now instead of a figure tuned for how
+// it once behaved.
+type adaptive struct {
+	next     http.RoundTripper
+	limits   AdaptiveLimits
+	provider attribute.KeyValue
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	// baseline is the fastest call of the previous and current windows, in
+	// seconds; 0 until the first call
+	baseline    float64
+	windowMin   float64
+	windowStart time.Time
+
+	rejected metric.Int64Counter
+}
+
+// WithAdaptiveLimit wraps next so provider's calls are limited adaptively
+// within limits; calls over the limit fail right away with
+// ErrConcurrencyLimit, since waiting would only add to the overload.
+func WithAdaptiveLimit(provider string, limits AdaptiveLimits, next http.RoundTripper) http.RoundTripper {
+	a := &adaptive{next: next, limits: limits, provider: attribute.String("provider", provider), limit: float64(limits.Initial)}
+
+	meter := otel.Meter(meterScope)
+	a.rejected, _ = meter.Int64Counter("upstream.concurrency.rejected",
+		metric.WithDescription("Upstream calls refused by the provider's adaptive concurrency limit"))
+	gauge, _ := meter.Int64ObservableGauge("upstream.concurrency.limit",
+		metric.WithDescription("Concurrent calls the adaptive limiter currently allows, by provider"))
+	meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		o.ObserveInt64(gauge, int64(a.limit), metric.WithAttributes(a.provider))
+		return nil
+	}, gauge)
+
+	return a
+}
+
+func (a *adaptive) RoundTrip(req *http.Request) (*http.Response, error) {
+	a.mu.Lock()
+	if a.inFlight >= int(a.limit) {
+		limit := int(a.limit)
+		a.mu.Unlock()
+		a.rejected.Add(context.WithoutCancel(req.Context()), 1, metric.WithAttributes(a.provider))
+		return nil, fmt.Errorf("%s: %w (%d)", a.provider.Value.AsString(), ErrConcurrencyLimit, limit)
+	}
+	a.inFlight++
+	// Only a limit that is being used has earned a raise
+	busy := float64(a.inFlight*2) >= a.limit
+	admittedAt := a.limit
+	a.mu.Unlock()
+
+	start := time.Now()
+	resp, err := a.next.RoundTrip(req)
+	latency := time.Since(start).Seconds()
+
+	// A call the client gave up on says nothing about the provider
+	canceled := err != nil && (errors.Is(err, context.Canceled) || req.Context().Err() != nil)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.inFlight--
+	if canceled {
+		return resp, err
+	}
+
+	overloaded := err != nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError ||
+		(a.baseline > 0 && latency > a.limits.Tolerance*a.baseline)
+	switch {
+	case overloaded:
+		// Calls caught in the same overload cut the limit once, not each
+		if a.limit >= admittedAt {
+			a.limit = max(a.limit*0.9, float64(a.limits.Min))
+		}
+	case busy:
+		a.limit = min(a.limit+1, float64(a.limits.Max))
+	}
+	if err == nil {
+		a.observe(start, latency)
+	}
+	return resp, err
+}
+
+// observe feeds a call's latency to the baseline
+func (a *adaptive) observe(at time.Time, latency float64) {
+	if at.Sub(a.windowStart) >= baselineWindow {
+		// The window ends: its fastest call is the baseline for the next one
+		if a.windowMin > 0 {
+			a.baseline = a.windowMin
+		}
+		a.windowMin, a.windowStart = latency, at
+	}
+	a.windowMin = min(a.windowMin, latency)
+	if a.baseline == 0 || latency < a.baseline {
+		a.baseline = latency
+	}
+}
@@ -0,0 +1,135 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// ErrBulkheadFull is returned when an upstream already has as many calls in
+// flight as its bulkhead allows and no slot freed up in time.
+var ErrBulkheadFull = errors.New("too many concurrent upstream calls")
+
+// ParseLimits parses per-provider concurrency limits like
+// "viacep=16,weatherapi=64".
+func ParseLimits(v string) (map[string]int, error) {
+	limits := make(map[string]int)
+	if v == "" {
+		return limits, nil
+	}
+	for _, pair := range strings.Split(v, ",") {
+		name, limit, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		n, err := strconv.Atoi(limit)
+		if !ok || name == "" || err != nil || n < 0 {
+			return nil, fmt.Errorf("expected provider=limit, got %q", pair)
+		}
+		limits[name] = n
+	}
+	return limits, nil
+}
+
+// bulkhead lets at most cap(slots) calls to one provider run at a time, so a
+// slow provider ties up its own slots and not every goroutine and
+// connection service B has
+type bulkhead struct {
+	next     http.RoundTripper
+	slots    chan struct{}
+	wait     time.Duration
+	provider attribute.KeyValue
+
+	inFlight metric.Int64UpDownCounter
+	rejected metric.Int64Counter
+}
+
+// WithBulkhead wraps next so at most limit of provider's calls are in
+// flight; a call waits up to wait for a slot and then fails with
+// ErrBulkheadFull. A limit of 0 leaves next unbounded.
+func WithBulkhead(provider string, limit int, wait time.Duration, next http.RoundTripper) http.RoundTripper {
+	if limit == 0 {
+		return next
+	}
+
+	meter := otel.Meter(meterScope)
+	inFlight, _ := meter.Int64UpDownCounter("upstream.bulkhead.in_flight",
+		metric.WithDescription("Upstream calls holding a bulkhead slot, by provider"))
+	rejected, _ := meter.Int64Counter("upstream.bulkhead.rejected",
+		metric.WithDescription("Upstream calls refused because the provider's bulkhead was full"))
+
+	return &bulkhead{
+		next:     next,
+		slots:    make(chan struct{}, limit),
+		wait:     wait,
+		provider: attribute.String("provider", provider),
+		inFlight: inFlight,
+		rejected: rejected,
+	}
+}
+
+func (b *bulkhead) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := context.WithoutCancel(req.Context())
+	if err := b.acquire(req.Context()); err != nil {
+		if errors.Is(err, ErrBulkheadFull) {
+			b.rejected.Add(ctx, 1, metric.WithAttributes(b.provider))
+		}
+		return nil, err
+	}
+
+	b.inFlight.Add(ctx, 1, metric.WithAttributes(b.provider))
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			<-b.slots
+			b.inFlight.Add(ctx, -1, metric.WithAttributes(b.provider))
+		})
+	}
+
+	resp, err := b.next.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	// The call holds its slot until the body is read and closed
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// acquire takes a slot, waiting for one up to b.wait
+func (b *bulkhead) acquire(ctx context.Context) error {
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	timer := time.NewTimer(b.wait)
+	defer timer.Stop()
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("%s: %w", b.provider.Value.AsString(), ErrBulkheadFull)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releasingBody frees a bulkhead slot when closed
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
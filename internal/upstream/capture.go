@@ -0,0 +1,193 @@
+package upstream
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/offerni/weathercheck/internal/privacy"
+)
+
+// captureLimit is how much of each body a capture keeps
+const captureLimit = 64 << 10
+
+// redacted stands in for secrets in captures
+const redacted = "[REDACTED]"
+
+// secretParams and secretHeaders are always redacted, whatever their value
+var (
+	secretParams  = map[string]bool{"key": true, "api_key": true, "apikey": true, "token": true, "access_token": true}
+	secretHeaders = map[string]bool{"Authorization": true, "Proxy-Authorization": true, "Cookie": true, "Set-Cookie": true, "X-Api-Key": true}
+)
+
+// capturing logs the full request and response of a sample of calls
+type capturing struct {
+	next     http.RoundTripper
+	provider string
+	ratio    float64
+	redact   *Redactor
+	logger   *log.Logger
+}
+
+// Redactor hides credentials in what is kept of upstream calls: the usual
+// API key parameters and auth headers, the headers it was given and every
+// secret value, wherever it shows up. With personal data redaction on, it
+// also redacts CEPs and the addresses in answers such as ViaCEP's.
+type Redactor struct {
+	secrets  *strings.Replacer
+	hidden   map[string]bool
+	personal *privacy.Redactor
+}
+
+// NewRedactor redacts secrets and the headers named in headers, and
+// personal data as personal says.
+func NewRedactor(secrets, headers []string, personal *privacy.Redactor) *Redactor {
+	var pairs []string
+	for _, s := range secrets {
+		if s != "" {
+			pairs = append(pairs, s, redacted)
+		}
+	}
+	hidden := make(map[string]bool, len(secretHeaders)+len(headers))
+	for name := range secretHeaders {
+		hidden[name] = true
+	}
+	for _, name := range headers {
+		hidden[http.CanonicalHeaderKey(name)] = true
+	}
+	return &Redactor{secrets: strings.NewReplacer(pairs...), hidden: hidden, personal: personal}
+}
+
+// WithCapture wraps next so ratio of provider's calls, 0 to 1, are logged
+// in full, bodies included, for debugging changes on the provider's side,
+// once redact has hidden their credentials. A ratio of 0 captures nothing.
+func WithCapture(provider string, ratio float64, redact *Redactor, logger *log.Logger, next http.RoundTripper) http.RoundTripper {
+	if ratio == 0 {
+		return next
+	}
+	return &capturing{next: next, provider: provider, ratio: ratio, redact: redact, logger: logger}
+}
+
+func (t *capturing) RoundTrip(req *http.Request) (*http.Response, error) {
+	if rand.Float64() >= t.ratio {
+		return t.next.RoundTrip(req)
+	}
+
+	var reqBody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		reqBody = body
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	span := oteltrace.SpanFromContext(req.Context())
+	span.AddEvent("upstream.captured")
+	prefix := "Upstream capture " + t.provider
+	if sc := span.SpanContext(); sc.HasTraceID() {
+		prefix += " trace=" + sc.TraceID().String()
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	reqLine := req.Method + " " + t.redact.URL(req.URL)
+	if err != nil {
+		t.logger.Printf("%s: %s failed after %s: %s\n  request headers: %s\n  request body: %s",
+			prefix, reqLine, elapsed, t.redact.String(err.Error()), t.redact.Header(req.Header), t.redact.Body(reqBody))
+		return nil, err
+	}
+
+	respBody, readErr := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	body := io.Reader(bytes.NewReader(respBody))
+	if readErr != nil {
+		// The caller still gets the failed read, after what did arrive
+		body = io.MultiReader(body, errReader{readErr})
+	}
+	resp.Body = io.NopCloser(body)
+
+	t.logger.Printf("%s: %s -> %d in %s\n  request headers: %s\n  request body: %s\n  response headers: %s\n  response body: %s",
+		prefix, reqLine, resp.StatusCode, elapsed, t.redact.Header(req.Header), t.redact.Body(reqBody), t.redact.Header(resp.Header), t.redact.Body(respBody))
+	return resp, nil
+}
+
+// URL returns u without credentials.
+func (r *Redactor) URL(u *url.URL) string {
+	query := u.Query()
+	for name := range query {
+		if secretParams[strings.ToLower(name)] {
+			query.Set(name, redacted)
+		}
+	}
+	clean := *u
+	clean.User = nil
+	clean.RawQuery = query.Encode()
+	// Encode escapes the placeholder's brackets; put them back
+	return r.String(strings.ReplaceAll(clean.String(), url.QueryEscape(redacted), redacted))
+}
+
+// Header returns h as sorted Name=value pairs, credentials hidden.
+func (r *Redactor) Header(h http.Header) string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		value := strings.Join(h[name], "; ")
+		if r.hidden[name] {
+			value = redacted
+		}
+		b.WriteString(name + "=" + r.String(value))
+	}
+	return b.String()
+}
+
+// Body returns body up to captureLimit, secrets hidden.
+func (r *Redactor) Body(body []byte) string {
+	if len(body) == 0 {
+		return "(empty)"
+	}
+	if r.personal.Enabled() {
+		// Only JSON answers can have their addresses found and redacted
+		if !json.Valid(body) {
+			return "(not JSON, omitted in privacy mode)"
+		}
+		body = r.personal.JSON(body)
+	}
+	if len(body) > captureLimit {
+		return r.String(string(body[:captureLimit])) + "... (truncated)"
+	}
+	return r.String(string(body))
+}
+
+// String hides the secret values and CEPs in s.
+func (r *Redactor) String(s string) string {
+	return r.personal.String(r.secrets.Replace(s))
+}
+
+// errReader fails every read with err
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,109 @@
+package upstream
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/offerni/weathercheck/internal/privacy"
+)
+
+// roundTripFunc adapts a function to http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// answering stands in for an upstream that always answers body
+func answering(contentType, body string) http.RoundTripper {
+	return roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {contentType}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    r,
+		}, nil
+	})
+}
+
+const viaCEPAnswer = `{"cep":"01001-000","logradouro":"Praça da Sé","complemento":"lado ímpar","bairro":"Sé","localidade":"São Paulo","uf":"SP"}`
+
+func TestCaptureRedaction(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        privacy.Mode
+		contentType string
+		body        string
+		want        []string
+		notWant     []string
+	}{
+		{
+			name:        "off keeps the answer",
+			mode:        privacy.Off,
+			contentType: "application/json",
+			body:        viaCEPAnswer,
+			want:        []string{"Praça da Sé", "01001000", "São Paulo"},
+			notWant:     []string{"s3cret"},
+		},
+		{
+			name:        "truncate strips addresses",
+			mode:        privacy.Truncate,
+			contentType: "application/json",
+			body:        viaCEPAnswer,
+			want:        []string{"São Paulo", "01001***"},
+			notWant:     []string{"Praça", "lado ímpar", `"Sé"`, "01001-000", "01001000", "s3cret"},
+		},
+		{
+			name:        "hash strips addresses",
+			mode:        privacy.Hash,
+			contentType: "application/json",
+			body:        viaCEPAnswer,
+			want:        []string{"São Paulo", "addr:"},
+			notWant:     []string{"Praça", "01001-000", "01001000"},
+		},
+		{
+			name:        "non-JSON body withheld",
+			mode:        privacy.Truncate,
+			contentType: "text/html",
+			body:        "<p>Praça da Sé</p>",
+			want:        []string{"omitted in privacy mode"},
+			notWant:     []string{"Praça"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			personal, err := privacy.New(tt.mode, "salt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var logs bytes.Buffer
+			redact := NewRedactor([]string{"s3cret"}, nil, personal)
+			rt := WithCapture("viacep", 1, redact, log.New(&logs, "", 0), answering(tt.contentType, tt.body))
+
+			req, _ := http.NewRequest(http.MethodGet, "https://viacep.com.br/ws/01001000/json/?key=s3cret", nil)
+			resp, err := rt.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("RoundTrip: %v", err)
+			}
+			// The caller still gets the answer as it came
+			if got, _ := io.ReadAll(resp.Body); string(got) != tt.body {
+				t.Errorf("body = %s, want %s", got, tt.body)
+			}
+
+			for _, s := range tt.want {
+				if !strings.Contains(logs.String(), s) {
+					t.Errorf("capture lacks %q:\n%s", s, logs.String())
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(logs.String(), s) {
+					t.Errorf("capture has %q:\n%s", s, logs.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+// Package upstream builds the network plumbing service B uses to reach its
+// external APIs.
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Family picks which IP versions upstream connections use.
+type Family string
+
+const (
+	AnyFamily  Family = "any"
+	PreferIPv4 Family = "prefer-ipv4"
+	PreferIPv6 Family = "prefer-ipv6"
+	OnlyIPv4   Family = "ipv4"
+	OnlyIPv6   Family = "ipv6"
+	meterScope        = "github.com/offerni/weathercheck/internal/upstream"
+)
+
+// dialBuckets spans local connections up to slow cross-region handshakes, in
+// seconds
+var dialBuckets = []float64{0.0005, 0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// ParseFamily validates an UPSTREAM_IP_FAMILY value.
+func ParseFamily(v string) (Family, error) {
+	switch f := Family(v); f {
+	case AnyFamily, PreferIPv4, PreferIPv6, OnlyIPv4, OnlyIPv6:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown IP family %q (expected any, prefer-ipv4, prefer-ipv6, ipv4 or ipv6)", v)
+	}
+}
+
+// Dialer connects to upstream hosts with happy eyeballs (RFC 8305): it dials
+// the preferred family first and races the other one after the fallback
+// delay. Every dial is measured by family and outcome.
+type Dialer struct {
+	family        Family
+	fallbackDelay time.Duration
+	lookup        func(ctx context.Context, host string) ([]string, error)
+	dialer        net.Dialer
+
+	dials     metric.Float64Histogram
+	open      metric.Int64UpDownCounter
+	fallbacks metric.Int64Counter
+}
+
+// NewDialer returns a Dialer resolving hosts with lookup (nil uses the
+// system resolver). A negative fallbackDelay disables racing: addresses are
+// tried one after the other.
+func NewDialer(family Family, fallbackDelay time.Duration, lookup func(ctx context.Context, host string) ([]string, error)) *Dialer {
+	if lookup == nil {
+		lookup = net.DefaultResolver.LookupHost
+	}
+
+	meter := otel.Meter(meterScope)
+	dials, _ := meter.Float64Histogram("upstream.dial.duration",
+		metric.WithDescription("Time to open a TCP connection to an upstream, by IP family and outcome"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(dialBuckets...))
+	open, _ := meter.Int64UpDownCounter("upstream.connections.open",
+		metric.WithDescription("Open TCP connections to upstreams, by IP family"))
+	fallbacks, _ := meter.Int64Counter("upstream.dial.fallbacks",
+		metric.WithDescription("Connections won by the fallback IP family"))
+
+	return &Dialer{
+		family:        family,
+		fallbackDelay: fallbackDelay,
+		lookup:        lookup,
+		dialer:        net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		dials:         dials,
+		open:          open,
+		fallbacks:     fallbacks,
+	}
+}
+
+// DialContext fits http.Transport.DialContext.
+func (d *Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err := d.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	primaries, fallbacks := d.partition(ips)
+	if len(primaries) == 0 {
+		return nil, fmt.Errorf("no %s addresses for %s", d.family, host)
+	}
+	if len(fallbacks) == 0 || d.fallbackDelay < 0 {
+		return d.dialSerial(ctx, network, append(primaries, fallbacks...), port)
+	}
+	return d.dialParallel(ctx, network, primaries, fallbacks, port)
+}
+
+// partition splits ips into the family to dial first and the fallback
+func (d *Dialer) partition(ips []string) (primaries, fallbacks []string) {
+	var v4, v6 []string
+	for _, ip := range ips {
+		if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() != nil {
+			v4 = append(v4, ip)
+		} else {
+			v6 = append(v6, ip)
+		}
+	}
+
+	switch d.family {
+	case OnlyIPv4:
+		return v4, nil
+	case OnlyIPv6:
+		return v6, nil
+	case PreferIPv4:
+		if len(v4) == 0 {
+			return v6, nil
+		}
+		return v4, v6
+	case PreferIPv6:
+		if len(v6) == 0 {
+			return v4, nil
+		}
+		return v6, v4
+	default:
+		// Follow the resolver's order, like the standard library
+		if len(ips) > 0 && familyOf(ips[0]) == "ipv6" {
+			return v6, v4
+		}
+		return v4, v6
+	}
+}
+
+// dialParallel dials primaries, starting fallbacks after the fallback delay
+// or as soon as the primaries fail, and returns the first connection
+func (d *Dialer) dialParallel(ctx context.Context, network string, primaries, fallbacks []string, port string) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		conn     net.Conn
+		err      error
+		fallback bool
+	}
+	results := make(chan result, 2)
+	dial := func(ips []string, fallback bool) {
+		conn, err := d.dialSerial(ctx, network, ips, port)
+		results <- result{conn: conn, err: err, fallback: fallback}
+	}
+
+	go dial(primaries, false)
+	timer := time.NewTimer(d.fallbackDelay)
+	defer timer.Stop()
+
+	pending, fallbackStarted := 1, false
+	var firstErr error
+	for {
+		select {
+		case <-timer.C:
+			if !fallbackStarted {
+				fallbackStarted = true
+				pending++
+				go dial(fallbacks, true)
+			}
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				// Close the loser if it connects too
+				go func(pending int) {
+					for ; pending > 0; pending-- {
+						if late := <-results; late.conn != nil {
+							late.conn.Close()
+						}
+					}
+				}(pending)
+				if res.fallback {
+					d.fallbacks.Add(ctx, 1)
+				}
+				return res.conn, nil
+			}
+			if firstErr == nil {
+				firstErr = res.err
+			}
+			if !fallbackStarted {
+				fallbackStarted = true
+				pending++
+				go dial(fallbacks, true)
+				continue
+			}
+			if pending == 0 {
+				return nil, firstErr
+			}
+		}
+	}
+}
+
+// dialSerial tries ips in order
+func (d *Dialer) dialSerial(ctx context.Context, network string, ips []string, port string) (net.Conn, error) {
+	var errs []error
+	for _, ip := range ips {
+		family := familyOf(ip)
+		start := time.Now()
+		conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+		outcome := "ok"
+		if err != nil {
+			outcome = "error"
+		}
+		d.dials.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(
+			attribute.String("family", family), attribute.String("outcome", outcome)))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		attrs := metric.WithAttributes(attribute.String("family", family))
+		d.open.Add(ctx, 1, attrs)
+		return &trackedConn{Conn: conn, onClose: func() { d.open.Add(context.Background(), -1, attrs) }}, nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+func familyOf(ip string) string {
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() != nil {
+		return "ipv4"
+	}
+	return "ipv6"
+}
+
+// trackedConn runs onClose once when the connection is closed
+type trackedConn struct {
+	net.Conn
+	once    sync.Once
+	onClose func()
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(c.onClose)
+	return c.Conn.Close()
+}
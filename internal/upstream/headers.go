@@ -0,0 +1,78 @@
+package upstream
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Headers are extra request headers by provider; those under "" go to every
+// provider.
+type Headers map[string]http.Header
+
+// ParseHeaders parses extra upstream headers like
+// "X-Gateway-Token=abc,weatherapi:X-Contract-Id=42": a header is sent to
+// every provider unless its name is prefixed with one.
+func ParseHeaders(v string) (Headers, error) {
+	headers := Headers{}
+	if v == "" {
+		return headers, nil
+	}
+	for _, pair := range strings.Split(v, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		provider, name, scoped := strings.Cut(key, ":")
+		if !scoped {
+			provider, name = "", key
+		}
+		if !ok || name == "" || (scoped && provider == "") || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("expected [provider:]Header=value, got %q", pair)
+		}
+		if headers[provider] == nil {
+			headers[provider] = http.Header{}
+		}
+		headers[provider].Add(name, value)
+	}
+	return headers, nil
+}
+
+// For returns the headers provider's requests get: the shared ones, then its
+// own, which replace shared headers of the same name.
+func (h Headers) For(provider string) http.Header {
+	header := h[""].Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	for name, values := range h[provider] {
+		header[name] = values
+	}
+	return header
+}
+
+// withHeaders sets an identifying User-Agent and the configured headers on
+// every request
+type withHeaders struct {
+	next      http.RoundTripper
+	userAgent string
+	header    http.Header
+}
+
+// WithHeaders wraps next so each request carries userAgent and header,
+// which provider contracts and enterprise gateways may require.
+func WithHeaders(userAgent string, header http.Header, next http.RoundTripper) http.RoundTripper {
+	if userAgent == "" && len(header) == 0 {
+		return next
+	}
+	return &withHeaders{next: next, userAgent: userAgent, header: header}
+}
+
+func (t *withHeaders) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must leave the caller's request alone
+	req = req.Clone(req.Context())
+	if t.userAgent != "" {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	for name, values := range t.header {
+		req.Header[name] = values
+	}
+	return t.next.RoundTrip(req)
+}
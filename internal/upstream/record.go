@@ -0,0 +1,96 @@
+package upstream
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Call is one upstream call made while serving a request, credentials
+// redacted.
+type Call struct {
+	Provider   string  `json:"provider"`
+	Method     string  `json:"method"`
+	URL        string  `json:"url"`
+	Status     int     `json:"status,omitempty"`
+	Error      string  `json:"error,omitempty"`
+	Body       string  `json:"body,omitempty"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// Recording collects the upstream calls of one request.
+type Recording struct {
+	mu    sync.Mutex
+	calls []Call
+}
+
+type recordingKey struct{}
+
+// WithRecording returns a context whose upstream calls, through transports
+// wrapped with Record, are collected in the returned Recording.
+func WithRecording(ctx context.Context) (context.Context, *Recording) {
+	rec := &Recording{}
+	return context.WithValue(ctx, recordingKey{}, rec), rec
+}
+
+// Calls returns the calls made so far, in order.
+func (r *Recording) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+func (r *Recording) add(c Call) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, c)
+}
+
+// recording adds each call made under a Recording to it
+type recording struct {
+	next     http.RoundTripper
+	provider string
+	redact   *Redactor
+}
+
+// Record wraps next so provider's calls made under WithRecording are
+// collected, answer included, once redact has hidden their credentials.
+// Calls made without a Recording pass straight through.
+func Record(provider string, redact *Redactor, next http.RoundTripper) http.RoundTripper {
+	return &recording{next: next, provider: provider, redact: redact}
+}
+
+func (t *recording) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec, ok := req.Context().Value(recordingKey{}).(*Recording)
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+
+	call := Call{Provider: t.provider, Method: req.Method, URL: t.redact.URL(req.URL)}
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	call.DurationMS = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		call.Error = t.redact.String(err.Error())
+		rec.add(call)
+		return nil, err
+	}
+
+	body, readErr := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	reader := io.Reader(bytes.NewReader(body))
+	if readErr != nil {
+		reader = io.MultiReader(reader, errReader{readErr})
+	}
+	resp.Body = io.NopCloser(reader)
+
+	call.Status = resp.StatusCode
+	if len(body) > 0 {
+		call.Body = t.redact.Body(body)
+	}
+	rec.add(call)
+	return resp, nil
+}
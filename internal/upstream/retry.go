@@ -0,0 +1,151 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// retryReserve is how many retries the budget holds on to, so quiet periods
+// can still retry the odd failure
+const retryReserve = 10
+
+// RetryBudget caps retries across every upstream at a fraction of the
+// requests sent: each request earns that fraction of a retry and each retry
+// spends a whole one, so a degraded provider can't turn into a retry storm
+// that makes the outage worse. Share one budget between all providers.
+type RetryBudget struct {
+	ratio float64
+
+	mu     sync.Mutex
+	tokens float64
+}
+
+// NewRetryBudget allows retries up to ratio of the requests, e.g. 0.2 for
+// one retry per five requests. A ratio of 0 allows none past the reserve.
+func NewRetryBudget(ratio float64) *RetryBudget {
+	return &RetryBudget{ratio: ratio, tokens: retryReserve}
+}
+
+// deposit credits the budget for a request
+func (b *RetryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.tokens+b.ratio, retryReserve)
+}
+
+// withdraw spends one retry, reporting false when the budget is exhausted
+func (b *RetryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Retry tunes how upstream requests are retried.
+type Retry struct {
+	// MaxRetries is how many times a failed request is tried again.
+	MaxRetries int
+	// Backoff is the base delay before a retry, doubled after each one and
+	// jittered so clients don't retry in lockstep.
+	Backoff time.Duration
+}
+
+// retrying retries idempotent requests that failed in transit or got a 502,
+// 503 or 504, while the budget allows
+type retrying struct {
+	next     http.RoundTripper
+	policy   Retry
+	budget   *RetryBudget
+	provider attribute.KeyValue
+
+	retries metric.Int64Counter
+}
+
+// WithRetries wraps next so provider's failed requests are retried by
+// policy, within budget.
+func WithRetries(provider string, policy Retry, budget *RetryBudget, next http.RoundTripper) http.RoundTripper {
+	meter := otel.Meter(meterScope)
+	retries, _ := meter.Int64Counter("upstream.retries",
+		metric.WithDescription("Upstream retries, by provider and outcome (attempted, or denied by the retry budget)"))
+
+	return &retrying{next: next, policy: policy, budget: budget, provider: attribute.String("provider", provider), retries: retries}
+}
+
+func (t *retrying) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.budget.deposit()
+
+	resp, err := t.next.RoundTrip(req)
+	// Only requests that are safe to repeat and have no body to replay
+	if (req.Method != http.MethodGet && req.Method != http.MethodHead) || (req.Body != nil && req.Body != http.NoBody) {
+		return resp, err
+	}
+
+	ctx := context.WithoutCancel(req.Context())
+	for attempt := 1; attempt <= t.policy.MaxRetries && retryable(resp, err) && req.Context().Err() == nil; attempt++ {
+		if !t.budget.withdraw() {
+			t.retries.Add(ctx, 1, metric.WithAttributes(t.provider, attribute.String("outcome", "denied")))
+			break
+		}
+		t.retries.Add(ctx, 1, metric.WithAttributes(t.provider, attribute.String("outcome", "attempted")))
+
+		delay := t.policy.Backoff << (attempt - 1)
+		if delay > 0 {
+			delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		}
+		oteltrace.SpanFromContext(req.Context()).AddEvent("retry_attempt", oteltrace.WithAttributes(
+			t.provider,
+			attribute.Int("attempt", attempt),
+			attribute.Int64("backoff_ms", delay.Milliseconds()),
+			attribute.String("reason", retryReason(resp, err)),
+		))
+		select {
+		case <-req.Context().Done():
+			return resp, err
+		case <-time.After(delay):
+		}
+
+		// The failed answer is dropped for the new one
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		resp, err = t.next.RoundTrip(req)
+	}
+	return resp, err
+}
+
+// retryReason tells what made the attempt before a retry fail
+func retryReason(resp *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return strconv.Itoa(resp.StatusCode)
+}
+
+// retryable reports whether an attempt is worth repeating: transport
+// failures and gateway errors are, cancellations and other answers are not
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,87 @@
+package upstream
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Pool tunes the connection pool each provider gets to itself.
+type Pool struct {
+	// MaxIdleConnsPerHost is how many keep-alive connections stay open per
+	// host; the standard library's default of 2 forces new dials under load.
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost caps connections per host (0 is unlimited).
+	MaxConnsPerHost int
+	// IdleConnTimeout closes keep-alive connections left unused this long.
+	IdleConnTimeout time.Duration
+}
+
+// NewTransport returns a transport with its own pool, dialing through d.
+// Give each provider its own so one slow API can't starve the others'
+// connections, and wrap it with Instrument to see how the pool behaves.
+func NewTransport(pool Pool, d *Dialer) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = d.DialContext
+	t.MaxIdleConns = 0
+	t.MaxIdleConnsPerHost = pool.MaxIdleConnsPerHost
+	t.MaxConnsPerHost = pool.MaxConnsPerHost
+	t.IdleConnTimeout = pool.IdleConnTimeout
+	return t
+}
+
+// instrumented records, per provider, whether each request got a pooled
+// connection or a new one and how long TLS handshakes took
+type instrumented struct {
+	next     http.RoundTripper
+	provider attribute.KeyValue
+
+	conns     metric.Int64Counter
+	handshake metric.Float64Histogram
+}
+
+// Instrument wraps next with connection metrics labelled with provider.
+func Instrument(provider string, next http.RoundTripper) http.RoundTripper {
+	meter := otel.Meter(meterScope)
+	conns, _ := meter.Int64Counter("upstream.connections.acquired",
+		metric.WithDescription("Connections handed to upstream requests, by provider and whether they were reused from the pool"))
+	handshake, _ := meter.Float64Histogram("upstream.tls.handshake.duration",
+		metric.WithDescription("TLS handshake time for new upstream connections, by provider and outcome"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(dialBuckets...))
+
+	return &instrumented{
+		next:      next,
+		provider:  attribute.String("provider", provider),
+		conns:     conns,
+		handshake: handshake,
+	}
+}
+
+func (t *instrumented) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Metrics outlive the request, so don't let its cancellation drop them
+	ctx := context.WithoutCancel(req.Context())
+
+	var handshakeStart time.Time
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.conns.Add(ctx, 1, metric.WithAttributes(t.provider, attribute.Bool("reused", info.Reused)))
+		},
+		TLSHandshakeStart: func() { handshakeStart = time.Now() },
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			outcome := "ok"
+			if err != nil {
+				outcome = "error"
+			}
+			t.handshake.Record(ctx, time.Since(handshakeStart).Seconds(), metric.WithAttributes(
+				t.provider, attribute.String("outcome", outcome)))
+		},
+	}
+	return t.next.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+}
@@ -0,0 +1,165 @@
+package vcr
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+
+	"github.com/offerni/weathercheck/internal/cache"
+	"github.com/offerni/weathercheck/internal/cep"
+	"github.com/offerni/weathercheck/internal/httpapi"
+	"github.com/offerni/weathercheck/internal/weather"
+)
+
+// cassettes holds recorded ViaCEP and WeatherAPI exchanges: CEP 01001000,
+// São Paulo's forecast and an unknown location
+const cassettes = "testdata"
+
+// offline fails any request that reaches it, so replays can't dial out
+type offline struct{ t *testing.T }
+
+func (o offline) RoundTrip(r *http.Request) (*http.Response, error) {
+	o.t.Errorf("replay reached the network: %s", r.URL.Redacted())
+	return nil, errors.New("offline")
+}
+
+func replaying(t *testing.T) *http.Client {
+	t.Helper()
+	transport, err := NewTransport(ModeReplay, cassettes, offline{t})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &http.Client{Transport: transport}
+}
+
+func TestReplayClients(t *testing.T) {
+	client := replaying(t)
+	tracer := otel.Tracer("test")
+
+	address, err := cep.NewClient(client, cep.DefaultBaseURL, tracer).Lookup(context.Background(), "01001000")
+	if err != nil {
+		t.Fatalf("CEP lookup: %v", err)
+	}
+	if address.Localidade != "São Paulo" || address.UF != "SP" || address.IBGE != "3550308" {
+		t.Errorf("address = %+v", address)
+	}
+
+	// Recordings match whatever the key, since it is never stored
+	weatherClient := weather.NewClient(client, weather.DefaultBaseURL, "any-key", tracer)
+	conditions, err := weatherClient.Current(context.Background(), "São Paulo")
+	if err != nil {
+		t.Fatalf("weather: %v", err)
+	}
+	if conditions.TempC != 17.3 || conditions.Humidity != 88 || conditions.Condition != weather.ConditionRain {
+		t.Errorf("conditions = %+v", conditions)
+	}
+	if conditions.Today == nil || conditions.Today.ChanceOfRain != 86 || len(conditions.Hourly) != 3 {
+		t.Errorf("forecast = %+v, %+v", conditions.Today, conditions.Hourly)
+	}
+
+	if _, err := weatherClient.Current(context.Background(), "Atlântida"); !errors.Is(err, weather.ErrLocationNotFound) {
+		t.Errorf("unknown location error = %v, want %v", err, weather.ErrLocationNotFound)
+	}
+
+	if _, err := weatherClient.Current(context.Background(), "Curitiba"); !errors.Is(err, ErrNoFixture) {
+		t.Errorf("unrecorded request error = %v, want %v", err, ErrNoFixture)
+	}
+}
+
+func TestReplayWeatherHandler(t *testing.T) {
+	client := replaying(t)
+	tracer := otel.Tracer("test")
+	h := httpapi.NewWeatherHandler(
+		cep.NewClient(client, cep.DefaultBaseURL, tracer),
+		nil,
+		weather.NewClient(client, weather.DefaultBaseURL, "any-key", tracer),
+		httpapi.NewReadings(cache.Options{MaxEntries: 10}, 0),
+		1, tracer, log.New(io.Discard, "", 0),
+	)
+
+	r := httptest.NewRequest(http.MethodPost, "/weather?units=C,F", strings.NewReader(`{"cep":"01001000"}`))
+	r.Header.Set("Content-Type", httpapi.FormatJSON)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+
+	want := `{"city":"São Paulo","temp_C":17.3,"temp_F":63.1,"condition":"rain","icon":"cloud-rain","feels_like_C":17.3,"dew_point_C":15.3}`
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != want {
+		t.Errorf("answer = %d %s\nwant 200 %s", rec.Code, rec.Body, want)
+	}
+	if got := rec.Header().Get(httpapi.ObservedAtHeader); got != "2025-10-14T08:00:00Z" {
+		t.Errorf("%s = %q", httpapi.ObservedAtHeader, got)
+	}
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	dir := t.TempDir()
+	var calls int
+	upstream := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"ok":true}`)),
+			Request:    r,
+		}, nil
+	})
+
+	record, err := NewTransport(ModeRecord, dir, upstream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&http.Client{Transport: record}).Get("http://api.weatherapi.com/v1/forecast.json?q=Recife&key=s3cret")
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if body, _ := io.ReadAll(resp.Body); string(body) != `{"ok":true}` {
+		t.Errorf("recorded body = %s", body)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "api.weatherapi.com-*.json"))
+	if len(files) != 1 {
+		t.Fatalf("fixtures = %v, want one", files)
+	}
+	data, _ := os.ReadFile(files[0])
+	if strings.Contains(string(data), "s3cret") {
+		t.Errorf("fixture has the API key:\n%s", data)
+	}
+
+	// Another key replays the same fixture without calling upstream
+	replay, err := NewTransport(ModeReplay, dir, upstream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = (&http.Client{Transport: replay}).Get("http://api.weatherapi.com/v1/forecast.json?key=other&q=Recife")
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if body, _ := io.ReadAll(resp.Body); string(body) != `{"ok":true}` || resp.StatusCode != http.StatusOK {
+		t.Errorf("replayed %d %s", resp.StatusCode, body)
+	}
+	if calls != 1 {
+		t.Errorf("upstream called %d times, want once", calls)
+	}
+}
+
+func TestNewTransportMode(t *testing.T) {
+	if _, err := NewTransport("rewind", t.TempDir(), nil); err == nil {
+		t.Error("unknown mode accepted")
+	}
+}
+
+// roundTripFunc adapts a function to http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
@@ -0,0 +1,43 @@
+package weather
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// cityAliases maps ViaCEP spellings WeatherAPI doesn't resolve, or resolves
+// to the wrong place, onto names it does. Keys are normalized with foldCity.
+var cityAliases = map[string]string{
+	"embu":             "Embu das Artes",
+	"parati":           "Paraty",
+	"sao joao del-rei": "Sao Joao del Rei",
+}
+
+// QueryName turns a ViaCEP city name into a WeatherAPI query: accents are
+// stripped, known aliases applied and the country appended so homonyms
+// abroad (e.g. "Santa Maria") don't win.
+func QueryName(city string) string {
+	folded := foldCity(city)
+	name, ok := cityAliases[folded]
+	if !ok {
+		name = stripAccents(strings.Join(strings.Fields(city), " "))
+	}
+	return name + ", Brazil"
+}
+
+func foldCity(city string) string {
+	return strings.ToLower(stripAccents(strings.Join(strings.Fields(city), " ")))
+}
+
+func stripAccents(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	out, _, err := transform.String(t, s)
+	if err != nil {
+		return s
+	}
+	return out
+}
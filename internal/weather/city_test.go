@@ -0,0 +1,62 @@
+package weather
+
+import "testing"
+
+func TestQueryName(t *testing.T) {
+	tests := []struct {
+		city string
+		want string
+	}{
+		{"São Paulo", "Sao Paulo, Brazil"},
+		{"Foz do Iguaçu", "Foz do Iguacu, Brazil"},
+		{"Santa Maria", "Santa Maria, Brazil"},
+		{"  Belo   Horizonte ", "Belo Horizonte, Brazil"},
+		// Apostrophes stay, the accents around them go
+		{"Santa Bárbara d'Oeste", "Santa Barbara d'Oeste, Brazil"},
+		{"Olho d'Água das Flores", "Olho d'Agua das Flores, Brazil"},
+		{"Pau D'Arco", "Pau D'Arco, Brazil"},
+		// Aliases match whatever the case, accents and spacing
+		{"Embu", "Embu das Artes, Brazil"},
+		{"EMBU", "Embu das Artes, Brazil"},
+		{"Parati", "Paraty, Brazil"},
+		{"São João del-Rei", "Sao Joao del Rei, Brazil"},
+		{"SÃO JOÃO  DEL-REI", "Sao Joao del Rei, Brazil"},
+		// A neighbour sharing part of an alias's name is left alone
+		{"Embu-Guaçu", "Embu-Guacu, Brazil"},
+		{"Paraty", "Paraty, Brazil"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.city, func(t *testing.T) {
+			if got := QueryName(tt.city); got != tt.want {
+				t.Errorf("QueryName(%q) = %q, want %q", tt.city, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFoldCity(t *testing.T) {
+	tests := []struct {
+		city string
+		want string
+	}{
+		{"São Paulo", "sao paulo"},
+		{" São \t Paulo\n", "sao paulo"},
+		{"SÃO JOÃO DEL-REI", "sao joao del-rei"},
+		{"Santa Bárbara d'Oeste", "santa barbara d'oeste"},
+		// Decomposed accents fold like precomposed ones
+		{"Sa\u0303o Paulo", "sao paulo"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := foldCity(tt.city); got != tt.want {
+			t.Errorf("foldCity(%q) = %q, want %q", tt.city, got, tt.want)
+		}
+	}
+
+	// An alias whose key isn't folded could never match
+	for key := range cityAliases {
+		if foldCity(key) != key {
+			t.Errorf("alias key %q is not folded: %q", key, foldCity(key))
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package weather
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const compareTimeout = 10 * time.Second
+
+// Provider returns the current weather for a city.
+type Provider interface {
+	Current(ctx context.Context, city string) (*Conditions, error)
+}
+
+// ComparingProvider answers from its primary provider and, in the
+// background, asks a secondary provider the same question to record how far
+// apart they are. The secondary never affects the response.
+type ComparingProvider struct {
+	primary       Provider
+	secondary     Provider
+	secondaryName string
+	enabled       func(ctx context.Context) bool
+	logger        *log.Logger
+	divergence    metric.Float64Histogram
+	comparisons   metric.Int64Counter
+}
+
+// NewComparingProvider compares primary against secondary whenever enabled
+// reports true for the request; a nil enabled always compares.
+func NewComparingProvider(primary, secondary Provider, secondaryName string, enabled func(ctx context.Context) bool, logger *log.Logger) *ComparingProvider {
+	meter := otel.Meter("github.com/offerni/weathercheck/internal/weather")
+	divergence, _ := meter.Float64Histogram("weather.provider.divergence",
+		metric.WithDescription("Absolute temperature difference between the primary and secondary provider"),
+		metric.WithUnit("Cel"))
+	comparisons, _ := meter.Int64Counter("weather.provider.comparisons",
+		metric.WithDescription("Provider comparisons, by outcome"))
+
+	return &ComparingProvider{
+		primary:       primary,
+		secondary:     secondary,
+		secondaryName: secondaryName,
+		enabled:       enabled,
+		logger:        logger,
+		divergence:    divergence,
+		comparisons:   comparisons,
+	}
+}
+
+// Current returns the primary provider's answer.
+func (p *ComparingProvider) Current(ctx context.Context, city string) (*Conditions, error) {
+	primary, err := p.primary.Current(ctx, city)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.enabled == nil || p.enabled(ctx) {
+		go p.compare(context.WithoutCancel(ctx), city, primary)
+	}
+	return primary, nil
+}
+
+func (p *ComparingProvider) compare(ctx context.Context, city string, primary *Conditions) {
+	ctx, cancel := context.WithTimeout(ctx, compareTimeout)
+	defer cancel()
+
+	secondary, err := p.secondary.Current(ctx, city)
+	if err != nil {
+		p.record(ctx, "secondary_error")
+		p.logger.Printf("Comparison provider %s failed for %s: %v", p.secondaryName, city, err)
+		return
+	}
+
+	if secondary.Location == "" {
+		p.record(ctx, "missing_fields")
+		p.logger.Printf("Comparison provider %s returned no location for %s", p.secondaryName, city)
+		return
+	}
+
+	delta := math.Abs(primary.TempC - secondary.TempC)
+	p.divergence.Record(ctx, delta, metric.WithAttributes(attribute.String("secondary", p.secondaryName)))
+	p.record(ctx, "ok")
+	p.logger.Printf("Provider comparison for %s: primary %.1f°C, %s %.1f°C (delta %.1f)",
+		city, primary.TempC, p.secondaryName, secondary.TempC, delta)
+}
+
+func (p *ComparingProvider) record(ctx context.Context, outcome string) {
+	p.comparisons.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("secondary", p.secondaryName),
+		attribute.String("outcome", outcome),
+	))
+}
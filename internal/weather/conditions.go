@@ -0,0 +1,84 @@
+package weather
+
+import "time"
+
+// Conditions is the provider-independent weather every provider maps its
+// answer into, always in metric units, so switching or mixing providers
+// never changes what the handlers see. A field the provider didn't report
+// keeps its zero value: Humidity 0 means unknown, Condition is
+// ConditionUnknown and Today is nil without a forecast.
+type Conditions struct {
+	// Location is the place name the provider matched, empty if it sent none.
+	Location  string
+	TempC     float64
+	Humidity  float64 // percent
+	WindKph   float64
+	Condition Condition
+	Today     *Day
+	// Hourly is the hourly forecast in order, from some time today on; it
+	// is empty when the provider sent none.
+	Hourly []Hour
+	// Provider names who answered: weatherapi, open-meteo or mock.
+	Provider string
+	// ObservedAt is when the provider last updated the current conditions,
+	// zero if it didn't say.
+	ObservedAt time.Time
+	// Coordinates are where the provider placed the location, nil if it
+	// didn't say.
+	Coordinates *Coordinates
+}
+
+// Coordinates are a latitude and longitude in degrees.
+type Coordinates struct {
+	Lat, Lon float64
+}
+
+// Day is one day of forecast.
+type Day struct {
+	MaxTempC     float64
+	MinTempC     float64
+	ChanceOfRain float64 // percent
+}
+
+// Hour is one hour of forecast.
+type Hour struct {
+	Start        time.Time
+	ChanceOfRain float64 // percent
+}
+
+// Condition is the sky condition, on a taxonomy coarse enough for every
+// provider's own codes to map onto it.
+type Condition string
+
+const (
+	ConditionUnknown      Condition = "unknown"
+	ConditionClear        Condition = "clear"
+	ConditionPartlyCloudy Condition = "partly_cloudy"
+	ConditionCloudy       Condition = "cloudy"
+	ConditionFog          Condition = "fog"
+	ConditionDrizzle      Condition = "drizzle"
+	ConditionRain         Condition = "rain"
+	ConditionSnow         Condition = "snow"
+	ConditionThunderstorm Condition = "thunderstorm"
+)
+
+// icons are the identifiers of the icon for each condition, named after
+// the usual weather icon sets so frontends can map them one to one.
+var icons = map[Condition]string{
+	ConditionClear:        "sun",
+	ConditionPartlyCloudy: "cloud-sun",
+	ConditionCloudy:       "cloud",
+	ConditionFog:          "fog",
+	ConditionDrizzle:      "cloud-drizzle",
+	ConditionRain:         "cloud-rain",
+	ConditionSnow:         "snowflake",
+	ConditionThunderstorm: "cloud-lightning",
+}
+
+// Icon returns the icon identifier for c; unknown conditions get "unknown".
+func (c Condition) Icon() string {
+	if icon, ok := icons[c]; ok {
+		return icon
+	}
+	return "unknown"
+}
@@ -0,0 +1,42 @@
+package weather
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrLocationNotFound is returned when a provider can't place the city.
+	ErrLocationNotFound = errors.New("no matching location")
+	// ErrUnauthorized is returned when the provider rejects the API key.
+	ErrUnauthorized = errors.New("weather API key rejected")
+	// ErrQuotaExceeded is returned when the API key ran out of calls.
+	ErrQuotaExceeded = errors.New("weather API quota exceeded")
+)
+
+// APIError is an error body returned by WeatherAPI, e.g.
+// {"error": {"code": 1006, "message": "No matching location found."}}.
+type APIError struct {
+	StatusCode int
+	Code       int    `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("weatherapi returned %d (code %d): %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Unwrap maps WeatherAPI's error codes onto the package's sentinel errors.
+func (e *APIError) Unwrap() error {
+	switch e.Code {
+	case 1006:
+		return ErrLocationNotFound
+	case 1002, 2006:
+		return ErrUnauthorized
+	// The 403s for a key over its monthly calls and for a key disabled
+	// until its quota resets
+	case 2007, 2008:
+		return ErrQuotaExceeded
+	}
+	return nil
+}
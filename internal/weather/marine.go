@@ -0,0 +1,125 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	// Tide times come in the place's local time, and the service images
+	// ship no zoneinfo
+	_ "time/tzdata"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Marine is the sea state off a coastal city: today's tides and the swell
+// for the current hour, nil when the provider sent none.
+type Marine struct {
+	Location string
+	Tides    []Tide
+	Swell    *Swell
+}
+
+// Tide is one high or low tide.
+type Tide struct {
+	Time    time.Time
+	HeightM float64
+	High    bool
+}
+
+// Swell is the sea swell at a given hour.
+type Swell struct {
+	HeightM    float64
+	PeriodSecs float64
+	// Direction is where the swell comes from, on a 16-point compass
+	Direction string
+}
+
+// marineResponse is the part of WeatherAPI's marine answer we use
+type marineResponse struct {
+	Location struct {
+		Name string `json:"name"`
+		TzID string `json:"tz_id"`
+	} `json:"location"`
+	Forecast struct {
+		Forecastday []struct {
+			Day struct {
+				Tides []struct {
+					Tide []struct {
+						Time   string `json:"tide_time"`
+						Height string `json:"tide_height_mt"`
+						Type   string `json:"tide_type"`
+					} `json:"tide"`
+				} `json:"tides"`
+			} `json:"day"`
+			Hour []struct {
+				TimeEpoch  int64   `json:"time_epoch"`
+				HeightM    float64 `json:"swell_ht_mt"`
+				PeriodSecs float64 `json:"swell_period_secs"`
+				Direction  string  `json:"swell_dir_16_point"`
+			} `json:"hour"`
+		} `json:"forecastday"`
+	} `json:"forecast"`
+}
+
+// marine maps the answer onto Marine, taking the swell of the hour now
+// falls in
+func (r *marineResponse) marine(now time.Time) (*Marine, error) {
+	m := &Marine{Location: r.Location.Name}
+	if len(r.Forecast.Forecastday) == 0 {
+		return m, nil
+	}
+	day := r.Forecast.Forecastday[0]
+
+	loc, err := time.LoadLocation(r.Location.TzID)
+	if err != nil {
+		return nil, fmt.Errorf("weatherapi returned an unknown time zone %q", r.Location.TzID)
+	}
+	for _, tides := range day.Day.Tides {
+		for _, tide := range tides.Tide {
+			at, err := time.ParseInLocation("2006-01-02 15:04", tide.Time, loc)
+			if err != nil {
+				return nil, fmt.Errorf("weatherapi returned an invalid tide time %q", tide.Time)
+			}
+			height, err := strconv.ParseFloat(tide.Height, 64)
+			if err != nil {
+				return nil, fmt.Errorf("weatherapi returned an invalid tide height %q", tide.Height)
+			}
+			m.Tides = append(m.Tides, Tide{Time: at.UTC(), HeightM: height, High: strings.EqualFold(tide.Type, "high")})
+		}
+	}
+
+	current := now.Truncate(time.Hour).Unix()
+	for _, hour := range day.Hour {
+		if hour.TimeEpoch == current {
+			m.Swell = &Swell{HeightM: hour.HeightM, PeriodSecs: hour.PeriodSecs, Direction: hour.Direction}
+		}
+	}
+	return m, nil
+}
+
+// Marine returns today's tides and the current swell off city, which must
+// be on the coast.
+func (c *Client) Marine(ctx context.Context, city string) (*Marine, error) {
+	ctx, span := c.tracer.Start(ctx, "get-marine")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("city", city))
+
+	var resp marineResponse
+	query := url.Values{"q": {QueryName(city)}, "days": {"1"}, "tides": {"yes"}}
+	if err := c.get(ctx, "marine.json", query, &resp); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	m, err := resp.marine(time.Now())
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("marine.tides", len(m.Tides)))
+	return m, nil
+}
@@ -0,0 +1,184 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// OpenMeteoClient queries Open-Meteo, which needs no API key but works on
+// coordinates, so each lookup geocodes the city first.
+type OpenMeteoClient struct {
+	httpClient *http.Client
+	tracer     oteltrace.Tracer
+}
+
+func NewOpenMeteoClient(httpClient *http.Client, tracer oteltrace.Tracer) *OpenMeteoClient {
+	return &OpenMeteoClient{httpClient: httpClient, tracer: tracer}
+}
+
+// Current returns the current weather and today's forecast for city.
+func (c *OpenMeteoClient) Current(ctx context.Context, city string) (*Conditions, error) {
+	ctx, span := c.tracer.Start(ctx, "get-weather-open-meteo")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("city", city))
+
+	place, err := c.Geocode(ctx, city)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	var forecast openMeteoForecast
+	// Units are asked for explicitly rather than relying on the defaults
+	forecastURL := fmt.Sprintf("https://api.open-meteo.com/v1/forecast?latitude=%f&longitude=%f"+
+		"&current=temperature_2m,relative_humidity_2m,wind_speed_10m,weather_code"+
+		"&daily=temperature_2m_max,temperature_2m_min,precipitation_probability_max&hourly=precipitation_probability"+
+		"&forecast_days=2&timezone=auto&timeformat=unixtime&temperature_unit=celsius&wind_speed_unit=kmh",
+		place.Latitude, place.Longitude)
+	if err := c.getJSON(ctx, forecastURL, &forecast); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	conditions, err := forecast.conditions(place.Name)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	conditions.Coordinates = &Coordinates{Lat: place.Latitude, Lon: place.Longitude}
+
+	span.SetAttributes(attribute.Float64("temperature.celsius", conditions.TempC))
+	return conditions, nil
+}
+
+// openMeteoForecast is the part of Open-Meteo's forecast answer we use.
+// Pointers tell missing fields from zero values.
+type openMeteoForecast struct {
+	Current struct {
+		Time        int64    `json:"time"`
+		Temperature *float64 `json:"temperature_2m"`
+		Humidity    float64  `json:"relative_humidity_2m"`
+		WindSpeed   float64  `json:"wind_speed_10m"`
+		WeatherCode *int     `json:"weather_code"`
+	} `json:"current"`
+	Daily struct {
+		MaxTemp      []float64 `json:"temperature_2m_max"`
+		MinTemp      []float64 `json:"temperature_2m_min"`
+		ChanceOfRain []float64 `json:"precipitation_probability_max"`
+	} `json:"daily"`
+	Hourly struct {
+		Time         []int64    `json:"time"`
+		ChanceOfRain []*float64 `json:"precipitation_probability"`
+	} `json:"hourly"`
+}
+
+// conditions maps the forecast for location onto the canonical model,
+// failing without a current temperature.
+func (f *openMeteoForecast) conditions(location string) (*Conditions, error) {
+	if f.Current.Temperature == nil {
+		return nil, fmt.Errorf("open-meteo returned no current temperature")
+	}
+
+	c := &Conditions{
+		Location:  location,
+		TempC:     *f.Current.Temperature,
+		Humidity:  f.Current.Humidity,
+		WindKph:   f.Current.WindSpeed,
+		Condition: ConditionUnknown,
+		Provider:  "open-meteo",
+	}
+	if f.Current.Time > 0 {
+		c.ObservedAt = time.Unix(f.Current.Time, 0).UTC()
+	}
+	if f.Current.WeatherCode != nil {
+		c.Condition = wmoCondition(*f.Current.WeatherCode)
+	}
+	if daily := f.Daily; len(daily.MaxTemp) > 0 && len(daily.MinTemp) > 0 {
+		c.Today = &Day{MaxTempC: daily.MaxTemp[0], MinTempC: daily.MinTemp[0]}
+		// Some places have no precipitation model; no chance is the default
+		if len(daily.ChanceOfRain) > 0 {
+			c.Today.ChanceOfRain = daily.ChanceOfRain[0]
+		}
+	}
+	for i, start := range f.Hourly.Time {
+		hour := Hour{Start: time.Unix(start, 0).UTC()}
+		if i < len(f.Hourly.ChanceOfRain) && f.Hourly.ChanceOfRain[i] != nil {
+			hour.ChanceOfRain = *f.Hourly.ChanceOfRain[i]
+		}
+		c.Hourly = append(c.Hourly, hour)
+	}
+	return c, nil
+}
+
+// wmoCondition maps the WMO weather interpretation codes Open-Meteo uses.
+func wmoCondition(code int) Condition {
+	switch {
+	case code == 0:
+		return ConditionClear
+	case code == 1 || code == 2:
+		return ConditionPartlyCloudy
+	case code == 3:
+		return ConditionCloudy
+	case code == 45 || code == 48:
+		return ConditionFog
+	case code >= 51 && code <= 57:
+		return ConditionDrizzle
+	case code >= 61 && code <= 67, code >= 80 && code <= 82:
+		return ConditionRain
+	case code >= 71 && code <= 77, code == 85 || code == 86:
+		return ConditionSnow
+	case code >= 95 && code <= 99:
+		return ConditionThunderstorm
+	default:
+		return ConditionUnknown
+	}
+}
+
+// Place is a geocoded city.
+type Place struct {
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Geocode places city, preferring Brazilian matches, or fails with
+// ErrLocationNotFound.
+func (c *OpenMeteoClient) Geocode(ctx context.Context, city string) (*Place, error) {
+	var places struct {
+		Results []Place `json:"results"`
+	}
+	geoURL := "https://geocoding-api.open-meteo.com/v1/search?count=1&language=pt&countryCode=BR&name=" + url.QueryEscape(city)
+	if err := c.getJSON(ctx, geoURL, &places); err != nil {
+		return nil, err
+	}
+	if len(places.Results) == 0 {
+		return nil, ErrLocationNotFound
+	}
+	return &places.Results[0], nil
+}
+
+func (c *OpenMeteoClient) getJSON(ctx context.Context, rawURL string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("open-meteo returned %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,85 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// QuotaGuard stops calling a provider whose quota ran out: once it answers
+// ErrQuotaExceeded, calls fail right away with that error for cooldown, and
+// the first call after it checks whether the quota is back. Callers can then
+// serve what they have cached instead of spending requests that will all be
+// refused until the quota resets.
+type QuotaGuard struct {
+	next     Provider
+	cooldown time.Duration
+	logger   *log.Logger
+
+	mu    sync.Mutex
+	until time.Time
+}
+
+// NewQuotaGuard wraps next, holding calls off for cooldown after next
+// reports its quota exceeded.
+func NewQuotaGuard(next Provider, cooldown time.Duration, logger *log.Logger) *QuotaGuard {
+	g := &QuotaGuard{next: next, cooldown: cooldown, logger: logger}
+
+	meter := otel.Meter("github.com/offerni/weathercheck/internal/weather")
+	exhausted, _ := meter.Int64ObservableGauge("weather.provider.quota_exceeded",
+		metric.WithDescription("1 while the weather provider's quota is exhausted and calls are held off"))
+	meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		var v int64
+		if g.Exhausted() {
+			v = 1
+		}
+		o.ObserveInt64(exhausted, v)
+		return nil
+	}, exhausted)
+
+	return g
+}
+
+// Exhausted reports whether calls are being held off.
+func (g *QuotaGuard) Exhausted() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return time.Now().Before(g.until)
+}
+
+// Current returns next's answer unless the quota is known to be exhausted.
+func (g *QuotaGuard) Current(ctx context.Context, city string) (*Conditions, error) {
+	g.mu.Lock()
+	until := g.until
+	g.mu.Unlock()
+	if time.Now().Before(until) {
+		oteltrace.SpanFromContext(ctx).AddEvent("breaker_open", oteltrace.WithAttributes(
+			attribute.String("provider", "weather"),
+			attribute.String("until", until.UTC().Format(time.RFC3339)),
+		))
+		return nil, fmt.Errorf("holding calls off until %s: %w", until.Format(time.RFC3339), ErrQuotaExceeded)
+	}
+
+	data, err := g.next.Current(ctx, city)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	switch {
+	case errors.Is(err, ErrQuotaExceeded):
+		if !time.Now().Before(g.until) {
+			g.logger.Printf("Weather provider quota exceeded, holding calls off for %s", g.cooldown)
+		}
+		g.until = time.Now().Add(g.cooldown)
+	case err == nil && !g.until.IsZero():
+		g.logger.Printf("Weather provider quota is back")
+		g.until = time.Time{}
+	}
+	return data, err
+}
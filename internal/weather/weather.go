@@ -0,0 +1,186 @@
+// Package weather fetches current conditions and today's forecast from
+// WeatherAPI or Open-Meteo.
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// weatherAPIResponse is the part of WeatherAPI's forecast answer we use.
+// Pointers tell missing fields from zero values.
+type weatherAPIResponse struct {
+	Location struct {
+		Name string   `json:"name"`
+		Lat  *float64 `json:"lat"`
+		Lon  *float64 `json:"lon"`
+	} `json:"location"`
+	Current struct {
+		LastUpdated int64    `json:"last_updated_epoch"`
+		TempC       *float64 `json:"temp_c"`
+		Humidity    float64  `json:"humidity"`
+		WindKph     float64  `json:"wind_kph"`
+		Condition   struct {
+			Code int `json:"code"`
+		} `json:"condition"`
+	} `json:"current"`
+	Forecast struct {
+		Forecastday []struct {
+			Day struct {
+				MaxTempC     float64 `json:"maxtemp_c"`
+				MinTempC     float64 `json:"mintemp_c"`
+				ChanceOfRain float64 `json:"daily_chance_of_rain"`
+			} `json:"day"`
+			Hour []struct {
+				TimeEpoch    int64   `json:"time_epoch"`
+				ChanceOfRain float64 `json:"chance_of_rain"`
+			} `json:"hour"`
+		} `json:"forecastday"`
+	} `json:"forecast"`
+}
+
+// conditions maps the answer onto the canonical model, failing without a
+// current temperature since there is nothing to answer with then.
+func (r *weatherAPIResponse) conditions() (*Conditions, error) {
+	if r.Current.TempC == nil {
+		return nil, fmt.Errorf("weatherapi returned no current temperature")
+	}
+
+	c := &Conditions{
+		Location:  r.Location.Name,
+		TempC:     *r.Current.TempC,
+		Humidity:  r.Current.Humidity,
+		WindKph:   r.Current.WindKph,
+		Condition: weatherAPICondition(r.Current.Condition.Code),
+		Provider:  "weatherapi",
+	}
+	if r.Current.LastUpdated > 0 {
+		c.ObservedAt = time.Unix(r.Current.LastUpdated, 0).UTC()
+	}
+	if r.Location.Lat != nil && r.Location.Lon != nil {
+		c.Coordinates = &Coordinates{Lat: *r.Location.Lat, Lon: *r.Location.Lon}
+	}
+	if days := r.Forecast.Forecastday; len(days) > 0 {
+		day := days[0].Day
+		c.Today = &Day{MaxTempC: day.MaxTempC, MinTempC: day.MinTempC, ChanceOfRain: day.ChanceOfRain}
+	}
+	for _, day := range r.Forecast.Forecastday {
+		for _, hour := range day.Hour {
+			c.Hourly = append(c.Hourly, Hour{Start: time.Unix(hour.TimeEpoch, 0).UTC(), ChanceOfRain: hour.ChanceOfRain})
+		}
+	}
+	return c, nil
+}
+
+// weatherAPICondition maps WeatherAPI's condition codes
+// (https://www.weatherapi.com/docs/weather_conditions.json).
+func weatherAPICondition(code int) Condition {
+	switch code {
+	case 1000:
+		return ConditionClear
+	case 1003:
+		return ConditionPartlyCloudy
+	case 1006, 1009:
+		return ConditionCloudy
+	case 1030, 1135, 1147:
+		return ConditionFog
+	case 1072, 1150, 1153, 1168, 1171:
+		return ConditionDrizzle
+	case 1063, 1180, 1183, 1186, 1189, 1192, 1195, 1198, 1201, 1240, 1243, 1246:
+		return ConditionRain
+	case 1066, 1069, 1114, 1117, 1204, 1207, 1210, 1213, 1216, 1219, 1222, 1225, 1237, 1249, 1252, 1255, 1258, 1261, 1264:
+		return ConditionSnow
+	case 1087, 1273, 1276, 1279, 1282:
+		return ConditionThunderstorm
+	default:
+		return ConditionUnknown
+	}
+}
+
+// DefaultBaseURL is WeatherAPI's public API.
+const DefaultBaseURL = "http://api.weatherapi.com/v1/"
+
+// Client queries WeatherAPI with a single API key.
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+	tracer     oteltrace.Tracer
+}
+
+// NewClient builds a client for the WeatherAPI at baseURL, normally
+// DefaultBaseURL, or a local mock or mirror serving the same endpoints.
+func NewClient(httpClient *http.Client, baseURL, apiKey string, tracer oteltrace.Tracer) *Client {
+	return &Client{httpClient: httpClient, baseURL: strings.TrimRight(baseURL, "/"), apiKey: apiKey, tracer: tracer}
+}
+
+// Current returns the current weather and today's forecast for city.
+func (c *Client) Current(ctx context.Context, city string) (*Conditions, error) {
+	ctx, span := c.tracer.Start(ctx, "get-weather")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("city", city))
+
+	// The forecast endpoint answers with the current conditions too; two
+	// days of hours always cover the next 24
+	var weatherData weatherAPIResponse
+	query := url.Values{"q": {QueryName(city)}, "days": {"2"}, "aqi": {"no"}, "alerts": {"no"}}
+	if err := c.get(ctx, "forecast.json", query, &weatherData); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	conditions, err := weatherData.conditions()
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Float64("temperature.celsius", conditions.TempC))
+	return conditions, nil
+}
+
+// get calls the WeatherAPI endpoint with query and the API key, decoding
+// the answer into v or an error body into an *APIError
+func (c *Client) get(ctx context.Context, endpoint string, query url.Values, v any) error {
+	if c.apiKey == "" {
+		return fmt.Errorf("weather API key not configured")
+	}
+	query.Set("key", c.apiKey)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/"+endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		var errBody struct {
+			Error *APIError `json:"error"`
+		}
+		if json.Unmarshal(body, &errBody) == nil && errBody.Error != nil {
+			apiErr.Code, apiErr.Message = errBody.Error.Code, errBody.Error.Message
+		}
+		return apiErr
+	}
+	return json.Unmarshal(body, v)
+}
@@ -0,0 +1,130 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+)
+
+const forecastAnswer = `{
+  "location": {"name": "Sao Paulo", "lat": -23.53, "lon": -46.62},
+  "current": {"last_updated_epoch": 1700000000, "temp_c": 28.5, "humidity": 60, "wind_kph": 12.2, "condition": {"code": 1183}},
+  "forecast": {"forecastday": [{
+    "day": {"maxtemp_c": 31, "mintemp_c": 19, "daily_chance_of_rain": 80},
+    "hour": [{"time_epoch": 1700002800, "chance_of_rain": 40}, {"time_epoch": 1700006400, "chance_of_rain": 70}]
+  }]}
+}`
+
+// weatherAPI stands in for WeatherAPI's forecast endpoint, answering every
+// call with status and body
+func weatherAPI(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/forecast.json" {
+			t.Errorf("path = %s, want /v1/forecast.json", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("q"); got != "Sao Paulo, Brazil" {
+			t.Errorf("q = %q, want the query name", got)
+		}
+		if got := r.URL.Query().Get("key"); got != "k" {
+			t.Errorf("key = %q, want k", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClientCurrent(t *testing.T) {
+	srv := weatherAPI(t, http.StatusOK, forecastAnswer)
+	client := NewClient(srv.Client(), srv.URL+"/v1/", "k", otel.Tracer("test"))
+
+	got, err := client.Current(context.Background(), "São Paulo")
+	if err != nil {
+		t.Fatalf("Current: %v", err)
+	}
+	if got.Location != "Sao Paulo" || got.TempC != 28.5 || got.Humidity != 60 || got.WindKph != 12.2 {
+		t.Errorf("Current = %+v, want the current conditions", got)
+	}
+	if got.Condition != ConditionRain || got.Provider != "weatherapi" {
+		t.Errorf("condition = %s from %s, want rain from weatherapi", got.Condition, got.Provider)
+	}
+	if !got.ObservedAt.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("observed at %v", got.ObservedAt)
+	}
+	if got.Coordinates == nil || *got.Coordinates != (Coordinates{Lat: -23.53, Lon: -46.62}) {
+		t.Errorf("coordinates = %+v", got.Coordinates)
+	}
+	if got.Today == nil || *got.Today != (Day{MaxTempC: 31, MinTempC: 19, ChanceOfRain: 80}) {
+		t.Errorf("today = %+v", got.Today)
+	}
+	if len(got.Hourly) != 2 || got.Hourly[1].ChanceOfRain != 70 {
+		t.Errorf("hourly = %+v", got.Hourly)
+	}
+}
+
+func TestClientCurrentErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr error
+	}{
+		{"unknown location", http.StatusBadRequest, `{"error":{"code":1006,"message":"No matching location found."}}`, ErrLocationNotFound},
+		{"invalid key", http.StatusUnauthorized, `{"error":{"code":2006,"message":"API key is invalid."}}`, ErrUnauthorized},
+		{"missing key", http.StatusUnauthorized, `{"error":{"code":1002,"message":"API key not provided."}}`, ErrUnauthorized},
+		{"quota exceeded", http.StatusForbidden, `{"error":{"code":2007,"message":"API key has exceeded calls per month quota."}}`, ErrQuotaExceeded},
+		{"key disabled", http.StatusForbidden, `{"error":{"code":2008,"message":"API key has been disabled."}}`, ErrQuotaExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := weatherAPI(t, tt.status, tt.body)
+			client := NewClient(srv.Client(), srv.URL+"/v1", "k", otel.Tracer("test"))
+
+			_, err := client.Current(context.Background(), "São Paulo")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Current error = %v, want %v", err, tt.wantErr)
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status {
+				t.Errorf("error = %#v, want an APIError with status %d", err, tt.status)
+			}
+		})
+	}
+}
+
+func TestClientCurrentUnusable(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"no current temperature", http.StatusOK, `{"location":{"name":"Sao Paulo"},"current":{"humidity":60}}`},
+		{"garbled answer", http.StatusOK, `{"current":`},
+		{"server error without a body", http.StatusInternalServerError, ``},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := weatherAPI(t, tt.status, tt.body)
+			client := NewClient(srv.Client(), srv.URL+"/v1", "k", otel.Tracer("test"))
+
+			got, err := client.Current(context.Background(), "São Paulo")
+			if err == nil {
+				t.Fatalf("Current = %+v, want an error", got)
+			}
+			for _, sentinel := range []error{ErrLocationNotFound, ErrUnauthorized, ErrQuotaExceeded} {
+				if errors.Is(err, sentinel) {
+					t.Errorf("error %v is %v", err, sentinel)
+				}
+			}
+		})
+	}
+}
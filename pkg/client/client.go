@@ -0,0 +1,364 @@
+// Package client is a typed Go client for the service-a weather API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultMaxRetries    = 2
+	defaultBackoff       = 200 * time.Millisecond
+	defaultTimeout       = 10 * time.Second
+	defaultBatchParallel = 4
+	instrumentationScope = "github.com/offerni/weathercheck/pkg/client"
+)
+
+// Weather is the current weather for the city a CEP resolves to.
+type Weather struct {
+	City  string  `json:"city"`
+	TempC float64 `json:"temp_C"`
+
+	// Nil when the scale isn't among the units asked for; see WithUnits
+	TempF *float64 `json:"temp_F,omitempty"`
+	TempK *float64 `json:"temp_K,omitempty"`
+	TempR *float64 `json:"temp_R,omitempty"`
+
+	// Condition is the sky condition, such as "rain", and Icon the
+	// identifier of its icon; see the OpenAPI spec for every value
+	Condition string `json:"condition"`
+	Icon      string `json:"icon"`
+
+	// Nil when the provider doesn't report humidity
+	FeelsLikeC *float64 `json:"feels_like_C,omitempty"`
+	DewPointC  *float64 `json:"dew_point_C,omitempty"`
+
+	// Approximate is set when the city was inferred from the CEP's prefix
+	Approximate bool `json:"approximate,omitempty"`
+
+	// Degraded is set when the CEP resolved but current weather wasn't
+	// available, which the API only answers with WithDegradedAnswers. Only
+	// City and Approximate are then set, and LastReading when the service
+	// had served the city before.
+	Degraded    bool     `json:"-"`
+	LastReading *Reading `json:"last_reading,omitempty"`
+}
+
+// Reading is a set of temperatures served earlier for a city.
+type Reading struct {
+	TempC      float64   `json:"temp_C"`
+	TempF      *float64  `json:"temp_F,omitempty"`
+	TempK      *float64  `json:"temp_K,omitempty"`
+	TempR      *float64  `json:"temp_R,omitempty"`
+	ObservedAt time.Time `json:"observed_at"`
+}
+
+// weatherAnswer is either answer /weather gives, a full or a degraded one
+type weatherAnswer struct {
+	Weather
+	WeatherAvailable *bool `json:"weather_available"`
+}
+
+// Forecast tells whether it will rain at a CEP in the next hours.
+type Forecast struct {
+	City  string `json:"city"`
+	Hours int    `json:"hours"`
+	// Threshold is the chance of rain, in percent, from which an hour
+	// counts as rainy
+	Threshold float64 `json:"threshold"`
+	WillRain  bool    `json:"will_rain"`
+	// Window is the first rainy run of hours; nil when no rain is expected
+	Window *RainWindow `json:"window,omitempty"`
+}
+
+// RainWindow is a run of consecutive hours whose chance of rain reaches the
+// forecast's threshold.
+type RainWindow struct {
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end"`
+	MaxChanceOfRain float64   `json:"max_chance_of_rain"`
+}
+
+// FieldError describes a single invalid field in a rejected request.
+type FieldError struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// Error is returned when the API answers with a non-2xx status.
+type Error struct {
+	StatusCode int          `json:"-"`
+	Message    string       `json:"message"`
+	Code       string       `json:"code,omitempty"`
+	TraceID    string       `json:"trace_id,omitempty"`
+	Errors     []FieldError `json:"errors,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.TraceID != "" {
+		return fmt.Sprintf("weathercheck: %d %s (trace %s)", e.StatusCode, e.Message, e.TraceID)
+	}
+	return fmt.Sprintf("weathercheck: %d %s", e.StatusCode, e.Message)
+}
+
+// BatchResult holds the outcome of one lookup in a Batch call.
+type BatchResult struct {
+	CEP     string
+	Weather *Weather
+	Err     error
+}
+
+// Client calls the service-a API. It is safe for concurrent use.
+type Client struct {
+	baseURL       string
+	httpClient    *http.Client
+	maxRetries    int
+	backoff       time.Duration
+	batchParallel int
+	units         string
+	degraded      bool
+	tracer        oteltrace.Tracer
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient replaces the default instrumented HTTP client.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithRetries sets how many times a failed request is retried and the base
+// delay between attempts, which doubles after each retry.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+	}
+}
+
+// WithBatchParallelism limits how many lookups a Batch call runs at once.
+func WithBatchParallelism(n int) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.batchParallel = n
+		}
+	}
+}
+
+// WithUnits asks for the temperatures in units, any of "C", "F", "K" and
+// "R", instead of the service's default. Celsius is always included.
+func WithUnits(units ...string) Option {
+	return func(c *Client) { c.units = strings.Join(units, ",") }
+}
+
+// WithDegradedAnswers accepts answers without current weather when only
+// the CEP can be resolved, reported as Weather.Degraded, instead of an
+// error.
+func WithDegradedAnswers() Option {
+	return func(c *Client) { c.degraded = true }
+}
+
+// New returns a Client for the service-a instance at baseURL, for example
+// "http://localhost:8080".
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+			Timeout:   defaultTimeout,
+		},
+		maxRetries:    defaultMaxRetries,
+		backoff:       defaultBackoff,
+		batchParallel: defaultBatchParallel,
+		tracer:        otel.Tracer(instrumentationScope),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// GetWeather returns the current weather for cep.
+func (c *Client) GetWeather(ctx context.Context, cep string) (*Weather, error) {
+	ctx, span := c.tracer.Start(ctx, "weathercheck.GetWeather")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("cep", cep))
+
+	body, err := json.Marshal(struct {
+		CEP string `json:"cep"`
+	}{CEP: cep})
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	if c.units != "" {
+		query.Set("units", c.units)
+	}
+	if c.degraded {
+		query.Set("degraded", "true")
+	}
+	path := "/weather"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var answer weatherAnswer
+	if err := c.do(ctx, http.MethodPost, path, body, &answer); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	weather := answer.Weather
+	weather.Degraded = answer.WeatherAvailable != nil && !*answer.WeatherAvailable
+	span.SetAttributes(attribute.Bool("weather.degraded", weather.Degraded))
+	return &weather, nil
+}
+
+// GetForecast returns whether it will rain at cep within the next hours,
+// counting the current one; hours of 0 or less uses the service's default.
+func (c *Client) GetForecast(ctx context.Context, cep string, hours int) (*Forecast, error) {
+	ctx, span := c.tracer.Start(ctx, "weathercheck.GetForecast")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("cep", cep), attribute.Int("forecast.hours", hours))
+
+	path := "/rain/" + url.PathEscape(cep)
+	if hours > 0 {
+		path += "?hours=" + strconv.Itoa(hours)
+	}
+
+	var forecast Forecast
+	if err := c.do(ctx, http.MethodGet, path, nil, &forecast); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	return &forecast, nil
+}
+
+// Batch looks up every CEP concurrently and returns one result per input,
+// in the same order.
+func (c *Client) Batch(ctx context.Context, ceps []string) []BatchResult {
+	ctx, span := c.tracer.Start(ctx, "weathercheck.Batch")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("batch.size", len(ceps)))
+
+	results := make([]BatchResult, len(ceps))
+	sem := make(chan struct{}, c.batchParallel)
+
+	var wg sync.WaitGroup
+	for i, cep := range ceps {
+		wg.Add(1)
+		go func(i int, cep string) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			weather, err := c.GetWeather(ctx, cep)
+			results[i] = BatchResult{CEP: cep, Weather: weather, Err: err}
+		}(i, cep)
+	}
+	wg.Wait()
+
+	return results
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body []byte, out any) error {
+	var lastErr error
+
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		if attempt > 0 {
+			delay := c.backoff << (attempt - 1)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		lastErr = c.attempt(ctx, method, path, body, out)
+		if lastErr == nil || !retryable(lastErr) {
+			return lastErr
+		}
+	}
+
+	return lastErr
+}
+
+func (c *Client) attempt(ctx context.Context, method, path string, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		if err := json.Unmarshal(data, apiErr); err != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		if apiErr.TraceID == "" {
+			apiErr.TraceID = resp.Header.Get("X-Trace-Id")
+		}
+		return apiErr
+	}
+
+	return json.Unmarshal(data, out)
+}
+
+// retryable reports whether err is worth another attempt: transport failures
+// and 5xx responses are, client errors and cancellations are not.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,322 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// api stands in for service A, answering each request with handle
+func api(t *testing.T, handle http.HandlerFunc) (*Client, *httptest.Server) {
+	t.Helper()
+	srv := httptest.NewServer(handle)
+	t.Cleanup(srv.Close)
+	return New(srv.URL, WithHTTPClient(srv.Client()), WithRetries(2, time.Millisecond)), srv
+}
+
+func ptr(v float64) *float64 { return &v }
+
+func TestGetWeather(t *testing.T) {
+	observed := time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		opts      []Option
+		wantQuery string
+		answer    string
+		want      Weather
+	}{
+		{
+			name:   "default units",
+			answer: `{"city":"São Paulo","temp_C":17.3,"temp_F":63.1,"temp_K":290.45,"condition":"rain","icon":"cloud-rain","feels_like_C":17.3,"dew_point_C":15.3}`,
+			want:   Weather{City: "São Paulo", TempC: 17.3, TempF: ptr(63.1), TempK: ptr(290.45), Condition: "rain", Icon: "cloud-rain", FeelsLikeC: ptr(17.3), DewPointC: ptr(15.3)},
+		},
+		{
+			// Scales left out are nil, not zero
+			name:      "Celsius only",
+			opts:      []Option{WithUnits("C")},
+			wantQuery: "units=C",
+			answer:    `{"city":"Bauru","temp_C":25,"condition":"clear","icon":"sun"}`,
+			want:      Weather{City: "Bauru", TempC: 25, Condition: "clear", Icon: "sun"},
+		},
+		{
+			name:      "Rankine",
+			opts:      []Option{WithUnits("C", "R")},
+			wantQuery: "units=C%2CR",
+			answer:    `{"city":"Bauru","temp_C":25,"temp_R":536.67,"condition":"clear","icon":"sun"}`,
+			want:      Weather{City: "Bauru", TempC: 25, TempR: ptr(536.67), Condition: "clear", Icon: "sun"},
+		},
+		{
+			name:      "degraded",
+			opts:      []Option{WithDegradedAnswers()},
+			wantQuery: "degraded=true",
+			answer:    `{"city":"Bauru","weather_available":false,"last_reading":{"temp_C":25,"temp_F":77,"observed_at":"2026-10-14T08:00:00Z"}}`,
+			want:      Weather{City: "Bauru", Degraded: true, LastReading: &Reading{TempC: 25, TempF: ptr(77), ObservedAt: observed}},
+		},
+		{
+			name:      "degraded without a last reading",
+			opts:      []Option{WithDegradedAnswers(), WithUnits("C", "F")},
+			wantQuery: "degraded=true&units=C%2CF",
+			answer:    `{"city":"Bauru","weather_available":false,"approximate":true}`,
+			want:      Weather{City: "Bauru", Degraded: true, Approximate: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := api(t, func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if r.Method != http.MethodPost || r.URL.Path != "/weather" || r.URL.RawQuery != tt.wantQuery {
+					t.Errorf("request = %s %s, want POST /weather?%s", r.Method, r.URL, tt.wantQuery)
+				}
+				if string(body) != `{"cep":"01001000"}` || r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("body = %s (%s)", body, r.Header.Get("Content-Type"))
+				}
+				io.WriteString(w, tt.answer)
+			})
+			for _, opt := range tt.opts {
+				opt(c)
+			}
+
+			got, err := c.GetWeather(context.Background(), "01001000")
+			if err != nil {
+				t.Fatalf("GetWeather: %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("GetWeather = %+v\nwant %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetForecast(t *testing.T) {
+	tests := []struct {
+		name     string
+		hours    int
+		wantPath string
+		answer   string
+		want     Forecast
+	}{
+		{
+			name:     "rain ahead",
+			hours:    3,
+			wantPath: "/rain/13015904?hours=3",
+			answer:   `{"city":"Campinas","hours":3,"threshold":50,"will_rain":true,"window":{"start":"2026-10-14T16:00:00Z","end":"2026-10-14T18:00:00Z","max_chance_of_rain":80}}`,
+			want: Forecast{City: "Campinas", Hours: 3, Threshold: 50, WillRain: true, Window: &RainWindow{
+				Start: time.Date(2026, 10, 14, 16, 0, 0, 0, time.UTC), End: time.Date(2026, 10, 14, 18, 0, 0, 0, time.UTC), MaxChanceOfRain: 80,
+			}},
+		},
+		{
+			name:     "service default",
+			wantPath: "/rain/13015904",
+			answer:   `{"city":"Campinas","hours":6,"threshold":50,"will_rain":false}`,
+			want:     Forecast{City: "Campinas", Hours: 6, Threshold: 50},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := api(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet || r.URL.RequestURI() != tt.wantPath {
+					t.Errorf("request = %s %s, want GET %s", r.Method, r.URL.RequestURI(), tt.wantPath)
+				}
+				if r.Header.Get("Content-Type") != "" {
+					t.Errorf("GET sent Content-Type %q", r.Header.Get("Content-Type"))
+				}
+				io.WriteString(w, tt.answer)
+			})
+			got, err := c.GetForecast(context.Background(), "13015904", tt.hours)
+			if err != nil {
+				t.Fatalf("GetForecast: %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("GetForecast = %+v\nwant %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		header  http.Header
+		answer  string
+		want    Error
+		wantMsg string
+	}{
+		{
+			name:    "API error",
+			status:  http.StatusNotFound,
+			answer:  `{"message":"can not find zipcode","code":"zipcode_not_found","trace_id":"abc123"}`,
+			want:    Error{StatusCode: 404, Message: "can not find zipcode", Code: "zipcode_not_found", TraceID: "abc123"},
+			wantMsg: "weathercheck: 404 can not find zipcode (trace abc123)",
+		},
+		{
+			name:   "field errors",
+			status: http.StatusUnprocessableEntity,
+			answer: `{"message":"invalid zipcode","code":"validation_failed","errors":[{"path":"/cep","reason":"must match ^\\d{8}$"}]}`,
+			want: Error{StatusCode: 422, Message: "invalid zipcode", Code: "validation_failed", Errors: []FieldError{
+				{Path: "/cep", Reason: `must match ^\d{8}$`},
+			}},
+			wantMsg: "weathercheck: 422 invalid zipcode",
+		},
+		{
+			name:    "trace ID from the header",
+			status:  http.StatusBadRequest,
+			header:  http.Header{"X-Trace-Id": {"def456"}},
+			answer:  `{"message":"bad request"}`,
+			want:    Error{StatusCode: 400, Message: "bad request", TraceID: "def456"},
+			wantMsg: "weathercheck: 400 bad request (trace def456)",
+		},
+		{
+			name:    "body that isn't JSON",
+			status:  http.StatusTooManyRequests,
+			answer:  "slow down\n",
+			want:    Error{StatusCode: 429, Message: "slow down"},
+			wantMsg: "weathercheck: 429 slow down",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := api(t, func(w http.ResponseWriter, r *http.Request) {
+				for name, values := range tt.header {
+					w.Header()[name] = values
+				}
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.answer)
+			})
+			_, err := c.GetWeather(context.Background(), "01001000")
+			var apiErr *Error
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("error = %v, want an *Error", err)
+			}
+			if !reflect.DeepEqual(*apiErr, tt.want) {
+				t.Errorf("error = %+v\nwant %+v", *apiErr, tt.want)
+			}
+			if err.Error() != tt.wantMsg {
+				t.Errorf("message = %q, want %q", err.Error(), tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []int
+		wantCalls int32
+		wantErr   int
+	}{
+		{"ok at once", []int{200}, 1, 0},
+		{"5xx then ok", []int{503, 502, 200}, 3, 0},
+		{"5xx every time", []int{500, 500, 500, 500}, 3, 500},
+		{"4xx isn't retried", []int{404, 200}, 1, 404},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			c, _ := api(t, func(w http.ResponseWriter, r *http.Request) {
+				status := tt.statuses[calls.Add(1)-1]
+				w.WriteHeader(status)
+				if status == http.StatusOK {
+					io.WriteString(w, `{"city":"Bauru","temp_C":25}`)
+					return
+				}
+				io.WriteString(w, `{"message":"failed"}`)
+			})
+
+			_, err := c.GetWeather(context.Background(), "01001000")
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+			var apiErr *Error
+			switch {
+			case tt.wantErr == 0 && err != nil:
+				t.Errorf("error = %v", err)
+			case tt.wantErr != 0 && (!errors.As(err, &apiErr) || apiErr.StatusCode != tt.wantErr):
+				t.Errorf("error = %v, want status %d", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRetriesTransportFailures(t *testing.T) {
+	// The first connection is dropped mid-answer, the retry succeeds
+	var calls atomic.Int32
+	c, _ := api(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		io.WriteString(w, `{"city":"Bauru","temp_C":25}`)
+	})
+	if _, err := c.GetWeather(context.Background(), "01001000"); err != nil {
+		t.Errorf("GetWeather: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want 2", calls.Load())
+	}
+
+	// A cancelled lookup isn't retried
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls.Store(1)
+	if _, err := c.GetWeather(ctx, "01001000"); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled GetWeather = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	c, _ := api(t, func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		var req struct {
+			CEP string `json:"cep"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if strings.HasPrefix(req.CEP, "9") {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"message":"can not find zipcode","code":"zipcode_not_found"}`)
+			return
+		}
+		io.WriteString(w, `{"city":"City `+req.CEP+`","temp_C":20}`)
+	})
+	WithBatchParallelism(2)(c)
+
+	ceps := []string{"01001000", "99999999", "13015904", "17055250", "90000000"}
+	results := c.Batch(context.Background(), ceps)
+	if len(results) != len(ceps) {
+		t.Fatalf("results = %d, want %d", len(results), len(ceps))
+	}
+	for i, r := range results {
+		if r.CEP != ceps[i] {
+			t.Errorf("results[%d].CEP = %s, want %s", i, r.CEP, ceps[i])
+		}
+		var apiErr *Error
+		switch notFound := strings.HasPrefix(r.CEP, "9"); {
+		case notFound && (!errors.As(r.Err, &apiErr) || apiErr.Code != "zipcode_not_found" || r.Weather != nil):
+			t.Errorf("%s: result = %+v, want zipcode_not_found", r.CEP, r)
+		case !notFound && (r.Err != nil || r.Weather == nil || r.Weather.City != "City "+r.CEP):
+			t.Errorf("%s: result = %+v, %v", r.CEP, r.Weather, r.Err)
+		}
+	}
+	if p := peak.Load(); p > 2 {
+		t.Errorf("%d lookups ran at once, want at most 2", p)
+	}
+}
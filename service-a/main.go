@@ -1,165 +0,0 @@
-package main
-
-import (
-	"bytes"
-	"context"
-	"encoding/json"
-	"fmt"
-	"io"
-	"log"
-	"net/http"
-	"regexp"
-
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
-	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/exporters/zipkin"
-	"go.opentelemetry.io/otel/sdk/resource"
-	"go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
-	oteltrace "go.opentelemetry.io/otel/trace"
-)
-
-type CEPRequest struct {
-	CEP string `json:"cep"`
-}
-
-type ErrorResponse struct {
-	Message string `json:"message"`
-}
-
-const serviceBURL = "http://service-b:8081/weather"
-
-var tracer oteltrace.Tracer
-
-func initTracer() func() {
-	// Create Zipkin exporter
-	exporter, err := zipkin.New("http://zipkin:9411/api/v2/spans")
-	if err != nil {
-		log.Fatalf("Failed to create Zipkin exporter: %v", err)
-	}
-
-	// Create resource
-	res, err := resource.New(context.Background(),
-		resource.WithAttributes(
-			semconv.ServiceNameKey.String("service-a"),
-			semconv.ServiceVersionKey.String("1.0.0"),
-		),
-	)
-	if err != nil {
-		log.Fatalf("Failed to create resource: %v", err)
-	}
-
-	// Create tracer provider
-	tp := trace.NewTracerProvider(
-		trace.WithBatcher(exporter),
-		trace.WithResource(res),
-	)
-
-	otel.SetTracerProvider(tp)
-	tracer = otel.Tracer("service-a")
-
-	return func() {
-		if err := tp.Shutdown(context.Background()); err != nil {
-			log.Printf("Error shutting down tracer provider: %v", err)
-		}
-	}
-}
-
-func validateCEP(cep string) bool {
-	// Check if CEP has exactly 8 digits
-	matched, _ := regexp.MatchString(`^\d{8}$`, cep)
-	return matched
-}
-
-func weatherHandler(w http.ResponseWriter, r *http.Request) {
-	ctx, span := tracer.Start(r.Context(), "weather-handler")
-	defer span.End()
-
-	// Parse request body
-	var req CEPRequest
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		span.RecordError(err)
-		http.Error(w, "Failed to read request body", http.StatusBadRequest)
-		return
-	}
-
-	if err := json.Unmarshal(body, &req); err != nil {
-		span.RecordError(err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusUnprocessableEntity)
-		json.NewEncoder(w).Encode(ErrorResponse{Message: "invalid zipcode"})
-		return
-	}
-
-	// Validate CEP format
-	if !validateCEP(req.CEP) {
-		span.SetAttributes(attribute.String("cep.invalid", req.CEP))
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusUnprocessableEntity)
-		json.NewEncoder(w).Encode(ErrorResponse{Message: "invalid zipcode"})
-		return
-	}
-
-	span.SetAttributes(attribute.String("cep.valid", req.CEP))
-
-	// Forward to Service B
-	forwardCtx, forwardSpan := tracer.Start(ctx, "forward-to-service-b")
-	defer forwardSpan.End()
-
-	client := &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
-	reqBody, _ := json.Marshal(req)
-
-	httpReq, err := http.NewRequestWithContext(forwardCtx, "POST", serviceBURL, bytes.NewBuffer(reqBody))
-	if err != nil {
-		forwardSpan.RecordError(err)
-		http.Error(w, "Failed to create request", http.StatusInternalServerError)
-		return
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	resp, err := client.Do(httpReq)
-	if err != nil {
-		forwardSpan.RecordError(err)
-		http.Error(w, "Failed to forward request", http.StatusInternalServerError)
-		return
-	}
-	defer resp.Body.Close()
-
-	// Copy response from Service B
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(resp.StatusCode)
-	io.Copy(w, resp.Body)
-}
-
-func main() {
-	// Initialize tracing
-	shutdown := initTracer()
-	defer shutdown()
-
-	// Setup Chi router
-	r := chi.NewRouter()
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
-
-	// Add OpenTelemetry middleware
-	r.Use(func(next http.Handler) http.Handler {
-		return otelhttp.NewHandler(next, "service-a")
-	})
-
-	// Routes
-	r.Post("/weather", weatherHandler)
-
-	// Health check
-	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	})
-
-	fmt.Println("Service A starting on port 8080")
-	log.Fatal(http.ListenAndServe(":8080", r))
-}